@@ -0,0 +1,19 @@
+-- Create sessions table
+-- One row per login (refresh token family). refresh_jti is the only refresh
+-- token of the family that is still valid; it changes on every refresh.
+CREATE TABLE sessions (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    refresh_jti     UUID NOT NULL,
+    user_agent      TEXT,
+    ip_address      VARCHAR(64),
+    created_at      TIMESTAMPTZ DEFAULT NOW(),
+    last_used_at    TIMESTAMPTZ DEFAULT NOW(),
+    expires_at      TIMESTAMPTZ NOT NULL,
+    revoked_at      TIMESTAMPTZ,
+    revoked_reason  VARCHAR(50)
+);
+
+-- Index for listing/revoking a user's active sessions
+CREATE INDEX idx_sessions_user_active ON sessions(user_id)
+    WHERE revoked_at IS NULL;
@@ -0,0 +1,17 @@
+-- Create user_tokens table
+-- Single-use tokens sent by email. Only a SHA-256 hash of the token is stored.
+CREATE TABLE user_tokens (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose     VARCHAR(30) NOT NULL,
+    token_hash  VARCHAR(64) UNIQUE NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    used_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT chk_user_token_purpose CHECK (purpose IN ('password_reset'))
+);
+
+-- Index for invalidating a user's outstanding tokens
+CREATE INDEX idx_user_tokens_user_purpose ON user_tokens(user_id, purpose)
+    WHERE used_at IS NULL;
@@ -0,0 +1,7 @@
+DELETE FROM user_tokens WHERE purpose = 'email_verification';
+
+ALTER TABLE user_tokens DROP CONSTRAINT chk_user_token_purpose;
+ALTER TABLE user_tokens ADD CONSTRAINT chk_user_token_purpose
+    CHECK (purpose IN ('password_reset'));
+
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
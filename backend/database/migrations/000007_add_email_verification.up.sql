@@ -0,0 +1,9 @@
+-- Add email verification
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+-- Accounts created before verification existed are trusted as they are
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
+
+ALTER TABLE user_tokens DROP CONSTRAINT chk_user_token_purpose;
+ALTER TABLE user_tokens ADD CONSTRAINT chk_user_token_purpose
+    CHECK (purpose IN ('password_reset', 'email_verification'));
@@ -0,0 +1,15 @@
+-- Add TOTP two-factor authentication
+-- totp_secret is set at enrollment; 2FA is only active once totp_enabled_at is set
+ALTER TABLE users ADD COLUMN totp_secret VARCHAR(64);
+ALTER TABLE users ADD COLUMN totp_enabled_at TIMESTAMPTZ;
+
+-- One-time recovery codes (SHA-256 hashes) to sign in without the authenticator
+CREATE TABLE recovery_codes (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash   VARCHAR(64) NOT NULL,
+    used_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT uq_recovery_codes_user_code UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,20 @@
+-- Create webauthn_credentials table
+-- Passkeys registered by users for passwordless login
+CREATE TABLE webauthn_credentials (
+    id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id           UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    credential_id     BYTEA UNIQUE NOT NULL,
+    public_key        BYTEA NOT NULL,
+    attestation_type  VARCHAR(32) NOT NULL DEFAULT '',
+    aaguid            BYTEA,
+    sign_count        BIGINT NOT NULL DEFAULT 0,
+    transports        TEXT[] NOT NULL DEFAULT '{}',
+    backup_eligible   BOOLEAN NOT NULL DEFAULT false,
+    backup_state      BOOLEAN NOT NULL DEFAULT false,
+    name              VARCHAR(100) NOT NULL,
+    created_at        TIMESTAMPTZ DEFAULT NOW(),
+    last_used_at      TIMESTAMPTZ
+);
+
+-- Index for listing a user's passkeys
+CREATE INDEX idx_webauthn_credentials_user ON webauthn_credentials(user_id);
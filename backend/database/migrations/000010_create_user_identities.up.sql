@@ -0,0 +1,15 @@
+-- Create user_identities table
+-- Accounts at external identity providers (OpenID Connect) linked to users
+CREATE TABLE user_identities (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider        VARCHAR(50) NOT NULL,
+    subject         VARCHAR(255) NOT NULL,
+    email           VARCHAR(255),
+    created_at      TIMESTAMPTZ DEFAULT NOW(),
+    last_login_at   TIMESTAMPTZ,
+    UNIQUE(provider, subject)
+);
+
+-- Index for listing a user's linked identities
+CREATE INDEX idx_user_identities_user ON user_identities(user_id);
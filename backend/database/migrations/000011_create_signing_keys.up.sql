@@ -0,0 +1,13 @@
+-- Create signing_keys table
+-- Asymmetric JWT signing keys (private keys are encrypted with JWT_SECRET)
+CREATE TABLE signing_keys (
+    kid             VARCHAR(64) PRIMARY KEY,
+    algorithm       VARCHAR(16) NOT NULL,
+    private_key     BYTEA NOT NULL,
+    created_at      TIMESTAMPTZ DEFAULT NOW(),
+    activates_at    TIMESTAMPTZ NOT NULL,       -- Used for signing from then on (published before)
+    expires_at      TIMESTAMPTZ                 -- Set when superseded: end of the grace period
+);
+
+-- Index for finding the newest key of an algorithm
+CREATE INDEX idx_signing_keys_activates_at ON signing_keys(algorithm, activates_at DESC);
@@ -0,0 +1,17 @@
+-- Add administrators (grant with: UPDATE users SET is_admin = true WHERE email = '...')
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT false;
+
+-- Security audit log (lockouts, admin actions)
+CREATE TABLE audit_log (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    actor_id        UUID REFERENCES users(id) ON DELETE SET NULL,    -- NULL for system events
+    action          VARCHAR(50) NOT NULL,
+    target_user_id  UUID REFERENCES users(id) ON DELETE SET NULL,
+    target_email    VARCHAR(255),
+    ip_address      VARCHAR(45),
+    metadata        JSONB NOT NULL DEFAULT '{}',
+    created_at      TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Index for browsing the log by action
+CREATE INDEX idx_audit_log_action_created ON audit_log(action, created_at DESC);
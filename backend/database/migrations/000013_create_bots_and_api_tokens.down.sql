@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS api_tokens;
+
+DROP INDEX IF EXISTS idx_users_bot_owner;
+ALTER TABLE users DROP COLUMN IF EXISTS bot_owner_id;
+ALTER TABLE users DROP COLUMN IF EXISTS is_bot;
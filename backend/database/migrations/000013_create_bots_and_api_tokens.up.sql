@@ -0,0 +1,24 @@
+-- Bot accounts: users without a password, managed by their owner
+ALTER TABLE users ADD COLUMN is_bot BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN bot_owner_id UUID REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_users_bot_owner ON users(bot_owner_id) WHERE bot_owner_id IS NOT NULL;
+
+-- Create api_tokens table
+-- Long-lived bearer tokens of users and bots. Only a SHA-256 hash of the token is stored.
+CREATE TABLE api_tokens (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,   -- Who the token acts as
+    created_by    UUID REFERENCES users(id) ON DELETE SET NULL,
+    name          VARCHAR(100) NOT NULL,
+    token_prefix  VARCHAR(16) NOT NULL,                                   -- Shown to tell tokens apart
+    token_hash    VARCHAR(64) UNIQUE NOT NULL,
+    scopes        TEXT[] NOT NULL,
+    expires_at    TIMESTAMPTZ,                                            -- NULL = never
+    last_used_at  TIMESTAMPTZ,
+    revoked_at    TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Index for listing a user's tokens
+CREATE INDEX idx_api_tokens_user ON api_tokens(user_id) WHERE revoked_at IS NULL;
@@ -0,0 +1,44 @@
+-- Create webhooks table
+-- Outgoing webhook subscriptions of a conversation or of a bot (every conversation it is in)
+CREATE TABLE webhooks (
+    id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_by        UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    conversation_id   UUID REFERENCES conversations(id) ON DELETE CASCADE,
+    bot_id            UUID REFERENCES users(id) ON DELETE CASCADE,
+    url               TEXT NOT NULL,
+    secret            VARCHAR(64) NOT NULL,                -- HMAC-SHA256 signing secret
+    events            TEXT[] NOT NULL,
+    failure_count     INT NOT NULL DEFAULT 0,              -- Consecutive failed deliveries
+    disabled_at       TIMESTAMPTZ,                         -- NULL = active
+    last_delivery_at  TIMESTAMPTZ,
+    created_at        TIMESTAMPTZ DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT webhooks_target CHECK ((conversation_id IS NULL) <> (bot_id IS NULL))
+);
+
+-- Indexes for finding the subscriptions of an event
+CREATE INDEX idx_webhooks_conversation ON webhooks(conversation_id) WHERE conversation_id IS NOT NULL;
+CREATE INDEX idx_webhooks_bot ON webhooks(bot_id) WHERE bot_id IS NOT NULL;
+
+CREATE TRIGGER update_webhooks_updated_at
+    BEFORE UPDATE ON webhooks
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Create webhook_deliveries table
+-- One row per delivery attempt
+CREATE TABLE webhook_deliveries (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id   UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_id     UUID NOT NULL,                            -- Same for every attempt of an event
+    event        VARCHAR(50) NOT NULL,
+    attempt      INT NOT NULL,
+    status_code  INT,                                      -- NULL when no response was received
+    error        TEXT,
+    duration_ms  INT NOT NULL,
+    success      BOOLEAN NOT NULL,
+    created_at   TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Index for browsing the delivery log of a webhook
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at DESC);
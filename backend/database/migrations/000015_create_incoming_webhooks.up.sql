@@ -0,0 +1,16 @@
+-- Create incoming_webhooks table
+-- Secret URLs that post messages into a group conversation as a bot
+CREATE TABLE incoming_webhooks (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    conversation_id  UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    bot_id           UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,    -- Sender of the posted messages
+    created_by       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name             VARCHAR(100) NOT NULL,
+    token_prefix     VARCHAR(16) NOT NULL,                                    -- Shown to tell webhooks apart
+    token_hash       VARCHAR(64) UNIQUE NOT NULL,                             -- SHA-256 of the path token
+    last_used_at     TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Index for listing the incoming webhooks of a conversation
+CREATE INDEX idx_incoming_webhooks_conversation ON incoming_webhooks(conversation_id);
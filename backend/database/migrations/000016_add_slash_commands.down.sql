@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS bot_commands;
+
+ALTER TABLE conversation_participants DROP COLUMN IF EXISTS muted_until;
+ALTER TABLE conversation_participants DROP COLUMN IF EXISTS muted;
+ALTER TABLE conversations DROP COLUMN IF EXISTS topic;
@@ -0,0 +1,22 @@
+-- Add slash commands
+-- Topic of a conversation, set with /topic
+ALTER TABLE conversations ADD COLUMN topic VARCHAR(250);
+
+-- Per-participant mute, set with /mute; muted_until NULL means until unmuted
+ALTER TABLE conversation_participants ADD COLUMN muted BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE conversation_participants ADD COLUMN muted_until TIMESTAMPTZ;
+
+-- Commands handled by a bot's HTTP endpoint, available in conversations the bot is in
+CREATE TABLE bot_commands (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    bot_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_by   UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name         VARCHAR(32) NOT NULL,                     -- Without the leading slash
+    description  VARCHAR(255) NOT NULL DEFAULT '',
+    usage        VARCHAR(100) NOT NULL DEFAULT '',         -- Arguments hint, e.g. "<environment>"
+    url          TEXT NOT NULL,
+    secret       VARCHAR(100) NOT NULL,                    -- HMAC key for the X-GoChat-Signature header
+    created_at   TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT uq_bot_commands_bot_name UNIQUE (bot_id, name)
+);
@@ -0,0 +1,9 @@
+-- Cards can't be represented without their metadata
+DELETE FROM messages WHERE type = 'card';
+
+ALTER TABLE messages DROP CONSTRAINT chk_message_type;
+ALTER TABLE messages ADD CONSTRAINT chk_message_type
+    CHECK (type IN ('text', 'image', 'file', 'audio'));
+
+ALTER TABLE messages DROP COLUMN IF EXISTS edited_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS metadata;
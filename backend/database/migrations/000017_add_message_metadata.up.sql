@@ -0,0 +1,7 @@
+-- Add message metadata for structured messages (interactive cards)
+ALTER TABLE messages ADD COLUMN metadata JSONB;   -- Card title, text and actions
+ALTER TABLE messages ADD COLUMN edited_at TIMESTAMPTZ;
+
+ALTER TABLE messages DROP CONSTRAINT chk_message_type;
+ALTER TABLE messages ADD CONSTRAINT chk_message_type
+    CHECK (type IN ('text', 'image', 'file', 'audio', 'card'));
@@ -0,0 +1,2 @@
+-- Add a description to group conversations
+ALTER TABLE conversations ADD COLUMN description VARCHAR(1000);
@@ -0,0 +1,23 @@
+-- Pinned messages, allowed by the role of the participant
+ALTER TABLE messages ADD COLUMN pinned_at TIMESTAMPTZ;
+ALTER TABLE messages ADD COLUMN pinned_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_messages_pinned ON messages(conversation_id, pinned_at)
+    WHERE pinned_at IS NOT NULL;
+
+-- Groups must have an admin: promote the longest-standing member of groups left without one
+UPDATE conversation_participants cp
+SET role = 'admin'
+FROM (
+    SELECT DISTINCT ON (p.conversation_id) p.conversation_id, p.user_id
+    FROM conversation_participants p
+    JOIN conversations c ON c.id = p.conversation_id
+    WHERE c.type = 'group'
+      AND p.left_at IS NULL
+      AND NOT EXISTS (
+          SELECT 1 FROM conversation_participants a
+          WHERE a.conversation_id = p.conversation_id AND a.left_at IS NULL AND a.role = 'admin'
+      )
+    ORDER BY p.conversation_id, p.joined_at
+) oldest
+WHERE cp.conversation_id = oldest.conversation_id AND cp.user_id = oldest.user_id;
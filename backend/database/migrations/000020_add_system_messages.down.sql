@@ -0,0 +1,5 @@
+DELETE FROM messages WHERE type = 'system';
+
+ALTER TABLE messages DROP CONSTRAINT chk_message_type;
+ALTER TABLE messages ADD CONSTRAINT chk_message_type
+    CHECK (type IN ('text', 'image', 'file', 'audio', 'card'));
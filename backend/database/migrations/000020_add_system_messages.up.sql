@@ -0,0 +1,5 @@
+-- System messages record conversation events (group created, members added, ...)
+-- with the event in metadata
+ALTER TABLE messages DROP CONSTRAINT chk_message_type;
+ALTER TABLE messages ADD CONSTRAINT chk_message_type
+    CHECK (type IN ('text', 'image', 'file', 'audio', 'card', 'system'));
@@ -0,0 +1,20 @@
+-- Create group_invites table
+-- Shareable links to join a group, created by its admins
+CREATE TABLE group_invites (
+    id                 UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    conversation_id    UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    created_by         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_prefix       VARCHAR(16) NOT NULL,                                  -- Shown to tell links apart
+    token_hash         VARCHAR(64) UNIQUE NOT NULL,                           -- SHA-256 of the token
+    expires_at         TIMESTAMPTZ,                                           -- NULL = never expires
+    max_uses           INTEGER,                                               -- NULL = unlimited
+    uses               INTEGER NOT NULL DEFAULT 0,
+    requires_approval  BOOLEAN NOT NULL DEFAULT FALSE,
+    revoked_at         TIMESTAMPTZ,
+    created_at         TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT chk_group_invite_max_uses CHECK (max_uses IS NULL OR max_uses > 0)
+);
+
+-- Index for listing the invite links of a group
+CREATE INDEX idx_group_invites_conversation ON group_invites(conversation_id);
@@ -0,0 +1,19 @@
+-- Create join_requests table
+-- Requests to join a group, approved or denied by its admins
+CREATE TABLE join_requests (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    conversation_id  UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    user_id          UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    invite_id        UUID REFERENCES group_invites(id) ON DELETE SET NULL,  -- Link used to ask, if any
+    message          VARCHAR(500),
+    status           VARCHAR(20) NOT NULL DEFAULT 'pending',
+    decided_by       UUID REFERENCES users(id) ON DELETE SET NULL,
+    decided_at       TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT chk_join_request_status CHECK (status IN ('pending', 'approved', 'denied'))
+);
+
+-- One pending request per user and group
+CREATE UNIQUE INDEX idx_join_requests_pending ON join_requests(conversation_id, user_id)
+    WHERE status = 'pending';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_conversations_public;
+ALTER TABLE conversations DROP CONSTRAINT IF EXISTS chk_conversation_public_group;
+ALTER TABLE conversations DROP CONSTRAINT IF EXISTS chk_conversation_visibility;
+ALTER TABLE conversations DROP COLUMN IF EXISTS visibility;
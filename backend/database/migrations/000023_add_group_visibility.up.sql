@@ -0,0 +1,10 @@
+-- Public groups are listed in the directory and anyone can join them.
+-- Direct conversations are always private.
+ALTER TABLE conversations ADD COLUMN visibility VARCHAR(20) NOT NULL DEFAULT 'private';
+ALTER TABLE conversations ADD CONSTRAINT chk_conversation_visibility
+    CHECK (visibility IN ('private', 'public'));
+ALTER TABLE conversations ADD CONSTRAINT chk_conversation_public_group
+    CHECK (type = 'group' OR visibility = 'private');
+
+-- Index for the group directory
+CREATE INDEX idx_conversations_public ON conversations(name) WHERE visibility = 'public';
@@ -0,0 +1,9 @@
+-- Channels become groups where everyone can post
+UPDATE conversations SET type = 'group' WHERE type = 'channel';
+
+ALTER TABLE conversations DROP CONSTRAINT chk_conversation_public_group;
+ALTER TABLE conversations ADD CONSTRAINT chk_conversation_public_group
+    CHECK (type = 'group' OR visibility = 'private');
+ALTER TABLE conversations DROP CONSTRAINT chk_conversation_type;
+ALTER TABLE conversations ADD CONSTRAINT chk_conversation_type
+    CHECK (type IN ('direct', 'group'));
@@ -0,0 +1,7 @@
+-- Channels are groups where only admins post (announcements)
+ALTER TABLE conversations DROP CONSTRAINT chk_conversation_type;
+ALTER TABLE conversations ADD CONSTRAINT chk_conversation_type
+    CHECK (type IN ('direct', 'group', 'channel'));
+ALTER TABLE conversations DROP CONSTRAINT chk_conversation_public_group;
+ALTER TABLE conversations ADD CONSTRAINT chk_conversation_public_group
+    CHECK (type <> 'direct' OR visibility = 'private');
@@ -15,40 +15,60 @@ CREATE TABLE users (
     username        VARCHAR(100) UNIQUE NOT NULL,
     password_hash   VARCHAR(255) NOT NULL,
     is_active       BOOLEAN DEFAULT true,
+    email_verified_at TIMESTAMPTZ,
+    totp_secret     VARCHAR(64),
+    totp_enabled_at TIMESTAMPTZ,
+    is_admin        BOOLEAN NOT NULL DEFAULT false,
+    is_bot          BOOLEAN NOT NULL DEFAULT false,
+    bot_owner_id    UUID REFERENCES users(id) ON DELETE CASCADE,      -- Set for bots
     created_at      TIMESTAMPTZ DEFAULT NOW(),
     updated_at      TIMESTAMPTZ DEFAULT NOW()
 );
 
 CREATE INDEX idx_users_email ON users(email);
 CREATE INDEX idx_users_username ON users(username);
+CREATE INDEX idx_users_bot_owner ON users(bot_owner_id) WHERE bot_owner_id IS NOT NULL;
 
 -- ============================================================================
 -- CONVERSATIONS
 -- ============================================================================
--- type: 'direct' (1:1) or 'group'
--- name: only for groups, NULL for direct
+-- type: 'direct' (1:1), 'group' or 'channel' (a group where only admins post)
+-- name: only for groups and channels, NULL for direct
+-- topic: set with the /topic command
+-- visibility: 'public' groups are listed in the directory, direct chats are always 'private'
 CREATE TABLE conversations (
     id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     type        VARCHAR(20) NOT NULL DEFAULT 'direct',
     name        VARCHAR(255),
+    description VARCHAR(1000),
+    topic       VARCHAR(250),
+    visibility  VARCHAR(20) NOT NULL DEFAULT 'private',
     created_by  UUID REFERENCES users(id) ON DELETE SET NULL,
     created_at  TIMESTAMPTZ DEFAULT NOW(),
     updated_at  TIMESTAMPTZ DEFAULT NOW(),
     
-    CONSTRAINT chk_conversation_type CHECK (type IN ('direct', 'group'))
+    CONSTRAINT chk_conversation_type CHECK (type IN ('direct', 'group', 'channel')),
+    CONSTRAINT chk_conversation_visibility CHECK (visibility IN ('private', 'public')),
+    CONSTRAINT chk_conversation_public_group CHECK (type <> 'direct' OR visibility = 'private')
 );
 
+-- Index for the group directory
+CREATE INDEX idx_conversations_public ON conversations(name) WHERE visibility = 'public';
+
 -- ============================================================================
 -- CONVERSATION PARTICIPANTS
 -- ============================================================================
--- role: NULL for direct chats, 'admin'/'member' for groups
+-- role: NULL for direct chats, 'admin'/'member' for groups and channels
 -- left_at: NULL means still in conversation, timestamp means left
+-- muted/muted_until: set with the /mute command; muted_until NULL means until unmuted
 CREATE TABLE conversation_participants (
     conversation_id  UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
     user_id          UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     role             VARCHAR(20),
     joined_at        TIMESTAMPTZ DEFAULT NOW(),
     left_at          TIMESTAMPTZ,
+    muted            BOOLEAN NOT NULL DEFAULT false,
+    muted_until      TIMESTAMPTZ,
     
     PRIMARY KEY (conversation_id, user_id),
     CONSTRAINT chk_participant_role CHECK (role IS NULL OR role IN ('admin', 'member'))
@@ -65,17 +85,23 @@ CREATE INDEX idx_participants_conversation ON conversation_participants(conversa
 -- ============================================================================
 -- MESSAGES
 -- ============================================================================
--- type: 'text', 'image', 'file', 'audio' (for future use)
+-- type: 'text', 'image', 'file', 'audio' (for future use), 'card' (bots only;
+-- metadata holds the card, content its fallback text), 'system' (conversation
+-- events written by the server; metadata holds the event, sender_id the actor)
 CREATE TABLE messages (
     id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
     sender_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     content         TEXT NOT NULL,
     type            VARCHAR(20) DEFAULT 'text',
+    metadata        JSONB,
     sent_at         TIMESTAMPTZ NOT NULL,
+    edited_at       TIMESTAMPTZ,
+    pinned_at       TIMESTAMPTZ,
+    pinned_by       UUID REFERENCES users(id) ON DELETE SET NULL,
     created_at      TIMESTAMPTZ DEFAULT NOW(),
     
-    CONSTRAINT chk_message_type CHECK (type IN ('text', 'image', 'file', 'audio'))
+    CONSTRAINT chk_message_type CHECK (type IN ('text', 'image', 'file', 'audio', 'card', 'system'))
 );
 
 -- Index for fetching conversation history (most recent first)
@@ -84,6 +110,273 @@ CREATE INDEX idx_messages_conversation_time ON messages(conversation_id, sent_at
 -- Index for cursor-based pagination
 CREATE INDEX idx_messages_conversation_cursor ON messages(conversation_id, sent_at, id);
 
+-- Index for listing pinned messages
+CREATE INDEX idx_messages_pinned ON messages(conversation_id, pinned_at)
+    WHERE pinned_at IS NOT NULL;
+
+-- ============================================================================
+-- SESSIONS
+-- ============================================================================
+-- One row per login (refresh token family). refresh_jti is the only refresh
+-- token of the family that is still valid; it changes on every refresh.
+CREATE TABLE sessions (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    refresh_jti     UUID NOT NULL,
+    user_agent      TEXT,
+    ip_address      VARCHAR(64),
+    created_at      TIMESTAMPTZ DEFAULT NOW(),
+    last_used_at    TIMESTAMPTZ DEFAULT NOW(),
+    expires_at      TIMESTAMPTZ NOT NULL,
+    revoked_at      TIMESTAMPTZ,
+    revoked_reason  VARCHAR(50)
+);
+
+-- Index for listing/revoking a user's active sessions
+CREATE INDEX idx_sessions_user_active ON sessions(user_id)
+    WHERE revoked_at IS NULL;
+
+-- ============================================================================
+-- USER TOKENS
+-- ============================================================================
+-- Single-use tokens sent by email. Only a SHA-256 hash of the token is stored.
+CREATE TABLE user_tokens (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose     VARCHAR(30) NOT NULL,
+    token_hash  VARCHAR(64) UNIQUE NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    used_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT chk_user_token_purpose CHECK (purpose IN ('password_reset', 'email_verification'))
+);
+
+-- Index for invalidating a user's outstanding tokens
+CREATE INDEX idx_user_tokens_user_purpose ON user_tokens(user_id, purpose)
+    WHERE used_at IS NULL;
+
+-- ============================================================================
+-- RECOVERY CODES
+-- ============================================================================
+-- One-time 2FA recovery codes (SHA-256 hashes)
+CREATE TABLE recovery_codes (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash   VARCHAR(64) NOT NULL,
+    used_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT uq_recovery_codes_user_code UNIQUE (user_id, code_hash)
+);
+
+-- ============================================================================
+-- WEBAUTHN CREDENTIALS
+-- ============================================================================
+-- Passkeys registered by users for passwordless login
+CREATE TABLE webauthn_credentials (
+    id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id           UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    credential_id     BYTEA UNIQUE NOT NULL,
+    public_key        BYTEA NOT NULL,
+    attestation_type  VARCHAR(32) NOT NULL DEFAULT '',
+    aaguid            BYTEA,
+    sign_count        BIGINT NOT NULL DEFAULT 0,
+    transports        TEXT[] NOT NULL DEFAULT '{}',
+    backup_eligible   BOOLEAN NOT NULL DEFAULT false,
+    backup_state      BOOLEAN NOT NULL DEFAULT false,
+    name              VARCHAR(100) NOT NULL,
+    created_at        TIMESTAMPTZ DEFAULT NOW(),
+    last_used_at      TIMESTAMPTZ
+);
+
+CREATE INDEX idx_webauthn_credentials_user ON webauthn_credentials(user_id);
+
+-- ============================================================================
+-- USER IDENTITIES
+-- ============================================================================
+-- Accounts at external identity providers (OpenID Connect) linked to users
+CREATE TABLE user_identities (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider        VARCHAR(50) NOT NULL,
+    subject         VARCHAR(255) NOT NULL,
+    email           VARCHAR(255),
+    created_at      TIMESTAMPTZ DEFAULT NOW(),
+    last_login_at   TIMESTAMPTZ,
+    UNIQUE(provider, subject)
+);
+
+CREATE INDEX idx_user_identities_user ON user_identities(user_id);
+
+-- ============================================================================
+-- SIGNING KEYS
+-- ============================================================================
+-- Asymmetric JWT signing keys (private keys are encrypted with JWT_SECRET)
+CREATE TABLE signing_keys (
+    kid             VARCHAR(64) PRIMARY KEY,
+    algorithm       VARCHAR(16) NOT NULL,
+    private_key     BYTEA NOT NULL,
+    created_at      TIMESTAMPTZ DEFAULT NOW(),
+    activates_at    TIMESTAMPTZ NOT NULL,       -- Used for signing from then on (published before)
+    expires_at      TIMESTAMPTZ                 -- Set when superseded: end of the grace period
+);
+
+CREATE INDEX idx_signing_keys_activates_at ON signing_keys(algorithm, activates_at DESC);
+
+-- ============================================================================
+-- AUDIT LOG
+-- ============================================================================
+-- Security events (lockouts, admin actions)
+CREATE TABLE audit_log (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    actor_id        UUID REFERENCES users(id) ON DELETE SET NULL,    -- NULL for system events
+    action          VARCHAR(50) NOT NULL,
+    target_user_id  UUID REFERENCES users(id) ON DELETE SET NULL,
+    target_email    VARCHAR(255),
+    ip_address      VARCHAR(45),
+    metadata        JSONB NOT NULL DEFAULT '{}',
+    created_at      TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_log_action_created ON audit_log(action, created_at DESC);
+
+-- ============================================================================
+-- API TOKENS
+-- ============================================================================
+-- Long-lived bearer tokens of users and bots (only a SHA-256 hash is stored)
+CREATE TABLE api_tokens (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,   -- Who the token acts as
+    created_by    UUID REFERENCES users(id) ON DELETE SET NULL,
+    name          VARCHAR(100) NOT NULL,
+    token_prefix  VARCHAR(16) NOT NULL,                                   -- Shown to tell tokens apart
+    token_hash    VARCHAR(64) UNIQUE NOT NULL,
+    scopes        TEXT[] NOT NULL,
+    expires_at    TIMESTAMPTZ,                                            -- NULL = never
+    last_used_at  TIMESTAMPTZ,
+    revoked_at    TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_api_tokens_user ON api_tokens(user_id) WHERE revoked_at IS NULL;
+
+-- ============================================================================
+-- WEBHOOKS
+-- ============================================================================
+-- Outgoing webhook subscriptions of a conversation or of a bot
+CREATE TABLE webhooks (
+    id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_by        UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    conversation_id   UUID REFERENCES conversations(id) ON DELETE CASCADE,
+    bot_id            UUID REFERENCES users(id) ON DELETE CASCADE,
+    url               TEXT NOT NULL,
+    secret            VARCHAR(64) NOT NULL,                -- HMAC-SHA256 signing secret
+    events            TEXT[] NOT NULL,
+    failure_count     INT NOT NULL DEFAULT 0,              -- Consecutive failed deliveries
+    disabled_at       TIMESTAMPTZ,                         -- NULL = active
+    last_delivery_at  TIMESTAMPTZ,
+    created_at        TIMESTAMPTZ DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT webhooks_target CHECK ((conversation_id IS NULL) <> (bot_id IS NULL))
+);
+
+CREATE INDEX idx_webhooks_conversation ON webhooks(conversation_id) WHERE conversation_id IS NOT NULL;
+CREATE INDEX idx_webhooks_bot ON webhooks(bot_id) WHERE bot_id IS NOT NULL;
+
+-- One row per delivery attempt
+CREATE TABLE webhook_deliveries (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id   UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_id     UUID NOT NULL,                            -- Same for every attempt of an event
+    event        VARCHAR(50) NOT NULL,
+    attempt      INT NOT NULL,
+    status_code  INT,                                      -- NULL when no response was received
+    error        TEXT,
+    duration_ms  INT NOT NULL,
+    success      BOOLEAN NOT NULL,
+    created_at   TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at DESC);
+
+-- Secret URLs that post messages into a group conversation as a bot
+CREATE TABLE incoming_webhooks (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    conversation_id  UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    bot_id           UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,    -- Sender of the posted messages
+    created_by       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name             VARCHAR(100) NOT NULL,
+    token_prefix     VARCHAR(16) NOT NULL,                                    -- Shown to tell webhooks apart
+    token_hash       VARCHAR(64) UNIQUE NOT NULL,                             -- SHA-256 of the path token
+    last_used_at     TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_incoming_webhooks_conversation ON incoming_webhooks(conversation_id);
+
+-- ============================================================================
+-- BOT COMMANDS
+-- ============================================================================
+-- Slash commands handled by a bot's HTTP endpoint, available in conversations the bot is in
+CREATE TABLE bot_commands (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    bot_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_by   UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name         VARCHAR(32) NOT NULL,                     -- Without the leading slash
+    description  VARCHAR(255) NOT NULL DEFAULT '',
+    usage        VARCHAR(100) NOT NULL DEFAULT '',         -- Arguments hint, e.g. "<environment>"
+    url          TEXT NOT NULL,
+    secret       VARCHAR(100) NOT NULL,                    -- HMAC key for the X-GoChat-Signature header
+    created_at   TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT uq_bot_commands_bot_name UNIQUE (bot_id, name)
+);
+
+-- ============================================================================
+-- GROUP INVITES
+-- ============================================================================
+-- Shareable links to join a group, created by its admins
+CREATE TABLE group_invites (
+    id                 UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    conversation_id    UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    created_by         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_prefix       VARCHAR(16) NOT NULL,                                  -- Shown to tell links apart
+    token_hash         VARCHAR(64) UNIQUE NOT NULL,                           -- SHA-256 of the token
+    expires_at         TIMESTAMPTZ,                                           -- NULL = never expires
+    max_uses           INTEGER,                                               -- NULL = unlimited
+    uses               INTEGER NOT NULL DEFAULT 0,
+    requires_approval  BOOLEAN NOT NULL DEFAULT FALSE,
+    revoked_at         TIMESTAMPTZ,
+    created_at         TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT chk_group_invite_max_uses CHECK (max_uses IS NULL OR max_uses > 0)
+);
+
+CREATE INDEX idx_group_invites_conversation ON group_invites(conversation_id);
+
+-- ============================================================================
+-- JOIN REQUESTS
+-- ============================================================================
+-- Requests to join a group, approved or denied by its admins
+CREATE TABLE join_requests (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    conversation_id  UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    user_id          UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    invite_id        UUID REFERENCES group_invites(id) ON DELETE SET NULL,  -- Link used to ask, if any
+    message          VARCHAR(500),
+    status           VARCHAR(20) NOT NULL DEFAULT 'pending',
+    decided_by       UUID REFERENCES users(id) ON DELETE SET NULL,
+    decided_at       TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT chk_join_request_status CHECK (status IN ('pending', 'approved', 'denied'))
+);
+
+CREATE UNIQUE INDEX idx_join_requests_pending ON join_requests(conversation_id, user_id)
+    WHERE status = 'pending';
+
 -- ============================================================================
 -- HELPER FUNCTIONS
 -- ============================================================================
@@ -105,3 +398,7 @@ CREATE TRIGGER update_users_updated_at
 CREATE TRIGGER update_conversations_updated_at
     BEFORE UPDATE ON conversations
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_webhooks_updated_at
+    BEFORE UPDATE ON webhooks
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
@@ -9,14 +10,18 @@ import (
 	"go.uber.org/fx"
 
 	"github.com/Beretta350/gochat/internal/app/auth"
+	"github.com/Beretta350/gochat/internal/app/chat"
 	appfx "github.com/Beretta350/gochat/internal/app/fx"
 	"github.com/Beretta350/gochat/internal/app/handler"
 	"github.com/Beretta350/gochat/internal/app/middleware"
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/webhook"
 	"github.com/Beretta350/gochat/internal/app/worker"
 	"github.com/Beretta350/gochat/internal/config"
 	"github.com/Beretta350/gochat/pkg/logger"
 	"github.com/Beretta350/gochat/pkg/postgres"
 	"github.com/Beretta350/gochat/pkg/redisclient"
+	"github.com/Beretta350/gochat/pkg/validator"
 )
 
 // Run starts the application with Fx dependency injection
@@ -26,10 +31,26 @@ func Run() {
 		appfx.Module,
 
 		// Invoke the server
+		fx.Invoke(loadBreachedPasswords),
 		fx.Invoke(startServer),
 	).Run()
 }
 
+// loadBreachedPasswords enables the breached-password check of new passwords (PASSWORD_BREACHED_LIST)
+func loadBreachedPasswords(cfg *config.Config) error {
+	if cfg.Password.BreachedList == "" {
+		return nil
+	}
+
+	count, err := validator.LoadBreachedPasswords(cfg.Password.BreachedList)
+	if err != nil {
+		return fmt.Errorf("load breached passwords: %w", err)
+	}
+
+	logger.Infof("Loaded %d breached passwords from %s", count, cfg.Password.BreachedList)
+	return nil
+}
+
 // ServerParams holds all dependencies needed to start the server
 type ServerParams struct {
 	fx.In
@@ -38,19 +59,40 @@ type ServerParams struct {
 	Config       *config.Config
 	Postgres     *postgres.Client
 	Redis        *redisclient.Client
-	JWTService   *auth.JWTService
+	AuthService  *auth.Service
+	JWT          *auth.JWTService
+	Chat         *chat.Service
 	Health       *handler.HealthHandler
 	Auth         *handler.AuthHandler
+	JWKS         *handler.JWKSHandler
+	Admin        *handler.AdminHandler
+	Bot          *handler.BotHandler
+	Webhook      *handler.WebhookHandler
+	Command      *handler.CommandHandler
 	Conversation *handler.ConversationHandler
+	Group        *handler.GroupHandler
+	Message      *handler.MessageHandler
 	WebSocket    *handler.WebSocketHandler
 	Worker       *worker.MessageWorker
+	Dispatcher   *webhook.Dispatcher
+}
+
+// fiberConfig returns the server configuration. Behind nginx every connection comes from
+// the proxy, so the client IP (sessions, login lockout) is read from the X-Real-IP header,
+// but only when the request comes from a trusted proxy: anyone else could set it.
+func fiberConfig(cfg *config.Config) fiber.Config {
+	return fiber.Config{
+		AppName:                 "GoChat API v1.0",
+		ErrorHandler:            middleware.CustomErrorHandler,
+		ProxyHeader:             "X-Real-IP", // Set by nginx to the address it got the request from
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          cfg.Server.TrustedProxies,
+		EnableIPValidation:      true, // Falls back to the connection's IP without a valid header
+	}
 }
 
 func startServer(p ServerParams) {
-	app := fiber.New(fiber.Config{
-		AppName:      "GoChat API v1.0",
-		ErrorHandler: middleware.CustomErrorHandler,
-	})
+	app := fiber.New(fiberConfig(p.Config))
 
 	// Setup middlewares
 	middleware.Setup(app, p.Config)
@@ -74,6 +116,9 @@ func startServer(p ServerParams) {
 			var workerCtx context.Context
 			workerCtx, workerCancel = context.WithCancel(context.Background())
 			go p.Worker.Start(workerCtx)
+			go p.Chat.Start(workerCtx)
+			go p.JWT.Start(workerCtx)
+			go p.Dispatcher.Start(workerCtx)
 
 			// Start server in background
 			go func() {
@@ -97,6 +142,9 @@ func startServer(p ServerParams) {
 }
 
 func setupRoutes(app *fiber.App, p ServerParams) {
+	// Public keys to verify our JWTs (RS256/EdDSA)
+	app.Get("/.well-known/jwks.json", p.JWKS.JWKS)
+
 	// API v1 routes
 	api := app.Group("/api/v1")
 
@@ -109,17 +157,108 @@ func setupRoutes(app *fiber.App, p ServerParams) {
 	authGroup.Post("/login", p.Auth.Login)
 	authGroup.Post("/refresh", p.Auth.Refresh)
 	authGroup.Post("/logout", p.Auth.Logout)
+	authGroup.Get("/csrf", p.Auth.CSRF)
+	authGroup.Post("/forgot-password", middleware.StrictRateLimiter(), p.Auth.ForgotPassword)
+	authGroup.Post("/reset-password", middleware.StrictRateLimiter(), p.Auth.ResetPassword)
+	authGroup.Post("/verify-email", p.Auth.VerifyEmail)
+	authGroup.Post("/2fa/verify", middleware.StrictRateLimiter(), p.Auth.VerifyTwoFactor)
+	authGroup.Post("/passkeys/login/begin", p.Auth.BeginPasskeyLogin)
+	authGroup.Post("/passkeys/login/finish", middleware.StrictRateLimiter(), p.Auth.FinishPasskeyLogin)
+	authGroup.Get("/oidc/providers", p.Auth.OIDCProviders)
+	authGroup.Get("/oidc/:provider/login", p.Auth.OIDCLogin)
+	authGroup.Get("/oidc/:provider/callback", middleware.StrictRateLimiter(), p.Auth.OIDCCallback)
 
 	// Protected auth routes
-	authGroup.Get("/me", middleware.AuthMiddleware(p.JWTService), p.Auth.Me)
-
-	// Conversation routes (protected)
-	convGroup := api.Group("/conversations", middleware.AuthMiddleware(p.JWTService))
-	convGroup.Post("/", p.Conversation.Create)
-	convGroup.Get("/", p.Conversation.List)
-	convGroup.Get("/:id", p.Conversation.Get)
-	convGroup.Get("/:id/messages", p.Conversation.GetMessages)
-	convGroup.Get("/:id/online", p.Conversation.GetOnlineStatus)
+	authMiddleware := middleware.AuthMiddleware(p.AuthService)
+	authGroup.Get("/me", authMiddleware, p.Auth.Me)
+	authGroup.Get("/sessions", authMiddleware, p.Auth.ListSessions)
+	authGroup.Delete("/sessions", authMiddleware, p.Auth.RevokeOtherSessions)
+	authGroup.Delete("/sessions/:id", authMiddleware, p.Auth.RevokeSession)
+	authGroup.Post("/verify-email/resend", authMiddleware, middleware.StrictRateLimiter(), p.Auth.ResendVerification)
+	authGroup.Post("/2fa/enroll", authMiddleware, p.Auth.EnrollTwoFactor)
+	authGroup.Post("/2fa/confirm", authMiddleware, p.Auth.ConfirmTwoFactor)
+	authGroup.Post("/2fa/disable", authMiddleware, middleware.StrictRateLimiter(), p.Auth.DisableTwoFactor)
+	authGroup.Get("/passkeys", authMiddleware, p.Auth.ListPasskeys)
+	authGroup.Post("/passkeys/register/begin", authMiddleware, p.Auth.BeginPasskeyRegistration)
+	authGroup.Post("/passkeys/register/finish", authMiddleware, p.Auth.FinishPasskeyRegistration)
+	authGroup.Delete("/passkeys/:id", authMiddleware, p.Auth.DeletePasskey)
+	authGroup.Get("/tokens", authMiddleware, p.Auth.ListAPITokens)
+	authGroup.Post("/tokens", authMiddleware, p.Auth.CreateAPIToken)
+	authGroup.Delete("/tokens/:id", authMiddleware, p.Auth.RevokeAPIToken)
+
+	// Bot management (protected, sessions only)
+	botGroup := api.Group("/bots", authMiddleware)
+	botGroup.Post("/", p.Bot.Create)
+	botGroup.Get("/", p.Bot.List)
+	botGroup.Delete("/:id", p.Bot.Delete)
+	botGroup.Get("/:id/tokens", p.Bot.ListTokens)
+	botGroup.Post("/:id/tokens", p.Bot.CreateToken)
+	botGroup.Delete("/:id/tokens/:tokenId", p.Bot.RevokeToken)
+	botGroup.Get("/:id/webhooks", p.Webhook.ListForBot)
+	botGroup.Post("/:id/webhooks", p.Webhook.CreateForBot)
+	botGroup.Get("/:id/commands", p.Command.ListForBot)
+	botGroup.Post("/:id/commands", p.Command.CreateForBot)
+	botGroup.Delete("/:id/commands/:commandId", p.Command.DeleteForBot)
+
+	// Conversation routes (protected, also open to API tokens with the right scope)
+	convGroup := api.Group("/conversations")
+	canRead := middleware.AuthMiddleware(p.AuthService, model.ScopeConversationsRead)
+	canManage := middleware.AuthMiddleware(p.AuthService, model.ScopeGroupsManage)
+	canWrite := middleware.AuthMiddleware(p.AuthService, model.ScopeMessagesWrite)
+	requireVerified := middleware.RequireVerifiedEmail(p.AuthService, p.Config.Auth.VerifiedEmailForConversations())
+	requireVerifiedToSend := middleware.RequireVerifiedEmail(p.AuthService, p.Config.Auth.VerifiedEmailForMessaging())
+	convGroup.Post("/", canManage, requireVerified, p.Conversation.Create)
+	convGroup.Get("/", canRead, p.Conversation.List)
+	convGroup.Get("/:id", canRead, p.Conversation.Get)
+	convGroup.Patch("/:id", canManage, requireVerified, p.Group.Update)
+	convGroup.Post("/:id/participants", canManage, requireVerified, p.Group.AddMembers)
+	convGroup.Delete("/:id/participants/:userId", canManage, p.Group.RemoveMember)
+	convGroup.Put("/:id/participants/:userId/role", canManage, p.Group.SetRole)
+	convGroup.Post("/:id/leave", canManage, p.Group.Leave)
+	convGroup.Post("/:id/join", authMiddleware, requireVerified, p.Group.JoinPublic)
+	convGroup.Get("/:id/invites", canManage, p.Group.ListInvites)
+	convGroup.Post("/:id/invites", canManage, requireVerified, p.Group.CreateInvite)
+	convGroup.Delete("/:id/invites/:inviteId", canManage, p.Group.RevokeInvite)
+	convGroup.Post("/:id/join-requests", authMiddleware, requireVerified, p.Group.RequestToJoin)
+	convGroup.Get("/:id/join-requests", canManage, p.Group.ListJoinRequests)
+	convGroup.Post("/:id/join-requests/:requestId/approve", canManage, p.Group.ApproveJoinRequest)
+	convGroup.Post("/:id/join-requests/:requestId/deny", canManage, p.Group.DenyJoinRequest)
+	convGroup.Get("/:id/messages", canRead, p.Conversation.GetMessages)
+	convGroup.Post("/:id/messages", canWrite, requireVerifiedToSend, p.Message.Send)
+	convGroup.Patch("/:id/messages/:messageId", canWrite, p.Message.Update)
+	convGroup.Delete("/:id/messages/:messageId", canWrite, p.Message.Delete)
+	convGroup.Post("/:id/messages/:messageId/pin", canWrite, p.Message.Pin)
+	convGroup.Delete("/:id/messages/:messageId/pin", canWrite, p.Message.Unpin)
+	convGroup.Get("/:id/pins", canRead, p.Message.ListPinned)
+	convGroup.Get("/:id/online", canRead, p.Conversation.GetOnlineStatus)
+	convGroup.Get("/:id/commands", canRead, p.Command.ListForConversation)
+	convGroup.Get("/:id/webhooks", authMiddleware, p.Webhook.ListForConversation)
+	convGroup.Post("/:id/webhooks", authMiddleware, p.Webhook.CreateForConversation)
+	convGroup.Get("/:id/incoming-webhooks", authMiddleware, p.Webhook.ListIncoming)
+	convGroup.Post("/:id/incoming-webhooks", authMiddleware, p.Webhook.CreateIncoming)
+
+	// Directory of public groups (protected, also open to API tokens that can read conversations)
+	api.Get("/groups", canRead, p.Group.Directory)
+
+	// Invite links (protected, sessions only: people join groups, bots are added)
+	inviteGroup := api.Group("/invites", authMiddleware)
+	inviteGroup.Get("/:token", p.Group.PreviewInvite)
+	inviteGroup.Post("/:token/join", requireVerified, p.Group.JoinByInvite)
+
+	// Webhook management (protected, sessions only)
+	webhookGroup := api.Group("/webhooks", authMiddleware)
+	webhookGroup.Delete("/:id", p.Webhook.Delete)
+	webhookGroup.Post("/:id/enable", p.Webhook.Enable)
+	webhookGroup.Get("/:id/deliveries", p.Webhook.Deliveries)
+	api.Delete("/incoming-webhooks/:id", authMiddleware, p.Webhook.DeleteIncoming)
+
+	// Incoming webhooks (public, authenticated by the secret token in the path)
+	api.Post("/hooks/:token", p.Webhook.Receive)
+
+	// Admin routes (protected, administrators only)
+	adminGroup := api.Group("/admin", authMiddleware, middleware.RequireAdmin(p.AuthService))
+	adminGroup.Post("/login-lockouts/unlock", p.Admin.UnlockLogin)
+	adminGroup.Get("/audit-log", p.Admin.AuditLog)
 
 	// WebSocket routes (JWT in query string)
 	ws := app.Group("/ws")
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Beretta350/gochat/internal/config"
+)
+
+func TestClientIPFromTrustedProxy(t *testing.T) {
+	// Requests sent with app.Test come from 0.0.0.0
+	tests := []struct {
+		name    string
+		proxies []string
+		realIP  string
+		want    string
+	}{
+		{name: "trusted proxy", proxies: []string{"0.0.0.0"}, realIP: "203.0.113.7", want: "203.0.113.7"},
+		{name: "trusted range", proxies: []string{"0.0.0.0/8"}, realIP: "203.0.113.7", want: "203.0.113.7"},
+		{name: "untrusted client setting the header", proxies: []string{"127.0.0.1"}, realIP: "203.0.113.7", want: "0.0.0.0"},
+		{name: "trusted proxy without the header", proxies: []string{"0.0.0.0"}, want: "0.0.0.0"},
+		{name: "invalid header", proxies: []string{"0.0.0.0"}, realIP: "not an ip", want: "0.0.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New(fiberConfig(&config.Config{Server: config.ServerConfig{TrustedProxies: tt.proxies}}))
+			// What sessions and the login lockout are keyed by
+			app.Get("/ip", func(c *fiber.Ctx) error { return c.SendString(c.IP()) })
+
+			req := httptest.NewRequest(fiber.MethodGet, "/ip", nil)
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tt.want {
+				t.Errorf("IP = %q, want %q", body, tt.want)
+			}
+		})
+	}
+}
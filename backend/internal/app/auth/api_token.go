@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/redisclient"
+)
+
+// APITokenPrefix starts every API token, which tells them apart from JWTs
+// (and makes leaked tokens easy to find with secret scanners)
+const APITokenPrefix = "gct_"
+
+// apiTokenDisplayLength is how much of a token is kept to identify it in lists
+const apiTokenDisplayLength = len(APITokenPrefix) + 8
+
+var (
+	ErrInvalidAPIToken  = errors.New("invalid api token")
+	ErrAPITokenNotFound = errors.New("api token not found")
+)
+
+// CreateAPITokenRequest represents a request to create an API token
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=conversations:read messages:write groups:manage"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=3650"` // 0 = never expires
+}
+
+// CreatedAPIToken is returned once, when a token is created: the plain token is never shown again
+type CreatedAPIToken struct {
+	model.APITokenResponse
+	Token string `json:"token"`
+}
+
+// APITokenAuth is the identity an API token authenticates
+type APITokenAuth struct {
+	TokenID  string
+	UserID   string
+	Email    string
+	Username string
+	IsBot    bool
+	Scopes   []string
+}
+
+// IsAPIToken checks if a bearer credential is an API token rather than a JWT
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// ValidateAPIToken checks an API token and returns who it acts as.
+// Revoked, expired and unknown tokens, and tokens of deactivated users, are all ErrInvalidAPIToken.
+func (s *Service) ValidateAPIToken(ctx context.Context, token string) (*APITokenAuth, error) {
+	if !IsAPIToken(token) {
+		return nil, ErrInvalidAPIToken
+	}
+
+	stored, err := s.apiTokenRepo.GetByHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrAPITokenNotFound) {
+			return nil, ErrInvalidAPIToken
+		}
+		return nil, err
+	}
+	if !stored.IsUsable() {
+		return nil, ErrInvalidAPIToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidAPIToken
+		}
+		return nil, err
+	}
+
+	if err := s.apiTokenRepo.MarkUsed(ctx, stored.ID); err != nil {
+		logger.Errorf("Failed to record use of API token %s: %v", stored.ID, err)
+	}
+
+	return &APITokenAuth{
+		TokenID:  stored.ID,
+		UserID:   user.ID,
+		Email:    user.Email,
+		Username: user.Username,
+		IsBot:    user.IsBot,
+		Scopes:   stored.Scopes,
+	}, nil
+}
+
+// CreateAPIToken creates a personal API token acting as the user
+func (s *Service) CreateAPIToken(ctx context.Context, userID string, req *CreateAPITokenRequest) (*CreatedAPIToken, error) {
+	return s.createAPIToken(ctx, userID, userID, req)
+}
+
+// ListAPITokens returns the personal API tokens of a user
+func (s *Service) ListAPITokens(ctx context.Context, userID string) ([]model.APITokenResponse, error) {
+	return s.listAPITokens(ctx, userID)
+}
+
+// RevokeAPIToken revokes one of the user's personal API tokens
+func (s *Service) RevokeAPIToken(ctx context.Context, userID, tokenID string) error {
+	return s.revokeAPIToken(ctx, userID, tokenID)
+}
+
+// createAPIToken generates a token acting as userID. Returns the plain token, which is only shown once.
+func (s *Service) createAPIToken(ctx context.Context, creatorID, userID string, req *CreateAPITokenRequest) (*CreatedAPIToken, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	plain := APITokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	token := &model.APIToken{
+		UserID:      userID,
+		CreatedBy:   &creatorID,
+		Name:        req.Name,
+		TokenPrefix: plain[:apiTokenDisplayLength],
+		TokenHash:   hashToken(plain),
+		Scopes:      uniqueScopes(req.Scopes),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := s.apiTokenRepo.Create(ctx, token); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("API token %s created for user %s by %s (scopes %v)", token.ID, userID, creatorID, token.Scopes)
+	return &CreatedAPIToken{APITokenResponse: token.ToResponse(), Token: plain}, nil
+}
+
+func (s *Service) listAPITokens(ctx context.Context, userID string) ([]model.APITokenResponse, error) {
+	tokens, err := s.apiTokenRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.APITokenResponse, 0, len(tokens))
+	for _, t := range tokens {
+		result = append(result, t.ToResponse())
+	}
+	return result, nil
+}
+
+func (s *Service) revokeAPIToken(ctx context.Context, userID, tokenID string) error {
+	if _, err := uuid.Parse(tokenID); err != nil {
+		return ErrAPITokenNotFound
+	}
+
+	if err := s.apiTokenRepo.Revoke(ctx, userID, tokenID); err != nil {
+		if errors.Is(err, repository.ErrAPITokenNotFound) {
+			return ErrAPITokenNotFound
+		}
+		return err
+	}
+
+	s.disconnectAPITokens(ctx, tokenID)
+	logger.Infof("API token %s of user %s revoked", tokenID, userID)
+	return nil
+}
+
+// disconnectAPITokens closes the WebSocket connections opened with revoked tokens.
+// Requests are checked against the database, so this only matters for open connections.
+func (s *Service) disconnectAPITokens(ctx context.Context, tokenIDs ...string) {
+	for _, id := range tokenIDs {
+		if err := s.redis.Publish(ctx, redisclient.SessionRevokedChannel, id); err != nil {
+			logger.Errorf("Failed to publish revocation of API token %s: %v", id, err)
+		}
+	}
+}
+
+// uniqueScopes removes duplicate scopes, keeping the canonical order
+func uniqueScopes(scopes []string) []string {
+	var result []string
+	for _, scope := range model.APITokenScopes {
+		for _, s := range scopes {
+			if s == scope {
+				result = append(result, scope)
+				break
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/config"
+)
+
+func TestAPITokenLifecycle(t *testing.T) {
+	env := newTestEnv(t, config.AuthConfig{})
+	ctx := context.Background()
+	alice := env.addUser(t, "alice@example.com")
+	bob := env.addUser(t, "bob@example.com")
+
+	created, err := env.service.CreateAPIToken(ctx, alice.ID, &CreateAPITokenRequest{
+		Name:   "deploy script",
+		Scopes: []string{model.ScopeMessagesWrite, model.ScopeConversationsRead, model.ScopeMessagesWrite},
+	})
+	if err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+	if !IsAPIToken(created.Token) {
+		t.Errorf("token %q doesn't start with %s", created.Token, APITokenPrefix)
+	}
+
+	// Only the hash is stored
+	env.apiKeys.mu.Lock()
+	for _, stored := range env.apiKeys.tokens {
+		if stored.TokenHash == created.Token || !strings.HasPrefix(created.Token, stored.TokenPrefix) {
+			t.Errorf("stored token = %+v", stored)
+		}
+	}
+	env.apiKeys.mu.Unlock()
+
+	principal, err := env.service.ValidateAPIToken(ctx, created.Token)
+	if err != nil {
+		t.Fatalf("ValidateAPIToken: %v", err)
+	}
+	wantScopes := []string{model.ScopeConversationsRead, model.ScopeMessagesWrite}
+	if principal.UserID != alice.ID || !slices.Equal(principal.Scopes, wantScopes) {
+		t.Errorf("principal = %+v, want alice with %v", principal, wantScopes)
+	}
+
+	for name, token := range map[string]string{
+		"unknown":   APITokenPrefix + "unknown",
+		"tampered":  created.Token + "x",
+		"not a gct": strings.TrimPrefix(created.Token, APITokenPrefix),
+	} {
+		if _, err := env.service.ValidateAPIToken(ctx, token); !errors.Is(err, ErrInvalidAPIToken) {
+			t.Errorf("%s token: err = %v, want %v", name, err, ErrInvalidAPIToken)
+		}
+	}
+
+	// Only the owner revokes
+	if err := env.service.RevokeAPIToken(ctx, bob.ID, created.ID); !errors.Is(err, ErrAPITokenNotFound) {
+		t.Errorf("revoked by another user: err = %v, want %v", err, ErrAPITokenNotFound)
+	}
+	if err := env.service.RevokeAPIToken(ctx, alice.ID, created.ID); err != nil {
+		t.Fatalf("RevokeAPIToken: %v", err)
+	}
+	if _, err := env.service.ValidateAPIToken(ctx, created.Token); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Errorf("revoked token: err = %v, want %v", err, ErrInvalidAPIToken)
+	}
+}
+
+func TestExpiredAPIToken(t *testing.T) {
+	env := newTestEnv(t, config.AuthConfig{})
+	ctx := context.Background()
+	alice := env.addUser(t, "alice@example.com")
+
+	created, err := env.service.CreateAPIToken(ctx, alice.ID, &CreateAPITokenRequest{
+		Name:          "expiring",
+		Scopes:        []string{model.ScopeConversationsRead},
+		ExpiresInDays: 1,
+	})
+	if err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+
+	env.apiKeys.mu.Lock()
+	expired := time.Now().Add(-time.Minute)
+	env.apiKeys.tokens[0].ExpiresAt = &expired
+	env.apiKeys.mu.Unlock()
+
+	if _, err := env.service.ValidateAPIToken(ctx, created.Token); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Errorf("err = %v, want %v", err, ErrInvalidAPIToken)
+	}
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+// maxBotsPerOwner limits how many bots a user can create
+const maxBotsPerOwner = 20
+
+// botEmailDomain is used for the placeholder email of bots (.invalid never resolves, RFC 2606)
+const botEmailDomain = "@bots.invalid"
+
+var (
+	ErrBotNotFound   = errors.New("bot not found")
+	ErrUsernameTaken = errors.New("username already taken")
+	ErrTooManyBots   = errors.New("too many bots")
+)
+
+// CreateBotRequest represents a request to create a bot account
+type CreateBotRequest struct {
+	Username string `json:"username" validate:"required,min=3,max=50,alphanum"`
+}
+
+// CreateBot creates a bot account owned by the user. Bots have no password and
+// authenticate with API tokens only.
+func (s *Service) CreateBot(ctx context.Context, ownerID string, req *CreateBotRequest) (*model.UserResponse, error) {
+	bots, err := s.userRepo.ListBots(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(bots) >= maxBotsPerOwner {
+		return nil, ErrTooManyBots
+	}
+
+	now := time.Now()
+	bot := &model.User{
+		Email:           "bot-" + uuid.New().String() + botEmailDomain,
+		Username:        req.Username,
+		IsActive:        true,
+		EmailVerifiedAt: &now, // Nothing to verify, and it must not be blocked by REQUIRE_VERIFIED_EMAIL
+		IsBot:           true,
+		BotOwnerID:      &ownerID,
+	}
+	if err := s.userRepo.Create(ctx, bot); err != nil {
+		if errors.Is(err, repository.ErrUserAlreadyExists) {
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+
+	logger.Infof("Bot %s (%s) created by %s", bot.Username, bot.ID, ownerID)
+	return bot.ToResponse(), nil
+}
+
+// ListBots returns the bots of a user
+func (s *Service) ListBots(ctx context.Context, ownerID string) ([]*model.UserResponse, error) {
+	bots, err := s.userRepo.ListBots(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.UserResponse, 0, len(bots))
+	for _, bot := range bots {
+		result = append(result, bot.ToResponse())
+	}
+	return result, nil
+}
+
+// DeleteBot deactivates a bot and revokes its tokens
+func (s *Service) DeleteBot(ctx context.Context, ownerID, botID string) error {
+	if _, err := s.ownedBot(ctx, ownerID, botID); err != nil {
+		return err
+	}
+
+	tokenIDs, err := s.apiTokenRepo.RevokeAllForUser(ctx, botID)
+	if err != nil {
+		return err
+	}
+	s.disconnectAPITokens(ctx, tokenIDs...)
+
+	if err := s.userRepo.Delete(ctx, botID); err != nil {
+		return err
+	}
+
+	logger.Infof("Bot %s deleted by %s, %d token(s) revoked", botID, ownerID, len(tokenIDs))
+	return nil
+}
+
+// CreateBotToken creates an API token acting as one of the user's bots
+func (s *Service) CreateBotToken(ctx context.Context, ownerID, botID string, req *CreateAPITokenRequest) (*CreatedAPIToken, error) {
+	if _, err := s.ownedBot(ctx, ownerID, botID); err != nil {
+		return nil, err
+	}
+	return s.createAPIToken(ctx, ownerID, botID, req)
+}
+
+// ListBotTokens returns the API tokens of one of the user's bots
+func (s *Service) ListBotTokens(ctx context.Context, ownerID, botID string) ([]model.APITokenResponse, error) {
+	if _, err := s.ownedBot(ctx, ownerID, botID); err != nil {
+		return nil, err
+	}
+	return s.listAPITokens(ctx, botID)
+}
+
+// RevokeBotToken revokes an API token of one of the user's bots
+func (s *Service) RevokeBotToken(ctx context.Context, ownerID, botID, tokenID string) error {
+	if _, err := s.ownedBot(ctx, ownerID, botID); err != nil {
+		return err
+	}
+	return s.revokeAPIToken(ctx, botID, tokenID)
+}
+
+// ownedBot returns a bot if it belongs to the user (ErrBotNotFound otherwise)
+func (s *Service) ownedBot(ctx context.Context, ownerID, botID string) (*model.User, error) {
+	if _, err := uuid.Parse(botID); err != nil {
+		return nil, ErrBotNotFound
+	}
+
+	bot, err := s.userRepo.GetByID(ctx, botID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrBotNotFound
+		}
+		return nil, err
+	}
+	if !bot.IsBot || bot.BotOwnerID == nil || *bot.BotOwnerID != ownerID {
+		return nil, ErrBotNotFound
+	}
+	return bot, nil
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/mailer"
+)
+
+var (
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	ErrEmailAlreadyVerified     = errors.New("email already verified")
+)
+
+// VerifyEmailRequest represents a request to confirm an email address
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// sendVerificationEmail emails a new verification link, invalidating the previous one
+func (s *Service) sendVerificationEmail(ctx context.Context, user *model.User) error {
+	token, err := s.issueUserToken(ctx, user.ID, model.UserTokenEmailVerification, s.config.Auth.EmailVerificationExpiry)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", s.config.Server.FrontendURL, url.QueryEscape(token))
+	s.sendMailAsync(&mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your GoChat email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nWelcome to GoChat! Please confirm your email address within %s:\n\n%s\n\n"+
+				"If you didn't create an account, you can ignore this email.\n",
+			user.Username, s.config.Auth.EmailVerificationExpiry, link,
+		),
+	})
+	return nil
+}
+
+// VerifyEmail confirms the email address a verification token was sent to
+func (s *Service) VerifyEmail(ctx context.Context, req *VerifyEmailRequest) error {
+	token, err := s.tokenRepo.Consume(ctx, model.UserTokenEmailVerification, hashToken(req.Token))
+	if err != nil {
+		if errors.Is(err, repository.ErrUserTokenNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		return err
+	}
+
+	if err := s.userRepo.MarkEmailVerified(ctx, token.UserID); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		return err
+	}
+
+	logger.Infof("Email verified for user %s", token.UserID)
+	return nil
+}
+
+// ResendVerification sends a new verification link to the user
+func (s *Service) ResendVerification(ctx context.Context, userID string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	if user.IsEmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		return err
+	}
+
+	logger.Infof("Verification email resent to user %s", user.ID)
+	return nil
+}
+
+// IsEmailVerified checks if the user confirmed their email address
+func (s *Service) IsEmailVerified(ctx context.Context, userID string) (bool, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.IsEmailVerified(), nil
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Beretta350/gochat/internal/config"
+)
+
+// linkToken extracts the token of the link in an email
+var linkToken = regexp.MustCompile(`\?token=(\S+)`)
+
+// emailedToken waits for the next email and returns the token of its link
+func emailedToken(t *testing.T, env *testEnv) string {
+	t.Helper()
+
+	select {
+	case msg := <-env.mail.sent:
+		match := linkToken.FindStringSubmatch(msg.Body)
+		if match == nil {
+			t.Fatalf("no link in %q", msg.Body)
+		}
+		token, err := url.QueryUnescape(match[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	case <-time.After(time.Second):
+		t.Fatal("no email sent")
+		return ""
+	}
+}
+
+func TestEmailVerification(t *testing.T) {
+	env := newTestEnv(t, config.AuthConfig{EmailVerificationExpiry: time.Hour})
+	ctx := context.Background()
+
+	resp, err := env.service.Register(ctx, &RegisterRequest{Email: "alice@example.com", Username: "alice", Password: testPassword}, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	userID := resp.User.ID
+	first := emailedToken(t, env)
+	if verified, _ := env.service.IsEmailVerified(ctx, userID); verified {
+		t.Fatal("verified before following the link")
+	}
+
+	// A new link replaces the previous one
+	if err := env.service.ResendVerification(ctx, userID); err != nil {
+		t.Fatalf("ResendVerification: %v", err)
+	}
+	second := emailedToken(t, env)
+	if err := env.service.VerifyEmail(ctx, &VerifyEmailRequest{Token: first}); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Errorf("replaced link: err = %v, want %v", err, ErrInvalidVerificationToken)
+	}
+
+	if err := env.service.VerifyEmail(ctx, &VerifyEmailRequest{Token: second}); err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+	if verified, _ := env.service.IsEmailVerified(ctx, userID); !verified {
+		t.Error("not verified after following the link")
+	}
+
+	if err := env.service.VerifyEmail(ctx, &VerifyEmailRequest{Token: second}); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Errorf("link used twice: err = %v, want %v", err, ErrInvalidVerificationToken)
+	}
+	if err := env.service.ResendVerification(ctx, userID); !errors.Is(err, ErrEmailAlreadyVerified) {
+		t.Errorf("resend once verified: err = %v, want %v", err, ErrEmailAlreadyVerified)
+	}
+}
+
+func TestEmailVerificationLinkExpires(t *testing.T) {
+	env := newTestEnv(t, config.AuthConfig{EmailVerificationExpiry: time.Millisecond})
+	ctx := context.Background()
+
+	if _, err := env.service.Register(ctx, &RegisterRequest{Email: "alice@example.com", Username: "alice", Password: testPassword}, ClientInfo{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	token := emailedToken(t, env)
+	time.Sleep(5 * time.Millisecond)
+
+	if err := env.service.VerifyEmail(ctx, &VerifyEmailRequest{Token: token}); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Errorf("expired link: err = %v, want %v", err, ErrInvalidVerificationToken)
+	}
+}
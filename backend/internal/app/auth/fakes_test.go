@@ -0,0 +1,382 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/internal/config"
+	"github.com/Beretta350/gochat/pkg/mailer"
+	"github.com/Beretta350/gochat/pkg/password"
+	"github.com/Beretta350/gochat/pkg/redisclient"
+)
+
+const testPassword = "correct-Horse-9"
+
+// testEnv is an auth service backed by miniredis and in-memory repositories
+type testEnv struct {
+	service  *Service
+	redis    *miniredis.Miniredis
+	users    *fakeUserRepo
+	sessions *fakeSessionRepo
+	tokens   *fakeTokenRepo
+	mail     *recordingMailer
+	audit    *fakeAuditRepo
+	apiKeys  *fakeAPITokenRepo
+}
+
+func newTestEnv(t *testing.T, auth config.AuthConfig) *testEnv {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	cfg := &config.Config{
+		JWT: config.JWTConfig{
+			Secret:        "test-secret",
+			AccessExpiry:  15 * time.Minute,
+			RefreshExpiry: time.Hour,
+			Algorithm:     HS256,
+		},
+		Redis: config.RedisConfig{Addr: mr.Addr()},
+		Auth:  auth,
+	}
+
+	client, err := redisclient.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("connecting to miniredis: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	jwtService, err := NewJWTService(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// bcrypt at its lowest cost keeps the tests fast
+	hasher, err := password.New(password.Params{Algorithm: password.Bcrypt, BcryptCost: bcrypt.MinCost})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := &testEnv{
+		redis:    mr,
+		users:    &fakeUserRepo{users: make(map[string]*model.User)},
+		sessions: &fakeSessionRepo{},
+		tokens:   &fakeTokenRepo{},
+		mail:     &recordingMailer{sent: make(chan *mailer.Message, 8)},
+		audit:    &fakeAuditRepo{},
+		apiKeys:  &fakeAPITokenRepo{},
+	}
+	env.service = NewService(ServiceParams{
+		Config:       cfg,
+		UserRepo:     env.users,
+		SessionRepo:  env.sessions,
+		TokenRepo:    env.tokens,
+		AuditRepo:    env.audit,
+		APITokenRepo: env.apiKeys,
+		Redis:        client,
+		JWTService:   jwtService,
+		Passwords:    hasher,
+		Mailer:       env.mail,
+	})
+	return env
+}
+
+// addUser stores an active user whose password is testPassword
+func (e *testEnv) addUser(t *testing.T, email string) *model.User {
+	t.Helper()
+
+	hash, err := e.service.passwords.Hash(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &model.User{ID: uuid.New().String(), Email: email, Username: strings.Split(email, "@")[0], PasswordHash: hash, IsActive: true}
+	e.users.mu.Lock()
+	e.users.users[user.ID] = user
+	e.users.mu.Unlock()
+	return user
+}
+
+// fakeUserRepo keeps users in memory; methods not overridden panic
+type fakeUserRepo struct {
+	repository.UserRepository
+
+	mu    sync.Mutex
+	users map[string]*model.User
+}
+
+func (r *fakeUserRepo) GetByID(_ context.Context, id string) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *fakeUserRepo) GetByEmail(_ context.Context, email string) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (r *fakeUserRepo) Create(_ context.Context, user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if strings.EqualFold(existing.Email, user.Email) {
+			return repository.ErrUserAlreadyExists
+		}
+	}
+	user.ID = uuid.New().String()
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+func (r *fakeUserRepo) MarkEmailVerified(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	return nil
+}
+
+// fakeSessionRepo keeps sessions in memory, like PostgreSQL
+type fakeSessionRepo struct {
+	repository.SessionRepository
+
+	mu       sync.Mutex
+	sessions []model.Session
+}
+
+func (r *fakeSessionRepo) Create(_ context.Context, session *model.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session.ID = uuid.New().String()
+	session.CreatedAt = time.Now()
+	session.LastUsedAt = session.CreatedAt
+	r.sessions = append(r.sessions, *session)
+	return nil
+}
+
+// find returns the stored session with the ID; the lock must be held
+func (r *fakeSessionRepo) find(id string) *model.Session {
+	for i := range r.sessions {
+		if r.sessions[i].ID == id {
+			return &r.sessions[i]
+		}
+	}
+	return nil
+}
+
+func (r *fakeSessionRepo) GetByID(_ context.Context, id string) (*model.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session := r.find(id)
+	if session == nil {
+		return nil, repository.ErrSessionNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (r *fakeSessionRepo) Rotate(_ context.Context, id, currentJTI, newJTI string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session := r.find(id)
+	if session == nil || session.RevokedAt != nil || session.RefreshJTI != currentJTI {
+		return repository.ErrStaleRefreshJTI
+	}
+	session.RefreshJTI, session.ExpiresAt, session.LastUsedAt = newJTI, expiresAt, time.Now()
+	return nil
+}
+
+func (r *fakeSessionRepo) ListActiveByUser(_ context.Context, userID string) ([]model.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var active []model.Session
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.IsActive() {
+			active = append(active, session)
+		}
+	}
+	return active, nil
+}
+
+func (r *fakeSessionRepo) Revoke(_ context.Context, id, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if session := r.find(id); session != nil && session.RevokedAt == nil {
+		now := time.Now()
+		session.RevokedAt, session.RevokedReason = &now, &reason
+	}
+	return nil
+}
+
+func (r *fakeSessionRepo) RevokeAllForUser(_ context.Context, userID, exceptID, reason string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ids []string
+	for i := range r.sessions {
+		session := &r.sessions[i]
+		if session.UserID == userID && session.ID != exceptID && session.RevokedAt == nil {
+			now := time.Now()
+			session.RevokedAt, session.RevokedReason = &now, &reason
+			ids = append(ids, session.ID)
+		}
+	}
+	return ids, nil
+}
+
+// fakeAuditRepo records the audit entries written
+type fakeAuditRepo struct {
+	repository.AuditRepository
+
+	mu      sync.Mutex
+	entries []model.AuditEntry
+}
+
+func (r *fakeAuditRepo) Create(_ context.Context, entry *model.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+// fakeTokenRepo keeps the user tokens issued
+type fakeTokenRepo struct {
+	repository.UserTokenRepository
+
+	mu     sync.Mutex
+	tokens []model.UserToken
+}
+
+func (r *fakeTokenRepo) Create(_ context.Context, token *model.UserToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens = append(r.tokens, *token)
+	return nil
+}
+
+// Consume returns and removes an unexpired token, like PostgreSQL
+func (r *fakeTokenRepo) Consume(_ context.Context, purpose model.UserTokenPurpose, tokenHash string) (*model.UserToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, token := range r.tokens {
+		if token.Purpose == purpose && token.TokenHash == tokenHash && time.Now().Before(token.ExpiresAt) {
+			r.tokens = slices.Delete(r.tokens, i, i+1)
+			return &token, nil
+		}
+	}
+	return nil, repository.ErrUserTokenNotFound
+}
+
+func (r *fakeTokenRepo) InvalidateForUser(_ context.Context, userID string, purpose model.UserTokenPurpose) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens = slices.DeleteFunc(r.tokens, func(t model.UserToken) bool {
+		return t.UserID == userID && t.Purpose == purpose
+	})
+	return nil
+}
+
+// recordingMailer hands the emails sent to the test
+type recordingMailer struct {
+	sent chan *mailer.Message
+}
+
+func (m *recordingMailer) Send(_ context.Context, msg *mailer.Message) error {
+	m.sent <- msg
+	return nil
+}
+
+// fakeAPITokenRepo keeps API tokens in memory
+type fakeAPITokenRepo struct {
+	repository.APITokenRepository
+
+	mu     sync.Mutex
+	tokens []model.APIToken
+}
+
+func (r *fakeAPITokenRepo) Create(_ context.Context, token *model.APIToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token.ID = uuid.New().String()
+	token.CreatedAt = time.Now()
+	r.tokens = append(r.tokens, *token)
+	return nil
+}
+
+func (r *fakeAPITokenRepo) GetByHash(_ context.Context, tokenHash string) (*model.APIToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+	return nil, repository.ErrAPITokenNotFound
+}
+
+func (r *fakeAPITokenRepo) MarkUsed(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.tokens {
+		if r.tokens[i].ID == id {
+			now := time.Now()
+			r.tokens[i].LastUsedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *fakeAPITokenRepo) Revoke(_ context.Context, userID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.tokens {
+		if r.tokens[i].ID == id && r.tokens[i].UserID == userID && r.tokens[i].RevokedAt == nil {
+			now := time.Now()
+			r.tokens[i].RevokedAt = &now
+			return nil
+		}
+	}
+	return repository.ErrAPITokenNotFound
+}
@@ -1,34 +1,47 @@
 package auth
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 
+	"github.com/Beretta350/gochat/internal/app/repository"
 	"github.com/Beretta350/gochat/internal/config"
+	"github.com/Beretta350/gochat/pkg/keyset"
 	"github.com/Beretta350/gochat/pkg/logger"
 )
 
 var (
-	ErrInvalidToken = errors.New("invalid token")
-	ErrExpiredToken = errors.New("token expired")
+	ErrInvalidToken    = errors.New("invalid token")
+	ErrExpiredToken    = errors.New("token expired")
+	ErrNoSigningKey    = errors.New("no active signing key")
+	ErrUnsupportedAlgo = errors.New("unsupported JWT algorithm")
 )
 
+// HS256 signs tokens with the shared JWT_SECRET (no key rotation, no JWKS)
+const HS256 = "HS256"
+
 // TokenType represents the type of JWT token
 type TokenType string
 
 const (
 	AccessToken  TokenType = "access"
 	RefreshToken TokenType = "refresh"
+	// MFAChallenge proves the password was checked; it is exchanged for a TokenPair with a 2FA code
+	MFAChallenge TokenType = "mfa_challenge"
 )
 
 // Claims represents the JWT claims
 type Claims struct {
-	UserID   string    `json:"user_id"`
-	Email    string    `json:"email"`
-	Username string    `json:"username"`
-	Type     TokenType `json:"type"`
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Username  string    `json:"username"`
+	Type      TokenType `json:"type"`
+	SessionID string    `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -39,31 +52,79 @@ type TokenPair struct {
 	ExpiresIn    int64  `json:"expires_in"` // seconds until access token expires
 }
 
-// JWTService handles JWT operations
+// JWTService handles JWT operations.
+// With RS256 or EdDSA, tokens are signed with rotating keys stored in PostgreSQL
+// (see keys.go); with HS256 they are signed with JWT_SECRET.
 type JWTService struct {
 	secret        []byte
 	accessExpiry  time.Duration
 	refreshExpiry time.Duration
+
+	algorithm     string
+	hs256Fallback bool // Accept HS256 tokens while using asymmetric keys
+	rotation      time.Duration
+	grace         time.Duration
+	keyRepo       repository.SigningKeyRepository
+
+	mu   sync.RWMutex
+	keys []signingKey // Usable keys, newest activation first
 }
 
 // NewJWTService creates a new JWT service (Fx provider)
-func NewJWTService(cfg *config.Config) *JWTService {
-	logger.Info("JWT service initialized")
-	return &JWTService{
+func NewJWTService(cfg *config.Config, keyRepo repository.SigningKeyRepository) (*JWTService, error) {
+	s := &JWTService{
 		secret:        []byte(cfg.JWT.Secret),
 		accessExpiry:  cfg.JWT.AccessExpiry,
 		refreshExpiry: cfg.JWT.RefreshExpiry,
+		algorithm:     cfg.JWT.Algorithm,
+		hs256Fallback: cfg.JWT.HS256Fallback,
+		rotation:      cfg.JWT.KeyRotation,
+		grace:         cfg.JWT.KeyGracePeriod,
+		keyRepo:       keyRepo,
+	}
+
+	if s.algorithm == HS256 {
+		logger.Info("JWT service initialized (HS256)")
+		return s, nil
+	}
+
+	if !keyset.IsSupported(s.algorithm) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgo, s.algorithm)
+	}
+
+	// Tokens signed by a superseded key must stay valid until they expire
+	if s.grace < s.refreshExpiry {
+		logger.Warnf("JWT_KEY_GRACE_PERIOD (%s) is shorter than JWT_REFRESH_EXPIRY, using %s", s.grace, s.refreshExpiry)
+		s.grace = s.refreshExpiry
+	}
+
+	if err := s.rotateKeys(context.Background()); err != nil {
+		return nil, err
 	}
+
+	logger.Infof("JWT service initialized (%s, keys rotated every %s)", s.algorithm, s.rotation)
+	return s, nil
+}
+
+// AccessExpiry returns the access token lifetime
+func (s *JWTService) AccessExpiry() time.Duration {
+	return s.accessExpiry
+}
+
+// RefreshExpiry returns the refresh token lifetime
+func (s *JWTService) RefreshExpiry() time.Duration {
+	return s.refreshExpiry
 }
 
-// GenerateTokenPair generates both access and refresh tokens
-func (s *JWTService) GenerateTokenPair(userID, email, username string) (*TokenPair, error) {
-	accessToken, err := s.generateToken(userID, email, username, AccessToken, s.accessExpiry)
+// GenerateTokenPair generates both access and refresh tokens for a session.
+// refreshJTI identifies the refresh token so it can be rotated and checked for reuse.
+func (s *JWTService) GenerateTokenPair(userID, email, username, sessionID, refreshJTI string) (*TokenPair, error) {
+	accessToken, err := s.generateToken(userID, email, username, sessionID, "", AccessToken, s.accessExpiry)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := s.generateToken(userID, email, username, RefreshToken, s.refreshExpiry)
+	refreshToken, err := s.generateToken(userID, email, username, sessionID, refreshJTI, RefreshToken, s.refreshExpiry)
 	if err != nil {
 		return nil, err
 	}
@@ -75,38 +136,70 @@ func (s *JWTService) GenerateTokenPair(userID, email, username string) (*TokenPa
 	}, nil
 }
 
-// GenerateAccessToken generates only an access token (for refresh)
-func (s *JWTService) GenerateAccessToken(userID, email, username string) (string, error) {
-	return s.generateToken(userID, email, username, AccessToken, s.accessExpiry)
+// GenerateAccessToken generates only an access token for a session
+func (s *JWTService) GenerateAccessToken(userID, email, username, sessionID string) (string, error) {
+	return s.generateToken(userID, email, username, sessionID, "", AccessToken, s.accessExpiry)
 }
 
-func (s *JWTService) generateToken(userID, email, username string, tokenType TokenType, expiry time.Duration) (string, error) {
+// GenerateChallengeToken generates a short-lived 2FA login challenge
+func (s *JWTService) GenerateChallengeToken(userID, email, username, challengeID string, expiry time.Duration) (string, error) {
+	return s.generateToken(userID, email, username, "", challengeID, MFAChallenge, expiry)
+}
+
+func (s *JWTService) generateToken(userID, email, username, sessionID, jti string, tokenType TokenType, expiry time.Duration) (string, error) {
 	now := time.Now()
 
 	claims := &Claims{
-		UserID:   userID,
-		Email:    email,
-		Username: username,
-		Type:     tokenType,
+		UserID:    userID,
+		Email:     email,
+		Username:  username,
+		Type:      tokenType,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.secret)
+	if s.algorithm == HS256 {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString(s.secret)
+	}
+
+	key := s.signingKey()
+	if key == nil {
+		return "", ErrNoSigningKey
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
 }
 
-// ValidateToken validates a token and returns the claims
-func (s *JWTService) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+// verificationKey returns the key to check a token signature with
+func (s *JWTService) verificationKey(token *jwt.Token) (interface{}, error) {
+	if token.Method == jwt.SigningMethodHS256 {
+		if s.algorithm != HS256 && !s.hs256Fallback {
 			return nil, ErrInvalidToken
 		}
 		return s.secret, nil
-	})
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key := s.keyByID(kid)
+	if key == nil || jwt.GetSigningMethod(key.Algorithm) != token.Method {
+		return nil, ErrInvalidToken
+	}
+	return key.Public(), nil
+}
+
+// ValidateToken validates a token and returns the claims
+func (s *JWTService) ValidateToken(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, s.verificationKey,
+		jwt.WithValidMethods([]string{HS256, keyset.RS256, keyset.EdDSA}),
+	)
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -150,3 +243,17 @@ func (s *JWTService) ValidateRefreshToken(tokenString string) (*Claims, error) {
 
 	return claims, nil
 }
+
+// ValidateChallengeToken validates a 2FA login challenge
+func (s *JWTService) ValidateChallengeToken(tokenString string) (*Claims, error) {
+	claims, err := s.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Type != MFAChallenge || claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/keyset"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+const (
+	// keyCheckInterval is how often keys are reloaded (other instances may rotate them)
+	keyCheckInterval = time.Minute
+	// keyPublishLead is how long a new key is published in the JWKS before it signs tokens,
+	// so that services caching the JWKS know it in time
+	keyPublishLead = time.Hour
+)
+
+// signingKey is a usable key and the time it starts signing tokens
+type signingKey struct {
+	*keyset.Key
+	activatesAt time.Time
+}
+
+// Start reloads and rotates the signing keys until the context is cancelled
+func (s *JWTService) Start(ctx context.Context) {
+	if s.algorithm == HS256 {
+		return
+	}
+
+	ticker := time.NewTicker(keyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.rotateKeys(ctx); err != nil {
+				logger.Errorf("Failed to rotate JWT signing keys: %v", err)
+			}
+		}
+	}
+}
+
+// rotateKeys creates the next key when the newest one is due for rotation,
+// drops keys past their grace period and reloads the key set
+func (s *JWTService) rotateKeys(ctx context.Context) error {
+	stored, err := s.keyRepo.ListUsable(ctx)
+	if err != nil {
+		return err
+	}
+
+	var newest *model.SigningKey
+	for i := range stored {
+		if stored[i].Algorithm == s.algorithm && stored[i].ExpiresAt == nil {
+			newest = &stored[i]
+			break
+		}
+	}
+
+	lead := keyPublishLead
+	if lead > s.rotation/2 {
+		lead = s.rotation / 2
+	}
+
+	now := time.Now()
+	if newest == nil || !now.Before(newest.ActivatesAt.Add(s.rotation-lead)) {
+		created, err := s.createNextKey(ctx, newest, now)
+		if err != nil {
+			return err
+		}
+		if created {
+			if stored, err = s.keyRepo.ListUsable(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	if count, err := s.keyRepo.DeleteExpired(ctx); err != nil {
+		logger.Errorf("Failed to delete expired JWT signing keys: %v", err)
+	} else if count > 0 {
+		logger.Infof("Deleted %d expired JWT signing key(s)", count)
+	}
+
+	s.loadKeys(stored)
+	return nil
+}
+
+// createNextKey generates the key that follows newest (nil: first key of the algorithm, active now)
+func (s *JWTService) createNextKey(ctx context.Context, newest *model.SigningKey, now time.Time) (bool, error) {
+	key, err := keyset.Generate(s.algorithm)
+	if err != nil {
+		return false, err
+	}
+	private, err := key.MarshalPrivate(s.secret)
+	if err != nil {
+		return false, err
+	}
+
+	activatesAt := now
+	var previous *time.Time
+	if newest != nil {
+		previous = &newest.ActivatesAt
+		if next := newest.ActivatesAt.Add(s.rotation); next.After(now) {
+			activatesAt = next
+		}
+	}
+
+	created, err := s.keyRepo.CreateNext(ctx, &model.SigningKey{
+		KID:         key.ID,
+		Algorithm:   key.Algorithm,
+		PrivateKey:  private,
+		ActivatesAt: activatesAt,
+	}, previous, s.grace)
+	if err != nil {
+		return false, err
+	}
+
+	if created {
+		logger.Infof("Created JWT signing key %s (%s), active from %s", key.ID, key.Algorithm, activatesAt.Format(time.RFC3339))
+	}
+	return created, nil
+}
+
+// loadKeys decrypts the stored keys and replaces the in-memory key set
+func (s *JWTService) loadKeys(stored []model.SigningKey) {
+	keys := make([]signingKey, 0, len(stored))
+	for _, k := range stored {
+		key, err := keyset.ParsePrivate(k.KID, k.Algorithm, k.PrivateKey, s.secret)
+		if err != nil {
+			logger.Errorf("Skipping JWT signing key %s: %v", k.KID, err)
+			continue
+		}
+		keys = append(keys, signingKey{Key: key, activatesAt: k.ActivatesAt})
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+}
+
+// signingKey returns the newest active key of the configured algorithm
+func (s *JWTService) signingKey() *keyset.Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	for _, k := range s.keys {
+		if k.Algorithm == s.algorithm && !k.activatesAt.After(now) {
+			return k.Key
+		}
+	}
+	return nil
+}
+
+// keyByID returns a usable key (active, upcoming or in its grace period)
+func (s *JWTService) keyByID(kid string) *keyset.Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, k := range s.keys {
+		if k.ID == kid {
+			return k.Key
+		}
+	}
+	return nil
+}
+
+// JWKS returns the public keys other services can verify tokens with
+// (empty with HS256, whose secret can't be published)
+func (s *JWTService) JWKS() keyset.Set {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set := keyset.Set{Keys: make([]keyset.JWK, 0, len(s.keys))}
+	for _, k := range s.keys {
+		set.Keys = append(set.Keys, k.JWK())
+	}
+	return set
+}
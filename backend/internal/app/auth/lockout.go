@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+var ErrLoginBlocked = errors.New("too many failed login attempts")
+
+// maxLoginBackoff caps the delay between attempts before the lockout
+const maxLoginBackoff = time.Minute
+
+// LoginBlockedError is returned while an account or IP is throttled.
+// It is returned before the user is looked up, so it looks the same for unknown emails.
+type LoginBlockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string {
+	return fmt.Sprintf("%v, retry in %s", ErrLoginBlocked, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LoginBlockedError) Is(target error) bool {
+	return target == ErrLoginBlocked
+}
+
+// UnlockLoginRequest represents an admin request to lift a login lockout
+type UnlockLoginRequest struct {
+	Email string `json:"email" validate:"omitempty,email,required_without=IP"`
+	IP    string `json:"ip" validate:"omitempty,ip,required_without=Email"`
+}
+
+// loginLimit is a failed-login counter: per account or per IP
+type loginLimit struct {
+	scope       string // "email" or "ip"
+	value       string
+	maxFailures int
+}
+
+func (l loginLimit) key() string {
+	return l.scope + ":" + l.value
+}
+
+// loginLimits returns the counters a login attempt is checked against.
+// Accounts are tracked by email, whether the user exists or not.
+func (s *Service) loginLimits(email, ip string) []loginLimit {
+	var limits []loginLimit
+	if email = normalizeEmail(email); email != "" && s.config.Auth.LoginMaxFailures > 0 {
+		limits = append(limits, loginLimit{"email", email, s.config.Auth.LoginMaxFailures})
+	}
+	if ip != "" && s.config.Auth.LoginMaxFailuresPerIP > 0 {
+		limits = append(limits, loginLimit{"ip", ip, s.config.Auth.LoginMaxFailuresPerIP})
+	}
+	return limits
+}
+
+// normalizeEmail lower-cases an email for throttling keys
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// checkLoginAllowed returns a LoginBlockedError while the account or IP is in backoff or locked out.
+// Redis errors let the login through: throttling must not take logins down.
+func (s *Service) checkLoginAllowed(ctx context.Context, email, ip string) error {
+	limits := s.loginLimits(email, ip)
+	if len(limits) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(limits))
+	for _, l := range limits {
+		keys = append(keys, l.key())
+	}
+
+	wait, err := s.redis.LoginBlockedFor(ctx, keys...)
+	if err != nil {
+		logger.Errorf("Error checking login throttling: %v", err)
+		return nil
+	}
+	if wait > 0 {
+		return &LoginBlockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed login and blocks the next attempts:
+// exponential backoff after a third of the allowed failures, then a lockout
+func (s *Service) recordLoginFailure(ctx context.Context, email, ip string, user *model.User) {
+	lockout := s.config.Auth.LoginLockoutDuration
+
+	for _, l := range s.loginLimits(email, ip) {
+		failures, err := s.redis.IncrLoginFailures(ctx, l.key(), lockout)
+		if err != nil {
+			logger.Errorf("Error recording failed login for %s: %v", l.key(), err)
+			continue
+		}
+
+		delay := loginBackoff(failures, l.maxFailures, lockout)
+		if delay == 0 {
+			continue
+		}
+		if err := s.redis.BlockLogin(ctx, l.key(), delay); err != nil {
+			logger.Errorf("Error blocking login for %s: %v", l.key(), err)
+		}
+
+		if failures == int64(l.maxFailures) {
+			logger.Warnf("Login locked for %s after %d failed attempts", l.key(), failures)
+			s.auditLockout(ctx, l, user, ip, failures, lockout)
+		}
+	}
+}
+
+// clearLoginFailures resets the account counter after a successful login.
+// The IP counter is kept: one valid credential doesn't clear a stuffing attack.
+func (s *Service) clearLoginFailures(ctx context.Context, email string) {
+	if err := s.redis.ClearLoginFailures(ctx, "email:"+normalizeEmail(email)); err != nil {
+		logger.Errorf("Error clearing failed logins: %v", err)
+	}
+}
+
+// loginBackoff returns how long to block logins after the given number of failures
+func loginBackoff(failures int64, maxFailures int, lockout time.Duration) time.Duration {
+	if failures >= int64(maxFailures) {
+		return lockout
+	}
+
+	free := int64(maxFailures / 3)
+	if free < 1 {
+		free = 1
+	}
+	if failures < free {
+		return 0
+	}
+
+	// 1s, 2s, 4s... (the shift is bounded to avoid overflow)
+	if failures-free >= 6 {
+		return maxLoginBackoff
+	}
+	if delay := time.Second << (failures - free); delay < maxLoginBackoff {
+		return delay
+	}
+	return maxLoginBackoff
+}
+
+// auditLockout records a lockout in the audit log
+func (s *Service) auditLockout(ctx context.Context, l loginLimit, user *model.User, ip string, failures int64, lockout time.Duration) {
+	entry := &model.AuditEntry{
+		Action:    model.AuditLoginLocked,
+		IPAddress: ip,
+		Metadata: map[string]interface{}{
+			"scope":    l.scope,
+			"failures": failures,
+			"duration": lockout.String(),
+		},
+	}
+	if l.scope == "email" {
+		entry.TargetEmail = l.value
+		if user != nil {
+			entry.TargetUserID = &user.ID
+		}
+	}
+
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		logger.Errorf("Failed to write audit entry for lockout of %s: %v", l.key(), err)
+	}
+}
+
+// UnlockLogin lifts the lockout (and resets the counters) of an email and/or IP
+func (s *Service) UnlockLogin(ctx context.Context, actorID string, req *UnlockLoginRequest) error {
+	var scopes []string
+	if email := normalizeEmail(req.Email); email != "" {
+		if err := s.redis.ClearLoginFailures(ctx, "email:"+email); err != nil {
+			return err
+		}
+		scopes = append(scopes, "email")
+	}
+	if req.IP != "" {
+		if err := s.redis.ClearLoginFailures(ctx, "ip:"+req.IP); err != nil {
+			return err
+		}
+		scopes = append(scopes, "ip")
+	}
+
+	entry := &model.AuditEntry{
+		ActorID:     &actorID,
+		Action:      model.AuditLoginUnlocked,
+		TargetEmail: normalizeEmail(req.Email),
+		IPAddress:   req.IP,
+		Metadata:    map[string]interface{}{"scopes": scopes},
+	}
+	if user, err := s.userRepo.GetByEmail(ctx, req.Email); err == nil {
+		entry.TargetUserID = &user.ID
+	}
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		logger.Errorf("Failed to write audit entry for unlock by %s: %v", actorID, err)
+	}
+
+	logger.Infof("Login unlocked by admin %s (email %q, ip %q)", actorID, req.Email, req.IP)
+	return nil
+}
+
+// ListAuditLog returns the latest audit entries, optionally of one action
+func (s *Service) ListAuditLog(ctx context.Context, action string, limit int) ([]model.AuditEntry, error) {
+	return s.auditRepo.List(ctx, action, limit)
+}
+
+// IsAdmin checks if the user is an administrator
+func (s *Service) IsAdmin(ctx context.Context, userID string) (bool, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.IsAdmin, nil
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/config"
+)
+
+func TestLoginBackoff(t *testing.T) {
+	const lockout = 15 * time.Minute
+
+	tests := []struct {
+		failures    int64
+		maxFailures int
+		want        time.Duration
+	}{
+		{failures: 1, maxFailures: 9, want: 0},
+		{failures: 2, maxFailures: 9, want: 0},
+		{failures: 3, maxFailures: 9, want: time.Second},
+		{failures: 4, maxFailures: 9, want: 2 * time.Second},
+		{failures: 8, maxFailures: 9, want: 32 * time.Second},
+		{failures: 9, maxFailures: 9, want: lockout},
+		{failures: 12, maxFailures: 9, want: lockout},
+		{failures: 17, maxFailures: 30, want: maxLoginBackoff},
+		{failures: 1, maxFailures: 2, want: time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := loginBackoff(tt.failures, tt.maxFailures, lockout); got != tt.want {
+			t.Errorf("loginBackoff(%d, %d) = %s, want %s", tt.failures, tt.maxFailures, got, tt.want)
+		}
+	}
+}
+
+// lockoutEnv locks accounts after 3 failed logins and IPs after 5
+func lockoutEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	return newTestEnv(t, config.AuthConfig{
+		LoginMaxFailures:      3,
+		LoginMaxFailuresPerIP: 5,
+		LoginLockoutDuration:  15 * time.Minute,
+	})
+}
+
+func TestLockoutLooksTheSameForUnknownEmails(t *testing.T) {
+	tests := map[string]bool{"registered": true, "unknown": false}
+
+	for name, registered := range tests {
+		t.Run(name, func(t *testing.T) {
+			env := lockoutEnv(t)
+			ctx := context.Background()
+			email := "nobody@example.com"
+			if registered {
+				email = env.addUser(t, "alice@example.com").Email
+			}
+
+			for i, want := range []error{ErrInvalidCredentials, ErrInvalidCredentials, ErrInvalidCredentials, ErrLoginBlocked} {
+				_, err := env.service.Login(ctx, &LoginRequest{Email: email, Password: "wrong"}, ClientInfo{})
+				if !errors.Is(err, want) {
+					t.Errorf("attempt %d: err = %v, want %v", i+1, err, want)
+				}
+				env.redis.FastForward(time.Minute) // Past the backoff, not the lockout
+			}
+
+			env.audit.mu.Lock()
+			defer env.audit.mu.Unlock()
+			if len(env.audit.entries) != 1 || env.audit.entries[0].Action != model.AuditLoginLocked || env.audit.entries[0].TargetEmail != email {
+				t.Errorf("audit log = %+v, want the lockout of %s", env.audit.entries, email)
+			}
+		})
+	}
+}
+
+func TestIPLockoutAndUnlock(t *testing.T) {
+	env := lockoutEnv(t)
+	ctx := context.Background()
+	user := env.addUser(t, "alice@example.com")
+	attacker := ClientInfo{IP: "203.0.113.7"}
+
+	// Credential stuffing: one attempt per account, all from the same IP
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"} {
+		env.redis.FastForward(time.Minute)
+		if _, err := env.service.Login(ctx, &LoginRequest{Email: email, Password: "wrong"}, attacker); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("attempt for %s: err = %v", email, err)
+		}
+	}
+
+	env.redis.FastForward(time.Minute)
+	_, err := env.service.Login(ctx, &LoginRequest{Email: user.Email, Password: testPassword}, attacker)
+	var blocked *LoginBlockedError
+	if !errors.As(err, &blocked) || blocked.RetryAfter <= 0 {
+		t.Fatalf("right password from the locked IP: err = %v, want a LoginBlockedError", err)
+	}
+	if _, err := env.service.Login(ctx, &LoginRequest{Email: user.Email, Password: testPassword}, ClientInfo{IP: "198.51.100.23"}); err != nil {
+		t.Errorf("from another IP: %v", err)
+	}
+
+	if err := env.service.UnlockLogin(ctx, "admin-id", &UnlockLoginRequest{IP: attacker.IP}); err != nil {
+		t.Fatalf("UnlockLogin: %v", err)
+	}
+	if _, err := env.service.Login(ctx, &LoginRequest{Email: user.Email, Password: testPassword}, attacker); err != nil {
+		t.Errorf("after the unlock: %v", err)
+	}
+
+	env.audit.mu.Lock()
+	defer env.audit.mu.Unlock()
+	if last := env.audit.entries[len(env.audit.entries)-1]; last.Action != model.AuditLoginUnlocked || last.IPAddress != attacker.IP {
+		t.Errorf("last audit entry = %+v, want the unlock of %s", last, attacker.IP)
+	}
+}
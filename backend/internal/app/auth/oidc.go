@@ -0,0 +1,321 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/oidc"
+)
+
+var (
+	ErrOIDCProviderNotFound = errors.New("unknown identity provider")
+	ErrOIDCInvalidState     = errors.New("invalid or expired SSO login")
+	ErrOIDCFailed           = errors.New("identity provider login failed")
+	ErrOIDCEmailMissing     = errors.New("identity provider did not share an email")
+	ErrOIDCEmailNotVerified = errors.New("identity provider email not verified")
+)
+
+// oidcState is kept in Redis between the redirect to the provider and the callback
+type oidcState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	Redirect     string `json:"redirect"`
+}
+
+// OIDCLogin is the result of an SSO callback
+type OIDCLogin struct {
+	*AuthResponse
+	Redirect string // Frontend path to return to
+}
+
+// OIDCProviders returns the configured identity providers (for login buttons)
+func (s *Service) OIDCProviders() []oidc.ProviderInfo {
+	return s.oidc.List()
+}
+
+// BeginOIDCLogin starts an SSO login and returns the provider's authorization URL
+// and the state, which the caller must also bind to the browser
+func (s *Service) BeginOIDCLogin(ctx context.Context, providerID, redirect string) (authURL, state string, err error) {
+	provider, ok := s.oidc.Get(providerID)
+	if !ok {
+		return "", "", ErrOIDCProviderNotFound
+	}
+
+	state, err = oidc.RandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err = provider.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		logger.Errorf("OIDC provider %s unavailable: %v", providerID, err)
+		return "", "", ErrOIDCFailed
+	}
+
+	data, err := json.Marshal(&oidcState{
+		Provider:     providerID,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		Redirect:     safeRedirect(redirect),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.redis.SaveOIDCState(ctx, state, data, s.config.OIDC.StateExpiry); err != nil {
+		return "", "", err
+	}
+
+	return authURL, state, nil
+}
+
+// FinishOIDCLogin handles the provider's callback: it exchanges the code, validates the ID token
+// and logs in the linked user, linking or creating the account on first login
+func (s *Service) FinishOIDCLogin(ctx context.Context, providerID, code, state string, client ClientInfo) (*OIDCLogin, error) {
+	provider, ok := s.oidc.Get(providerID)
+	if !ok {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	data, err := s.redis.TakeOIDCState(ctx, state)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrOIDCInvalidState
+		}
+		return nil, err
+	}
+
+	var st oidcState
+	if err := json.Unmarshal(data, &st); err != nil || st.Provider != providerID {
+		return nil, ErrOIDCInvalidState
+	}
+
+	token, err := provider.Exchange(ctx, code, st.CodeVerifier)
+	if err != nil {
+		logger.Warnf("OIDC code exchange with %s failed: %v", providerID, err)
+		return nil, ErrOIDCFailed
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, st.Nonce)
+	if err != nil {
+		logger.Warnf("OIDC ID token from %s rejected: %v", providerID, err)
+		return nil, ErrOIDCFailed
+	}
+
+	user, err := s.resolveOIDCUser(ctx, providerID, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	login := &OIDCLogin{Redirect: st.Redirect}
+
+	if user.IsTwoFactorEnabled() {
+		login.AuthResponse, err = s.startTwoFactorChallenge(user)
+		return login, err
+	}
+
+	tokens, err := s.startSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("User logged in with %s: %s", providerID, user.Email)
+
+	login.AuthResponse = &AuthResponse{
+		User:   user.ToResponse(),
+		Tokens: tokens,
+	}
+	return login, nil
+}
+
+// resolveOIDCUser finds the user of a provider account: an already linked identity,
+// an existing user with the same verified email (linked now), or a new user
+func (s *Service) resolveOIDCUser(ctx context.Context, providerID string, claims *oidc.Claims) (*model.User, error) {
+	identity, err := s.identities.GetByProviderSubject(ctx, providerID, claims.Subject)
+	if err == nil {
+		user, err := s.userRepo.GetByID(ctx, identity.UserID)
+		if err != nil {
+			if errors.Is(err, repository.ErrUserNotFound) {
+				return nil, ErrUserNotFound
+			}
+			return nil, err
+		}
+		if err := s.identities.MarkLogin(ctx, identity.ID, claims.Email); err != nil {
+			logger.Errorf("Failed to update identity %s: %v", identity.ID, err)
+		}
+		return user, nil
+	}
+	if !errors.Is(err, repository.ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	email := strings.TrimSpace(claims.Email)
+	if email == "" {
+		return nil, ErrOIDCEmailMissing
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if err := s.linkOIDCUser(ctx, user, providerID, claims); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, repository.ErrUserNotFound):
+		user, err = s.provisionOIDCUser(ctx, email, providerID, claims)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// linkOIDCUser links a provider account to an existing user with the same email.
+// Only a provider-verified email is trusted for this.
+func (s *Service) linkOIDCUser(ctx context.Context, user *model.User, providerID string, claims *oidc.Claims) error {
+	if !claims.IsEmailVerified() {
+		return ErrOIDCEmailNotVerified
+	}
+
+	if !user.IsEmailVerified() {
+		// Nobody proved owning this email before: someone could have registered it in advance
+		// to share the account with its real owner. Drop their password and sessions.
+		user.PasswordHash = ""
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return err
+		}
+		if _, err := s.revokeSessions(ctx, user.ID, "", model.SessionRevokedSSO); err != nil {
+			return err
+		}
+		if err := s.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+			return err
+		}
+		logger.Warnf("Unverified account %s taken over by its %s identity, password cleared", user.ID, providerID)
+	}
+
+	if err := s.identities.Create(ctx, &model.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerID,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}); err != nil {
+		return err
+	}
+
+	logger.Infof("Linked %s identity to user %s", providerID, user.ID)
+	return nil
+}
+
+// provisionOIDCUser creates the account of a first SSO login. The user has no password
+// (they can set one with the password reset flow).
+func (s *Service) provisionOIDCUser(ctx context.Context, email, providerID string, claims *oidc.Claims) (*model.User, error) {
+	username, err := s.availableUsername(ctx, claims, email)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &model.User{
+		Email:    email,
+		Username: username,
+		IsActive: true,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrUserAlreadyExists) {
+			return nil, ErrEmailAlreadyExists
+		}
+		return nil, err
+	}
+
+	if claims.IsEmailVerified() {
+		if err := s.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		if user, err = s.userRepo.GetByID(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.identities.Create(ctx, &model.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerID,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("User registered with %s: %s", providerID, user.Email)
+
+	s.onUserRegistered(ctx, user)
+
+	return user, nil
+}
+
+// availableUsername derives a free username (alphanumeric, 3-50 chars) from the provider's profile
+func (s *Service) availableUsername(ctx context.Context, claims *oidc.Claims, email string) (string, error) {
+	base := ""
+	for _, candidate := range []string{claims.PreferredUsername, claims.Name, strings.Split(email, "@")[0]} {
+		if base = sanitizeUsername(candidate); len(base) >= 3 {
+			break
+		}
+	}
+	if len(base) < 3 {
+		base = "user"
+	}
+	if len(base) > 40 {
+		base = base[:40]
+	}
+
+	username := base
+	for i := 1; i <= 20; i++ {
+		_, err := s.userRepo.GetByUsername(ctx, username)
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return username, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		username = fmt.Sprintf("%s%d", base, i+1)
+	}
+
+	return base + strings.ReplaceAll(uuid.New().String(), "-", "")[:8], nil
+}
+
+// sanitizeUsername keeps the ASCII letters and digits of a name
+func sanitizeUsername(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// safeRedirect only allows local paths, so the login can't be used as an open redirect
+func safeRedirect(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.Contains(path, "\\") {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,331 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/passkey"
+)
+
+var (
+	ErrPasskeyFailed        = errors.New("passkey verification failed")
+	ErrPasskeyNotFound      = errors.New("passkey not found")
+	ErrPasskeyAlreadyExists = errors.New("passkey already registered")
+	ErrCeremonyExpired      = errors.New("passkey ceremony expired")
+)
+
+const (
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+)
+
+// PasskeyOptions is returned when a ceremony starts. Options is passed as-is to
+// navigator.credentials.create() / get(); CeremonyID is sent back to finish it.
+type PasskeyOptions struct {
+	CeremonyID string      `json:"ceremony_id"`
+	Options    interface{} `json:"options"`
+}
+
+// FinishPasskeyRegistrationRequest carries the authenticator's attestation
+type FinishPasskeyRegistrationRequest struct {
+	CeremonyID string          `json:"ceremony_id" validate:"required"`
+	Name       string          `json:"name" validate:"max=100"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
+
+// FinishPasskeyLoginRequest carries the authenticator's assertion
+type FinishPasskeyLoginRequest struct {
+	CeremonyID string          `json:"ceremony_id" validate:"required"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
+
+// ceremony is the server-side state of a passkey ceremony, kept in Redis
+type ceremony struct {
+	Kind    string               `json:"kind"`
+	UserID  string               `json:"user_id,omitempty"`
+	Session webauthn.SessionData `json:"session"`
+}
+
+// userHandle returns the WebAuthn user handle of a user (the raw UUID bytes)
+func userHandle(userID string) []byte {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return []byte(userID)
+	}
+	return id[:]
+}
+
+// passkeyUser builds the WebAuthn view of a user and their credentials
+func passkeyUser(user *model.User, passkeys []model.Passkey) *passkey.User {
+	creds := make([]webauthn.Credential, 0, len(passkeys))
+	for _, p := range passkeys {
+		transports := make([]protocol.AuthenticatorTransport, 0, len(p.Transports))
+		for _, t := range p.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(t))
+		}
+
+		creds = append(creds, webauthn.Credential{
+			ID:              p.CredentialID,
+			PublicKey:       p.PublicKey,
+			AttestationType: p.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				UserPresent:    true,
+				UserVerified:   true,
+				BackupEligible: p.BackupEligible,
+				BackupState:    p.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    p.AAGUID,
+				SignCount: p.SignCount,
+			},
+		})
+	}
+
+	return &passkey.User{
+		ID:          userHandle(user.ID),
+		Name:        user.Email,
+		DisplayName: user.Username,
+		Credentials: creds,
+	}
+}
+
+// saveCeremony stores a ceremony in Redis and returns its ID
+func (s *Service) saveCeremony(ctx context.Context, c *ceremony) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	id := uuid.New().String()
+	if err := s.redis.SaveWebAuthnCeremony(ctx, id, data, s.passkeys.Timeout()); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// takeCeremony loads (and consumes) a ceremony of the expected kind
+func (s *Service) takeCeremony(ctx context.Context, id, kind string) (*ceremony, error) {
+	data, err := s.redis.TakeWebAuthnCeremony(ctx, id)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrCeremonyExpired
+		}
+		return nil, err
+	}
+
+	var c ceremony
+	if err := json.Unmarshal(data, &c); err != nil || c.Kind != kind {
+		return nil, ErrCeremonyExpired
+	}
+	return &c, nil
+}
+
+// BeginPasskeyRegistration starts registering a new passkey for the user
+func (s *Service) BeginPasskeyRegistration(ctx context.Context, userID string) (*PasskeyOptions, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	existing, err := s.passkeyRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	creation, session, err := s.passkeys.BeginRegistration(passkeyUser(user, existing))
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.saveCeremony(ctx, &ceremony{Kind: ceremonyRegistration, UserID: user.ID, Session: *session})
+	if err != nil {
+		return nil, err
+	}
+
+	return &PasskeyOptions{CeremonyID: id, Options: creation}, nil
+}
+
+// FinishPasskeyRegistration verifies the attestation and stores the new passkey
+func (s *Service) FinishPasskeyRegistration(ctx context.Context, userID string, req *FinishPasskeyRegistrationRequest, client ClientInfo) (*model.PasskeyResponse, error) {
+	c, err := s.takeCeremony(ctx, req.CeremonyID, ceremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if c.UserID != userID {
+		return nil, ErrCeremonyExpired
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	existing, err := s.passkeyRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	cred, err := s.passkeys.FinishRegistration(passkeyUser(user, existing), c.Session, req.Credential)
+	if err != nil {
+		logger.Warnf("Passkey registration failed for user %s: %v", user.ID, err)
+		return nil, ErrPasskeyFailed
+	}
+
+	transports := make([]string, 0, len(cred.Transport))
+	for _, t := range cred.Transport {
+		transports = append(transports, string(t))
+	}
+
+	// Default to a "Browser on OS" label, like the session list
+	name := req.Name
+	if name == "" {
+		name = describeDevice(client.UserAgent)
+	}
+
+	p := &model.Passkey{
+		UserID:          user.ID,
+		CredentialID:    cred.ID,
+		PublicKey:       cred.PublicKey,
+		AttestationType: cred.AttestationType,
+		AAGUID:          cred.Authenticator.AAGUID,
+		SignCount:       cred.Authenticator.SignCount,
+		Transports:      transports,
+		BackupEligible:  cred.Flags.BackupEligible,
+		BackupState:     cred.Flags.BackupState,
+		Name:            name,
+	}
+	if err := s.passkeyRepo.Create(ctx, p); err != nil {
+		if errors.Is(err, repository.ErrPasskeyAlreadyExists) {
+			return nil, ErrPasskeyAlreadyExists
+		}
+		return nil, err
+	}
+
+	logger.Infof("Passkey %s registered for user %s", p.ID, user.ID)
+
+	response := p.ToResponse()
+	return &response, nil
+}
+
+// BeginPasskeyLogin starts a passwordless login (the authenticator picks the account)
+func (s *Service) BeginPasskeyLogin(ctx context.Context) (*PasskeyOptions, error) {
+	assertion, session, err := s.passkeys.BeginLogin()
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.saveCeremony(ctx, &ceremony{Kind: ceremonyLogin, Session: *session})
+	if err != nil {
+		return nil, err
+	}
+
+	return &PasskeyOptions{CeremonyID: id, Options: assertion}, nil
+}
+
+// FinishPasskeyLogin verifies the assertion and starts a session, like Login.
+// A passkey is already two factors (device + biometrics/PIN), so TOTP is not asked.
+func (s *Service) FinishPasskeyLogin(ctx context.Context, req *FinishPasskeyLoginRequest, client ClientInfo) (*AuthResponse, error) {
+	c, err := s.takeCeremony(ctx, req.CeremonyID, ceremonyLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *model.User
+	var passkeys []model.Passkey
+
+	lookup := func(credentialID, handle []byte) (*passkey.User, error) {
+		p, err := s.passkeyRepo.GetByCredentialID(ctx, credentialID)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(handle, userHandle(p.UserID)) {
+			return nil, ErrPasskeyNotFound
+		}
+
+		user, err = s.userRepo.GetByID(ctx, p.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		passkeys, err = s.passkeyRepo.ListByUser(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return passkeyUser(user, passkeys), nil
+	}
+
+	_, cred, err := s.passkeys.FinishLogin(c.Session, req.Credential, lookup)
+	if err != nil {
+		logger.Warnf("Passkey login failed: %v", err)
+		return nil, ErrPasskeyFailed
+	}
+
+	for _, p := range passkeys {
+		if bytes.Equal(p.CredentialID, cred.ID) {
+			if err := s.passkeyRepo.MarkUsed(ctx, p.ID, cred.Authenticator.SignCount, cred.Flags.BackupState); err != nil {
+				logger.Errorf("Failed to update passkey %s: %v", p.ID, err)
+			}
+			break
+		}
+	}
+
+	tokens, err := s.startSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("User logged in with passkey: %s", user.Email)
+
+	return &AuthResponse{
+		User:   user.ToResponse(),
+		Tokens: tokens,
+	}, nil
+}
+
+// ListPasskeys returns the user's registered passkeys
+func (s *Service) ListPasskeys(ctx context.Context, userID string) ([]model.PasskeyResponse, error) {
+	passkeys, err := s.passkeyRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.PasskeyResponse, 0, len(passkeys))
+	for _, p := range passkeys {
+		result = append(result, p.ToResponse())
+	}
+	return result, nil
+}
+
+// DeletePasskey removes one of the user's passkeys
+func (s *Service) DeletePasskey(ctx context.Context, userID, passkeyID string) error {
+	if _, err := uuid.Parse(passkeyID); err != nil {
+		return ErrPasskeyNotFound
+	}
+
+	if err := s.passkeyRepo.Delete(ctx, userID, passkeyID); err != nil {
+		if errors.Is(err, repository.ErrPasskeyNotFound) {
+			return ErrPasskeyNotFound
+		}
+		return err
+	}
+
+	logger.Infof("Passkey %s deleted by user %s", passkeyID, userID)
+	return nil
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/config"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/password"
+)
+
+// NewPasswordHasher creates the password hasher from the PASSWORD_* settings (Fx provider)
+func NewPasswordHasher(cfg *config.Config) (*password.Hasher, error) {
+	pc := cfg.Password
+	hasher, err := password.New(password.Params{
+		Algorithm:   pc.Algorithm,
+		Memory:      uint32(max(pc.Argon2Memory, 0)),
+		Iterations:  uint32(max(pc.Argon2Iterations, 0)),
+		Parallelism: uint8(min(max(pc.Argon2Parallelism, 0), 255)),
+		BcryptCost:  pc.BcryptCost,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("password hashing: %w", err)
+	}
+
+	logger.Infof("Password hasher initialized (%s)", pc.Algorithm)
+	return hasher, nil
+}
+
+// setPassword hashes and sets the user's password
+func (s *Service) setPassword(user *model.User, plain string) error {
+	hash, err := s.passwords.Hash(plain)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+	return nil
+}
+
+// checkPassword verifies a password and upgrades its hash when it was made with
+// older parameters (e.g. bcrypt before argon2id). Users without a password (SSO
+// accounts, or nil for an unknown email) are compared to a dummy hash, so every
+// failure costs the same time.
+func (s *Service) checkPassword(ctx context.Context, user *model.User, plain string) bool {
+	if user == nil || user.PasswordHash == "" {
+		_, _, _ = s.passwords.Verify(plain, s.dummyHash())
+		return false
+	}
+
+	ok, needsRehash, err := s.passwords.Verify(plain, user.PasswordHash)
+	if err != nil {
+		logger.Errorf("Cannot verify password of user %s: %v", user.ID, err)
+		return false
+	}
+	if ok && needsRehash {
+		s.rehashPassword(ctx, user, plain)
+	}
+	return ok
+}
+
+// rehashPassword stores a hash with the current parameters; failures only delay the upgrade
+func (s *Service) rehashPassword(ctx context.Context, user *model.User, plain string) {
+	hash, err := s.passwords.Hash(plain)
+	if err != nil {
+		logger.Errorf("Cannot rehash password of user %s: %v", user.ID, err)
+		return
+	}
+	if err := s.userRepo.RehashPassword(ctx, user.ID, user.PasswordHash, hash); err != nil {
+		logger.Errorf("Cannot store rehashed password of user %s: %v", user.ID, err)
+		return
+	}
+	user.PasswordHash = hash
+	logger.Infof("Password hash of user %s upgraded", user.ID)
+}
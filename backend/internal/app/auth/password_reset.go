@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/mailer"
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+)
+
+// ForgotPasswordRequest represents a request to receive a password reset link
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest represents a request to set a new password with a reset token
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8,max=128,strongpassword"`
+}
+
+// ForgotPassword emails a password reset link if the account exists.
+// It never reports whether the email is registered: the account lookup, the token
+// and the email are handled in the background, so the response takes as long either way.
+func (s *Service) ForgotPassword(req *ForgotPasswordRequest) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+
+		if err := s.sendResetLink(ctx, req.Email); err != nil {
+			logger.Errorf("Failed to send password reset link: %v", err)
+		}
+	}()
+}
+
+// sendResetLink emails a new password reset link to the account with the email, if any
+func (s *Service) sendResetLink(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			logger.Infof("Password reset requested for unknown email")
+			return nil
+		}
+		return err
+	}
+
+	token, err := s.issueUserToken(ctx, user.ID, model.UserTokenPasswordReset, s.config.Auth.PasswordResetExpiry)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", s.config.Server.FrontendURL, url.QueryEscape(token))
+	err = s.mailer.Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your GoChat password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nSomeone asked to reset the password of your GoChat account.\n"+
+				"Use the link below within %s to choose a new password:\n\n%s\n\n"+
+				"If you didn't ask for this, you can ignore this email.\n",
+			user.Username, s.config.Auth.PasswordResetExpiry, link,
+		),
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Password reset requested for user %s", user.ID)
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token and logs out every session
+func (s *Service) ResetPassword(ctx context.Context, req *ResetPasswordRequest) error {
+	token, err := s.tokenRepo.Consume(ctx, model.UserTokenPasswordReset, hashToken(req.Token))
+	if err != nil {
+		if errors.Is(err, repository.ErrUserTokenNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, token.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	if err := s.setPassword(user, req.Password); err != nil {
+		return err
+	}
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	count, err := s.revokeSessions(ctx, user.ID, "", model.SessionRevokedReset)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Password reset for user %s, %d session(s) revoked", user.ID, count)
+	return nil
+}
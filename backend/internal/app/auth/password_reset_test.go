@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/config"
+)
+
+// blockingUserRepo holds account lookups by email until released
+type blockingUserRepo struct {
+	*fakeUserRepo
+	release chan struct{}
+}
+
+func (r *blockingUserRepo) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	<-r.release
+	return r.fakeUserRepo.GetByEmail(ctx, email)
+}
+
+func TestForgotPasswordDoesNotRevealAccounts(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		wantMail bool
+	}{
+		{name: "registered", email: "alice@example.com", wantMail: true},
+		{name: "unknown", email: "nobody@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, config.AuthConfig{PasswordResetExpiry: time.Hour})
+			user := env.addUser(t, "alice@example.com")
+			lookup := make(chan struct{})
+			env.service.userRepo = &blockingUserRepo{fakeUserRepo: env.users, release: lookup}
+
+			// The answer doesn't wait for the lookup, whether the account exists or not
+			answered := make(chan struct{})
+			go func() {
+				env.service.ForgotPassword(&ForgotPasswordRequest{Email: tt.email})
+				close(answered)
+			}()
+			select {
+			case <-answered:
+			case <-time.After(time.Second):
+				t.Fatal("ForgotPassword waited for the account lookup")
+			}
+			close(lookup)
+
+			select {
+			case msg := <-env.mail.sent:
+				if !tt.wantMail {
+					t.Fatalf("email sent to %s", msg.To)
+				}
+				if msg.To != user.Email {
+					t.Errorf("email sent to %s, want %s", msg.To, user.Email)
+				}
+				env.tokens.mu.Lock()
+				defer env.tokens.mu.Unlock()
+				if len(env.tokens.tokens) != 1 || env.tokens.tokens[0].Purpose != model.UserTokenPasswordReset {
+					t.Errorf("tokens issued = %+v, want one reset token", env.tokens.tokens)
+				}
+			case <-time.After(200 * time.Millisecond):
+				if tt.wantMail {
+					t.Fatal("no reset email sent")
+				}
+			}
+		})
+	}
+}
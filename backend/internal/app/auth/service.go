@@ -3,10 +3,21 @@ package auth
 import (
 	"context"
 	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/fx"
 
 	"github.com/Beretta350/gochat/internal/app/model"
 	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/internal/config"
 	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/mailer"
+	"github.com/Beretta350/gochat/pkg/oidc"
+	"github.com/Beretta350/gochat/pkg/passkey"
+	"github.com/Beretta350/gochat/pkg/password"
+	"github.com/Beretta350/gochat/pkg/redisclient"
 )
 
 // DefaultChatUserEmail is the email of the user that all new users should have a direct chat with
@@ -18,23 +29,91 @@ var (
 	ErrUserNotFound       = errors.New("user not found")
 )
 
+// ConversationNotifier tells the participants of a new conversation about it, so
+// those connected follow it at once (implemented by chat.Service)
+type ConversationNotifier interface {
+	NotifyCreated(ctx context.Context, actorID string, conv *model.Conversation, participants []model.Participant)
+}
+
 // Service handles authentication operations
 type Service struct {
-	userRepo   repository.UserRepository
-	convRepo   repository.ConversationRepository
-	jwtService *JWTService
+	config       *config.Config
+	userRepo     repository.UserRepository
+	convRepo     repository.ConversationRepository
+	convNotifier ConversationNotifier
+	sessionRepo  repository.SessionRepository
+	tokenRepo    repository.UserTokenRepository
+	codeRepo     repository.RecoveryCodeRepository
+	passkeyRepo  repository.PasskeyRepository
+	identities   repository.UserIdentityRepository
+	auditRepo    repository.AuditRepository
+	apiTokenRepo repository.APITokenRepository
+	redis        *redisclient.Client
+	mailer       mailer.Mailer
+	passkeys     *passkey.Service
+	oidc         *oidc.Registry
+	jwtService   *JWTService
+	passwords    *password.Hasher
+	dummyHash    func() string // Hash compared when there is no password to check (see checkPassword)
+}
+
+// ServiceParams holds the dependencies of the auth service
+type ServiceParams struct {
+	fx.In
+
+	Config       *config.Config
+	UserRepo     repository.UserRepository
+	ConvRepo     repository.ConversationRepository
+	ConvNotifier ConversationNotifier
+	SessionRepo  repository.SessionRepository
+	TokenRepo    repository.UserTokenRepository
+	CodeRepo     repository.RecoveryCodeRepository
+	PasskeyRepo  repository.PasskeyRepository
+	Identities   repository.UserIdentityRepository
+	AuditRepo    repository.AuditRepository
+	APITokenRepo repository.APITokenRepository
+	Redis        *redisclient.Client
+	Mailer       mailer.Mailer
+	Passkeys     *passkey.Service
+	OIDC         *oidc.Registry
+	JWTService   *JWTService
+	Passwords    *password.Hasher
 }
 
 // NewService creates a new auth service (Fx provider)
-func NewService(userRepo repository.UserRepository, convRepo repository.ConversationRepository, jwtService *JWTService) *Service {
+func NewService(p ServiceParams) *Service {
 	logger.Info("Auth service initialized")
 	return &Service{
-		userRepo:   userRepo,
-		convRepo:   convRepo,
-		jwtService: jwtService,
+		config:       p.Config,
+		userRepo:     p.UserRepo,
+		convRepo:     p.ConvRepo,
+		convNotifier: p.ConvNotifier,
+		sessionRepo:  p.SessionRepo,
+		tokenRepo:    p.TokenRepo,
+		codeRepo:     p.CodeRepo,
+		passkeyRepo:  p.PasskeyRepo,
+		identities:   p.Identities,
+		auditRepo:    p.AuditRepo,
+		apiTokenRepo: p.APITokenRepo,
+		redis:        p.Redis,
+		mailer:       p.Mailer,
+		passkeys:     p.Passkeys,
+		oidc:         p.OIDC,
+		jwtService:   p.JWTService,
+		passwords:    p.Passwords,
+		dummyHash: sync.OnceValue(func() string {
+			hash, _ := p.Passwords.Hash(uuid.New().String())
+			return hash
+		}),
 	}
 }
 
+// ClientInfo describes the device a session is created from
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
 // RegisterRequest represents a registration request
 type RegisterRequest struct {
 	Email    string `json:"email" validate:"required,email,max=255"`
@@ -53,14 +132,18 @@ type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
-// AuthResponse represents the authentication response
+// AuthResponse represents the authentication response.
+// When the user has 2FA enabled, Login only returns a challenge token
+// that must be exchanged with VerifyTwoFactor.
 type AuthResponse struct {
-	User   *model.UserResponse `json:"user"`
-	Tokens *TokenPair          `json:"tokens"`
+	User           *model.UserResponse `json:"user,omitempty"`
+	Tokens         *TokenPair          `json:"tokens,omitempty"`
+	MFARequired    bool                `json:"mfa_required,omitempty"`
+	ChallengeToken string              `json:"challenge_token,omitempty"`
 }
 
 // Register creates a new user and returns tokens
-func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*AuthResponse, error) {
+func (s *Service) Register(ctx context.Context, req *RegisterRequest, client ClientInfo) (*AuthResponse, error) {
 	// Check if email already exists
 	_, err := s.userRepo.GetByEmail(ctx, req.Email)
 	if err == nil {
@@ -77,7 +160,7 @@ func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*AuthResp
 		IsActive: true,
 	}
 
-	if err := user.SetPassword(req.Password); err != nil {
+	if err := s.setPassword(user, req.Password); err != nil {
 		return nil, err
 	}
 
@@ -88,20 +171,15 @@ func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*AuthResp
 		return nil, err
 	}
 
-	// Generate tokens
-	tokens, err := s.jwtService.GenerateTokenPair(user.ID, user.Email, user.Username)
+	// Start a session and generate tokens
+	tokens, err := s.startSession(ctx, user, client)
 	if err != nil {
 		return nil, err
 	}
 
 	logger.Infof("User registered: %s", user.Email)
 
-	// Auto-create direct chat with Gabriel if:
-	// 1. The registering user is NOT Gabriel
-	// 2. Gabriel's user exists in the system
-	if req.Email != DefaultChatUserEmail {
-		s.createDefaultDirectChat(ctx, user.ID)
-	}
+	s.onUserRegistered(ctx, user)
 
 	return &AuthResponse{
 		User:   user.ToResponse(),
@@ -109,6 +187,23 @@ func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*AuthResp
 	}, nil
 }
 
+// onUserRegistered runs the side effects of a new account (password or SSO sign-up)
+func (s *Service) onUserRegistered(ctx context.Context, user *model.User) {
+	// Registration succeeds even if the email can't be sent; the user can ask for a new link
+	if !user.IsEmailVerified() {
+		if err := s.sendVerificationEmail(ctx, user); err != nil {
+			logger.Errorf("Failed to send verification email to user %s: %v", user.ID, err)
+		}
+	}
+
+	// Auto-create direct chat with Gabriel if:
+	// 1. The registering user is NOT Gabriel
+	// 2. Gabriel's user exists in the system
+	if user.Email != DefaultChatUserEmail {
+		s.createDefaultDirectChat(ctx, user.ID)
+	}
+}
+
 // createDefaultDirectChat creates a direct conversation between the new user and Gabriel
 func (s *Service) createDefaultDirectChat(ctx context.Context, newUserID string) {
 	// Check if Gabriel's user exists
@@ -141,24 +236,47 @@ func (s *Service) createDefaultDirectChat(ctx context.Context, newUserID string)
 		return
 	}
 
+	// Let Gabriel's open connections follow the new conversation
+	participants, err := s.convRepo.GetParticipants(ctx, conv.ID)
+	if err != nil {
+		logger.Errorf("Error loading participants of default direct chat %s: %v", conv.ID, err)
+	} else {
+		s.convNotifier.NotifyCreated(ctx, newUserID, conv, participants)
+	}
+
 	logger.Infof("Auto-created direct chat between new user %s and Gabriel", newUserID)
 }
 
-// Login authenticates a user and returns tokens
-func (s *Service) Login(ctx context.Context, req *LoginRequest) (*AuthResponse, error) {
+// Login authenticates a user and returns tokens.
+// Failed attempts are throttled per account and per IP (see lockout.go).
+func (s *Service) Login(ctx context.Context, req *LoginRequest, client ClientInfo) (*AuthResponse, error) {
+	if err := s.checkLoginAllowed(ctx, req.Email, client.IP); err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.GetByEmail(ctx, req.Email)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
+			// Same work and same response as a wrong password
+			s.checkPassword(ctx, nil, req.Password)
+			s.recordLoginFailure(ctx, req.Email, client.IP, nil)
 			return nil, ErrInvalidCredentials
 		}
 		return nil, err
 	}
 
-	if !user.CheckPassword(req.Password) {
+	if !s.checkPassword(ctx, user, req.Password) {
+		s.recordLoginFailure(ctx, req.Email, client.IP, user)
 		return nil, ErrInvalidCredentials
 	}
 
-	tokens, err := s.jwtService.GenerateTokenPair(user.ID, user.Email, user.Username)
+	// The failures are only cleared once the second factor is right too (see VerifyTwoFactor)
+	if user.IsTwoFactorEnabled() {
+		return s.startTwoFactorChallenge(user)
+	}
+	s.clearLoginFailures(ctx, req.Email)
+
+	tokens, err := s.startSession(ctx, user, client)
 	if err != nil {
 		return nil, err
 	}
@@ -171,13 +289,35 @@ func (s *Service) Login(ctx context.Context, req *LoginRequest) (*AuthResponse,
 	}, nil
 }
 
-// Refresh generates a new access token from a refresh token
+// Refresh rotates the refresh token of a session and returns a new token pair.
+// Presenting a refresh token that was already rotated revokes the whole session.
 func (s *Service) Refresh(ctx context.Context, req *RefreshRequest) (*TokenPair, error) {
 	claims, err := s.jwtService.ValidateRefreshToken(req.RefreshToken)
 	if err != nil {
 		return nil, err
 	}
 
+	// Refresh tokens issued before server-side sessions have no family to rotate
+	if claims.SessionID == "" || claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+
+	session, err := s.sessionRepo.GetByID(ctx, claims.SessionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if session.UserID != claims.UserID {
+		return nil, ErrInvalidToken
+	}
+
+	if !session.IsActive() {
+		return nil, ErrSessionRevoked
+	}
+
 	// Verify user still exists and is active
 	user, err := s.userRepo.GetByID(ctx, claims.UserID)
 	if err != nil {
@@ -188,8 +328,22 @@ func (s *Service) Refresh(ctx context.Context, req *RefreshRequest) (*TokenPair,
 		return nil, ErrUserNotFound
 	}
 
+	// Rotate: the presented token must be the current one of the family
+	newJTI := uuid.New().String()
+	expiresAt := time.Now().Add(s.jwtService.RefreshExpiry())
+	if err := s.sessionRepo.Rotate(ctx, session.ID, claims.ID, newJTI, expiresAt); err != nil {
+		if errors.Is(err, repository.ErrStaleRefreshJTI) {
+			logger.Warnf("Refresh token reuse detected for session %s (user %s), revoking session", session.ID, user.ID)
+			if err := s.revokeSession(ctx, session.ID, model.SessionRevokedReuse); err != nil {
+				logger.Errorf("Failed to revoke session %s: %v", session.ID, err)
+			}
+			return nil, ErrTokenReused
+		}
+		return nil, err
+	}
+
 	// Generate new token pair
-	tokens, err := s.jwtService.GenerateTokenPair(user.ID, user.Email, user.Username)
+	tokens, err := s.jwtService.GenerateTokenPair(user.ID, user.Email, user.Username, session.ID, newJTI)
 	if err != nil {
 		return nil, err
 	}
@@ -198,8 +352,3 @@ func (s *Service) Refresh(ctx context.Context, req *RefreshRequest) (*TokenPair,
 
 	return tokens, nil
 }
-
-// GetUserFromToken extracts user info from a valid access token
-func (s *Service) GetUserFromToken(tokenString string) (*Claims, error) {
-	return s.jwtService.ValidateAccessToken(tokenString)
-}
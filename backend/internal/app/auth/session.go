@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+var (
+	ErrSessionRevoked  = errors.New("session revoked")
+	ErrTokenReused     = errors.New("refresh token reused")
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// startSession creates a server-side session for the user and issues its first token pair
+func (s *Service) startSession(ctx context.Context, user *model.User, client ClientInfo) (*TokenPair, error) {
+	session := &model.Session{
+		UserID:     user.ID,
+		RefreshJTI: uuid.New().String(),
+		UserAgent:  client.UserAgent,
+		IPAddress:  client.IP,
+		ExpiresAt:  time.Now().Add(s.jwtService.RefreshExpiry()),
+	}
+
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return s.jwtService.GenerateTokenPair(user.ID, user.Email, user.Username, session.ID, session.RefreshJTI)
+}
+
+// revokeSession revokes a session in PostgreSQL (no more refreshes) and in Redis
+// (its access tokens stop working and its WebSocket connections are closed)
+func (s *Service) revokeSession(ctx context.Context, sessionID, reason string) error {
+	if err := s.sessionRepo.Revoke(ctx, sessionID, reason); err != nil {
+		return err
+	}
+	return s.redis.RevokeSession(ctx, sessionID, s.jwtService.AccessExpiry())
+}
+
+// Logout revokes the session the given tokens belong to.
+// Either token may be empty or expired; the refresh token is preferred.
+func (s *Service) Logout(ctx context.Context, refreshToken, accessToken string) error {
+	var claims *Claims
+	if refreshToken != "" {
+		claims, _ = s.jwtService.ValidateRefreshToken(refreshToken)
+	}
+	if claims == nil && accessToken != "" {
+		claims, _ = s.jwtService.ValidateAccessToken(accessToken)
+	}
+	if claims == nil || claims.SessionID == "" {
+		return nil
+	}
+
+	if err := s.revokeSession(ctx, claims.SessionID, model.SessionRevokedLogout); err != nil {
+		return err
+	}
+
+	logger.Infof("Session %s revoked (logout) for user %s", claims.SessionID, claims.UserID)
+	return nil
+}
+
+// ValidateAccessToken validates an access token and checks that its session was not revoked
+func (s *Service) ValidateAccessToken(ctx context.Context, tokenString string) (*Claims, error) {
+	claims, err := s.jwtService.ValidateAccessToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.SessionID == "" {
+		return claims, nil
+	}
+
+	revoked, err := s.redis.IsSessionRevoked(ctx, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrSessionRevoked
+	}
+
+	return claims, nil
+}
+
+// revokeSessions revokes all sessions of a user except exceptID (may be empty)
+func (s *Service) revokeSessions(ctx context.Context, userID, exceptID, reason string) (int, error) {
+	ids, err := s.sessionRepo.RevokeAllForUser(ctx, userID, exceptID, reason)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if err := s.redis.RevokeSession(ctx, id, s.jwtService.AccessExpiry()); err != nil {
+			logger.Errorf("Failed to publish revocation of session %s: %v", id, err)
+		}
+	}
+	return len(ids), nil
+}
+
+// ListSessions returns the active sessions (devices) of a user
+func (s *Service) ListSessions(ctx context.Context, userID, currentSessionID string) ([]model.SessionResponse, error) {
+	sessions, err := s.sessionRepo.ListActiveByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		ids = append(ids, session.ID)
+	}
+
+	connected, err := s.redis.GetConnectedSessions(ctx, ids)
+	if err != nil {
+		logger.Errorf("Error getting connected sessions for user %s: %v", userID, err)
+		connected = map[string]bool{}
+	}
+
+	result := make([]model.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, model.SessionResponse{
+			ID:         session.ID,
+			Device:     describeDevice(session.UserAgent),
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentSessionID,
+			Connected:  connected[session.ID],
+		})
+	}
+	return result, nil
+}
+
+// RevokeSession revokes one of the user's own sessions
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+
+	// Don't reveal sessions of other users
+	if session.UserID != userID || session.RevokedAt != nil {
+		return ErrSessionNotFound
+	}
+
+	if err := s.revokeSession(ctx, sessionID, model.SessionRevokedByUser); err != nil {
+		return err
+	}
+
+	logger.Infof("Session %s revoked by user %s", sessionID, userID)
+	return nil
+}
+
+// RevokeOtherSessions logs the user out everywhere except the current session
+func (s *Service) RevokeOtherSessions(ctx context.Context, userID, currentSessionID string) (int, error) {
+	count, err := s.revokeSessions(ctx, userID, currentSessionID, model.SessionRevokedByUser)
+	if err != nil {
+		return 0, err
+	}
+
+	logger.Infof("User %s revoked %d other session(s)", userID, count)
+	return count, nil
+}
+
+// describeDevice builds a short "Browser on OS" label from a user agent
+func describeDevice(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	ua := strings.ToLower(userAgent)
+
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/") || strings.Contains(ua, "crios/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"):
+		browser = "curl"
+	}
+
+	os := ""
+	switch {
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		os = "iOS"
+	case strings.Contains(ua, "android"):
+		os = "Android"
+	case strings.Contains(ua, "windows"):
+		os = "Windows"
+	case strings.Contains(ua, "mac os"):
+		os = "macOS"
+	case strings.Contains(ua, "linux"):
+		os = "Linux"
+	}
+
+	if os == "" {
+		return browser
+	}
+	return browser + " on " + os
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/config"
+)
+
+// login logs a user in from a device and returns the tokens of the new session
+func login(t *testing.T, env *testEnv, user *model.User, client ClientInfo) *TokenPair {
+	t.Helper()
+
+	resp, err := env.service.Login(context.Background(), &LoginRequest{Email: user.Email, Password: testPassword}, client)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	return resp.Tokens
+}
+
+// sessionID returns the session the tokens belong to
+func sessionID(t *testing.T, env *testEnv, tokens *TokenPair) string {
+	t.Helper()
+
+	claims, err := env.service.ValidateAccessToken(context.Background(), tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken: %v", err)
+	}
+	return claims.SessionID
+}
+
+func TestRefreshRotatesTokens(t *testing.T) {
+	env := newTestEnv(t, config.AuthConfig{})
+	ctx := context.Background()
+	user := env.addUser(t, "alice@example.com")
+	first := login(t, env, user, ClientInfo{})
+
+	second, err := env.service.Refresh(ctx, &RefreshRequest{RefreshToken: first.RefreshToken})
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh token not rotated")
+	}
+	third, err := env.service.Refresh(ctx, &RefreshRequest{RefreshToken: second.RefreshToken})
+	if err != nil {
+		t.Fatalf("Refresh with the rotated token: %v", err)
+	}
+
+	// A stolen token used after its rotation revokes the whole family
+	if _, err := env.service.Refresh(ctx, &RefreshRequest{RefreshToken: first.RefreshToken}); !errors.Is(err, ErrTokenReused) {
+		t.Fatalf("reused token: err = %v, want %v", err, ErrTokenReused)
+	}
+	if _, err := env.service.Refresh(ctx, &RefreshRequest{RefreshToken: third.RefreshToken}); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("latest token after the reuse: err = %v, want %v", err, ErrSessionRevoked)
+	}
+	if _, err := env.service.ValidateAccessToken(ctx, third.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("access token after the reuse: err = %v, want %v", err, ErrSessionRevoked)
+	}
+}
+
+func TestLogoutRevokesSession(t *testing.T) {
+	tests := []struct {
+		name   string
+		logout func(tokens *TokenPair) (refresh, access string)
+	}{
+		{name: "with the refresh token", logout: func(tokens *TokenPair) (string, string) { return tokens.RefreshToken, "" }},
+		{name: "with the access token", logout: func(tokens *TokenPair) (string, string) { return "", tokens.AccessToken }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, config.AuthConfig{})
+			ctx := context.Background()
+			user := env.addUser(t, "alice@example.com")
+			tokens := login(t, env, user, ClientInfo{})
+			other := login(t, env, user, ClientInfo{})
+
+			refresh, access := tt.logout(tokens)
+			if err := env.service.Logout(ctx, refresh, access); err != nil {
+				t.Fatalf("Logout: %v", err)
+			}
+			if _, err := env.service.Refresh(ctx, &RefreshRequest{RefreshToken: tokens.RefreshToken}); !errors.Is(err, ErrSessionRevoked) {
+				t.Errorf("refresh after logout: err = %v, want %v", err, ErrSessionRevoked)
+			}
+			if _, err := env.service.ValidateAccessToken(ctx, tokens.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+				t.Errorf("access token after logout: err = %v, want %v", err, ErrSessionRevoked)
+			}
+
+			// The other devices stay logged in
+			if _, err := env.service.Refresh(ctx, &RefreshRequest{RefreshToken: other.RefreshToken}); err != nil {
+				t.Errorf("refresh of another session: %v", err)
+			}
+		})
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	env := newTestEnv(t, config.AuthConfig{})
+	ctx := context.Background()
+	user := env.addUser(t, "alice@example.com")
+
+	laptop := login(t, env, user, ClientInfo{IP: "203.0.113.7", UserAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0"})
+	phone := login(t, env, user, ClientInfo{IP: "198.51.100.23", UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) Version/17.5 Mobile/15E148 Safari/604.1"})
+	laptopID, phoneID := sessionID(t, env, laptop), sessionID(t, env, phone)
+	if err := env.service.redis.SessionConnected(ctx, phoneID); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := env.service.ListSessions(ctx, user.ID, laptopID)
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+	want := map[string]model.SessionResponse{
+		laptopID: {IPAddress: "203.0.113.7", Device: "Firefox on Linux", Current: true},
+		phoneID:  {IPAddress: "198.51.100.23", Device: "Safari on iOS", Connected: true},
+	}
+	if len(sessions) != len(want) {
+		t.Fatalf("%d sessions, want %d", len(sessions), len(want))
+	}
+	for _, got := range sessions {
+		w := want[got.ID]
+		if got.IPAddress != w.IPAddress || got.Device != w.Device || got.Current != w.Current || got.Connected != w.Connected {
+			t.Errorf("session %s = %+v, want %+v", got.ID, got, w)
+		}
+	}
+}
+
+func TestRevokeSessions(t *testing.T) {
+	env := newTestEnv(t, config.AuthConfig{})
+	ctx := context.Background()
+	alice := env.addUser(t, "alice@example.com")
+	bob := env.addUser(t, "bob@example.com")
+
+	current := sessionID(t, env, login(t, env, alice, ClientInfo{}))
+	laptop := sessionID(t, env, login(t, env, alice, ClientInfo{}))
+	phone := sessionID(t, env, login(t, env, alice, ClientInfo{}))
+	bobs := sessionID(t, env, login(t, env, bob, ClientInfo{}))
+
+	// Other users' sessions look like missing ones
+	if err := env.service.RevokeSession(ctx, alice.ID, bobs); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("revoking a session of another user: err = %v, want %v", err, ErrSessionNotFound)
+	}
+	if err := env.service.RevokeSession(ctx, alice.ID, laptop); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	if err := env.service.RevokeSession(ctx, alice.ID, laptop); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("revoking twice: err = %v, want %v", err, ErrSessionNotFound)
+	}
+
+	count, err := env.service.RevokeOtherSessions(ctx, alice.ID, current)
+	if err != nil {
+		t.Fatalf("RevokeOtherSessions: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("%d sessions revoked, want 1 (the phone)", count)
+	}
+
+	for id, wantRevoked := range map[string]bool{current: false, laptop: true, phone: true, bobs: false} {
+		revoked, err := env.service.redis.IsSessionRevoked(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revoked != wantRevoked {
+			t.Errorf("session %s revoked = %v, want %v", id, revoked, wantRevoked)
+		}
+	}
+}
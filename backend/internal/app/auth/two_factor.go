@@ -0,0 +1,337 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/totp"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication not enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor enrollment not started")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrInvalidChallenge        = errors.New("invalid or expired challenge")
+	ErrTooManyAttempts         = errors.New("too many attempts")
+)
+
+const (
+	recoveryCodeCount = 10
+	// totpSkew accepts codes one step (30s) before/after to tolerate clock drift
+	totpSkew = 1
+	// maxChallengeAttempts limits code guesses per login challenge
+	maxChallengeAttempts = 5
+)
+
+// recoveryAlphabet has 32 characters (no bias from byte % 32) and no look-alikes (i, l, o, 1)
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz023456789"
+
+// TwoFactorEnrollment is returned when 2FA enrollment starts
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// ConfirmTwoFactorRequest confirms enrollment with a code from the authenticator app
+type ConfirmTwoFactorRequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// VerifyTwoFactorRequest completes a login with a TOTP code or a recovery code
+type VerifyTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode   string `json:"recovery_code" validate:"required_without=Code,omitempty,max=32"`
+}
+
+// DisableTwoFactorRequest re-authenticates the user before turning 2FA off.
+// Code may be a TOTP code or a recovery code.
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required,max=32"`
+}
+
+// EnrollTwoFactor generates a new TOTP secret for the user.
+// 2FA is not enforced until the secret is confirmed with ConfirmTwoFactor.
+func (s *Service) EnrollTwoFactor(ctx context.Context, userID string) (*TwoFactorEnrollment, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if user.IsTwoFactorEnabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.SetPendingTOTP(ctx, user.ID, secret); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrTwoFactorAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	logger.Infof("2FA enrollment started for user %s", user.ID)
+
+	return &TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.config.Auth.TOTPIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor enables 2FA once the user proves the authenticator app works.
+// Returns the recovery codes; they are only shown this once.
+func (s *Service) ConfirmTwoFactor(ctx context.Context, userID string, req *ConfirmTwoFactorRequest) ([]string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if user.IsTwoFactorEnabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	if err := s.checkTOTP(ctx, user, req.Code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.codeRepo.Replace(ctx, user.ID, hashes); err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.EnableTOTP(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("2FA enabled for user %s", user.ID)
+	return codes, nil
+}
+
+// DisableTwoFactor turns 2FA off after checking the password and a second factor
+func (s *Service) DisableTwoFactor(ctx context.Context, userID string, req *DisableTwoFactorRequest) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	if !user.IsTwoFactorEnabled() {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if !s.checkPassword(ctx, user, req.Password) {
+		return ErrInvalidCredentials
+	}
+
+	if err := s.checkSecondFactor(ctx, user, req.Code); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.DisableTOTP(ctx, user.ID); err != nil {
+		return err
+	}
+
+	if err := s.codeRepo.DeleteForUser(ctx, user.ID); err != nil {
+		return err
+	}
+
+	logger.Infof("2FA disabled for user %s", user.ID)
+	return nil
+}
+
+// startTwoFactorChallenge issues the challenge token returned by Login instead of a TokenPair
+func (s *Service) startTwoFactorChallenge(user *model.User) (*AuthResponse, error) {
+	challenge, err := s.jwtService.GenerateChallengeToken(
+		user.ID, user.Email, user.Username, uuid.New().String(), s.config.Auth.MFAChallengeExpiry,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("2FA challenge issued for user %s", user.ID)
+
+	return &AuthResponse{
+		MFARequired:    true,
+		ChallengeToken: challenge,
+	}, nil
+}
+
+// VerifyTwoFactor completes a 2FA login and starts the session. Wrong codes count as failed
+// logins, so codes can't be guessed by starting new challenges with a known password.
+func (s *Service) VerifyTwoFactor(ctx context.Context, req *VerifyTwoFactorRequest, client ClientInfo) (*AuthResponse, error) {
+	claims, err := s.jwtService.ValidateChallengeToken(req.ChallengeToken)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	if err := s.checkLoginAllowed(ctx, claims.Email, client.IP); err != nil {
+		return nil, err
+	}
+
+	ttl := s.config.Auth.MFAChallengeExpiry
+	attempts, err := s.redis.IncrMFAChallengeAttempts(ctx, claims.ID, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if attempts > maxChallengeAttempts {
+		return nil, ErrTooManyAttempts
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+
+	// 2FA was turned off in the meantime: the user has to log in again
+	if !user.IsTwoFactorEnabled() {
+		return nil, ErrInvalidChallenge
+	}
+
+	if req.Code != "" {
+		err = s.checkTOTP(ctx, user, req.Code)
+	} else {
+		err = s.checkRecoveryCode(ctx, user, req.RecoveryCode)
+	}
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		s.recordLoginFailure(ctx, user.Email, client.IP, user)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A challenge can only start one session
+	first, err := s.redis.MarkMFAChallengeUsed(ctx, claims.ID, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !first {
+		return nil, ErrInvalidChallenge
+	}
+
+	s.clearLoginFailures(ctx, user.Email)
+
+	tokens, err := s.startSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("User logged in with 2FA: %s", user.Email)
+
+	return &AuthResponse{
+		User:   user.ToResponse(),
+		Tokens: tokens,
+	}, nil
+}
+
+// checkSecondFactor accepts either a TOTP code or a recovery code
+func (s *Service) checkSecondFactor(ctx context.Context, user *model.User, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits && isDigits(code) {
+		return s.checkTOTP(ctx, user, code)
+	}
+	return s.checkRecoveryCode(ctx, user, code)
+}
+
+// checkTOTP validates a TOTP code and rejects codes that were already used
+func (s *Service) checkTOTP(ctx context.Context, user *model.User, code string) error {
+	now := time.Now()
+	step, ok := totp.Validate(user.TOTPSecret, code, now, totpSkew)
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	// Keep the marker until the step can no longer be accepted
+	ttl := time.Duration(2*totpSkew+1) * totp.Period
+	first, err := s.redis.MarkTOTPStepUsed(ctx, user.ID, step, ttl)
+	if err != nil {
+		return err
+	}
+	if !first {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// checkRecoveryCode consumes a one-time recovery code
+func (s *Service) checkRecoveryCode(ctx context.Context, user *model.User, code string) error {
+	err := s.codeRepo.Consume(ctx, user.ID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecoveryCodeNotFound) {
+			return ErrInvalidTwoFactorCode
+		}
+		return err
+	}
+
+	remaining, err := s.codeRepo.CountUnused(ctx, user.ID)
+	if err == nil {
+		logger.Infof("Recovery code used by user %s (%d left)", user.ID, remaining)
+	}
+	return nil
+}
+
+// generateRecoveryCodes returns recovery codes formatted as "xxxxx-xxxxx" and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		for j := range b {
+			b[j] = recoveryAlphabet[int(b[j])%len(recoveryAlphabet)]
+		}
+
+		code := string(b[:5]) + "-" + string(b[5:])
+		codes = append(codes, code)
+		hashes = append(hashes, hashToken(normalizeRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes so codes can be typed loosely
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
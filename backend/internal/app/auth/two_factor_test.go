@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/config"
+	"github.com/Beretta350/gochat/pkg/totp"
+)
+
+// twoFactorEnv has a user with 2FA enabled; logins lock the account after 3 failures
+func twoFactorEnv(t *testing.T) (*testEnv, *model.User) {
+	t.Helper()
+
+	env := newTestEnv(t, config.AuthConfig{
+		MFAChallengeExpiry:   5 * time.Minute,
+		LoginMaxFailures:     3,
+		LoginLockoutDuration: 15 * time.Minute,
+	})
+	user := env.addUser(t, "alice@example.com")
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	enabledAt := time.Now()
+	env.users.users[user.ID].TOTPSecret = secret
+	env.users.users[user.ID].TOTPEnabledAt = &enabledAt
+	return env, env.users.users[user.ID]
+}
+
+// challenge logs in with the right password and returns the 2FA challenge
+func challenge(t *testing.T, env *testEnv, user *model.User) string {
+	t.Helper()
+
+	resp, err := env.service.Login(context.Background(), &LoginRequest{Email: user.Email, Password: testPassword}, ClientInfo{IP: "203.0.113.7"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if !resp.MFARequired {
+		t.Fatal("no 2FA challenge")
+	}
+	return resp.ChallengeToken
+}
+
+// wrongCode returns a code the authenticator app doesn't show now
+func wrongCode(t *testing.T, secret string) string {
+	t.Helper()
+
+	for i := 0; ; i++ {
+		code := fmt.Sprintf("%06d", i)
+		if _, ok := totp.Validate(secret, code, time.Now(), totpSkew); !ok {
+			return code
+		}
+	}
+}
+
+// accountFailures returns the failed logins counted for an email
+func accountFailures(env *testEnv, email string) string {
+	failures, _ := env.redis.Get("login:failures:email:" + email)
+	return failures
+}
+
+func TestLoginFailuresClearedAfterSecondFactor(t *testing.T) {
+	env, user := twoFactorEnv(t)
+	ctx := context.Background()
+
+	if _, err := env.service.Login(ctx, &LoginRequest{Email: user.Email, Password: "wrong"}, ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("wrong password: err = %v", err)
+	}
+	env.redis.FastForward(2 * time.Second) // Past the backoff
+
+	// The password alone doesn't prove the account is the user's
+	token := challenge(t, env, user)
+	if got := accountFailures(env, user.Email); got != "1" {
+		t.Fatalf("failures after the password = %q, want 1", got)
+	}
+
+	code, err := totp.Code(user.TOTPSecret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.service.VerifyTwoFactor(ctx, &VerifyTwoFactorRequest{ChallengeToken: token, Code: code}, ClientInfo{}); err != nil {
+		t.Fatalf("VerifyTwoFactor: %v", err)
+	}
+	if got := accountFailures(env, user.Email); got != "" {
+		t.Errorf("failures after the second factor = %q, want none", got)
+	}
+}
+
+func TestTwoFactorFailuresLockAccount(t *testing.T) {
+	env, user := twoFactorEnv(t)
+	ctx := context.Background()
+	wrong := wrongCode(t, user.TOTPSecret)
+
+	// A new challenge for each guess: the per-challenge limit doesn't apply
+	for i := 1; i <= 3; i++ {
+		token := challenge(t, env, user)
+		_, err := env.service.VerifyTwoFactor(ctx, &VerifyTwoFactorRequest{ChallengeToken: token, Code: wrong}, ClientInfo{})
+		if !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("guess %d: err = %v, want %v", i, err, ErrInvalidTwoFactorCode)
+		}
+		if got, want := accountFailures(env, user.Email), fmt.Sprint(i); got != want {
+			t.Fatalf("failures after guess %d = %q, want %s", i, got, want)
+		}
+		if i < 3 {
+			env.redis.FastForward(time.Minute) // Past the backoff, not the lockout
+		}
+	}
+
+	_, err := env.service.Login(ctx, &LoginRequest{Email: user.Email, Password: testPassword}, ClientInfo{})
+	if !errors.Is(err, ErrLoginBlocked) {
+		t.Errorf("login after the lockout: err = %v, want %v", err, ErrLoginBlocked)
+	}
+	if len(env.sessions.sessions) != 0 {
+		t.Errorf("%d sessions started by wrong codes", len(env.sessions.sessions))
+	}
+}
+
+func TestTwoFactorBlockedDuringLockout(t *testing.T) {
+	env, user := twoFactorEnv(t)
+	ctx := context.Background()
+
+	// Challenge obtained before the account got locked
+	token := challenge(t, env, user)
+	for i := 0; i < 3; i++ {
+		env.redis.FastForward(time.Minute)
+		_, _ = env.service.Login(ctx, &LoginRequest{Email: user.Email, Password: "wrong"}, ClientInfo{})
+	}
+
+	code, err := totp.Code(user.TOTPSecret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = env.service.VerifyTwoFactor(ctx, &VerifyTwoFactorRequest{ChallengeToken: token, Code: code}, ClientInfo{})
+	if !errors.Is(err, ErrLoginBlocked) {
+		t.Errorf("err = %v, want %v", err, ErrLoginBlocked)
+	}
+}
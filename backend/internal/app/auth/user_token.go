@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/mailer"
+)
+
+// mailTimeout bounds how long a background email delivery may take
+const mailTimeout = 30 * time.Second
+
+// hashToken returns the hex SHA-256 of a token (what gets stored)
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueUserToken creates a single-use token, invalidating older ones of the same purpose.
+// Returns the plain token, which is only ever sent to the user.
+func (s *Service) issueUserToken(ctx context.Context, userID string, purpose model.UserTokenPurpose, ttl time.Duration) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	if err := s.tokenRepo.InvalidateForUser(ctx, userID, purpose); err != nil {
+		return "", err
+	}
+
+	err := s.tokenRepo.Create(ctx, &model.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// sendMailAsync delivers an email in the background, so response time doesn't
+// depend on the mail server (or reveal whether an account exists)
+func (s *Service) sendMailAsync(msg *mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			logger.Errorf("Failed to send email %q to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
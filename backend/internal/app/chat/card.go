@@ -0,0 +1,132 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+)
+
+// Card limits
+const (
+	maxCardTitle    = 150
+	maxCardText     = 3000
+	maxCardActions  = 10
+	maxCardOptions  = 25
+	maxCardLabel    = 75
+	maxActionValue  = 2000
+	maxCardMetadata = 32 << 10
+)
+
+// actionIDPattern is the syntax of the action IDs a bot gives its buttons and menus
+var actionIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,64}$`)
+
+// parseCard validates the metadata of a card message. Errors wrap ErrInvalidCard
+// and say what is wrong, for the bot developer.
+func parseCard(raw json.RawMessage) (*model.Card, error) {
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("%w: metadata is required", ErrInvalidCard)
+	}
+	if len(raw) > maxCardMetadata {
+		return nil, fmt.Errorf("%w: metadata is larger than %d bytes", ErrInvalidCard, maxCardMetadata)
+	}
+
+	var card model.Card
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&card); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCard, err)
+	}
+
+	switch {
+	case card.Title == "" && card.Text == "":
+		return nil, fmt.Errorf("%w: a title or text is required", ErrInvalidCard)
+	case utf8.RuneCountInString(card.Title) > maxCardTitle:
+		return nil, fmt.Errorf("%w: title is longer than %d characters", ErrInvalidCard, maxCardTitle)
+	case utf8.RuneCountInString(card.Text) > maxCardText:
+		return nil, fmt.Errorf("%w: text is longer than %d characters", ErrInvalidCard, maxCardText)
+	case len(card.Actions) > maxCardActions:
+		return nil, fmt.Errorf("%w: more than %d actions", ErrInvalidCard, maxCardActions)
+	}
+
+	seen := make(map[string]bool, len(card.Actions))
+	for i := range card.Actions {
+		action := &card.Actions[i]
+		if !actionIDPattern.MatchString(action.ActionID) {
+			return nil, fmt.Errorf("%w: action_id %q must be 1-64 letters, digits or _.:-", ErrInvalidCard, action.ActionID)
+		}
+		if seen[action.ActionID] {
+			return nil, fmt.Errorf("%w: duplicate action_id %q", ErrInvalidCard, action.ActionID)
+		}
+		seen[action.ActionID] = true
+
+		if err := validateAction(action); err != nil {
+			return nil, fmt.Errorf("%w: action %q: %v", ErrInvalidCard, action.ActionID, err)
+		}
+	}
+	return &card, nil
+}
+
+func validateAction(action *model.CardAction) error {
+	if utf8.RuneCountInString(action.Label) > maxCardLabel || utf8.RuneCountInString(action.Placeholder) > maxCardLabel {
+		return fmt.Errorf("label is longer than %d characters", maxCardLabel)
+	}
+
+	switch action.Type {
+	case model.CardActionButton:
+		switch {
+		case action.Label == "":
+			return fmt.Errorf("label is required")
+		case len(action.Value) > maxActionValue:
+			return fmt.Errorf("value is longer than %d bytes", maxActionValue)
+		case len(action.Options) > 0:
+			return fmt.Errorf("buttons don't have options")
+		}
+		switch action.Style {
+		case model.CardStyleDefault, model.CardStylePrimary, model.CardStyleDanger:
+		default:
+			return fmt.Errorf("style must be %q or %q", model.CardStylePrimary, model.CardStyleDanger)
+		}
+
+	case model.CardActionSelect:
+		switch {
+		case len(action.Options) == 0 || len(action.Options) > maxCardOptions:
+			return fmt.Errorf("a select needs 1 to %d options", maxCardOptions)
+		case action.Value != "" || action.Style != "":
+			return fmt.Errorf("selects don't have a value or style")
+		}
+		values := make(map[string]bool, len(action.Options))
+		for _, o := range action.Options {
+			switch {
+			case o.Label == "" || o.Value == "":
+				return fmt.Errorf("options need a label and a value")
+			case utf8.RuneCountInString(o.Label) > maxCardLabel:
+				return fmt.Errorf("option label is longer than %d characters", maxCardLabel)
+			case len(o.Value) > maxActionValue:
+				return fmt.Errorf("option value is longer than %d bytes", maxActionValue)
+			case values[o.Value]:
+				return fmt.Errorf("duplicate option value %q", o.Value)
+			}
+			values[o.Value] = true
+		}
+
+	default:
+		return fmt.Errorf("type must be %q or %q", model.CardActionButton, model.CardActionSelect)
+	}
+	return nil
+}
+
+// interactionValue returns the value an interaction sends for an action: the
+// button's value, or the option the user picked
+func interactionValue(action *model.CardAction, picked string) (string, error) {
+	if action.Type == model.CardActionButton {
+		return action.Value, nil
+	}
+	if !action.HasOption(picked) {
+		return "", ErrInvalidAction
+	}
+	return picked, nil
+}
@@ -0,0 +1,139 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+)
+
+func TestParseCard(t *testing.T) {
+	tests := []struct {
+		name    string
+		card    string
+		wantErr string // Substring of the error, "" when valid
+	}{
+		{
+			name: "buttons and a select",
+			card: `{"title": "Deploy?", "actions": [
+				{"type": "button", "action_id": "approve", "label": "Approve", "style": "primary"},
+				{"type": "select", "action_id": "env", "options": [{"label": "Staging", "value": "staging"}]}
+			]}`,
+		},
+		{name: "text only", card: `{"text": "Deployed"}`},
+		{name: "empty", card: `{}`, wantErr: "a title or text is required"},
+		{name: "unknown field", card: `{"title": "x", "color": "red"}`, wantErr: "unknown field"},
+		{
+			name:    "duplicate action",
+			card:    `{"title": "x", "actions": [{"type": "button", "action_id": "a", "label": "A"}, {"type": "button", "action_id": "a", "label": "B"}]}`,
+			wantErr: `duplicate action_id "a"`,
+		},
+		{name: "invalid action ID", card: `{"title": "x", "actions": [{"type": "button", "action_id": "a b", "label": "A"}]}`, wantErr: "action_id"},
+		{name: "button without label", card: `{"title": "x", "actions": [{"type": "button", "action_id": "a"}]}`, wantErr: "label is required"},
+		{name: "unknown style", card: `{"title": "x", "actions": [{"type": "button", "action_id": "a", "label": "A", "style": "green"}]}`, wantErr: "style must be"},
+		{name: "select without options", card: `{"title": "x", "actions": [{"type": "select", "action_id": "a"}]}`, wantErr: "1 to 25 options"},
+		{name: "unknown action type", card: `{"title": "x", "actions": [{"type": "link", "action_id": "a"}]}`, wantErr: "type must be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseCard(json.RawMessage(tt.card))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("err = %v, want none", err)
+			case tt.wantErr != "" && (!errors.Is(err, ErrInvalidCard) || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("err = %v, want %v containing %q", err, ErrInvalidCard, tt.wantErr)
+			}
+		})
+	}
+}
+
+// makeBot turns a participant into a bot in all their conversations
+func (r *fakeConvRepo) makeBot(userID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, participants := range r.participants {
+		if p := findParticipant(participants, userID); p != nil {
+			user := *p.User
+			user.IsBot = true
+			p.User = &user
+		}
+	}
+}
+
+func TestCardInteraction(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	botID, userID := uuid.New().String(), uuid.New().String()
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup}
+	env.convs.add(conv, botID, userID)
+	env.convs.makeBot(botID)
+
+	card := json.RawMessage(`{"title": "Deploy?", "actions": [
+		{"type": "button", "action_id": "approve", "label": "Approve", "value": "yes"},
+		{"type": "select", "action_id": "env", "options": [{"label": "Staging", "value": "staging"}]}
+	]}`)
+	cardMsg := &WebSocketMessage{ConversationID: conv.ID, Content: "Deploy?", Type: string(model.MessageTypeCard), Metadata: card}
+
+	if _, err := env.service.PostMessage(ctx, userID, cardMsg); !errors.Is(err, ErrCardsBotsOnly) {
+		t.Errorf("card from a user: err = %v, want %v", err, ErrCardsBotsOnly)
+	}
+	sent, err := env.service.PostMessage(ctx, botID, cardMsg)
+	if err != nil {
+		t.Fatalf("PostMessage: %v", err)
+	}
+	env.msgs.add(&model.Message{ID: sent.ID, ConversationID: conv.ID, SenderID: botID, Content: sent.Content, Type: model.MessageTypeCard, Metadata: sent.Metadata})
+
+	interact := func(messageID, actionID, value string) error {
+		return env.service.Interact(ctx, userID, &WebSocketMessage{
+			ConversationID: conv.ID, Type: interactionFrame, MessageID: messageID, ActionID: actionID, Value: value,
+		})
+	}
+	invalid := []struct {
+		name      string
+		messageID string
+		actionID  string
+		value     string
+		wantErr   error
+	}{
+		{name: "unknown action", messageID: sent.ID, actionID: "reject", wantErr: ErrInvalidAction},
+		{name: "unknown option", messageID: sent.ID, actionID: "env", value: "production", wantErr: ErrInvalidAction},
+		{name: "unknown message", messageID: uuid.New().String(), actionID: "approve", wantErr: ErrMessageNotFound},
+	}
+	for _, tt := range invalid {
+		if err := interact(tt.messageID, tt.actionID, tt.value); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+	if events := queuedWebhookEvents(t, env); len(events) != 0 {
+		t.Fatalf("invalid interactions sent to the bot: %+v", events)
+	}
+
+	if err := interact(sent.ID, "env", "staging"); err != nil {
+		t.Fatalf("Interact: %v", err)
+	}
+	event := onlyWebhookEvent(t, env)
+	var interaction model.CardInteraction
+	if err := json.Unmarshal(event.Data, &interaction); err != nil {
+		t.Fatal(err)
+	}
+	if event.Event != model.WebhookEventMessageInteraction || event.BotID != botID ||
+		interaction.ActionID != "env" || interaction.Value != "staging" || interaction.UserID != userID {
+		t.Errorf("event = %+v, interaction = %+v", event, interaction)
+	}
+
+	// The bot answers by updating its card
+	updated, err := env.service.UpdateMessage(ctx, botID, conv.ID, sent.ID, &model.MessageUpdate{Metadata: json.RawMessage(`{"title": "Deploying to staging"}`)})
+	if err != nil {
+		t.Fatalf("UpdateMessage: %v", err)
+	}
+	if !strings.Contains(string(updated.Metadata), "Deploying to staging") || strings.Contains(string(updated.Metadata), "actions") {
+		t.Errorf("updated card = %s", updated.Metadata)
+	}
+}
@@ -0,0 +1,116 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+)
+
+func TestOnlyAdminsPostInChannels(t *testing.T) {
+	tests := []struct {
+		name     string
+		convType model.ConversationType
+		admin    bool // Whether the admin sends, rather than a member
+		wantErr  error
+	}{
+		{name: "admin of a channel", convType: model.ConversationTypeChannel, admin: true},
+		{name: "member of a channel", convType: model.ConversationTypeChannel, wantErr: ErrReadOnly},
+		{name: "member of a group", convType: model.ConversationTypeGroup},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			adminID, memberID := uuid.New().String(), uuid.New().String()
+			conv := &model.Conversation{ID: uuid.New().String(), Type: tt.convType}
+			env.convs.add(conv, adminID, memberID)
+
+			senderID := memberID
+			if tt.admin {
+				senderID = adminID
+			}
+			// Both the WebSocket and the REST API send through here
+			msg, _, err := env.service.Send(context.Background(), Client{UserID: senderID}, &WebSocketMessage{ConversationID: conv.ID, Content: "hi"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if sent := msg != nil; sent != (tt.wantErr == nil) {
+				t.Errorf("sent = %v after err %v", sent, err)
+			}
+			if n := len(streamedMessages(t, env)); (n == 1) != (tt.wantErr == nil) {
+				t.Errorf("%d messages in the stream after err %v", n, err)
+			}
+		})
+	}
+}
+
+func TestChannelMessagesPublishedOnce(t *testing.T) {
+	tests := []struct {
+		name        string
+		convType    model.ConversationType
+		wantPending int // Queued for each offline member
+	}{
+		{name: "channel", convType: model.ConversationTypeChannel},
+		{name: "group", convType: model.ConversationTypeGroup, wantPending: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			ctx := context.Background()
+			adminID, onlineID := uuid.New().String(), uuid.New().String()
+			offlineIDs := []string{uuid.New().String(), uuid.New().String()}
+			conv := &model.Conversation{ID: uuid.New().String(), Type: tt.convType}
+			env.convs.add(conv, append([]string{adminID, onlineID}, offlineIDs...)...)
+
+			if err := env.service.redis.ConnectionAlive(ctx, onlineID, uuid.New().String(), presenceTTL); err != nil {
+				t.Fatal(err)
+			}
+			channels := []string{conversationChannel(conv.ID), "user:" + onlineID}
+			for _, userID := range offlineIDs {
+				channels = append(channels, "user:"+userID)
+			}
+			pubsub := env.service.redis.Subscribe(ctx, channels...)
+			defer func() { _ = pubsub.Close() }()
+			for _, channel := range channels {
+				waitSubscribed(t, env.redis, channel)
+			}
+
+			if _, err := env.service.PostMessage(ctx, adminID, &WebSocketMessage{ConversationID: conv.ID, Content: "hi"}); err != nil {
+				t.Fatalf("PostMessage: %v", err)
+			}
+			// Anything published for the message comes before this
+			env.redis.Publish(conversationChannel(conv.ID), "done")
+
+			ch := pubsub.Channel()
+			published := make(map[string]int)
+			timeout := time.After(2 * time.Second)
+			for done := false; !done; {
+				select {
+				case msg := <-ch:
+					if msg.Payload == "done" {
+						done = true
+					} else {
+						published[msg.Channel]++
+					}
+				case <-timeout:
+					t.Fatal("published messages not received")
+				}
+			}
+			if len(published) != 1 || published[conversationChannel(conv.ID)] != 1 {
+				t.Errorf("published %v, want once to the conversation", published)
+			}
+
+			for _, userID := range offlineIDs {
+				if n := pendingCount(env, userID); n != tt.wantPending {
+					t.Errorf("%d messages queued for an offline member, want %d", n, tt.wantPending)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/command"
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/config"
+)
+
+func TestSendRunsCommandsWithTheirRequirements(t *testing.T) {
+	adminID, memberID := uuid.New().String(), uuid.New().String()
+	session := func(userID string) Client { return Client{UserID: userID, SessionID: uuid.New().String()} }
+	token := func(userID string, scopes ...string) Client {
+		return Client{UserID: userID, APITokenID: uuid.New().String(), Scopes: scopes}
+	}
+
+	tests := []struct {
+		name      string
+		convType  model.ConversationType
+		client    Client
+		text      string
+		wantReply string // Prefix of the reply
+		wantLeft  bool   // Whether the member left the conversation
+	}{
+		{
+			name:      "token without groups:manage",
+			convType:  model.ConversationTypeGroup,
+			client:    token(memberID, model.ScopeMessagesWrite),
+			text:      "/leave",
+			wantReply: "This token can't run /leave (missing groups:manage scope)",
+		},
+		{
+			name:      "token with groups:manage",
+			convType:  model.ConversationTypeGroup,
+			client:    token(memberID, model.ScopeMessagesWrite, model.ScopeGroupsManage),
+			text:      "/leave",
+			wantReply: "You left the group",
+			wantLeft:  true,
+		},
+		{
+			name:      "member of a channel can't add members",
+			convType:  model.ConversationTypeChannel,
+			client:    session(memberID),
+			text:      "/invite @carol",
+			wantReply: "You don't have permission to run /invite",
+		},
+		{
+			name:      "group command in a direct conversation",
+			convType:  model.ConversationTypeDirect,
+			client:    session(memberID),
+			text:      "/leave",
+			wantReply: "/leave only works in groups",
+		},
+		{
+			name:      "member can't change the topic",
+			convType:  model.ConversationTypeGroup,
+			client:    session(memberID),
+			text:      "/topic Release on Friday",
+			wantReply: "Only admins can change the topic",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.service.commands = command.NewService(&config.Config{}, env.convs, fakeUserRepo{}, nil)
+			conv := &model.Conversation{ID: uuid.New().String(), Type: tt.convType}
+			env.convs.add(conv, adminID, memberID)
+
+			msg, reply, err := env.service.Send(context.Background(), tt.client, &WebSocketMessage{ConversationID: conv.ID, Content: tt.text})
+			if err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			if msg != nil || reply == nil {
+				t.Fatalf("command posted as a message: %+v", msg)
+			}
+			if !strings.HasPrefix(reply.Text, tt.wantReply) {
+				t.Errorf("reply = %q, want %q...", reply.Text, tt.wantReply)
+			}
+
+			participants, _ := env.convs.GetParticipants(context.Background(), conv.ID)
+			if left := findParticipant(participants, memberID) == nil; left != tt.wantLeft {
+				t.Errorf("member left = %v, want %v", left, tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestSendEscapedCommand(t *testing.T) {
+	env := newTestEnv(t)
+	aliceID, bobID := uuid.New().String(), uuid.New().String()
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup}
+	env.convs.add(conv, aliceID, bobID)
+
+	msg, reply, err := env.service.Send(context.Background(), Client{UserID: bobID}, &WebSocketMessage{ConversationID: conv.ID, Content: "//leave"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if reply != nil || msg == nil || msg.Content != "/leave" {
+		t.Errorf("message = %+v, reply = %+v, want the message /leave", msg, reply)
+	}
+}
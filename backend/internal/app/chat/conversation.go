@@ -0,0 +1,93 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+// Live events of conversation changes, to keep the conversation lists of the
+// participants up to date
+const (
+	EventConversationCreated = "conversation_created"
+	EventConversationUpdated = "conversation_updated"
+	EventParticipantAdded    = "participant_added"
+	EventParticipantRemoved  = "participant_removed"
+	EventParticipantUpdated  = "participant_updated"
+)
+
+// ConversationEvent tells the participants of a conversation that it was created or changed
+type ConversationEvent struct {
+	Type           string                       `json:"type"`
+	ConversationID string                       `json:"conversation_id"`
+	ActorID        string                       `json:"actor_id"`               // Who made the change
+	Conversation   *model.Conversation          `json:"conversation,omitempty"` // conversation_created, conversation_updated
+	Participants   []*model.ParticipantResponse `json:"participants,omitempty"` // conversation_created, participant_added, participant_updated
+	UserID         string                       `json:"user_id,omitempty"`      // participant_removed
+}
+
+// NotifyCreated tells the participants of a new conversation about it
+func (s *Service) NotifyCreated(ctx context.Context, actorID string, conv *model.Conversation, participants []model.Participant) {
+	userIDs := make([]string, 0, len(participants))
+	for _, p := range participants {
+		userIDs = append(userIDs, p.UserID)
+	}
+	s.MembersChanged(ctx, conv.ID, userIDs, nil)
+
+	s.deliverEvent(ctx, participants, &ConversationEvent{
+		Type:           EventConversationCreated,
+		ConversationID: conv.ID,
+		ActorID:        actorID,
+		Conversation:   conv,
+		Participants:   participantResponses(participants),
+	})
+}
+
+// NotifyUpdated tells the participants that a conversation changed, with its new version
+func (s *Service) NotifyUpdated(ctx context.Context, actorID, conversationID string) error {
+	conv, err := s.convRepo.GetByID(ctx, conversationID)
+	if err != nil {
+		if errors.Is(err, repository.ErrConversationNotFound) {
+			return ErrConversationNotFound
+		}
+		return err
+	}
+	participants, err := s.convRepo.GetParticipants(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+
+	s.deliverEvent(ctx, participants, &ConversationEvent{
+		Type:           EventConversationUpdated,
+		ConversationID: conv.ID,
+		ActorID:        actorID,
+		Conversation:   conv,
+	})
+	return nil
+}
+
+// deliverEvent sends an event to all the participants: published to those online,
+// queued for the others
+func (s *Service) deliverEvent(ctx context.Context, participants []model.Participant, event *ConversationEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logger.Errorf("Error marshaling %s event: %v", event.Type, err)
+		return
+	}
+	s.deliver(ctx, participants, "", payload)
+}
+
+// participantResponses converts participants to their public form
+func participantResponses(participants []model.Participant) []*model.ParticipantResponse {
+	responses := make([]*model.ParticipantResponse, 0, len(participants))
+	for i := range participants {
+		if resp := participants[i].ToResponse(); resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	return responses
+}
@@ -0,0 +1,92 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+)
+
+// receiveEvent waits for the next conversation event written to a connection
+func receiveEvent(t *testing.T, conn *recordingConn) ConversationEvent {
+	t.Helper()
+
+	select {
+	case frame := <-conn.frames:
+		var event ConversationEvent
+		if err := json.Unmarshal(frame, &event); err != nil {
+			t.Fatalf("invalid frame %s: %v", frame, err)
+		}
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received")
+	}
+	return ConversationEvent{}
+}
+
+func TestConversationEventsReachEveryParticipant(t *testing.T) {
+	env := newTestEnv(t)
+	s := env.service
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	aliceID, bobID, carolID := uuid.New().String(), uuid.New().String(), uuid.New().String()
+
+	// Bob is connected, Carol is not
+	conn := &recordingConn{frames: make(chan []byte, 16)}
+	if err := s.redis.ConnectionAlive(ctx, bobID, uuid.New().String(), presenceTTL); err != nil {
+		t.Fatal(err)
+	}
+	go s.listenForMessages(ctx, conn, bobID)
+	waitSubscribed(t, env.redis, "user:"+bobID)
+
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup}
+	participants := env.convs.add(conv, aliceID, bobID, carolID)
+	s.NotifyCreated(ctx, aliceID, conv, participants)
+
+	created := receiveEvent(t, conn)
+	if created.Type != EventConversationCreated || created.ConversationID != conv.ID || created.ActorID != aliceID {
+		t.Fatalf("event = %+v, want %s", created, EventConversationCreated)
+	}
+	if len(created.Participants) != 3 {
+		t.Errorf("got %d participants, want 3", len(created.Participants))
+	}
+
+	name := "Release"
+	if err := env.convs.SetName(ctx, conv.ID, name); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.NotifyUpdated(ctx, aliceID, conv.ID); err != nil {
+		t.Fatalf("NotifyUpdated: %v", err)
+	}
+	updated := receiveEvent(t, conn)
+	if updated.Type != EventConversationUpdated || updated.Conversation == nil || updated.Conversation.Name == nil || *updated.Conversation.Name != name {
+		t.Errorf("event = %+v, want %s with the new name", updated, EventConversationUpdated)
+	}
+
+	// Offline participants, the actor included, find them when they reconnect
+	want := []string{EventConversationCreated, EventConversationUpdated}
+	for _, userID := range []string{aliceID, carolID} {
+		if got := pendingTypes(t, env, userID); !slices.Equal(got, want) {
+			t.Errorf("queued for %s = %v, want %v", userID, got, want)
+		}
+	}
+	if n := pendingCount(env, bobID); n != 0 {
+		t.Errorf("%d events queued for a connected user", n)
+	}
+}
+
+func TestNotifyUpdatedUnknownConversation(t *testing.T) {
+	env := newTestEnv(t)
+
+	err := env.service.NotifyUpdated(context.Background(), uuid.New().String(), uuid.New().String())
+	if !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("err = %v, want %v", err, ErrConversationNotFound)
+	}
+}
@@ -0,0 +1,74 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+)
+
+func TestJoinPublicHidesOtherConversations(t *testing.T) {
+	env := newTestEnv(t)
+	ownerID, outsiderID := uuid.New().String(), uuid.New().String()
+
+	private := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup, Visibility: model.VisibilityPrivate}
+	env.convs.add(private, ownerID)
+	direct := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeDirect, Visibility: model.VisibilityPublic}
+	env.convs.add(direct, ownerID, uuid.New().String())
+
+	// A private group looks like one that doesn't exist
+	tests := map[string]string{
+		"private":    private.ID,
+		"direct":     direct.ID,
+		"unknown":    uuid.New().String(),
+		"invalid id": "not-a-uuid",
+	}
+	for name, conversationID := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := env.service.JoinPublic(context.Background(), outsiderID, conversationID); !errors.Is(err, ErrConversationNotFound) {
+				t.Errorf("err = %v, want %v", err, ErrConversationNotFound)
+			}
+		})
+	}
+
+	participants, _ := env.convs.GetParticipants(context.Background(), private.ID)
+	if findParticipant(participants, outsiderID) != nil {
+		t.Error("outsider joined a private group")
+	}
+}
+
+func TestJoinPublicGroup(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	adminID, userID := uuid.New().String(), uuid.New().String()
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup, Visibility: model.VisibilityPrivate}
+	env.convs.add(conv, adminID)
+
+	// Made public by its admin, then anyone can join
+	public := string(model.VisibilityPublic)
+	if _, err := env.service.UpdateGroup(ctx, adminID, conv.ID, &model.GroupUpdate{Visibility: &public}); err != nil {
+		t.Fatalf("UpdateGroup: %v", err)
+	}
+	joined, err := env.service.JoinPublic(ctx, userID, conv.ID)
+	if err != nil {
+		t.Fatalf("JoinPublic: %v", err)
+	}
+	if joined.ID != conv.ID {
+		t.Errorf("joined %s, want %s", joined.ID, conv.ID)
+	}
+	if _, err := env.service.JoinPublic(ctx, userID, conv.ID); !errors.Is(err, ErrAlreadyMember) {
+		t.Errorf("joining twice: err = %v, want %v", err, ErrAlreadyMember)
+	}
+
+	participants, _ := env.convs.GetParticipants(ctx, conv.ID)
+	if p := findParticipant(participants, userID); p == nil || p.IsAdmin() {
+		t.Errorf("participant = %+v, want a member", p)
+	}
+	events := systemEvents(t, env)
+	if len(events) != 2 || events[0].Event != model.SystemEventVisibilityChanged || events[1].Event != model.SystemEventMemberJoined || events[1].ActorID != userID {
+		t.Errorf("system events = %+v, want visibility changed then member joined", events)
+	}
+}
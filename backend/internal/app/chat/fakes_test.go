@@ -0,0 +1,398 @@
+package chat
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/internal/config"
+	"github.com/Beretta350/gochat/pkg/redisclient"
+)
+
+// testEnv is a chat service backed by miniredis and in-memory repositories
+type testEnv struct {
+	service      *Service
+	redis        *miniredis.Miniredis
+	convs        *fakeConvRepo
+	msgs         *fakeMsgRepo
+	invites      *fakeInviteRepo
+	joinRequests *fakeJoinRequestRepo
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client, err := redisclient.NewClient(&config.Config{Redis: config.RedisConfig{Addr: mr.Addr()}})
+	if err != nil {
+		t.Fatalf("connecting to miniredis: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	env := &testEnv{
+		redis:        mr,
+		convs:        newFakeConvRepo(),
+		msgs:         &fakeMsgRepo{messages: make(map[string]*model.Message)},
+		invites:      &fakeInviteRepo{invites: make(map[string]*model.GroupInvite)},
+		joinRequests: &fakeJoinRequestRepo{requests: make(map[string]*model.JoinRequest)},
+	}
+	env.service = NewService(&config.Config{}, client, env.convs, fakeUserRepo{}, env.msgs, env.invites, env.joinRequests, nil)
+	return env
+}
+
+// fakeConvRepo keeps conversations in memory; methods not overridden panic
+type fakeConvRepo struct {
+	repository.ConversationRepository
+
+	mu            sync.Mutex
+	conversations map[string]*model.Conversation
+	participants  map[string][]model.Participant
+}
+
+func newFakeConvRepo() *fakeConvRepo {
+	return &fakeConvRepo{
+		conversations: make(map[string]*model.Conversation),
+		participants:  make(map[string][]model.Participant),
+	}
+}
+
+// add stores a conversation with its participants and returns them. The first one
+// is the admin of a group.
+func (r *fakeConvRepo) add(conv *model.Conversation, userIDs ...string) []model.Participant {
+	r.mu.Lock()
+	r.conversations[conv.ID] = conv
+	r.mu.Unlock()
+
+	for i, userID := range userIDs {
+		role := model.ParticipantRoleMember
+		if i == 0 {
+			role = model.ParticipantRoleAdmin
+		}
+		_ = r.AddParticipant(context.Background(), conv.ID, userID, &role)
+	}
+	participants, _ := r.GetParticipants(context.Background(), conv.ID)
+	return participants
+}
+
+func (r *fakeConvRepo) GetByID(_ context.Context, id string) (*model.Conversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conv, ok := r.conversations[id]
+	if !ok {
+		return nil, repository.ErrConversationNotFound
+	}
+	return conv, nil
+}
+
+func (r *fakeConvRepo) GetByUserID(_ context.Context, userID string) ([]model.Conversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []model.Conversation
+	for id, participants := range r.participants {
+		if findParticipant(participants, userID) != nil {
+			result = append(result, *r.conversations[id])
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeConvRepo) AddParticipant(_ context.Context, conversationID, userID string, role *model.ParticipantRole) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.participants[conversationID] = append(r.participants[conversationID], model.Participant{
+		ConversationID: conversationID,
+		UserID:         userID,
+		Role:           role,
+		JoinedAt:       time.Now(),
+		User:           &model.UserResponse{ID: userID, Username: userID[:8]},
+	})
+	return nil
+}
+
+func (r *fakeConvRepo) RemoveParticipant(_ context.Context, conversationID, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.participants[conversationID] = slices.DeleteFunc(r.participants[conversationID], func(p model.Participant) bool {
+		return p.UserID == userID
+	})
+	return nil
+}
+
+func (r *fakeConvRepo) GetParticipants(_ context.Context, conversationID string) ([]model.Participant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.participants[conversationID]), nil
+}
+
+func (r *fakeConvRepo) SetRole(_ context.Context, conversationID, userID string, role model.ParticipantRole) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.participants[conversationID] {
+		if p := &r.participants[conversationID][i]; p.UserID == userID {
+			p.Role = &role
+		}
+	}
+	return nil
+}
+
+func (r *fakeConvRepo) SetName(_ context.Context, conversationID, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.conversations[conversationID].Name = &name
+	return nil
+}
+
+func (r *fakeConvRepo) SetDescription(_ context.Context, conversationID string, description *string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.conversations[conversationID].Description = description
+	return nil
+}
+
+func (r *fakeConvRepo) SetVisibility(_ context.Context, conversationID string, visibility model.Visibility) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.conversations[conversationID].Visibility = visibility
+	return nil
+}
+
+// fakeUserRepo knows every user, all active
+type fakeUserRepo struct {
+	repository.UserRepository
+}
+
+func (fakeUserRepo) GetByID(_ context.Context, id string) (*model.User, error) {
+	return &model.User{ID: id, Username: id[:8], IsActive: true}, nil
+}
+
+// fakeMsgRepo keeps messages in memory
+type fakeMsgRepo struct {
+	repository.MessageRepository
+
+	mu       sync.Mutex
+	messages map[string]*model.Message
+}
+
+func (r *fakeMsgRepo) add(msg *model.Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages[msg.ID] = msg
+}
+
+func (r *fakeMsgRepo) GetByID(_ context.Context, id string) (*model.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	msg, ok := r.messages[id]
+	if !ok {
+		return nil, repository.ErrMessageNotFound
+	}
+	copied := *msg
+	return &copied, nil
+}
+
+func (r *fakeMsgRepo) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.messages[id]; !ok {
+		return repository.ErrMessageNotFound
+	}
+	delete(r.messages, id)
+	return nil
+}
+
+// Update saves the message and sets its edited_at, like PostgreSQL
+func (r *fakeMsgRepo) Update(_ context.Context, msg *model.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	msg.EditedAt = &now
+	copied := *msg
+	r.messages[msg.ID] = &copied
+	return nil
+}
+
+// fakeInviteRepo keeps invite links in memory
+type fakeInviteRepo struct {
+	repository.GroupInviteRepository
+
+	mu      sync.Mutex
+	invites map[string]*model.GroupInvite
+}
+
+func (r *fakeInviteRepo) Create(_ context.Context, invite *model.GroupInvite) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invite.ID = uuid.New().String()
+	invite.CreatedAt = time.Now()
+	copied := *invite
+	r.invites[invite.ID] = &copied
+	return nil
+}
+
+func (r *fakeInviteRepo) GetByID(_ context.Context, id string) (*model.GroupInvite, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invite, ok := r.invites[id]
+	if !ok {
+		return nil, repository.ErrGroupInviteNotFound
+	}
+	copied := *invite
+	return &copied, nil
+}
+
+func (r *fakeInviteRepo) GetByHash(_ context.Context, tokenHash string) (*model.GroupInvite, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, invite := range r.invites {
+		if invite.TokenHash == tokenHash {
+			copied := *invite
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrGroupInviteNotFound
+}
+
+func (r *fakeInviteRepo) ListByConversation(_ context.Context, conversationID string) ([]model.GroupInvite, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []model.GroupInvite
+	for _, invite := range r.invites {
+		if invite.ConversationID == conversationID && invite.RevokedAt == nil {
+			result = append(result, *invite)
+		}
+	}
+	return result, nil
+}
+
+// Use only counts a use of a usable link, like PostgreSQL
+func (r *fakeInviteRepo) Use(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invite, ok := r.invites[id]
+	if !ok || !invite.IsUsable() {
+		return repository.ErrGroupInviteUsedUp
+	}
+	invite.Uses++
+	return nil
+}
+
+func (r *fakeInviteRepo) Revoke(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invite, ok := r.invites[id]
+	if !ok || invite.RevokedAt != nil {
+		return repository.ErrGroupInviteNotFound
+	}
+	now := time.Now()
+	invite.RevokedAt = &now
+	return nil
+}
+
+// fakeJoinRequestRepo keeps join requests in memory, one pending per user and group
+type fakeJoinRequestRepo struct {
+	repository.JoinRequestRepository
+
+	mu       sync.Mutex
+	requests map[string]*model.JoinRequest
+}
+
+func (r *fakeJoinRequestRepo) Create(_ context.Context, req *model.JoinRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.requests {
+		if existing.ConversationID == req.ConversationID && existing.UserID == req.UserID && existing.Status == model.JoinRequestPending {
+			return repository.ErrJoinRequestExists
+		}
+	}
+	req.ID = uuid.New().String()
+	req.Status = model.JoinRequestPending
+	req.CreatedAt = time.Now()
+	copied := *req
+	r.requests[req.ID] = &copied
+	return nil
+}
+
+func (r *fakeJoinRequestRepo) GetByID(_ context.Context, id string) (*model.JoinRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	req, ok := r.requests[id]
+	if !ok {
+		return nil, repository.ErrJoinRequestNotFound
+	}
+	copied := *req
+	return &copied, nil
+}
+
+func (r *fakeJoinRequestRepo) ListPending(_ context.Context, conversationID string) ([]model.JoinRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []model.JoinRequest
+	for _, req := range r.requests {
+		if req.ConversationID == conversationID && req.Status == model.JoinRequestPending {
+			result = append(result, *req)
+		}
+	}
+	return result, nil
+}
+
+// Decide only decides pending requests, like PostgreSQL
+func (r *fakeJoinRequestRepo) Decide(_ context.Context, req *model.JoinRequest, status model.JoinRequestStatus, decidedBy string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.requests[req.ID]
+	if !ok || stored.Status != model.JoinRequestPending {
+		return repository.ErrJoinRequestNotFound
+	}
+	now := time.Now()
+	stored.Status, stored.DecidedBy, stored.DecidedAt = status, &decidedBy, &now
+	req.Status, req.DecidedBy, req.DecidedAt = status, &decidedBy, &now
+	return nil
+}
+
+// recordingConn collects what listenForMessages writes
+type recordingConn struct {
+	frames chan []byte
+}
+
+func (c *recordingConn) WriteMessage(_ int, data []byte) error {
+	c.frames <- data
+	return nil
+}
+
+// waitSubscribed waits until someone listens to a Pub/Sub channel
+func waitSubscribed(t *testing.T, mr *miniredis.Miniredis, channel string) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for mr.PubSubNumSub(channel)[channel] == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("nobody subscribed to %s", channel)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
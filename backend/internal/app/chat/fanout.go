@@ -0,0 +1,110 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+// conversationChannelPrefix is the prefix of the Pub/Sub channels of conversations,
+// where what concerns all the participants is published once
+const conversationChannelPrefix = "conversation:"
+
+// conversationChannel returns the Pub/Sub channel of a conversation
+func conversationChannel(conversationID string) string {
+	return conversationChannelPrefix + conversationID
+}
+
+// subscriptionsChannel returns the Pub/Sub channel telling the connections of a user
+// to follow or stop following a conversation
+func subscriptionsChannel(userID string) string {
+	return "subscriptions:" + userID
+}
+
+// conversationEnvelope wraps what is published to a conversation, so the listeners
+// of the sender can skip it
+type conversationEnvelope struct {
+	SkipID  string          `json:"skip_id,omitempty"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// subscriptionChange tells the connections of a user that they joined or left a conversation
+type subscriptionChange struct {
+	ConversationID string `json:"conversation_id"`
+	Subscribed     bool   `json:"subscribed"`
+}
+
+// broadcast sends an event to the participants of a conversation but skipID: published
+// once for those connected anywhere, queued for the others in one round trip. Channels
+// don't queue: their members offline find the messages in the history.
+func (s *Service) broadcast(ctx context.Context, conv *model.Conversation, participants []model.Participant, skipID string, payload []byte) {
+	s.publishToConversation(ctx, conv.ID, skipID, payload)
+	if conv.Type == model.ConversationTypeChannel {
+		return
+	}
+
+	userIDs := make([]string, 0, len(participants))
+	for _, p := range participants {
+		if p.UserID != skipID {
+			userIDs = append(userIDs, p.UserID)
+		}
+	}
+	isOnline := s.onlineUsers(ctx, userIDs)
+	offline := slices.DeleteFunc(userIDs, func(userID string) bool { return isOnline[userID] })
+	if err := s.redis.AddToPendingMany(ctx, offline, string(payload)); err != nil {
+		logger.Errorf("Error adding to pending for conversation %s: %v", conv.ID, err)
+	}
+}
+
+// publishToConversation publishes an event once to the participants of a conversation
+// connected anywhere, except skipID
+func (s *Service) publishToConversation(ctx context.Context, conversationID, skipID string, payload []byte) {
+	data, err := json.Marshal(&conversationEnvelope{SkipID: skipID, Payload: payload})
+	if err != nil {
+		logger.Errorf("Error marshaling event for conversation %s: %v", conversationID, err)
+		return
+	}
+	if err := s.redis.Publish(ctx, conversationChannel(conversationID), data); err != nil {
+		logger.Errorf("Error publishing to conversation %s: %v", conversationID, err)
+	}
+}
+
+// setSubscribed makes the connections of the users follow (or stop following) a
+// conversation after they joined (or left) it
+func (s *Service) setSubscribed(ctx context.Context, conversationID string, userIDs []string, subscribed bool) {
+	data, err := json.Marshal(&subscriptionChange{ConversationID: conversationID, Subscribed: subscribed})
+	if err != nil {
+		logger.Errorf("Error marshaling subscription change: %v", err)
+		return
+	}
+	for _, userID := range userIDs {
+		if err := s.redis.Publish(ctx, subscriptionsChannel(userID), data); err != nil {
+			logger.Errorf("Error publishing subscription change to %s: %v", userID, err)
+		}
+	}
+}
+
+// updateSubscription applies a subscription change to the Pub/Sub of a connection
+func (s *Service) updateSubscription(ctx context.Context, pubsub *redis.PubSub, userID, payload string) {
+	var change subscriptionChange
+	if err := json.Unmarshal([]byte(payload), &change); err != nil {
+		logger.Errorf("Error parsing subscription change for %s: %v", userID, err)
+		return
+	}
+
+	channel := conversationChannel(change.ConversationID)
+	var err error
+	if change.Subscribed {
+		err = pubsub.Subscribe(ctx, channel)
+	} else {
+		err = pubsub.Unsubscribe(ctx, channel)
+	}
+	if err != nil {
+		logger.Errorf("Error updating subscription of %s to %s: %v", userID, channel, err)
+	}
+}
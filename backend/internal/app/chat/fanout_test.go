@@ -0,0 +1,220 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/redisclient"
+)
+
+func TestConnectedPeerReceivesMessagesOfNewConversation(t *testing.T) {
+	env := newTestEnv(t)
+	s := env.service
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	aliceID, bobID := uuid.New().String(), uuid.New().String()
+
+	// Bob connects before the conversation exists
+	conn := &recordingConn{frames: make(chan []byte, 16)}
+	s.users.Add(bobID, "", nil)
+	if err := s.redis.ConnectionAlive(ctx, bobID, uuid.New().String(), presenceTTL); err != nil {
+		t.Fatal(err)
+	}
+	go s.listenForMessages(ctx, conn, bobID)
+	waitSubscribed(t, env.redis, subscriptionsChannel(bobID))
+
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeDirect}
+	participants := env.convs.add(conv, aliceID, bobID)
+	s.NotifyCreated(ctx, aliceID, conv, participants)
+	waitSubscribed(t, env.redis, conversationChannel(conv.ID))
+
+	sent, err := s.PostMessage(ctx, aliceID, &WebSocketMessage{ConversationID: conv.ID, Content: "hi"})
+	if err != nil {
+		t.Fatalf("PostMessage: %v", err)
+	}
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case frame := <-conn.frames:
+			var received OutgoingMessage
+			if err := json.Unmarshal(frame, &received); err != nil {
+				t.Fatalf("invalid frame %s: %v", frame, err)
+			}
+			if received.ID == sent.ID {
+				if received.Content != "hi" {
+					t.Errorf("content = %q, want %q", received.Content, "hi")
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatal("message not received by the connected peer")
+		}
+	}
+}
+
+// pendingCount returns how many events are queued for a user while offline
+func pendingCount(env *testEnv, userID string) int {
+	queued, _ := env.redis.List("pending:" + userID)
+	return len(queued)
+}
+
+func TestPresenceFollowsConnectionsOfEveryInstance(t *testing.T) {
+	env := newTestEnv(t)
+	s := env.service
+	ctx := context.Background()
+
+	aliceID, bobID := uuid.New().String(), uuid.New().String()
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup}
+	participants := env.convs.add(conv, aliceID, bobID)
+
+	// Bob is connected to two instances; neither has his other connection
+	first, second := uuid.New().String(), uuid.New().String()
+	for _, connID := range []string{first, second} {
+		if err := s.redis.ConnectionAlive(ctx, bobID, connID, presenceTTL); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !s.connectionClosed(ctx, bobID, first, 0) {
+		t.Fatal("offline after closing one of two connections")
+	}
+	s.deliver(ctx, participants, aliceID, []byte(`{"type":"test"}`))
+	s.broadcast(ctx, conv, participants, aliceID, []byte(`{"type":"test"}`))
+	if n := pendingCount(env, bobID); n != 0 {
+		t.Errorf("%d events queued for a connected user", n)
+	}
+
+	if s.connectionClosed(ctx, bobID, second, 0) {
+		t.Fatal("online after closing every connection")
+	}
+	s.deliver(ctx, participants, aliceID, []byte(`{"type":"test"}`))
+	s.broadcast(ctx, conv, participants, aliceID, []byte(`{"type":"test"}`))
+	if n := pendingCount(env, bobID); n != 2 {
+		t.Errorf("%d events queued for an offline user, want 2", n)
+	}
+}
+
+func TestPresenceExpiresWithoutHeartbeat(t *testing.T) {
+	env := newTestEnv(t)
+	s := env.service
+	ctx := context.Background()
+
+	aliceID, bobID := uuid.New().String(), uuid.New().String()
+	participants := env.convs.add(&model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeDirect}, aliceID, bobID)
+
+	connID := uuid.New().String()
+	if err := s.redis.ConnectionAlive(ctx, bobID, connID, presenceTTL); err != nil {
+		t.Fatal(err)
+	}
+
+	// The heartbeat keeps the connection online past the TTL
+	env.redis.FastForward(presenceTTL - presenceHeartbeat)
+	if err := s.redis.ConnectionAlive(ctx, bobID, connID, presenceTTL); err != nil {
+		t.Fatal(err)
+	}
+	env.redis.FastForward(presenceTTL - presenceHeartbeat)
+	if online, _ := s.redis.GetOnlineUsersFromList(ctx, []string{bobID}); len(online) != 1 {
+		t.Fatal("offline despite the heartbeat")
+	}
+
+	// Its instance crashed: no heartbeat, nor disconnection
+	env.redis.FastForward(presenceTTL)
+	if online, _ := s.redis.GetOnlineUsersFromList(ctx, []string{bobID}); len(online) != 0 {
+		t.Errorf("still online without heartbeat: %v", online)
+	}
+	s.deliver(ctx, participants, aliceID, []byte(`{"type":"test"}`))
+	if n := pendingCount(env, bobID); n != 1 {
+		t.Errorf("%d events queued after the connection expired, want 1", n)
+	}
+}
+
+func TestMemberCacheInvalidatedOnEveryInstance(t *testing.T) {
+	env := newTestEnv(t)
+	s := env.service
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Another instance, sharing Redis and PostgreSQL
+	other := NewService(s.config, s.redis, env.convs, fakeUserRepo{}, env.msgs, env.invites, env.joinRequests, nil)
+	go other.Start(ctx)
+	waitSubscribed(t, env.redis, redisclient.MembersChangedChannel)
+
+	adminID, memberID, newID := uuid.New().String(), uuid.New().String(), uuid.New().String()
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup}
+	env.convs.add(conv, adminID, memberID)
+
+	for _, instance := range []*Service{s, other} {
+		if _, err := instance.PostMessage(ctx, adminID, &WebSocketMessage{ConversationID: conv.ID, Content: "hi"}); err != nil {
+			t.Fatalf("PostMessage: %v", err)
+		}
+		if entry, ok := instance.memberCache.get(conv.ID); !ok || len(entry.participants) != 2 {
+			t.Fatalf("members not cached after sending: %+v", entry)
+		}
+	}
+
+	if _, err := s.AddMembers(ctx, adminID, conv.ID, []string{newID}); err != nil {
+		t.Fatalf("AddMembers: %v", err)
+	}
+	if _, ok := s.memberCache.get(conv.ID); ok {
+		t.Error("members still cached by the instance that changed them")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for _, ok := other.memberCache.get(conv.ID); ok; _, ok = other.memberCache.get(conv.ID) {
+		if time.Now().After(deadline) {
+			t.Fatal("members still cached by the other instance")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// The new member gets what is sent through the other instance
+	before := pendingCount(env, newID)
+	if _, err := other.PostMessage(ctx, adminID, &WebSocketMessage{ConversationID: conv.ID, Content: "welcome"}); err != nil {
+		t.Fatalf("PostMessage: %v", err)
+	}
+	if n := pendingCount(env, newID); n != before+1 {
+		t.Errorf("%d messages queued for the new member, want %d", n, before+1)
+	}
+}
+
+func TestConnectionsFollowMembershipChanges(t *testing.T) {
+	env := newTestEnv(t)
+	s := env.service
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	adminID, bobID := uuid.New().String(), uuid.New().String()
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup}
+	env.convs.add(conv, adminID)
+
+	// Bob is connected before being added
+	conn := &recordingConn{frames: make(chan []byte, 16)}
+	if err := s.redis.ConnectionAlive(ctx, bobID, uuid.New().String(), presenceTTL); err != nil {
+		t.Fatal(err)
+	}
+	go s.listenForMessages(ctx, conn, bobID)
+	waitSubscribed(t, env.redis, subscriptionsChannel(bobID))
+
+	if _, err := s.AddMembers(ctx, adminID, conv.ID, []string{bobID}); err != nil {
+		t.Fatalf("AddMembers: %v", err)
+	}
+	waitSubscribed(t, env.redis, conversationChannel(conv.ID))
+
+	if err := s.RemoveMember(ctx, adminID, conv.ID, bobID); err != nil {
+		t.Fatalf("RemoveMember: %v", err)
+	}
+	channel := conversationChannel(conv.ID)
+	deadline := time.Now().Add(2 * time.Second)
+	for env.redis.PubSubNumSub(channel)[channel] != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("still subscribed to %s after being removed", channel)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
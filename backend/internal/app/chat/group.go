@@ -0,0 +1,397 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+var (
+	ErrNotGroup      = errors.New("conversation is not a group")
+	ErrUserNotFound  = errors.New("user not found")
+	ErrAlreadyMember = errors.New("already a member of the group")
+	ErrNotMember     = errors.New("not a member of the group")
+	ErrForbidden     = errors.New("not allowed by the participant's role")
+	ErrLastAdmin     = errors.New("the group needs another admin first")
+)
+
+// group loads a group and its participants, and checks that the user is a member
+// allowed to do what they want (no permission to check when empty)
+func (s *Service) group(ctx context.Context, userID, conversationID string, permission model.Permission) (*model.Conversation, []model.Participant, error) {
+	if _, err := uuid.Parse(conversationID); err != nil {
+		return nil, nil, ErrConversationNotFound
+	}
+
+	conv, err := s.convRepo.GetByID(ctx, conversationID)
+	if err != nil {
+		if errors.Is(err, repository.ErrConversationNotFound) {
+			return nil, nil, ErrConversationNotFound
+		}
+		return nil, nil, err
+	}
+
+	participants, err := s.convRepo.GetParticipants(ctx, conversationID)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := findParticipant(participants, userID)
+	if p == nil {
+		return nil, nil, ErrNotParticipant
+	}
+	if !conv.Type.IsGroup() {
+		return nil, nil, ErrNotGroup
+	}
+	if permission != "" && !p.Can(conv.Type, permission) {
+		return nil, nil, ErrForbidden
+	}
+	return conv, participants, nil
+}
+
+// UpdateGroup renames a group and/or changes its description or visibility
+func (s *Service) UpdateGroup(ctx context.Context, actorID, conversationID string, update *model.GroupUpdate) (*model.Conversation, error) {
+	conv, participants, err := s.group(ctx, actorID, conversationID, model.PermissionEditInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := false
+	if update.Name != nil && (conv.Name == nil || *conv.Name != *update.Name) {
+		if err := s.convRepo.SetName(ctx, conv.ID, *update.Name); err != nil {
+			return nil, err
+		}
+		conv.Name = update.Name
+		changed = true
+		s.announce(ctx, conv, participants, &model.SystemEvent{
+			Event:   model.SystemEventGroupRenamed,
+			ActorID: actorID,
+			Name:    update.Name,
+		}, `renamed the group to "`+*update.Name+`"`)
+	}
+	if update.Description != nil {
+		description := update.Description
+		if *description == "" {
+			description = nil
+		}
+		if err := s.convRepo.SetDescription(ctx, conv.ID, description); err != nil {
+			return nil, err
+		}
+		conv.Description = description
+		changed = true
+		text := "changed the group description"
+		if description == nil {
+			text = "removed the group description"
+		}
+		s.announce(ctx, conv, participants, &model.SystemEvent{
+			Event:       model.SystemEventDescriptionChanged,
+			ActorID:     actorID,
+			Description: description,
+		}, text)
+	}
+	if update.Visibility != nil && conv.Visibility != model.Visibility(*update.Visibility) {
+		visibility := model.Visibility(*update.Visibility)
+		if err := s.convRepo.SetVisibility(ctx, conv.ID, visibility); err != nil {
+			return nil, err
+		}
+		conv.Visibility = visibility
+		changed = true
+		text := "made the group public"
+		if visibility == model.VisibilityPrivate {
+			text = "made the group private"
+		}
+		s.announce(ctx, conv, participants, &model.SystemEvent{
+			Event:      model.SystemEventVisibilityChanged,
+			ActorID:    actorID,
+			Visibility: visibility,
+		}, text)
+	}
+
+	if !changed {
+		return conv, nil
+	}
+
+	logger.Infof("Group %s updated by %s", conv.ID, actorID)
+	s.deliverEvent(ctx, participants, &ConversationEvent{
+		Type:           EventConversationUpdated,
+		ConversationID: conv.ID,
+		ActorID:        actorID,
+		Conversation:   conv,
+	})
+	return conv, nil
+}
+
+// AddMembers adds people or bots to a group and returns the new participants.
+// Users already in the group are skipped.
+func (s *Service) AddMembers(ctx context.Context, actorID, conversationID string, userIDs []string) ([]model.Participant, error) {
+	conv, participants, err := s.group(ctx, actorID, conversationID, model.PermissionAddMembers)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check everyone first, so a typo doesn't leave the group half updated
+	var toAdd []string
+	for _, userID := range userIDs {
+		user, err := s.userRepo.GetByID(ctx, userID)
+		if errors.Is(err, repository.ErrUserNotFound) || (err == nil && !user.IsActive) {
+			return nil, ErrUserNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if findParticipant(participants, user.ID) == nil && !slices.Contains(toAdd, user.ID) {
+			toAdd = append(toAdd, user.ID)
+		}
+	}
+	if len(toAdd) == 0 {
+		return nil, ErrAlreadyMember
+	}
+
+	memberRole := model.ParticipantRoleMember
+	for _, userID := range toAdd {
+		if err := s.convRepo.AddParticipant(ctx, conv.ID, userID, &memberRole); err != nil {
+			return nil, err
+		}
+	}
+
+	updated, err := s.convRepo.GetParticipants(ctx, conv.ID)
+	if err != nil {
+		return nil, err
+	}
+	var added []model.Participant
+	var names []string
+	for _, p := range updated {
+		if slices.Contains(toAdd, p.UserID) {
+			added = append(added, p)
+			if p.User != nil {
+				names = append(names, "@"+p.User.Username)
+			}
+		}
+	}
+
+	logger.Infof("User %s added %d member(s) to group %s", actorID, len(added), conv.ID)
+	s.MembersChanged(ctx, conv.ID, toAdd, nil)
+	s.announce(ctx, conv, updated, &model.SystemEvent{
+		Event:   model.SystemEventMembersAdded,
+		ActorID: actorID,
+		UserIDs: toAdd,
+	}, "added "+strings.Join(names, ", "))
+
+	s.deliverEvent(ctx, updated, &ConversationEvent{
+		Type:           EventParticipantAdded,
+		ConversationID: conv.ID,
+		ActorID:        actorID,
+		Participants:   participantResponses(added),
+	})
+	s.notifyParticipantsAdded(ctx, conv.ID, actorID, added)
+	return added, nil
+}
+
+// RemoveMember removes someone else from a group
+func (s *Service) RemoveMember(ctx context.Context, actorID, conversationID, userID string) error {
+	if userID == actorID {
+		return s.LeaveGroup(ctx, actorID, conversationID)
+	}
+
+	conv, participants, err := s.group(ctx, actorID, conversationID, model.PermissionRemoveMembers)
+	if err != nil {
+		return err
+	}
+	member := findParticipant(participants, userID)
+	if member == nil {
+		return ErrNotMember
+	}
+
+	name := "a member"
+	if member.User != nil {
+		name = "@" + member.User.Username
+	}
+	// Post first, so the removed member sees it too
+	s.announce(ctx, conv, participants, &model.SystemEvent{
+		Event:   model.SystemEventMemberRemoved,
+		ActorID: actorID,
+		UserIDs: []string{userID},
+	}, "removed "+name)
+	if err := s.convRepo.RemoveParticipant(ctx, conv.ID, userID); err != nil {
+		return err
+	}
+
+	logger.Infof("User %s removed %s from group %s", actorID, userID, conv.ID)
+	s.MembersChanged(ctx, conv.ID, nil, []string{userID})
+	s.deliverEvent(ctx, participants, &ConversationEvent{
+		Type:           EventParticipantRemoved,
+		ConversationID: conv.ID,
+		ActorID:        actorID,
+		UserID:         userID,
+	})
+	s.notifyParticipantRemoved(ctx, conv.ID, actorID, userID)
+	return nil
+}
+
+// LeaveGroup removes the user from a group. The last admin has to promote someone
+// else first, unless they are the last member.
+func (s *Service) LeaveGroup(ctx context.Context, userID, conversationID string) error {
+	conv, participants, err := s.group(ctx, userID, conversationID, "")
+	if err != nil {
+		return err
+	}
+	if len(participants) > 1 && isLastAdmin(participants, userID) {
+		return ErrLastAdmin
+	}
+
+	// Post while still a participant, so the others see who left
+	s.announce(ctx, conv, participants, &model.SystemEvent{
+		Event:   model.SystemEventMemberLeft,
+		ActorID: userID,
+	}, "left the group")
+	if err := s.convRepo.RemoveParticipant(ctx, conv.ID, userID); err != nil {
+		return err
+	}
+
+	logger.Infof("User %s left group %s", userID, conv.ID)
+	s.MembersChanged(ctx, conv.ID, nil, []string{userID})
+	s.deliverEvent(ctx, participants, &ConversationEvent{
+		Type:           EventParticipantRemoved,
+		ConversationID: conv.ID,
+		ActorID:        userID,
+		UserID:         userID,
+	})
+	s.notifyParticipantRemoved(ctx, conv.ID, userID, userID)
+	return nil
+}
+
+// SetRole promotes a member to admin or demotes an admin to member
+func (s *Service) SetRole(ctx context.Context, actorID, conversationID, userID string, role model.ParticipantRole) (*model.Participant, error) {
+	conv, participants, err := s.group(ctx, actorID, conversationID, model.PermissionManageRoles)
+	if err != nil {
+		return nil, err
+	}
+	member := findParticipant(participants, userID)
+	if member == nil {
+		return nil, ErrNotMember
+	}
+	if member.EffectiveRole() == role {
+		return member, nil
+	}
+	if role != model.ParticipantRoleAdmin && isLastAdmin(participants, userID) {
+		return nil, ErrLastAdmin
+	}
+
+	if err := s.convRepo.SetRole(ctx, conv.ID, userID, role); err != nil {
+		return nil, err
+	}
+	s.MembersChanged(ctx, conv.ID, nil, nil)
+	member.Role = &role
+
+	name := "a member"
+	if member.User != nil {
+		name = "@" + member.User.Username
+	}
+	text := "made " + name + " an admin"
+	if role != model.ParticipantRoleAdmin {
+		text = "removed " + name + " as admin"
+	}
+
+	logger.Infof("User %s set the role of %s in group %s to %s", actorID, userID, conv.ID, role)
+	s.announce(ctx, conv, participants, &model.SystemEvent{
+		Event:   model.SystemEventRoleChanged,
+		ActorID: actorID,
+		UserIDs: []string{userID},
+		Role:    role,
+	}, text)
+
+	s.deliverEvent(ctx, participants, &ConversationEvent{
+		Type:           EventParticipantUpdated,
+		ConversationID: conv.ID,
+		ActorID:        actorID,
+		Participants:   participantResponses([]model.Participant{*member}),
+	})
+	return member, nil
+}
+
+// JoinPublic adds the user to a public group found in the directory. Private groups
+// look like they don't exist, so their existence never leaks.
+func (s *Service) JoinPublic(ctx context.Context, userID, conversationID string) (*model.Conversation, error) {
+	if _, err := uuid.Parse(conversationID); err != nil {
+		return nil, ErrConversationNotFound
+	}
+
+	conv, err := s.convRepo.GetByID(ctx, conversationID)
+	if err != nil {
+		if errors.Is(err, repository.ErrConversationNotFound) {
+			return nil, ErrConversationNotFound
+		}
+		return nil, err
+	}
+	if !conv.Type.IsGroup() || conv.Visibility != model.VisibilityPublic {
+		return nil, ErrConversationNotFound
+	}
+
+	participants, err := s.convRepo.GetParticipants(ctx, conv.ID)
+	if err != nil {
+		return nil, err
+	}
+	if findParticipant(participants, userID) != nil {
+		return nil, ErrAlreadyMember
+	}
+
+	event := &model.SystemEvent{Event: model.SystemEventMemberJoined, ActorID: userID}
+	if err := s.join(ctx, conv, userID, event, "joined the group"); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("User %s joined public group %s", userID, conv.ID)
+	return conv, nil
+}
+
+// SearchDirectory lists the public groups matching a query
+func (s *Service) SearchDirectory(ctx context.Context, userID, query string, limit, offset int) ([]model.DirectoryGroup, error) {
+	return s.convRepo.SearchPublic(ctx, userID, query, limit, offset)
+}
+
+// join adds a user to a group on their own (e.g. with an invite link), with the same
+// system message and events as members added by someone else
+func (s *Service) join(ctx context.Context, conv *model.Conversation, userID string, event *model.SystemEvent, text string) error {
+	memberRole := model.ParticipantRoleMember
+	if err := s.convRepo.AddParticipant(ctx, conv.ID, userID, &memberRole); err != nil {
+		return err
+	}
+
+	participants, err := s.convRepo.GetParticipants(ctx, conv.ID)
+	if err != nil {
+		return err
+	}
+	member := findParticipant(participants, userID)
+	if member == nil {
+		return ErrNotMember
+	}
+
+	s.MembersChanged(ctx, conv.ID, []string{userID}, nil)
+	s.announce(ctx, conv, participants, event, text)
+	s.deliverEvent(ctx, participants, &ConversationEvent{
+		Type:           EventParticipantAdded,
+		ConversationID: conv.ID,
+		ActorID:        event.ActorID,
+		Participants:   participantResponses([]model.Participant{*member}),
+	})
+	s.notifyParticipantsAdded(ctx, conv.ID, event.ActorID, []model.Participant{*member})
+	return nil
+}
+
+// isLastAdmin checks if the user is the only admin of the group
+func isLastAdmin(participants []model.Participant, userID string) bool {
+	admins := 0
+	isAdmin := false
+	for i := range participants {
+		if participants[i].IsAdmin() {
+			admins++
+			isAdmin = isAdmin || participants[i].UserID == userID
+		}
+	}
+	return isAdmin && admins == 1
+}
@@ -0,0 +1,212 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/redisclient"
+)
+
+// streamedMessages returns the messages queued for the message worker, oldest first
+func streamedMessages(t *testing.T, env *testEnv) []OutgoingMessage {
+	t.Helper()
+
+	entries, err := env.redis.Stream(redisclient.MessagesStream)
+	if err != nil {
+		return nil
+	}
+	messages := make([]OutgoingMessage, 0, len(entries))
+	for _, entry := range entries {
+		for i := 0; i+1 < len(entry.Values); i += 2 {
+			if entry.Values[i] != "data" {
+				continue
+			}
+			var msg OutgoingMessage
+			if err := json.Unmarshal([]byte(entry.Values[i+1]), &msg); err != nil {
+				t.Fatalf("invalid message %s: %v", entry.Values[i+1], err)
+			}
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// systemEvents returns the events of the system messages posted, oldest first
+func systemEvents(t *testing.T, env *testEnv) []model.SystemEvent {
+	t.Helper()
+
+	var events []model.SystemEvent
+	for _, msg := range streamedMessages(t, env) {
+		if msg.Type != string(model.MessageTypeSystem) {
+			continue
+		}
+		var event model.SystemEvent
+		if err := json.Unmarshal(msg.Metadata, &event); err != nil {
+			t.Fatalf("invalid system event %s: %v", msg.Metadata, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// pendingTypes returns the types of what is queued for an offline user, oldest first
+func pendingTypes(t *testing.T, env *testEnv, userID string) []string {
+	t.Helper()
+
+	queued, _ := env.redis.List("pending:" + userID)
+	types := make([]string, 0, len(queued))
+	for _, payload := range queued {
+		var event struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			t.Fatalf("invalid pending payload %s: %v", payload, err)
+		}
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestUpdateGroup(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	adminID, memberID := uuid.New().String(), uuid.New().String()
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup, Visibility: model.VisibilityPrivate}
+	env.convs.add(conv, adminID, memberID)
+
+	name, description := "Release", "Shipping on Friday"
+	if _, err := env.service.UpdateGroup(ctx, memberID, conv.ID, &model.GroupUpdate{Name: &name}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("renamed by a member: err = %v, want %v", err, ErrForbidden)
+	}
+
+	updated, err := env.service.UpdateGroup(ctx, adminID, conv.ID, &model.GroupUpdate{Name: &name, Description: &description})
+	if err != nil {
+		t.Fatalf("UpdateGroup: %v", err)
+	}
+	if updated.Name == nil || *updated.Name != name || updated.Description == nil || *updated.Description != description {
+		t.Errorf("group = %+v, want renamed with a description", updated)
+	}
+
+	events := systemEvents(t, env)
+	if len(events) != 2 || events[0].Event != model.SystemEventGroupRenamed || events[1].Event != model.SystemEventDescriptionChanged {
+		t.Fatalf("system events = %+v, want renamed then description changed", events)
+	}
+	if events[0].ActorID != adminID || events[0].Name == nil || *events[0].Name != name {
+		t.Errorf("rename event = %+v", events[0])
+	}
+
+	// System messages first, then a single conversation_updated
+	want := []string{string(model.MessageTypeSystem), string(model.MessageTypeSystem), EventConversationUpdated}
+	if got := pendingTypes(t, env, memberID); !slices.Equal(got, want) {
+		t.Errorf("queued for the member = %v, want %v", got, want)
+	}
+
+	// Nothing changed, nothing announced
+	if _, err := env.service.UpdateGroup(ctx, adminID, conv.ID, &model.GroupUpdate{Name: &name}); err != nil {
+		t.Fatalf("UpdateGroup without change: %v", err)
+	}
+	if n := len(systemEvents(t, env)); n != 2 {
+		t.Errorf("%d system messages after an update without change, want 2", n)
+	}
+}
+
+func TestAddAndRemoveMembers(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	adminID, memberID, newID := uuid.New().String(), uuid.New().String(), uuid.New().String()
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup}
+	env.convs.add(conv, adminID, memberID)
+
+	if _, err := env.service.AddMembers(ctx, adminID, conv.ID, []string{memberID}); !errors.Is(err, ErrAlreadyMember) {
+		t.Errorf("adding a member again: err = %v, want %v", err, ErrAlreadyMember)
+	}
+
+	added, err := env.service.AddMembers(ctx, memberID, conv.ID, []string{newID, newID})
+	if err != nil {
+		t.Fatalf("AddMembers: %v", err)
+	}
+	if len(added) != 1 || added[0].UserID != newID || added[0].IsAdmin() {
+		t.Fatalf("added = %+v, want the new user once, as a member", added)
+	}
+	if got, want := pendingTypes(t, env, newID), []string{string(model.MessageTypeSystem), EventParticipantAdded}; !slices.Equal(got, want) {
+		t.Errorf("queued for the new member = %v, want %v", got, want)
+	}
+
+	// Members add people but don't remove them
+	if err := env.service.RemoveMember(ctx, memberID, conv.ID, newID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("removed by a member: err = %v, want %v", err, ErrForbidden)
+	}
+	if err := env.service.RemoveMember(ctx, adminID, conv.ID, uuid.New().String()); !errors.Is(err, ErrNotMember) {
+		t.Errorf("removing a stranger: err = %v, want %v", err, ErrNotMember)
+	}
+	if err := env.service.RemoveMember(ctx, adminID, conv.ID, newID); err != nil {
+		t.Fatalf("RemoveMember: %v", err)
+	}
+
+	participants, _ := env.convs.GetParticipants(ctx, conv.ID)
+	if findParticipant(participants, newID) != nil {
+		t.Error("removed member still in the group")
+	}
+	// The removed member is told, with the system message first
+	want := []string{string(model.MessageTypeSystem), EventParticipantAdded, string(model.MessageTypeSystem), EventParticipantRemoved}
+	if got := pendingTypes(t, env, newID); !slices.Equal(got, want) {
+		t.Errorf("queued for the removed member = %v, want %v", got, want)
+	}
+
+	events := systemEvents(t, env)
+	if len(events) != 2 || events[0].Event != model.SystemEventMembersAdded || events[1].Event != model.SystemEventMemberRemoved {
+		t.Fatalf("system events = %+v, want members added then member removed", events)
+	}
+	if events[1].ActorID != adminID || len(events[1].UserIDs) != 1 || events[1].UserIDs[0] != newID {
+		t.Errorf("removal event = %+v", events[1])
+	}
+}
+
+func TestLeaveGroup(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	adminID, memberID := uuid.New().String(), uuid.New().String()
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup}
+	env.convs.add(conv, adminID, memberID)
+
+	// Removing oneself is leaving
+	if err := env.service.RemoveMember(ctx, memberID, conv.ID, memberID); err != nil {
+		t.Fatalf("leaving: %v", err)
+	}
+	if err := env.service.LeaveGroup(ctx, memberID, conv.ID); !errors.Is(err, ErrNotParticipant) {
+		t.Errorf("leaving twice: err = %v, want %v", err, ErrNotParticipant)
+	}
+
+	events := systemEvents(t, env)
+	if len(events) != 1 || events[0].Event != model.SystemEventMemberLeft || events[0].ActorID != memberID {
+		t.Errorf("system events = %+v, want the member left", events)
+	}
+	if got, want := pendingTypes(t, env, adminID), []string{string(model.MessageTypeSystem), EventParticipantRemoved}; !slices.Equal(got, want) {
+		t.Errorf("queued for the admin = %v, want %v", got, want)
+	}
+
+	// The last member can leave, even as the last admin
+	if err := env.service.LeaveGroup(ctx, adminID, conv.ID); err != nil {
+		t.Errorf("last member leaving: %v", err)
+	}
+}
+
+func TestGroupChangesInDirectConversation(t *testing.T) {
+	env := newTestEnv(t)
+	aliceID, bobID := uuid.New().String(), uuid.New().String()
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeDirect}
+	env.convs.add(conv, aliceID, bobID)
+
+	if _, err := env.service.AddMembers(context.Background(), aliceID, conv.ID, []string{uuid.New().String()}); !errors.Is(err, ErrNotGroup) {
+		t.Errorf("adding to a direct conversation: err = %v, want %v", err, ErrNotGroup)
+	}
+	if err := env.service.LeaveGroup(context.Background(), aliceID, conv.ID); !errors.Is(err, ErrNotGroup) {
+		t.Errorf("leaving a direct conversation: err = %v, want %v", err, ErrNotGroup)
+	}
+}
@@ -0,0 +1,240 @@
+package chat
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+var (
+	ErrInviteNotFound = errors.New("invite link not found")
+	ErrInviteExpired  = errors.New("invite link expired or used up")
+	ErrTooManyInvites = errors.New("too many invite links")
+)
+
+const (
+	// InviteTokenPrefix makes invite tokens recognizable
+	InviteTokenPrefix = "inv_"
+
+	inviteTokenDisplayLength = len(InviteTokenPrefix) + 6
+	// maxInvitesPerGroup limits the links of a group that can still be used
+	maxInvitesPerGroup = 25
+)
+
+// CreatedInvite is returned once at creation, with the token and the link to share
+type CreatedInvite struct {
+	model.GroupInviteResponse
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// CreateInvite creates an invite link to a group
+func (s *Service) CreateInvite(ctx context.Context, actorID, conversationID string, req *model.GroupInviteCreate) (*CreatedInvite, error) {
+	conv, _, err := s.group(ctx, actorID, conversationID, model.PermissionManageInvites)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.invites.ListByConversation(ctx, conv.ID)
+	if err != nil {
+		return nil, err
+	}
+	usable := 0
+	for i := range existing {
+		if existing[i].IsUsable() {
+			usable++
+		}
+	}
+	if usable >= maxInvitesPerGroup {
+		return nil, ErrTooManyInvites
+	}
+
+	token, err := generateInviteToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invite := &model.GroupInvite{
+		ConversationID:   conv.ID,
+		CreatedBy:        actorID,
+		TokenPrefix:      token[:inviteTokenDisplayLength],
+		TokenHash:        hashInviteToken(token),
+		RequiresApproval: req.RequiresApproval,
+	}
+	if req.ExpiresInHours > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		invite.ExpiresAt = &expiresAt
+	}
+	if req.MaxUses > 0 {
+		maxUses := req.MaxUses
+		invite.MaxUses = &maxUses
+	}
+	if err := s.invites.Create(ctx, invite); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Invite link %s created for group %s by %s", invite.ID, conv.ID, actorID)
+	return &CreatedInvite{
+		GroupInviteResponse: invite.ToResponse(),
+		Token:               token,
+		URL:                 strings.TrimRight(s.config.Server.FrontendURL, "/") + "/invite/" + token,
+	}, nil
+}
+
+// ListInvites returns the invite links of a group that weren't revoked
+func (s *Service) ListInvites(ctx context.Context, actorID, conversationID string) ([]model.GroupInviteResponse, error) {
+	conv, _, err := s.group(ctx, actorID, conversationID, model.PermissionManageInvites)
+	if err != nil {
+		return nil, err
+	}
+
+	invites, err := s.invites.ListByConversation(ctx, conv.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.GroupInviteResponse, 0, len(invites))
+	for i := range invites {
+		result = append(result, invites[i].ToResponse())
+	}
+	return result, nil
+}
+
+// RevokeInvite revokes an invite link of a group; it can't be used anymore
+func (s *Service) RevokeInvite(ctx context.Context, actorID, conversationID, inviteID string) error {
+	if _, err := uuid.Parse(inviteID); err != nil {
+		return ErrInviteNotFound
+	}
+	conv, _, err := s.group(ctx, actorID, conversationID, model.PermissionManageInvites)
+	if err != nil {
+		return err
+	}
+
+	invite, err := s.invites.GetByID(ctx, inviteID)
+	if errors.Is(err, repository.ErrGroupInviteNotFound) || (err == nil && invite.ConversationID != conv.ID) {
+		return ErrInviteNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.invites.Revoke(ctx, invite.ID); err != nil {
+		if errors.Is(err, repository.ErrGroupInviteNotFound) {
+			return ErrInviteNotFound
+		}
+		return err
+	}
+
+	logger.Infof("Invite link %s of group %s revoked by %s", invite.ID, conv.ID, actorID)
+	return nil
+}
+
+// PreviewInvite returns what the group of an invite link looks like, before joining it
+func (s *Service) PreviewInvite(ctx context.Context, userID, token string) (*model.GroupPreview, error) {
+	invite, conv, participants, err := s.loadInvite(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.GroupPreview{
+		ConversationID:   conv.ID,
+		Name:             conv.Name,
+		Description:      conv.Description,
+		MemberCount:      len(participants),
+		RequiresApproval: invite.RequiresApproval,
+		ExpiresAt:        invite.ExpiresAt,
+		IsMember:         findParticipant(participants, userID) != nil,
+	}, nil
+}
+
+// JoinByInvite adds the user to the group of an invite link. When the link needs
+// approval, a join request is created instead and returned.
+func (s *Service) JoinByInvite(ctx context.Context, userID, token string) (*model.Conversation, *model.JoinRequest, error) {
+	invite, conv, participants, err := s.loadInvite(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if findParticipant(participants, userID) != nil {
+		return nil, nil, ErrAlreadyMember
+	}
+	if invite.RequiresApproval {
+		// The use is counted once approved
+		jr, err := s.createJoinRequest(ctx, conv, participants, userID, &invite.ID, "")
+		return nil, jr, err
+	}
+
+	if err := s.invites.Use(ctx, invite.ID); err != nil {
+		if errors.Is(err, repository.ErrGroupInviteUsedUp) {
+			return nil, nil, ErrInviteExpired
+		}
+		return nil, nil, err
+	}
+
+	if err := s.join(ctx, conv, userID, &model.SystemEvent{
+		Event:   model.SystemEventMemberJoined,
+		ActorID: userID,
+	}, "joined the group with an invite link"); err != nil {
+		return nil, nil, err
+	}
+
+	logger.Infof("User %s joined group %s with invite link %s", userID, conv.ID, invite.ID)
+	return conv, nil, nil
+}
+
+// loadInvite returns a usable invite link with its group and members
+func (s *Service) loadInvite(ctx context.Context, token string) (*model.GroupInvite, *model.Conversation, []model.Participant, error) {
+	if !strings.HasPrefix(token, InviteTokenPrefix) {
+		return nil, nil, nil, ErrInviteNotFound
+	}
+
+	invite, err := s.invites.GetByHash(ctx, hashInviteToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrGroupInviteNotFound) {
+			return nil, nil, nil, ErrInviteNotFound
+		}
+		return nil, nil, nil, err
+	}
+	if invite.RevokedAt != nil {
+		return nil, nil, nil, ErrInviteNotFound
+	}
+	if !invite.IsUsable() {
+		return nil, nil, nil, ErrInviteExpired
+	}
+
+	conv, err := s.convRepo.GetByID(ctx, invite.ConversationID)
+	if err != nil {
+		if errors.Is(err, repository.ErrConversationNotFound) {
+			return nil, nil, nil, ErrInviteNotFound
+		}
+		return nil, nil, nil, err
+	}
+	participants, err := s.convRepo.GetParticipants(ctx, conv.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return invite, conv, participants, nil
+}
+
+func generateInviteToken() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return InviteTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
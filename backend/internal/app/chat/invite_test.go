@@ -0,0 +1,153 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+)
+
+// inviteGroup returns a group of an admin and a member
+func inviteGroup(env *testEnv) (conv *model.Conversation, adminID, memberID string) {
+	adminID, memberID = uuid.New().String(), uuid.New().String()
+	name := "Release"
+	conv = &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup, Name: &name}
+	env.convs.add(conv, adminID, memberID)
+	return conv, adminID, memberID
+}
+
+func TestJoinByInvite(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	conv, adminID, memberID := inviteGroup(env)
+	guestID, lateID := uuid.New().String(), uuid.New().String()
+
+	if _, err := env.service.CreateInvite(ctx, memberID, conv.ID, &model.GroupInviteCreate{}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("created by a member: err = %v, want %v", err, ErrForbidden)
+	}
+	invite, err := env.service.CreateInvite(ctx, adminID, conv.ID, &model.GroupInviteCreate{MaxUses: 1})
+	if err != nil {
+		t.Fatalf("CreateInvite: %v", err)
+	}
+	if invite.URL != "/invite/"+invite.Token || invite.TokenPrefix != invite.Token[:inviteTokenDisplayLength] {
+		t.Errorf("invite = %+v", invite)
+	}
+
+	preview, err := env.service.PreviewInvite(ctx, guestID, invite.Token)
+	if err != nil {
+		t.Fatalf("PreviewInvite: %v", err)
+	}
+	if preview.ConversationID != conv.ID || preview.MemberCount != 2 || preview.IsMember || *preview.Name != *conv.Name {
+		t.Errorf("preview = %+v", preview)
+	}
+
+	joined, jr, err := env.service.JoinByInvite(ctx, guestID, invite.Token)
+	if err != nil || jr != nil {
+		t.Fatalf("JoinByInvite: %v, join request %+v", err, jr)
+	}
+	if joined.ID != conv.ID {
+		t.Errorf("joined %s, want %s", joined.ID, conv.ID)
+	}
+	participants, _ := env.convs.GetParticipants(ctx, conv.ID)
+	if p := findParticipant(participants, guestID); p == nil || p.IsAdmin() {
+		t.Errorf("guest = %+v, want a member", p)
+	}
+
+	// Same system message and events as members added by an admin
+	events := systemEvents(t, env)
+	if len(events) != 1 || events[0].Event != model.SystemEventMemberJoined || events[0].ActorID != guestID {
+		t.Errorf("system events = %+v, want the guest joined", events)
+	}
+	if got := pendingTypes(t, env, adminID); len(got) != 2 || got[1] != EventParticipantAdded {
+		t.Errorf("queued for the admin = %v, want a system message and %s", got, EventParticipantAdded)
+	}
+
+	// Used up
+	if _, err := env.service.PreviewInvite(ctx, lateID, invite.Token); !errors.Is(err, ErrInviteExpired) {
+		t.Errorf("preview of a used up link: err = %v, want %v", err, ErrInviteExpired)
+	}
+	if _, _, err := env.service.JoinByInvite(ctx, lateID, invite.Token); !errors.Is(err, ErrInviteExpired) {
+		t.Errorf("join with a used up link: err = %v, want %v", err, ErrInviteExpired)
+	}
+}
+
+func TestInviteLinkRefused(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	conv, adminID, memberID := inviteGroup(env)
+	other, otherAdminID, _ := inviteGroup(env)
+
+	invite, err := env.service.CreateInvite(ctx, adminID, conv.ID, &model.GroupInviteCreate{ExpiresInHours: 1})
+	if err != nil {
+		t.Fatalf("CreateInvite: %v", err)
+	}
+	if _, _, err := env.service.JoinByInvite(ctx, memberID, invite.Token); !errors.Is(err, ErrAlreadyMember) {
+		t.Errorf("joined by a member: err = %v, want %v", err, ErrAlreadyMember)
+	}
+	if _, err := env.service.PreviewInvite(ctx, memberID, "not-a-token"); !errors.Is(err, ErrInviteNotFound) {
+		t.Errorf("invalid token: err = %v, want %v", err, ErrInviteNotFound)
+	}
+
+	// Expired
+	expiresAt := time.Now().Add(-time.Minute)
+	env.invites.invites[invite.ID].ExpiresAt = &expiresAt
+	if _, _, err := env.service.JoinByInvite(ctx, uuid.New().String(), invite.Token); !errors.Is(err, ErrInviteExpired) {
+		t.Errorf("expired link: err = %v, want %v", err, ErrInviteExpired)
+	}
+
+	// Revoked, only by the admins of its group
+	invite, err = env.service.CreateInvite(ctx, adminID, conv.ID, &model.GroupInviteCreate{})
+	if err != nil {
+		t.Fatalf("CreateInvite: %v", err)
+	}
+	if err := env.service.RevokeInvite(ctx, otherAdminID, other.ID, invite.ID); !errors.Is(err, ErrInviteNotFound) {
+		t.Errorf("revoked through another group: err = %v, want %v", err, ErrInviteNotFound)
+	}
+	if err := env.service.RevokeInvite(ctx, adminID, conv.ID, invite.ID); err != nil {
+		t.Fatalf("RevokeInvite: %v", err)
+	}
+	if _, _, err := env.service.JoinByInvite(ctx, uuid.New().String(), invite.Token); !errors.Is(err, ErrInviteNotFound) {
+		t.Errorf("revoked link: err = %v, want %v", err, ErrInviteNotFound)
+	}
+	if listed, err := env.service.ListInvites(ctx, adminID, conv.ID); err != nil || len(listed) != 1 {
+		t.Errorf("listed %d links (%v), want the expired one only", len(listed), err)
+	}
+}
+
+func TestInviteLinkNeedingApproval(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	conv, adminID, _ := inviteGroup(env)
+	guestID := uuid.New().String()
+
+	invite, err := env.service.CreateInvite(ctx, adminID, conv.ID, &model.GroupInviteCreate{MaxUses: 1, RequiresApproval: true})
+	if err != nil {
+		t.Fatalf("CreateInvite: %v", err)
+	}
+
+	joined, jr, err := env.service.JoinByInvite(ctx, guestID, invite.Token)
+	if err != nil || joined != nil || jr == nil {
+		t.Fatalf("JoinByInvite = %+v, %+v, %v, want a join request", joined, jr, err)
+	}
+	if jr.InviteID == nil || *jr.InviteID != invite.ID {
+		t.Errorf("join request invite = %v, want %s", jr.InviteID, invite.ID)
+	}
+	if uses := env.invites.invites[invite.ID].Uses; uses != 0 {
+		t.Errorf("%d uses before the approval, want 0", uses)
+	}
+
+	if _, err := env.service.DecideJoinRequest(ctx, adminID, conv.ID, jr.ID, true); err != nil {
+		t.Fatalf("DecideJoinRequest: %v", err)
+	}
+	participants, _ := env.convs.GetParticipants(ctx, conv.ID)
+	if findParticipant(participants, guestID) == nil {
+		t.Error("guest not in the group once approved")
+	}
+	if uses := env.invites.invites[invite.ID].Uses; uses != 1 {
+		t.Errorf("%d uses once approved, want 1", uses)
+	}
+}
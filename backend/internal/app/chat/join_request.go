@@ -0,0 +1,186 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+var (
+	ErrJoinRequestNotFound = errors.New("join request not found")
+	ErrJoinRequestPending  = errors.New("a join request is already pending")
+	ErrJoinRequestDecided  = errors.New("join request already decided")
+)
+
+// Live events of join requests
+const (
+	EventJoinRequestCreated = "join_request_created" // To the admins
+	EventJoinRequestDecided = "join_request_decided" // To the admins and the requester
+)
+
+// JoinRequestEvent tells the admins of a group about a join request, and the
+// requester about the decision
+type JoinRequestEvent struct {
+	Type           string                    `json:"type"`
+	ConversationID string                    `json:"conversation_id"`
+	Request        model.JoinRequestResponse `json:"request"`
+}
+
+// RequestToJoin asks the admins of a group to let the user in. It is how private groups,
+// which aren't in the directory, are joined without an invite link.
+func (s *Service) RequestToJoin(ctx context.Context, userID, conversationID string, req *model.JoinRequestCreate) (*model.JoinRequest, error) {
+	if _, err := uuid.Parse(conversationID); err != nil {
+		return nil, ErrConversationNotFound
+	}
+
+	conv, err := s.convRepo.GetByID(ctx, conversationID)
+	if err != nil {
+		if errors.Is(err, repository.ErrConversationNotFound) {
+			return nil, ErrConversationNotFound
+		}
+		return nil, err
+	}
+	if !conv.Type.IsGroup() {
+		return nil, ErrConversationNotFound
+	}
+
+	participants, err := s.convRepo.GetParticipants(ctx, conv.ID)
+	if err != nil {
+		return nil, err
+	}
+	if findParticipant(participants, userID) != nil {
+		return nil, ErrAlreadyMember
+	}
+
+	return s.createJoinRequest(ctx, conv, participants, userID, nil, req.Message)
+}
+
+// ListJoinRequests returns the requests of a group waiting for a decision
+func (s *Service) ListJoinRequests(ctx context.Context, actorID, conversationID string) ([]model.JoinRequestResponse, error) {
+	conv, _, err := s.group(ctx, actorID, conversationID, model.PermissionApproveMembers)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := s.joinRequests.ListPending(ctx, conv.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.JoinRequestResponse, 0, len(requests))
+	for i := range requests {
+		result = append(result, requests[i].ToResponse())
+	}
+	return result, nil
+}
+
+// DecideJoinRequest approves or denies a join request. Approved users join the group
+// like members added by the admin.
+func (s *Service) DecideJoinRequest(ctx context.Context, actorID, conversationID, requestID string, approve bool) (*model.JoinRequest, error) {
+	if _, err := uuid.Parse(requestID); err != nil {
+		return nil, ErrJoinRequestNotFound
+	}
+	conv, participants, err := s.group(ctx, actorID, conversationID, model.PermissionApproveMembers)
+	if err != nil {
+		return nil, err
+	}
+
+	jr, err := s.joinRequests.GetByID(ctx, requestID)
+	if errors.Is(err, repository.ErrJoinRequestNotFound) || (err == nil && jr.ConversationID != conv.ID) {
+		return nil, ErrJoinRequestNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status := model.JoinRequestDenied
+	if approve {
+		status = model.JoinRequestApproved
+	}
+	// Claims the request, so two admins can't decide it at once
+	if err := s.joinRequests.Decide(ctx, jr, status, actorID); err != nil {
+		if errors.Is(err, repository.ErrJoinRequestNotFound) {
+			return nil, ErrJoinRequestDecided
+		}
+		return nil, err
+	}
+
+	if approve && findParticipant(participants, jr.UserID) == nil {
+		if jr.InviteID != nil {
+			// Counted when used, but the admin's decision wins over the limits of the link
+			if err := s.invites.Use(ctx, *jr.InviteID); err != nil && !errors.Is(err, repository.ErrGroupInviteUsedUp) {
+				logger.Errorf("Error counting use of invite link %s: %v", *jr.InviteID, err)
+			}
+		}
+		if err := s.join(ctx, conv, jr.UserID, &model.SystemEvent{
+			Event:   model.SystemEventMembersAdded,
+			ActorID: actorID,
+			UserIDs: []string{jr.UserID},
+		}, "added @"+jr.Username); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Infof("Join request %s of %s to group %s %s by %s", jr.ID, jr.UserID, conv.ID, status, actorID)
+	s.notifyJoinRequest(ctx, participants, jr, EventJoinRequestDecided)
+	return jr, nil
+}
+
+// createJoinRequest records a join request and tells the admins about it
+func (s *Service) createJoinRequest(ctx context.Context, conv *model.Conversation, participants []model.Participant, userID string, inviteID *string, message string) (*model.JoinRequest, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	jr := &model.JoinRequest{
+		ConversationID: conv.ID,
+		UserID:         userID,
+		Username:       user.Username,
+		InviteID:       inviteID,
+	}
+	if message != "" {
+		jr.Message = &message
+	}
+	if err := s.joinRequests.Create(ctx, jr); err != nil {
+		if errors.Is(err, repository.ErrJoinRequestExists) {
+			return nil, ErrJoinRequestPending
+		}
+		return nil, err
+	}
+
+	logger.Infof("User %s asked to join group %s", userID, conv.ID)
+	s.notifyJoinRequest(ctx, participants, jr, EventJoinRequestCreated)
+	return jr, nil
+}
+
+// notifyJoinRequest sends a join request event to the members who can decide it
+// and, once decided, to the requester. Those offline get it when they reconnect.
+func (s *Service) notifyJoinRequest(ctx context.Context, participants []model.Participant, jr *model.JoinRequest, eventType string) {
+	var recipients []model.Participant
+	for _, p := range participants {
+		if p.UserID != jr.UserID && p.Can(model.ConversationTypeGroup, model.PermissionApproveMembers) {
+			recipients = append(recipients, p)
+		}
+	}
+	if eventType == EventJoinRequestDecided {
+		recipients = append(recipients, model.Participant{UserID: jr.UserID})
+	}
+
+	payload, err := json.Marshal(&JoinRequestEvent{
+		Type:           eventType,
+		ConversationID: jr.ConversationID,
+		Request:        jr.ToResponse(),
+	})
+	if err != nil {
+		logger.Errorf("Error marshaling %s event: %v", eventType, err)
+		return
+	}
+	s.deliver(ctx, recipients, "", payload)
+}
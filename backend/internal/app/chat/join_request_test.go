@@ -0,0 +1,88 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+)
+
+func TestRequestToJoinHidesConversations(t *testing.T) {
+	env := newTestEnv(t)
+	ownerID, outsiderID := uuid.New().String(), uuid.New().String()
+
+	direct := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeDirect}
+	env.convs.add(direct, ownerID, uuid.New().String())
+
+	tests := map[string]string{
+		"unknown":    uuid.New().String(),
+		"invalid id": "not-a-uuid",
+		"direct":     direct.ID,
+	}
+	for name, conversationID := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := env.service.RequestToJoin(context.Background(), outsiderID, conversationID, &model.JoinRequestCreate{})
+			if !errors.Is(err, ErrConversationNotFound) {
+				t.Errorf("err = %v, want %v", err, ErrConversationNotFound)
+			}
+		})
+	}
+}
+
+func TestJoinRequestToPrivateGroup(t *testing.T) {
+	tests := []struct {
+		name       string
+		approve    bool
+		wantStatus model.JoinRequestStatus
+	}{
+		{name: "approved", approve: true, wantStatus: model.JoinRequestApproved},
+		{name: "denied", wantStatus: model.JoinRequestDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			ctx := context.Background()
+			adminID, memberID, requesterID := uuid.New().String(), uuid.New().String(), uuid.New().String()
+			conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup, Visibility: model.VisibilityPrivate}
+			env.convs.add(conv, adminID, memberID)
+
+			jr, err := env.service.RequestToJoin(ctx, requesterID, conv.ID, &model.JoinRequestCreate{Message: "Hi!"})
+			if err != nil {
+				t.Fatalf("RequestToJoin: %v", err)
+			}
+			if _, err := env.service.RequestToJoin(ctx, requesterID, conv.ID, &model.JoinRequestCreate{}); !errors.Is(err, ErrJoinRequestPending) {
+				t.Errorf("second request: err = %v, want %v", err, ErrJoinRequestPending)
+			}
+
+			pending, err := env.service.ListJoinRequests(ctx, adminID, conv.ID)
+			if err != nil || len(pending) != 1 || pending[0].ID != jr.ID {
+				t.Fatalf("pending requests = %+v, %v", pending, err)
+			}
+
+			// Only members allowed to approve decide
+			if _, err := env.service.DecideJoinRequest(ctx, memberID, conv.ID, jr.ID, tt.approve); !errors.Is(err, ErrForbidden) {
+				t.Errorf("decided by a member: err = %v, want %v", err, ErrForbidden)
+			}
+
+			decided, err := env.service.DecideJoinRequest(ctx, adminID, conv.ID, jr.ID, tt.approve)
+			if err != nil {
+				t.Fatalf("DecideJoinRequest: %v", err)
+			}
+			if decided.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", decided.Status, tt.wantStatus)
+			}
+			if _, err := env.service.DecideJoinRequest(ctx, adminID, conv.ID, jr.ID, !tt.approve); !errors.Is(err, ErrJoinRequestDecided) {
+				t.Errorf("decided twice: err = %v, want %v", err, ErrJoinRequestDecided)
+			}
+
+			participants, _ := env.convs.GetParticipants(ctx, conv.ID)
+			if joined := findParticipant(participants, requesterID) != nil; joined != tt.approve {
+				t.Errorf("requester joined = %v, want %v", joined, tt.approve)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/redisclient"
+)
+
+const (
+	// memberCacheTTL bounds how long members are cached, in case an invalidation is missed
+	memberCacheTTL = time.Minute
+	// maxCachedConversations bounds the memory used by the member cache
+	maxCachedConversations = 10000
+)
+
+// cachedMembers is a conversation with its participants, as cached
+type cachedMembers struct {
+	conv         *model.Conversation
+	participants []model.Participant
+	expiresAt    time.Time
+}
+
+// memberCache keeps the participants of the conversations messages are sent to, so
+// sending doesn't query PostgreSQL each time. Entries are shared: don't modify them.
+type memberCache struct {
+	mu      sync.RWMutex
+	entries map[string]*cachedMembers
+}
+
+// newMemberCache creates an empty member cache
+func newMemberCache() *memberCache {
+	return &memberCache{entries: make(map[string]*cachedMembers)}
+}
+
+// get returns the cached members of a conversation, unless missing or expired
+func (c *memberCache) get(conversationID string) (*cachedMembers, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[conversationID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry, true
+}
+
+// set caches the members of a conversation for memberCacheTTL
+func (c *memberCache) set(conv *model.Conversation, participants []model.Participant) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Make room by dropping the expired entries, or everything if none expired
+	if len(c.entries) >= maxCachedConversations {
+		now := time.Now()
+		for id, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, id)
+			}
+		}
+		if len(c.entries) >= maxCachedConversations {
+			c.entries = make(map[string]*cachedMembers)
+		}
+	}
+	c.entries[conv.ID] = &cachedMembers{
+		conv:         conv,
+		participants: participants,
+		expiresAt:    time.Now().Add(memberCacheTTL),
+	}
+}
+
+// invalidate drops the cached members of a conversation
+func (c *memberCache) invalidate(conversationID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, conversationID)
+}
+
+// members returns a conversation with its participants, from the cache when possible.
+// Only what doesn't change (the type) or is invalidated (participants and roles) may
+// be relied on.
+func (s *Service) members(ctx context.Context, conversationID string) (*model.Conversation, []model.Participant, error) {
+	if entry, ok := s.memberCache.get(conversationID); ok {
+		return entry.conv, entry.participants, nil
+	}
+	if _, err := uuid.Parse(conversationID); err != nil {
+		return nil, nil, ErrConversationNotFound
+	}
+
+	conv, err := s.convRepo.GetByID(ctx, conversationID)
+	if err != nil {
+		if errors.Is(err, repository.ErrConversationNotFound) {
+			return nil, nil, ErrConversationNotFound
+		}
+		return nil, nil, err
+	}
+	participants, err := s.convRepo.GetParticipants(ctx, conversationID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.memberCache.set(conv, participants)
+	return conv, participants, nil
+}
+
+// MembersChanged is called after users joined or left a conversation, or a role changed:
+// it drops the cached participants on every instance and makes the connections of
+// those who joined or left follow the change
+func (s *Service) MembersChanged(ctx context.Context, conversationID string, joined, left []string) {
+	s.memberCache.invalidate(conversationID)
+	if err := s.redis.Publish(ctx, redisclient.MembersChangedChannel, conversationID); err != nil {
+		logger.Errorf("Error publishing member change of conversation %s: %v", conversationID, err)
+	}
+
+	s.setSubscribed(ctx, conversationID, joined, true)
+	s.setSubscribed(ctx, conversationID, left, false)
+}
@@ -0,0 +1,131 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+// ErrTooManyPins is returned when a conversation already has maxPinnedMessages pins
+var ErrTooManyPins = errors.New("too many pinned messages")
+
+// maxPinnedMessages limits the pinned messages of a conversation
+const maxPinnedMessages = 50
+
+// Live events of message changes (besides message_updated)
+const (
+	EventMessageDeleted  = "message_deleted"
+	EventMessagePinned   = "message_pinned"
+	EventMessageUnpinned = "message_unpinned"
+)
+
+// MessageEvent tells the participants that a message was deleted, pinned or unpinned
+type MessageEvent struct {
+	Type           string `json:"type"`
+	ConversationID string `json:"conversation_id"`
+	MessageID      string `json:"message_id"`
+	ActorID        string `json:"actor_id"`
+}
+
+// DeleteMessage deletes a message. Everyone can delete their own messages; deleting
+// other people's needs the delete_messages permission.
+func (s *Service) DeleteMessage(ctx context.Context, userID, conversationID, messageID string) error {
+	msg, conv, participants, err := s.loadMessage(ctx, userID, conversationID, messageID)
+	if err != nil {
+		return err
+	}
+	if msg.Type == model.MessageTypeSystem {
+		return ErrSystemMessage
+	}
+	if msg.SenderID != userID && !findParticipant(participants, userID).Can(conv.Type, model.PermissionDeleteMessages) {
+		return ErrForbidden
+	}
+
+	if err := s.msgRepo.Delete(ctx, msg.ID); err != nil {
+		if errors.Is(err, repository.ErrMessageNotFound) {
+			return ErrMessageNotFound
+		}
+		return err
+	}
+
+	logger.Infof("Message %s deleted by %s", msg.ID, userID)
+	s.sendMessageEvent(ctx, conv, participants, &MessageEvent{
+		Type:           EventMessageDeleted,
+		ConversationID: msg.ConversationID,
+		MessageID:      msg.ID,
+		ActorID:        userID,
+	})
+	return nil
+}
+
+// SetPinned pins or unpins a message of a conversation
+func (s *Service) SetPinned(ctx context.Context, userID, conversationID, messageID string, pinned bool) (*model.Message, error) {
+	msg, conv, participants, err := s.loadMessage(ctx, userID, conversationID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if !findParticipant(participants, userID).Can(conv.Type, model.PermissionPinMessages) {
+		return nil, ErrForbidden
+	}
+	if (msg.PinnedAt != nil) == pinned {
+		return msg, nil
+	}
+
+	var pinnedBy *string
+	eventType := EventMessageUnpinned
+	if pinned {
+		pins, err := s.msgRepo.GetPinned(ctx, conversationID)
+		if err != nil {
+			return nil, err
+		}
+		if len(pins) >= maxPinnedMessages {
+			return nil, ErrTooManyPins
+		}
+		pinnedBy = &userID
+		eventType = EventMessagePinned
+	}
+
+	if err := s.msgRepo.SetPinned(ctx, msg, pinnedBy); err != nil {
+		if errors.Is(err, repository.ErrMessageNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	logger.Infof("Message %s %s by %s", msg.ID, eventType[len("message_"):], userID)
+	s.sendMessageEvent(ctx, conv, participants, &MessageEvent{
+		Type:           eventType,
+		ConversationID: msg.ConversationID,
+		MessageID:      msg.ID,
+		ActorID:        userID,
+	})
+	return msg, nil
+}
+
+// PinnedMessages returns the pinned messages of a conversation the user takes part in
+func (s *Service) PinnedMessages(ctx context.Context, userID, conversationID string) ([]model.Message, error) {
+	_, participants, err := s.members(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if findParticipant(participants, userID) == nil {
+		return nil, ErrNotParticipant
+	}
+	return s.msgRepo.GetPinned(ctx, conversationID)
+}
+
+// sendMessageEvent delivers a message event to the participants (queued for those
+// offline) and to the other devices of the user who caused it
+func (s *Service) sendMessageEvent(ctx context.Context, conv *model.Conversation, participants []model.Participant, event *MessageEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logger.Errorf("Error marshaling %s event: %v", event.Type, err)
+		return
+	}
+	s.broadcast(ctx, conv, participants, event.ActorID, payload)
+	s.publishToUser(ctx, event.ActorID, event)
+}
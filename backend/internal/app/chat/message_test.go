@@ -0,0 +1,105 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+)
+
+func TestUpdateMessageNeedsPostPermission(t *testing.T) {
+	tests := []struct {
+		name     string
+		convType model.ConversationType
+		admin    bool // Whether the sender is an admin
+		wantErr  error
+	}{
+		{name: "member of a group", convType: model.ConversationTypeGroup},
+		{name: "admin of a channel", convType: model.ConversationTypeChannel, admin: true},
+		{name: "member of a channel", convType: model.ConversationTypeChannel, wantErr: ErrReadOnly},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			adminID, memberID := uuid.New().String(), uuid.New().String()
+			conv := &model.Conversation{ID: uuid.New().String(), Type: tt.convType}
+			env.convs.add(conv, adminID, memberID)
+
+			// E.g. a member who was demoted after posting
+			senderID := memberID
+			if tt.admin {
+				senderID = adminID
+			}
+			msg := &model.Message{ID: uuid.New().String(), ConversationID: conv.ID, SenderID: senderID, Content: "hi", Type: model.MessageTypeText}
+			env.msgs.add(msg)
+
+			content := "edited"
+			_, err := env.service.UpdateMessage(context.Background(), senderID, conv.ID, msg.ID, &model.MessageUpdate{Content: &content})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			stored, _ := env.msgs.GetByID(context.Background(), msg.ID)
+			if edited := stored.Content == content; edited != (tt.wantErr == nil) {
+				t.Errorf("content = %q after err %v", stored.Content, err)
+			}
+		})
+	}
+}
+
+func TestMessageNotSavedYet(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	aliceID, bobID := uuid.New().String(), uuid.New().String()
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup}
+	env.convs.add(conv, aliceID, bobID)
+	other := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup}
+	env.convs.add(other, aliceID)
+
+	sent, err := env.service.PostMessage(ctx, aliceID, &WebSocketMessage{ConversationID: conv.ID, Content: "hi"})
+	if err != nil {
+		t.Fatalf("PostMessage: %v", err)
+	}
+	content := "hello"
+	edit := func(conversationID string) error {
+		_, err := env.service.UpdateMessage(ctx, aliceID, conversationID, sent.ID, &model.MessageUpdate{Content: &content})
+		return err
+	}
+
+	// Acknowledged, but the message worker hasn't saved it yet
+	if err := edit(conv.ID); !errors.Is(err, ErrMessageNotSaved) {
+		t.Errorf("before it is saved: err = %v, want %v", err, ErrMessageNotSaved)
+	}
+	if err := edit(other.ID); !errors.Is(err, ErrMessageNotFound) {
+		t.Errorf("through another conversation: err = %v, want %v", err, ErrMessageNotFound)
+	}
+
+	env.msgs.add(&model.Message{ID: sent.ID, ConversationID: conv.ID, SenderID: aliceID, Content: sent.Content, Type: model.MessageTypeText})
+	if err := edit(conv.ID); err != nil {
+		t.Errorf("once saved: %v", err)
+	}
+}
+
+func TestMessageNeverSaved(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	aliceID := uuid.New().String()
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup}
+	env.convs.add(conv, aliceID)
+
+	sent, err := env.service.PostMessage(ctx, aliceID, &WebSocketMessage{ConversationID: conv.ID, Content: "hi"})
+	if err != nil {
+		t.Fatalf("PostMessage: %v", err)
+	}
+
+	// Long after being sent, a missing message is gone rather than late
+	env.redis.FastForward(unsavedMessageTTL)
+	err = env.service.DeleteMessage(ctx, aliceID, conv.ID, sent.ID)
+	if !errors.Is(err, ErrMessageNotFound) {
+		t.Errorf("err = %v, want %v", err, ErrMessageNotFound)
+	}
+}
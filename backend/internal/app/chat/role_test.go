@@ -0,0 +1,104 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+)
+
+func TestSetRole(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	adminID, memberID, otherID := uuid.New().String(), uuid.New().String(), uuid.New().String()
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup}
+	env.convs.add(conv, adminID, memberID, otherID)
+
+	if _, err := env.service.SetRole(ctx, memberID, conv.ID, otherID, model.ParticipantRoleAdmin); !errors.Is(err, ErrForbidden) {
+		t.Errorf("promoted by a member: err = %v, want %v", err, ErrForbidden)
+	}
+	if _, err := env.service.SetRole(ctx, adminID, conv.ID, uuid.New().String(), model.ParticipantRoleAdmin); !errors.Is(err, ErrNotMember) {
+		t.Errorf("promoting a stranger: err = %v, want %v", err, ErrNotMember)
+	}
+
+	// The last admin hands over the role before stepping down or leaving
+	if _, err := env.service.SetRole(ctx, adminID, conv.ID, adminID, model.ParticipantRoleMember); !errors.Is(err, ErrLastAdmin) {
+		t.Errorf("last admin stepping down: err = %v, want %v", err, ErrLastAdmin)
+	}
+	if err := env.service.LeaveGroup(ctx, adminID, conv.ID); !errors.Is(err, ErrLastAdmin) {
+		t.Errorf("last admin leaving: err = %v, want %v", err, ErrLastAdmin)
+	}
+
+	promoted, err := env.service.SetRole(ctx, adminID, conv.ID, memberID, model.ParticipantRoleAdmin)
+	if err != nil {
+		t.Fatalf("SetRole: %v", err)
+	}
+	if !promoted.IsAdmin() {
+		t.Errorf("role = %q, want admin", promoted.EffectiveRole())
+	}
+
+	// The new admin may remove people, and the first one may now leave
+	if err := env.service.RemoveMember(ctx, memberID, conv.ID, otherID); err != nil {
+		t.Errorf("removed by the new admin: %v", err)
+	}
+	if err := env.service.LeaveGroup(ctx, adminID, conv.ID); err != nil {
+		t.Errorf("leaving after handing over: %v", err)
+	}
+
+	var roleEvents []model.SystemEvent
+	for _, event := range systemEvents(t, env) {
+		if event.Event == model.SystemEventRoleChanged {
+			roleEvents = append(roleEvents, event)
+		}
+	}
+	if len(roleEvents) != 1 || roleEvents[0].Role != model.ParticipantRoleAdmin || roleEvents[0].UserIDs[0] != memberID {
+		t.Errorf("role events = %+v, want the member made admin", roleEvents)
+	}
+}
+
+func TestDeleteMessagesOfOthers(t *testing.T) {
+	tests := []struct {
+		name     string
+		convType model.ConversationType
+		admin    bool // Whether the admin deletes, rather than a member
+		wantErr  error
+	}{
+		{name: "admin of a group", convType: model.ConversationTypeGroup, admin: true},
+		{name: "member of a group", convType: model.ConversationTypeGroup, wantErr: ErrForbidden},
+		{name: "direct conversation", convType: model.ConversationTypeDirect, wantErr: ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			ctx := context.Background()
+			adminID, memberID, senderID := uuid.New().String(), uuid.New().String(), uuid.New().String()
+			conv := &model.Conversation{ID: uuid.New().String(), Type: tt.convType}
+			env.convs.add(conv, adminID, memberID, senderID)
+
+			msg := &model.Message{ID: uuid.New().String(), ConversationID: conv.ID, SenderID: senderID, Content: "hi", Type: model.MessageTypeText}
+			env.msgs.add(msg)
+
+			actorID := memberID
+			if tt.admin {
+				actorID = adminID
+			}
+			if err := env.service.DeleteMessage(ctx, actorID, conv.ID, msg.ID); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			// Everyone can delete their own
+			if tt.wantErr != nil {
+				if err := env.service.DeleteMessage(ctx, senderID, conv.ID, msg.ID); err != nil {
+					t.Errorf("deleted by the sender: %v", err)
+				}
+			}
+			if _, err := env.msgs.GetByID(ctx, msg.ID); err == nil {
+				t.Error("message not deleted")
+			}
+		})
+	}
+}
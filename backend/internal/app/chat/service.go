@@ -3,112 +3,298 @@ package chat
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gofiber/contrib/websocket"
 	"github.com/google/uuid"
 
+	"github.com/Beretta350/gochat/internal/app/command"
+	"github.com/Beretta350/gochat/internal/app/model"
 	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/internal/config"
 	"github.com/Beretta350/gochat/pkg/logger"
 	"github.com/Beretta350/gochat/pkg/redisclient"
 )
 
-// ConnectedUsers stores WebSocket connections by user ID
+var (
+	ErrConversationNotFound = errors.New("conversation not found")
+	ErrNotParticipant       = errors.New("not a participant of the conversation")
+	ErrMessageNotFound      = errors.New("message not found")
+	ErrMessageNotSaved      = errors.New("message not saved yet")
+	ErrNotSender            = errors.New("not the sender of the message")
+	ErrInvalidType          = errors.New("unknown message type")
+	ErrInvalidCard          = errors.New("invalid card")
+	ErrCardsBotsOnly        = errors.New("only bots can send cards")
+	ErrUnexpectedMetadata   = errors.New("metadata is only allowed on card messages")
+	ErrInvalidAction        = errors.New("invalid card action")
+	ErrSystemMessage        = errors.New("system messages can't be changed")
+	ErrReadOnly             = errors.New("only admins can post in a channel")
+)
+
+// interactionFrame is the type of the WebSocket frame sent when a user clicks a card action
+const interactionFrame = "interaction"
+
+// maxRunningCommands limits the slash commands running at once for a connection
+// (they run in the background, since a bot can take a few seconds to answer)
+const maxRunningCommands = 4
+
+// unsavedMessageTTL is how long after being sent a message missing from the database is
+// taken for one the message worker hasn't saved yet, rather than for a deleted one
+const unsavedMessageTTL = time.Minute
+
+const (
+	// presenceTTL is how long a connection counts as online without a heartbeat, so the
+	// connections of a crashed instance don't keep their users online
+	presenceTTL = 90 * time.Second
+	// presenceHeartbeat is how often a connection refreshes its presence
+	presenceHeartbeat = 30 * time.Second
+)
+
+// Client identifies who opened a WebSocket connection
+type Client struct {
+	UserID     string
+	SessionID  string   // Login session (empty for API tokens)
+	APITokenID string   // API token of a bot or script (empty for sessions)
+	Scopes     []string // Of the API token: messages:write to send, and those of the commands it runs
+}
+
+// caller returns who runs the commands sent by the client
+func (c Client) caller() command.Caller {
+	return command.Caller{UserID: c.UserID, APIToken: c.APITokenID != "", Scopes: c.Scopes}
+}
+
+// credentialID returns the session or API token the connection was authenticated with
+func (c Client) credentialID() string {
+	if c.APITokenID != "" {
+		return c.APITokenID
+	}
+	return c.SessionID
+}
+
+// ConnectedUsers stores WebSocket connections by user ID.
+// A user can be connected from several devices; each connection remembers
+// the session (or API token) it was authenticated with so revoked ones can be dropped.
 type ConnectedUsers struct {
-	connections sync.Map
+	mu    sync.RWMutex
+	conns map[string]map[*websocket.Conn]string // userID -> conn -> session or API token ID
 }
 
 // NewConnectedUsers creates a new ConnectedUsers instance
 func NewConnectedUsers() *ConnectedUsers {
-	return &ConnectedUsers{}
+	return &ConnectedUsers{
+		conns: make(map[string]map[*websocket.Conn]string),
+	}
 }
 
 // Add adds a connection
-func (c *ConnectedUsers) Add(userID string, conn *websocket.Conn) {
-	c.connections.Store(userID, conn)
-	logger.Infof("User %s connected", userID)
-}
+func (c *ConnectedUsers) Add(userID, sessionID string, conn *websocket.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-// Remove removes a connection
-func (c *ConnectedUsers) Remove(userID string) {
-	c.connections.Delete(userID)
-	logger.Infof("User %s disconnected", userID)
+	if c.conns[userID] == nil {
+		c.conns[userID] = make(map[*websocket.Conn]string)
+	}
+	c.conns[userID][conn] = sessionID
+	logger.Infof("User %s connected (%d connections)", userID, len(c.conns[userID]))
 }
 
-// Get gets a connection
-func (c *ConnectedUsers) Get(userID string) *websocket.Conn {
-	if conn, ok := c.connections.Load(userID); ok {
-		return conn.(*websocket.Conn)
+// Remove removes a connection and returns how many connections the user still has
+func (c *ConnectedUsers) Remove(userID string, conn *websocket.Conn) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.conns[userID], conn)
+	remaining := len(c.conns[userID])
+	if remaining == 0 {
+		delete(c.conns, userID)
 	}
-	return nil
+	logger.Infof("User %s disconnected (%d connections left)", userID, remaining)
+	return remaining
 }
 
-// IsOnline checks if a user is online
-func (c *ConnectedUsers) IsOnline(userID string) bool {
-	_, ok := c.connections.Load(userID)
-	return ok
+// DisconnectSession closes every connection opened with the given session.
+// The read loop of each connection then exits and cleans up as usual.
+func (c *ConnectedUsers) DisconnectSession(sessionID string) int {
+	c.mu.RLock()
+	var toClose []*websocket.Conn
+	for _, conns := range c.conns {
+		for conn, sid := range conns {
+			if sid == sessionID {
+				toClose = append(toClose, conn)
+			}
+		}
+	}
+	c.mu.RUnlock()
+
+	for _, conn := range toClose {
+		closeMsg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "session revoked")
+		_ = conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		_ = conn.Close()
+	}
+	return len(toClose)
 }
 
 // WebSocketMessage represents a message received via WebSocket
 type WebSocketMessage struct {
-	ConversationID string `json:"conversation_id"`
-	Content        string `json:"content"`
-	Type           string `json:"type,omitempty"`
+	ConversationID string          `json:"conversation_id"`
+	Content        string          `json:"content"`
+	Type           string          `json:"type,omitempty"`
+	Metadata       json.RawMessage `json:"metadata,omitempty"` // Card of a card message
+
+	// Interaction frames ("type": "interaction") with a card message
+	MessageID string `json:"message_id,omitempty"`
+	ActionID  string `json:"action_id,omitempty"`
+	Value     string `json:"value,omitempty"` // Option picked in a select menu
 }
 
 // OutgoingMessage represents a message sent to WebSocket clients
 type OutgoingMessage struct {
-	ID             string `json:"id"`
+	ID             string          `json:"id"`
+	ConversationID string          `json:"conversation_id"`
+	SenderID       string          `json:"sender_id"`
+	SenderUsername string          `json:"sender_username,omitempty"`
+	Content        string          `json:"content"`
+	Type           string          `json:"type"`
+	Metadata       json.RawMessage `json:"metadata,omitempty"`
+	SentAt         int64           `json:"sent_at"`
+	EditedAt       *int64          `json:"edited_at,omitempty"`
+}
+
+// toOutgoing converts a stored message to its WebSocket form
+func toOutgoing(msg *model.Message) *OutgoingMessage {
+	out := &OutgoingMessage{
+		ID:             msg.ID,
+		ConversationID: msg.ConversationID,
+		SenderID:       msg.SenderID,
+		SenderUsername: msg.SenderUsername,
+		Content:        msg.Content,
+		Type:           string(msg.Type),
+		Metadata:       msg.Metadata,
+		SentAt:         msg.SentAt.UnixMilli(),
+	}
+	if msg.EditedAt != nil {
+		editedAt := msg.EditedAt.UnixMilli()
+		out.EditedAt = &editedAt
+	}
+	return out
+}
+
+// MessageUpdatedEvent is sent to the participants when a message is edited
+type MessageUpdatedEvent struct {
+	Type    string           `json:"type"` // "message_updated"
+	Message *OutgoingMessage `json:"message"`
+}
+
+// CommandResponse is the reply to a slash command, sent only to the user who ran it
+type CommandResponse struct {
+	Type           string `json:"type"` // "command_response"
 	ConversationID string `json:"conversation_id"`
-	SenderID       string `json:"sender_id"`
-	SenderUsername string `json:"sender_username,omitempty"`
-	Content        string `json:"content"`
-	Type           string `json:"type"`
-	SentAt         int64  `json:"sent_at"`
+	Command        string `json:"command"`
+	Text           string `json:"text"`
 }
 
 // PresenceEvent represents an online/offline status change
 type PresenceEvent struct {
-	Type     string `json:"type"`     // "presence"
+	Type     string `json:"type"` // "presence"
 	UserID   string `json:"user_id"`
 	Username string `json:"username,omitempty"`
-	Status   string `json:"status"`   // "online" or "offline"
+	Status   string `json:"status"` // "online" or "offline"
 }
 
 // Service handles chat operations
 type Service struct {
-	redis    *redisclient.Client
-	convRepo repository.ConversationRepository
-	userRepo repository.UserRepository
-	users    *ConnectedUsers
+	config       *config.Config
+	redis        *redisclient.Client
+	convRepo     repository.ConversationRepository
+	userRepo     repository.UserRepository
+	msgRepo      repository.MessageRepository
+	invites      repository.GroupInviteRepository
+	joinRequests repository.JoinRequestRepository
+	commands     *command.Service
+	users        *ConnectedUsers
+	memberCache  *memberCache
 }
 
 // NewService creates a new chat service (Fx provider)
-func NewService(redis *redisclient.Client, convRepo repository.ConversationRepository, userRepo repository.UserRepository) *Service {
+func NewService(
+	cfg *config.Config,
+	redis *redisclient.Client,
+	convRepo repository.ConversationRepository,
+	userRepo repository.UserRepository,
+	msgRepo repository.MessageRepository,
+	invites repository.GroupInviteRepository,
+	joinRequests repository.JoinRequestRepository,
+	commands *command.Service,
+) *Service {
 	logger.Info("Chat service initialized")
 	return &Service{
-		redis:    redis,
-		convRepo: convRepo,
-		userRepo: userRepo,
-		users:    NewConnectedUsers(),
+		config:       cfg,
+		redis:        redis,
+		convRepo:     convRepo,
+		userRepo:     userRepo,
+		msgRepo:      msgRepo,
+		invites:      invites,
+		joinRequests: joinRequests,
+		commands:     commands,
+		users:        NewConnectedUsers(),
+		memberCache:  newMemberCache(),
+	}
+}
+
+// Start listens for revoked sessions, to close their local WebSocket connections, and
+// for conversations whose members changed, to drop the members cached here
+func (s *Service) Start(ctx context.Context) {
+	pubsub := s.redis.Subscribe(ctx, redisclient.SessionRevokedChannel, redisclient.MembersChangedChannel)
+	defer func() {
+		_ = pubsub.Close()
+	}()
+
+	logger.Info("Chat service listening for session revocations and member changes")
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			if msg.Channel == redisclient.MembersChangedChannel {
+				s.memberCache.invalidate(msg.Payload)
+				continue
+			}
+			if n := s.users.DisconnectSession(msg.Payload); n > 0 {
+				logger.Infof("Closed %d WebSocket connection(s) of revoked session %s", n, msg.Payload)
+			}
+		}
 	}
 }
 
 // HandleConnection handles a WebSocket connection
-func (s *Service) HandleConnection(ctx context.Context, conn *websocket.Conn, userID string) {
-	s.users.Add(userID, conn)
+func (s *Service) HandleConnection(ctx context.Context, conn *websocket.Conn, client Client) {
+	userID, sessionID := client.UserID, client.SessionID
+	s.users.Add(userID, client.credentialID(), conn)
+	s.trackSession(ctx, sessionID, true)
 
 	userCtx, cancel := context.WithCancel(ctx)
 
-	// Mark user as online in Redis and broadcast presence
-	s.handleUserOnline(userCtx, userID, conn)
+	// Mark the connection as online in Redis and broadcast presence
+	connID := uuid.New().String()
+	s.handleUserOnline(userCtx, userID, connID, conn)
+	go s.keepPresence(userCtx, userID, connID)
 
 	defer func() {
 		cancel()
-		s.users.Remove(userID)
-		// Mark user as offline in Redis and broadcast presence
-		s.handleUserOffline(context.Background(), userID)
+		s.trackSession(context.Background(), sessionID, false)
+		// Broadcast presence once the last device is gone, on every instance
+		remaining := s.users.Remove(userID, conn)
+		if !s.connectionClosed(context.Background(), userID, connID, remaining) {
+			s.handleUserOffline(context.Background(), userID)
+		}
 	}()
 
 	// Deliver pending messages first
@@ -118,7 +304,24 @@ func (s *Service) HandleConnection(ctx context.Context, conn *websocket.Conn, us
 	go s.listenForMessages(userCtx, conn, userID)
 
 	// Read messages from WebSocket
-	s.readAndPublishMessages(userCtx, conn, userID)
+	s.readAndPublishMessages(userCtx, conn, client)
+}
+
+// trackSession records open WebSocket connections per session (for the device list)
+func (s *Service) trackSession(ctx context.Context, sessionID string, connected bool) {
+	if sessionID == "" {
+		return
+	}
+
+	var err error
+	if connected {
+		err = s.redis.SessionConnected(ctx, sessionID)
+	} else {
+		err = s.redis.SessionDisconnected(ctx, sessionID)
+	}
+	if err != nil {
+		logger.Errorf("Error tracking WebSocket connection for session %s: %v", sessionID, err)
+	}
 }
 
 func (s *Service) deliverPendingMessages(ctx context.Context, conn *websocket.Conn, userID string) {
@@ -142,15 +345,38 @@ func (s *Service) deliverPendingMessages(ctx context.Context, conn *websocket.Co
 	}
 }
 
-func (s *Service) listenForMessages(ctx context.Context, conn *websocket.Conn, userID string) {
+// messageWriter is where listenForMessages forwards messages: a WebSocket connection
+type messageWriter interface {
+	WriteMessage(messageType int, data []byte) error
+}
+
+// listenForMessages forwards to the WebSocket what is published to the user and to
+// the conversations they take part in, following their memberships as they change
+func (s *Service) listenForMessages(ctx context.Context, conn messageWriter, userID string) {
 	channel := "user:" + userID
-	pubsub := s.redis.Subscribe(ctx, channel)
+	pubsub := s.redis.Subscribe(ctx, channel, subscriptionsChannel(userID))
 	defer func() {
 		_ = pubsub.Close()
 	}()
 
 	logger.Infof("User %s subscribed to channel %s", userID, channel)
 
+	// Memberships are read once subscribed to the changes, so a change made meanwhile
+	// is still applied (the changes are handled after these subscriptions)
+	conversations, err := s.convRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		logger.Errorf("Error getting conversations for user %s: %v", userID, err)
+	}
+	if len(conversations) > 0 {
+		channels := make([]string, 0, len(conversations))
+		for _, conv := range conversations {
+			channels = append(channels, conversationChannel(conv.ID))
+		}
+		if err := pubsub.Subscribe(ctx, channels...); err != nil {
+			logger.Errorf("Error subscribing %s to their conversations: %v", userID, err)
+		}
+	}
+
 	ch := pubsub.Channel()
 	for {
 		select {
@@ -162,8 +388,25 @@ func (s *Service) listenForMessages(ctx context.Context, conn *websocket.Conn, u
 				return
 			}
 
+			payload := msg.Payload
+			switch {
+			case msg.Channel == subscriptionsChannel(userID):
+				s.updateSubscription(ctx, pubsub, userID, msg.Payload)
+				continue
+			case strings.HasPrefix(msg.Channel, conversationChannelPrefix):
+				var envelope conversationEnvelope
+				if err := json.Unmarshal([]byte(msg.Payload), &envelope); err != nil {
+					logger.Errorf("Error parsing message of %s: %v", msg.Channel, err)
+					continue
+				}
+				if envelope.SkipID == userID {
+					continue
+				}
+				payload = string(envelope.Payload)
+			}
+
 			// Forward message to WebSocket
-			if err := conn.WriteMessage(websocket.TextMessage, []byte(msg.Payload)); err != nil {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(payload)); err != nil {
 				logger.Errorf("Error writing to WebSocket for %s: %v", userID, err)
 				return
 			}
@@ -171,7 +414,13 @@ func (s *Service) listenForMessages(ctx context.Context, conn *websocket.Conn, u
 	}
 }
 
-func (s *Service) readAndPublishMessages(ctx context.Context, conn *websocket.Conn, userID string) {
+func (s *Service) readAndPublishMessages(ctx context.Context, conn *websocket.Conn, client Client) {
+	userID := client.UserID
+	canSend := client.caller().HasScope(model.ScopeMessagesWrite)
+	// Once verified, stop checking; until then re-check so verifying doesn't need a reconnect
+	verified := !s.config.Auth.VerifiedEmailForMessaging()
+	commandSlots := make(chan struct{}, maxRunningCommands)
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -199,48 +448,209 @@ func (s *Service) readAndPublishMessages(ctx context.Context, conn *websocket.Co
 				continue
 			}
 
+			if !canSend {
+				s.sendError(conn, "This token can't send messages (missing messages:write scope)")
+				continue
+			}
+
+			if !verified {
+				verified = s.isEmailVerified(ctx, userID)
+				if !verified {
+					s.sendError(conn, "Verify your email address to send messages")
+					continue
+				}
+			}
+
+			if wsMsg.Type == interactionFrame {
+				if err := s.Interact(ctx, userID, &wsMsg); err != nil {
+					s.sendError(conn, errorMessage(err))
+				}
+				continue
+			}
+
 			if wsMsg.Content == "" {
 				s.sendError(conn, "content is required")
 				continue
 			}
 
+			if isCommand(&wsMsg) {
+				select {
+				case commandSlots <- struct{}{}:
+					go func(msg WebSocketMessage) {
+						defer func() { <-commandSlots }()
+						if resp := s.runCommand(ctx, client, &msg); resp.Text != "" {
+							s.publishToUser(ctx, userID, resp)
+						}
+					}(wsMsg)
+				default:
+					s.sendError(conn, "Too many commands running, wait for their answers")
+				}
+				continue
+			}
+
 			s.processMessage(ctx, conn, userID, &wsMsg)
 		}
 	}
 }
 
-func (s *Service) processMessage(ctx context.Context, conn *websocket.Conn, senderID string, wsMsg *WebSocketMessage) {
-	// Get conversation participants
-	participants, err := s.convRepo.GetParticipants(ctx, wsMsg.ConversationID)
-	if err != nil {
-		logger.Errorf("Error getting participants for conversation %s: %v", wsMsg.ConversationID, err)
-		s.sendError(conn, "Conversation not found")
-		return
+// Send handles a message sent through the REST API the way the WebSocket does: a slash
+// command is run and its reply returned, anything else is posted
+func (s *Service) Send(ctx context.Context, client Client, wsMsg *WebSocketMessage) (*OutgoingMessage, *CommandResponse, error) {
+	if isCommand(wsMsg) {
+		return nil, s.runCommand(ctx, client, wsMsg), nil
 	}
+	outMsg, err := s.PostMessage(ctx, client.UserID, wsMsg)
+	return outMsg, nil, err
+}
 
-	// Verify sender is participant
-	isParticipant := false
-	var senderUsername string
-	for _, p := range participants {
-		if p.UserID == senderID {
-			isParticipant = true
-			if p.User != nil {
-				senderUsername = p.User.Username
-			}
-			break
+// isCommand tells a slash command from a message. A double slash sends a message
+// starting with "/" instead: the escaping slash is removed.
+func isCommand(wsMsg *WebSocketMessage) bool {
+	if strings.HasPrefix(wsMsg.Content, "//") {
+		wsMsg.Content = wsMsg.Content[1:]
+		return false
+	}
+	return command.IsCommand(wsMsg.Content)
+}
+
+// runCommand runs a slash command with the permissions and scopes of the client, and
+// returns the reply for the user who typed it (no text when there is none)
+func (s *Service) runCommand(ctx context.Context, client Client, wsMsg *WebSocketMessage) *CommandResponse {
+	userID := client.UserID
+	post := func(ctx context.Context, senderID, content string) error {
+		outMsg, err := s.PostMessage(ctx, senderID, &WebSocketMessage{
+			ConversationID: wsMsg.ConversationID,
+			Content:        content,
+		})
+		if err != nil {
+			return err
 		}
+		// Messages aren't sent back to their sender, but this one wasn't written by the user's client
+		if senderID == userID {
+			s.publishToUser(ctx, userID, outMsg)
+		}
+		return nil
+	}
+
+	reply, err := s.commands.Run(ctx, client.caller(), wsMsg.ConversationID, wsMsg.Content, post, s)
+	switch {
+	case errors.Is(err, command.ErrNotParticipant):
+		reply = "You are not a participant of this conversation"
+	case errors.Is(err, command.ErrConversationNotFound):
+		reply = "Conversation not found"
+	case errors.Is(err, ErrForbidden):
+		reply = "You don't have permission to do this in this group"
+	case errors.Is(err, ErrLastAdmin):
+		reply = "Make another member an admin before leaving"
+	case err != nil:
+		logger.Errorf("Error running command in conversation %s for %s: %v", wsMsg.ConversationID, userID, err)
+		reply = "The command failed, please try again"
+	}
+
+	return &CommandResponse{
+		Type:           "command_response",
+		ConversationID: wsMsg.ConversationID,
+		Command:        strings.Fields(wsMsg.Content)[0],
+		Text:           reply,
 	}
+}
 
-	if !isParticipant {
-		s.sendError(conn, "You are not a participant of this conversation")
+// publishToUser sends an event to the live connections of a user (it isn't queued when they are offline)
+func (s *Service) publishToUser(ctx context.Context, userID string, event interface{}) {
+	msgBytes, err := json.Marshal(event)
+	if err != nil {
+		logger.Errorf("Error marshaling event for %s: %v", userID, err)
 		return
 	}
+	if err := s.redis.Publish(ctx, "user:"+userID, msgBytes); err != nil {
+		logger.Errorf("Error publishing to %s: %v", userID, err)
+	}
+}
+
+func (s *Service) processMessage(ctx context.Context, conn *websocket.Conn, senderID string, wsMsg *WebSocketMessage) {
+	if _, err := s.PostMessage(ctx, senderID, wsMsg); err != nil {
+		s.sendError(conn, errorMessage(err))
+	}
+}
+
+// errorMessage returns the error sent to a WebSocket client for a failed message or interaction
+func errorMessage(err error) string {
+	switch {
+	case errors.Is(err, ErrNotParticipant):
+		return "You are not a participant of this conversation"
+	case errors.Is(err, ErrConversationNotFound):
+		return "Conversation not found"
+	case errors.Is(err, ErrMessageNotFound):
+		return "Message not found"
+	case errors.Is(err, ErrMessageNotSaved):
+		return "This message is still being saved, please try again in a moment"
+	case errors.Is(err, ErrInvalidAction):
+		return "This action is not available"
+	case errors.Is(err, ErrCardsBotsOnly):
+		return "Only bots can send cards"
+	case errors.Is(err, ErrInvalidType):
+		return "Unknown message type"
+	case errors.Is(err, ErrSystemMessage):
+		return "System messages can't be changed"
+	case errors.Is(err, ErrReadOnly):
+		return "Only admins can post in this channel"
+	case errors.Is(err, ErrInvalidCard), errors.Is(err, ErrUnexpectedMetadata):
+		return err.Error()
+	}
+	logger.Errorf("Chat error: %v", err)
+	return "Something went wrong, please try again"
+}
+
+// PostMessage sends a message on behalf of a participant: it is added to the stream
+// for persistence (and webhooks) and delivered to the other participants, live or
+// through their pending queue. The WebSocket and the REST API both go through here.
+func (s *Service) PostMessage(ctx context.Context, senderID string, wsMsg *WebSocketMessage) (*OutgoingMessage, error) {
+	return s.post(ctx, senderID, wsMsg, model.PermissionPostMessages)
+}
+
+// PostIncomingMessage sends the message of an incoming webhook as its bot. Whoever set
+// up the webhook was allowed to post, so the bot may post even where only admins do
+// (channels), without being an admin.
+func (s *Service) PostIncomingMessage(ctx context.Context, botID string, wsMsg *WebSocketMessage) (*OutgoingMessage, error) {
+	return s.post(ctx, botID, wsMsg, "")
+}
+
+// post sends a message, checking that the sender is allowed to post it (no permission
+// to check when empty)
+func (s *Service) post(ctx context.Context, senderID string, wsMsg *WebSocketMessage, permission model.Permission) (*OutgoingMessage, error) {
+	// Get the conversation and its participants (usually cached)
+	conv, participants, err := s.members(ctx, wsMsg.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify sender is participant, and may post (only admins in a channel)
+	sender := findParticipant(participants, senderID)
+	if sender == nil {
+		return nil, ErrNotParticipant
+	}
+	if permission != "" && !sender.Can(conv.Type, permission) {
+		return nil, ErrReadOnly
+	}
+	var senderUsername string
+	isBot := false
+	if sender.User != nil {
+		senderUsername = sender.User.Username
+		isBot = sender.User.IsBot
+	}
 
 	// Create outgoing message
 	msgType := wsMsg.Type
 	if msgType == "" {
 		msgType = "text"
 	}
+	if msgType == string(model.MessageTypeCard) && !isBot {
+		return nil, ErrCardsBotsOnly
+	}
+	metadata, err := validateMetadata(msgType, wsMsg.Metadata)
+	if err != nil {
+		return nil, err
+	}
 
 	outMsg := &OutgoingMessage{
 		ID:             uuid.New().String(),
@@ -249,13 +659,26 @@ func (s *Service) processMessage(ctx context.Context, conn *websocket.Conn, send
 		SenderUsername: senderUsername,
 		Content:        wsMsg.Content,
 		Type:           msgType,
+		Metadata:       metadata,
 		SentAt:         time.Now().UnixMilli(),
 	}
 
+	// Send to all participants but the sender
+	if err := s.send(ctx, conv, participants, senderID, outMsg); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Message in conversation %s from %s", wsMsg.ConversationID, senderID)
+	return outMsg, nil
+}
+
+// send adds a message to the stream for persistence and delivers it to the
+// participants but skipID
+func (s *Service) send(ctx context.Context, conv *model.Conversation, participants []model.Participant, skipID string, outMsg *OutgoingMessage) error {
 	msgJSON, err := json.Marshal(outMsg)
 	if err != nil {
 		logger.Errorf("Error marshaling message: %v", err)
-		return
+		return err
 	}
 
 	// Add to Redis Stream for persistence
@@ -269,32 +692,224 @@ func (s *Service) processMessage(ctx context.Context, conn *websocket.Conn, send
 	streamData["content"] = outMsg.Content
 	streamData["type"] = outMsg.Type
 	streamData["sent_at"] = outMsg.SentAt
+	if len(outMsg.Metadata) > 0 {
+		streamData["metadata"] = string(outMsg.Metadata)
+	}
 
 	if _, err := s.redis.AddToStream(ctx, streamData); err != nil {
 		logger.Errorf("Error adding to stream: %v", err)
+	} else if err := s.redis.MarkMessageUnsaved(ctx, outMsg.ID, outMsg.ConversationID, unsavedMessageTTL); err != nil {
+		logger.Errorf("Error marking message %s as unsaved: %v", outMsg.ID, err)
 	}
 
-	// Send to all participants
+	s.broadcast(ctx, conv, participants, skipID, msgJSON)
+	return nil
+}
+
+// deliver sends an event to some participants except skipID: published to those
+// online, added to the pending queue of the others. What concerns all the current
+// participants goes through broadcast instead.
+func (s *Service) deliver(ctx context.Context, participants []model.Participant, skipID string, payload []byte) {
+	userIDs := make([]string, 0, len(participants))
 	for _, p := range participants {
-		if p.UserID == senderID {
-			continue // Don't send back to sender
+		if p.UserID != skipID {
+			userIDs = append(userIDs, p.UserID)
 		}
+	}
+	isOnline := s.onlineUsers(ctx, userIDs)
 
-		if s.users.IsOnline(p.UserID) {
+	for _, userID := range userIDs {
+		if isOnline[userID] {
 			// Online: publish to Pub/Sub
-			recipientChannel := "user:" + p.UserID
-			if err := s.redis.Publish(ctx, recipientChannel, msgJSON); err != nil {
-				logger.Errorf("Error publishing to %s: %v", p.UserID, err)
+			if err := s.redis.Publish(ctx, "user:"+userID, payload); err != nil {
+				logger.Errorf("Error publishing to %s: %v", userID, err)
 			}
 		} else {
 			// Offline: add to pending queue
-			if err := s.redis.AddToPending(ctx, p.UserID, string(msgJSON)); err != nil {
-				logger.Errorf("Error adding to pending for %s: %v", p.UserID, err)
+			if err := s.redis.AddToPending(ctx, userID, string(payload)); err != nil {
+				logger.Errorf("Error adding to pending for %s: %v", userID, err)
 			}
 		}
 	}
+}
 
-	logger.Infof("Message in conversation %s from %s", wsMsg.ConversationID, senderID)
+// findParticipant returns the participant with the given user ID, or nil
+func findParticipant(participants []model.Participant, userID string) *model.Participant {
+	for i := range participants {
+		if participants[i].UserID == userID {
+			return &participants[i]
+		}
+	}
+	return nil
+}
+
+// validateMetadata checks the metadata of a message of the given type and returns
+// it as it is stored: cards are normalized, other types have none
+func validateMetadata(msgType string, metadata json.RawMessage) (json.RawMessage, error) {
+	hasMetadata := len(metadata) > 0 && string(metadata) != "null"
+
+	switch model.MessageType(msgType) {
+	case model.MessageTypeCard:
+		card, err := parseCard(metadata)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(card)
+	case model.MessageTypeText, model.MessageTypeImage, model.MessageTypeFile, model.MessageTypeAudio:
+		if hasMetadata {
+			return nil, ErrUnexpectedMetadata
+		}
+		return nil, nil
+	}
+	return nil, ErrInvalidType
+}
+
+// loadMessage returns a message of a conversation the user takes part in,
+// with the conversation and its participants. A message sent but not saved by the
+// message worker yet gives ErrMessageNotSaved: it can be retried shortly.
+func (s *Service) loadMessage(ctx context.Context, userID, conversationID, messageID string) (*model.Message, *model.Conversation, []model.Participant, error) {
+	if _, err := uuid.Parse(messageID); err != nil {
+		return nil, nil, nil, ErrMessageNotFound
+	}
+
+	conv, participants, err := s.members(ctx, conversationID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if findParticipant(participants, userID) == nil {
+		return nil, nil, nil, ErrNotParticipant
+	}
+
+	msg, err := s.msgRepo.GetByID(ctx, messageID)
+	if errors.Is(err, repository.ErrMessageNotFound) {
+		if unsaved, err := s.redis.IsMessageUnsaved(ctx, messageID, conversationID); err != nil {
+			logger.Errorf("Error checking if message %s is saved: %v", messageID, err)
+		} else if unsaved {
+			return nil, nil, nil, ErrMessageNotSaved
+		}
+		return nil, nil, nil, ErrMessageNotFound
+	}
+	if err == nil && msg.ConversationID != conversationID {
+		return nil, nil, nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return msg, conv, participants, nil
+}
+
+// Interact handles a click on an action of a card: it checks the action and sends a
+// message.interaction event to the webhooks of the bot that posted the card
+func (s *Service) Interact(ctx context.Context, userID string, wsMsg *WebSocketMessage) error {
+	msg, _, participants, err := s.loadMessage(ctx, userID, wsMsg.ConversationID, wsMsg.MessageID)
+	if err != nil {
+		return err
+	}
+	if msg.Type != model.MessageTypeCard {
+		return ErrInvalidAction
+	}
+
+	var card model.Card
+	if err := json.Unmarshal(msg.Metadata, &card); err != nil {
+		return ErrInvalidAction
+	}
+	action := card.Action(wsMsg.ActionID)
+	if action == nil {
+		return ErrInvalidAction
+	}
+	value, err := interactionValue(action, wsMsg.Value)
+	if err != nil {
+		return err
+	}
+
+	interaction := &model.CardInteraction{
+		MessageID: msg.ID,
+		ActionID:  action.ActionID,
+		Value:     value,
+		UserID:    userID,
+		Message:   msg,
+	}
+	if user := findParticipant(participants, userID).User; user != nil {
+		interaction.Username = user.Username
+	}
+
+	data, err := json.Marshal(interaction)
+	if err != nil {
+		return err
+	}
+	err = s.queueWebhookEvent(ctx, &model.WebhookEvent{
+		Event:          model.WebhookEventMessageInteraction,
+		ConversationID: msg.ConversationID,
+		ActorID:        userID,
+		BotID:          msg.SenderID,
+		Data:           data,
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("User %s used action %s of message %s", userID, action.ActionID, msg.ID)
+	return nil
+}
+
+// UpdateMessage edits a message sent by the user, typically a bot updating its card
+// after an interaction, and sends the new version to the participants. Editing is posting:
+// who may no longer post (e.g. demoted in a channel) may no longer edit either.
+func (s *Service) UpdateMessage(ctx context.Context, userID, conversationID, messageID string, update *model.MessageUpdate) (*OutgoingMessage, error) {
+	msg, conv, participants, err := s.loadMessage(ctx, userID, conversationID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.SenderID != userID {
+		return nil, ErrNotSender
+	}
+	if !findParticipant(participants, userID).Can(conv.Type, model.PermissionPostMessages) {
+		return nil, ErrReadOnly
+	}
+	if msg.Type == model.MessageTypeSystem {
+		return nil, ErrSystemMessage
+	}
+
+	if update.Content != nil {
+		msg.Content = *update.Content
+	}
+	if len(update.Metadata) > 0 {
+		if msg.Metadata, err = validateMetadata(string(msg.Type), update.Metadata); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.msgRepo.Update(ctx, msg); err != nil {
+		if errors.Is(err, repository.ErrMessageNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	outMsg := toOutgoing(msg)
+	event := &MessageUpdatedEvent{Type: "message_updated", Message: outMsg}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	s.broadcast(ctx, conv, participants, userID, payload)
+	// The sender's other devices
+	s.publishToUser(ctx, userID, event)
+	s.NotifyWebhooks(ctx, model.WebhookEventMessageUpdated, conv.ID, userID, outMsg)
+
+	logger.Infof("Message %s updated by %s", msg.ID, userID)
+	return outMsg, nil
+}
+
+// isEmailVerified checks if the user confirmed their email address
+func (s *Service) isEmailVerified(ctx context.Context, userID string) bool {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		logger.Errorf("Error checking email verification for user %s: %v", userID, err)
+		return false
+	}
+	return user.IsEmailVerified()
 }
 
 func (s *Service) sendError(conn *websocket.Conn, message string) {
@@ -306,10 +921,10 @@ func (s *Service) sendError(conn *websocket.Conn, message string) {
 	_ = conn.WriteMessage(websocket.TextMessage, msgBytes)
 }
 
-// handleUserOnline marks user as online and broadcasts to their contacts
-func (s *Service) handleUserOnline(ctx context.Context, userID string, conn *websocket.Conn) {
+// handleUserOnline marks a connection as online and broadcasts to the user's contacts
+func (s *Service) handleUserOnline(ctx context.Context, userID, connID string, conn *websocket.Conn) {
 	// Mark online in Redis
-	if err := s.redis.SetUserOnline(ctx, userID); err != nil {
+	if err := s.redis.ConnectionAlive(ctx, userID, connID, presenceTTL); err != nil {
 		logger.Errorf("Error setting user %s online in Redis: %v", userID, err)
 	}
 
@@ -369,13 +984,51 @@ func (s *Service) handleUserOnline(ctx context.Context, userID string, conn *web
 	s.broadcastPresence(ctx, presenceEvent, contactIDs)
 }
 
-// handleUserOffline marks user as offline and broadcasts to their contacts
-func (s *Service) handleUserOffline(ctx context.Context, userID string) {
-	// Mark offline in Redis
-	if err := s.redis.SetUserOffline(ctx, userID); err != nil {
+// keepPresence refreshes the presence of a connection until it closes
+func (s *Service) keepPresence(ctx context.Context, userID, connID string) {
+	ticker := time.NewTicker(presenceHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.redis.ConnectionAlive(ctx, userID, connID, presenceTTL); err != nil {
+				logger.Errorf("Error refreshing presence of user %s: %v", userID, err)
+			}
+		}
+	}
+}
+
+// connectionClosed removes the presence of a connection and returns whether the user
+// is still connected, here or elsewhere. When Redis can't tell, the connections left on
+// this instance decide.
+func (s *Service) connectionClosed(ctx context.Context, userID, connID string, remaining int) bool {
+	online, err := s.redis.ConnectionClosed(ctx, userID, connID)
+	if err != nil {
 		logger.Errorf("Error setting user %s offline in Redis: %v", userID, err)
+		return remaining > 0
 	}
+	return online
+}
 
+// onlineUsers returns which of the users have a live connection on any instance
+func (s *Service) onlineUsers(ctx context.Context, userIDs []string) map[string]bool {
+	online, err := s.redis.GetOnlineUsersFromList(ctx, userIDs)
+	if err != nil {
+		// Queued instead: delivered when they reconnect rather than lost
+		logger.Errorf("Error checking online users: %v", err)
+	}
+	isOnline := make(map[string]bool, len(online))
+	for _, userID := range online {
+		isOnline[userID] = true
+	}
+	return isOnline
+}
+
+// handleUserOffline broadcasts to the user's contacts that they went offline
+func (s *Service) handleUserOffline(ctx context.Context, userID string) {
 	// Get user info for username
 	user, err := s.userRepo.GetByID(ctx, userID)
 	var username string
@@ -427,8 +1080,9 @@ func (s *Service) broadcastPresence(ctx context.Context, event *PresenceEvent, u
 		return
 	}
 
+	isOnline := s.onlineUsers(ctx, userIDs)
 	for _, userID := range userIDs {
-		if s.users.IsOnline(userID) {
+		if isOnline[userID] {
 			// User is online, publish to their channel
 			channel := "user:" + userID
 			if err := s.redis.Publish(ctx, channel, msgBytes); err != nil {
@@ -439,11 +1093,6 @@ func (s *Service) broadcastPresence(ctx context.Context, event *PresenceEvent, u
 	}
 }
 
-// GetOnlineUsers returns the list of online user IDs (for API endpoint)
-func (s *Service) GetOnlineUsers(ctx context.Context) ([]string, error) {
-	return s.redis.GetOnlineUsers(ctx)
-}
-
 // GetOnlineUsersFromList checks which users from the list are online
 func (s *Service) GetOnlineUsersFromList(ctx context.Context, userIDs []string) ([]string, error) {
 	return s.redis.GetOnlineUsersFromList(ctx, userIDs)
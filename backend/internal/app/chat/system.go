@@ -0,0 +1,76 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+// PostSystemMessage writes a conversation event to its history as a system message.
+// text is the readable version without the actor's name (e.g. "added @bob").
+func (s *Service) PostSystemMessage(ctx context.Context, conversationID string, event *model.SystemEvent, text string) error {
+	conv, err := s.convRepo.GetByID(ctx, conversationID)
+	if err != nil {
+		if errors.Is(err, repository.ErrConversationNotFound) {
+			return ErrConversationNotFound
+		}
+		return err
+	}
+	participants, err := s.convRepo.GetParticipants(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+	return s.postSystem(ctx, conv, participants, event, text)
+}
+
+// postSystem sends a system message to every participant, the actor included since
+// no client wrote it
+func (s *Service) postSystem(ctx context.Context, conv *model.Conversation, participants []model.Participant, event *model.SystemEvent, text string) error {
+	actor := findParticipant(participants, event.ActorID)
+	if actor == nil {
+		return ErrNotParticipant
+	}
+	var actorUsername string
+	content := text
+	if actor.User != nil {
+		actorUsername = actor.User.Username
+		content = "@" + actorUsername + " " + text
+	}
+
+	metadata, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	outMsg := &OutgoingMessage{
+		ID:             uuid.New().String(),
+		ConversationID: conv.ID,
+		SenderID:       event.ActorID,
+		SenderUsername: actorUsername,
+		Content:        content,
+		Type:           string(model.MessageTypeSystem),
+		Metadata:       metadata,
+		SentAt:         time.Now().UnixMilli(),
+	}
+	if err := s.send(ctx, conv, participants, "", outMsg); err != nil {
+		return err
+	}
+
+	logger.Infof("System message %s in conversation %s", event.Event, conv.ID)
+	return nil
+}
+
+// announce writes a group change to the conversation history. The change is already
+// made, so a failure is only logged.
+func (s *Service) announce(ctx context.Context, conv *model.Conversation, participants []model.Participant, event *model.SystemEvent, text string) {
+	if err := s.postSystem(ctx, conv, participants, event, text); err != nil {
+		logger.Errorf("Error posting %s to conversation %s: %v", event.Event, conv.ID, err)
+	}
+}
@@ -0,0 +1,91 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+)
+
+func TestPostSystemMessage(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	aliceID, bobID := uuid.New().String(), uuid.New().String()
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup}
+	env.convs.add(conv, aliceID, bobID)
+
+	name := "Release"
+	event := &model.SystemEvent{Event: model.SystemEventGroupCreated, ActorID: aliceID, Name: &name}
+	if err := env.service.PostSystemMessage(ctx, conv.ID, event, "created the group"); err != nil {
+		t.Fatalf("PostSystemMessage: %v", err)
+	}
+
+	// Queued for the message worker like any message, with the event as metadata
+	messages := streamedMessages(t, env)
+	if len(messages) != 1 {
+		t.Fatalf("got %d messages in the stream, want 1", len(messages))
+	}
+	msg := messages[0]
+	if msg.Type != string(model.MessageTypeSystem) || msg.SenderID != aliceID || msg.ConversationID != conv.ID {
+		t.Errorf("message = %+v, want a system message of alice", msg)
+	}
+	if want := "@" + aliceID[:8] + " created the group"; msg.Content != want {
+		t.Errorf("content = %q, want %q", msg.Content, want)
+	}
+	if events := systemEvents(t, env); events[0].Event != model.SystemEventGroupCreated || events[0].Name == nil || *events[0].Name != name {
+		t.Errorf("event = %+v", events[0])
+	}
+
+	// The actor gets it too, since no client wrote it
+	for _, userID := range []string{aliceID, bobID} {
+		if n := pendingCount(env, userID); n != 1 {
+			t.Errorf("%d messages queued for %s, want 1", n, userID)
+		}
+	}
+}
+
+func TestPostSystemMessageNeedsParticipant(t *testing.T) {
+	env := newTestEnv(t)
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup}
+	env.convs.add(conv, uuid.New().String())
+
+	event := &model.SystemEvent{Event: model.SystemEventMemberLeft, ActorID: uuid.New().String()}
+	if err := env.service.PostSystemMessage(context.Background(), conv.ID, event, "left the group"); !errors.Is(err, ErrNotParticipant) {
+		t.Errorf("err = %v, want %v", err, ErrNotParticipant)
+	}
+	if err := env.service.PostSystemMessage(context.Background(), uuid.New().String(), event, "left the group"); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("unknown conversation: err = %v, want %v", err, ErrConversationNotFound)
+	}
+	if n := len(streamedMessages(t, env)); n != 0 {
+		t.Errorf("%d messages in the stream, want none", n)
+	}
+}
+
+func TestSystemMessagesCantBeChanged(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	adminID := uuid.New().String()
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup}
+	env.convs.add(conv, adminID)
+
+	// Even by the admin it names as the actor
+	msg := &model.Message{ID: uuid.New().String(), ConversationID: conv.ID, SenderID: adminID, Content: "created the group", Type: model.MessageTypeSystem}
+	env.msgs.add(msg)
+
+	content := "edited"
+	if _, err := env.service.UpdateMessage(ctx, adminID, conv.ID, msg.ID, &model.MessageUpdate{Content: &content}); !errors.Is(err, ErrSystemMessage) {
+		t.Errorf("edit: err = %v, want %v", err, ErrSystemMessage)
+	}
+	if err := env.service.DeleteMessage(ctx, adminID, conv.ID, msg.ID); !errors.Is(err, ErrSystemMessage) {
+		t.Errorf("delete: err = %v, want %v", err, ErrSystemMessage)
+	}
+
+	// Nor can clients post one
+	_, err := env.service.PostMessage(ctx, adminID, &WebSocketMessage{ConversationID: conv.ID, Content: "@bob left", Type: string(model.MessageTypeSystem)})
+	if !errors.Is(err, ErrInvalidType) {
+		t.Errorf("posted by a client: err = %v, want %v", err, ErrInvalidType)
+	}
+}
@@ -0,0 +1,60 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+// NotifyWebhooks queues an event of a conversation for its webhooks. Delivery happens in
+// the background (see webhook.Dispatcher); errors are logged, never returned to the caller.
+// New messages don't go through here: the dispatcher reads them from the message stream.
+func (s *Service) NotifyWebhooks(ctx context.Context, event, conversationID, actorID string, data interface{}) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		logger.Errorf("Error marshaling %s webhook event: %v", event, err)
+		return
+	}
+
+	err = s.queueWebhookEvent(ctx, &model.WebhookEvent{
+		Event:          event,
+		ConversationID: conversationID,
+		ActorID:        actorID,
+		Data:           raw,
+	})
+	if err != nil {
+		logger.Errorf("Error queuing %s webhook event for conversation %s: %v", event, conversationID, err)
+	}
+}
+
+// notifyParticipantsAdded sends the participant.added webhook event
+func (s *Service) notifyParticipantsAdded(ctx context.Context, conversationID, actorID string, added []model.Participant) {
+	s.NotifyWebhooks(ctx, model.WebhookEventParticipantAdded, conversationID, actorID, map[string]interface{}{
+		"participants": participantResponses(added),
+	})
+}
+
+// notifyParticipantRemoved sends the participant.removed webhook event; the actor is the
+// removed user when they left
+func (s *Service) notifyParticipantRemoved(ctx context.Context, conversationID, actorID, userID string) {
+	s.NotifyWebhooks(ctx, model.WebhookEventParticipantRemoved, conversationID, actorID, map[string]interface{}{
+		"user_id": userID,
+	})
+}
+
+// queueWebhookEvent adds an event to the webhook events stream, read by the dispatcher
+func (s *Service) queueWebhookEvent(ctx context.Context, event *model.WebhookEvent) error {
+	event.ID = uuid.New().String()
+	event.CreatedAt = time.Now().UTC()
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.redis.AddWebhookEvent(ctx, map[string]interface{}{"event": string(payload)})
+}
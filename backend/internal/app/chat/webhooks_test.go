@@ -0,0 +1,201 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/redisclient"
+)
+
+// queuedWebhookEvents returns the events queued for the webhook dispatcher, oldest first
+func queuedWebhookEvents(t *testing.T, env *testEnv) []model.WebhookEvent {
+	t.Helper()
+
+	entries, err := env.redis.Stream(redisclient.WebhookEventsStream)
+	if err != nil {
+		return nil
+	}
+	events := make([]model.WebhookEvent, 0, len(entries))
+	for _, entry := range entries {
+		for i := 0; i+1 < len(entry.Values); i += 2 {
+			if entry.Values[i] != "event" {
+				continue
+			}
+			var event model.WebhookEvent
+			if err := json.Unmarshal([]byte(entry.Values[i+1]), &event); err != nil {
+				t.Fatalf("invalid webhook event %s: %v", entry.Values[i+1], err)
+			}
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// onlyWebhookEvent returns the single queued event, failing otherwise
+func onlyWebhookEvent(t *testing.T, env *testEnv) model.WebhookEvent {
+	t.Helper()
+
+	events := queuedWebhookEvents(t, env)
+	if len(events) != 1 {
+		t.Fatalf("got %d webhook events, want 1: %+v", len(events), events)
+	}
+	return events[0]
+}
+
+func TestMemberChangesNotifyWebhooks(t *testing.T) {
+	adminID, memberID, otherID := uuid.New().String(), uuid.New().String(), uuid.New().String()
+
+	tests := []struct {
+		name      string
+		change    func(ctx context.Context, s *Service, convID string) error
+		wantEvent string
+		wantActor string
+		wantData  func(t *testing.T, data json.RawMessage)
+	}{
+		{
+			name: "added by an admin",
+			change: func(ctx context.Context, s *Service, convID string) error {
+				_, err := s.AddMembers(ctx, adminID, convID, []string{otherID})
+				return err
+			},
+			wantEvent: model.WebhookEventParticipantAdded,
+			wantActor: adminID,
+			wantData:  wantParticipants(otherID),
+		},
+		{
+			name: "joined a public group",
+			change: func(ctx context.Context, s *Service, convID string) error {
+				_, err := s.JoinPublic(ctx, otherID, convID)
+				return err
+			},
+			wantEvent: model.WebhookEventParticipantAdded,
+			wantActor: otherID,
+			wantData:  wantParticipants(otherID),
+		},
+		{
+			name: "removed by an admin",
+			change: func(ctx context.Context, s *Service, convID string) error {
+				return s.RemoveMember(ctx, adminID, convID, memberID)
+			},
+			wantEvent: model.WebhookEventParticipantRemoved,
+			wantActor: adminID,
+			wantData:  wantRemovedUser(memberID),
+		},
+		{
+			name: "left",
+			change: func(ctx context.Context, s *Service, convID string) error {
+				return s.LeaveGroup(ctx, memberID, convID)
+			},
+			wantEvent: model.WebhookEventParticipantRemoved,
+			wantActor: memberID,
+			wantData:  wantRemovedUser(memberID),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup, Visibility: model.VisibilityPublic}
+			env.convs.add(conv, adminID, memberID)
+
+			if err := tt.change(context.Background(), env.service, conv.ID); err != nil {
+				t.Fatalf("change failed: %v", err)
+			}
+
+			event := onlyWebhookEvent(t, env)
+			if event.Event != tt.wantEvent {
+				t.Errorf("event = %q, want %q", event.Event, tt.wantEvent)
+			}
+			if event.ConversationID != conv.ID {
+				t.Errorf("conversation_id = %q, want %q", event.ConversationID, conv.ID)
+			}
+			if event.ActorID != tt.wantActor {
+				t.Errorf("actor_id = %q, want %q", event.ActorID, tt.wantActor)
+			}
+			if event.ID == "" || event.CreatedAt.IsZero() {
+				t.Errorf("event without id or created_at: %+v", event)
+			}
+			tt.wantData(t, event.Data)
+		})
+	}
+}
+
+func wantParticipants(userIDs ...string) func(t *testing.T, data json.RawMessage) {
+	return func(t *testing.T, data json.RawMessage) {
+		t.Helper()
+
+		var body struct {
+			Participants []model.ParticipantResponse `json:"participants"`
+		}
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Fatalf("invalid data %s: %v", data, err)
+		}
+		if len(body.Participants) != len(userIDs) {
+			t.Fatalf("got %d participants, want %d: %s", len(body.Participants), len(userIDs), data)
+		}
+		for i, userID := range userIDs {
+			if body.Participants[i].ID != userID {
+				t.Errorf("participants[%d].id = %q, want %q", i, body.Participants[i].ID, userID)
+			}
+		}
+	}
+}
+
+func wantRemovedUser(userID string) func(t *testing.T, data json.RawMessage) {
+	return func(t *testing.T, data json.RawMessage) {
+		t.Helper()
+
+		var body struct {
+			UserID string `json:"user_id"`
+		}
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Fatalf("invalid data %s: %v", data, err)
+		}
+		if body.UserID != userID {
+			t.Errorf("user_id = %q, want %q", body.UserID, userID)
+		}
+	}
+}
+
+func TestUpdateMessageNotifiesWebhooks(t *testing.T) {
+	env := newTestEnv(t)
+	senderID, peerID := uuid.New().String(), uuid.New().String()
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeDirect}
+	env.convs.add(conv, senderID, peerID)
+
+	msg := &model.Message{
+		ID:             uuid.New().String(),
+		ConversationID: conv.ID,
+		SenderID:       senderID,
+		Content:        "draft",
+		Type:           model.MessageTypeText,
+		SentAt:         time.Now(),
+	}
+	env.msgs.add(msg)
+
+	content := "final"
+	if _, err := env.service.UpdateMessage(context.Background(), senderID, conv.ID, msg.ID, &model.MessageUpdate{Content: &content}); err != nil {
+		t.Fatalf("UpdateMessage: %v", err)
+	}
+
+	event := onlyWebhookEvent(t, env)
+	if event.Event != model.WebhookEventMessageUpdated {
+		t.Errorf("event = %q, want %q", event.Event, model.WebhookEventMessageUpdated)
+	}
+	if event.ConversationID != conv.ID || event.ActorID != senderID {
+		t.Errorf("conversation_id, actor_id = %q, %q; want %q, %q", event.ConversationID, event.ActorID, conv.ID, senderID)
+	}
+
+	var updated OutgoingMessage
+	if err := json.Unmarshal(event.Data, &updated); err != nil {
+		t.Fatalf("invalid data %s: %v", event.Data, err)
+	}
+	if updated.ID != msg.ID || updated.Content != "final" || updated.EditedAt == nil {
+		t.Errorf("data = %s, want message %s edited to %q", event.Data, msg.ID, "final")
+	}
+}
@@ -0,0 +1,145 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/webhooksig"
+)
+
+const (
+	// botTimeout bounds the call to a bot's command endpoint; the user is waiting for the answer
+	botTimeout = 5 * time.Second
+	// maxReplyLength is the longest text a bot can answer with
+	maxReplyLength = 4000
+)
+
+// Response types of a bot's answer
+const (
+	ResponseEphemeral = "ephemeral"  // Shown to the invoker only (default)
+	ResponseInChannel = "in_channel" // Posted to the conversation by the bot
+)
+
+// BotRequest is the JSON body POSTed to a bot's command endpoint
+type BotRequest struct {
+	ID             string    `json:"id"`
+	CommandID      string    `json:"command_id"`
+	Command        string    `json:"command"` // e.g. "/deploy"
+	Text           string    `json:"text"`    // Arguments after the command name
+	ConversationID string    `json:"conversation_id"`
+	UserID         string    `json:"user_id"`
+	Username       string    `json:"username"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// BotResponse is the optional JSON answer of a bot (Slack-compatible)
+type BotResponse struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// findBotCommand picks the bot command matching an invocation. "/name@bot" selects
+// the bot; otherwise the oldest registration among the conversation's bots wins.
+func (s *Service) findBotCommand(ctx context.Context, inv *Invocation) (*model.BotCommand, error) {
+	commands, err := s.commandRepo.ListForConversation(ctx, inv.Conversation.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range commands {
+		cmd := &commands[i]
+		if cmd.Name == inv.Name && (inv.Bot == "" || strings.EqualFold(cmd.BotUsername, inv.Bot)) {
+			return cmd, nil
+		}
+	}
+	return nil, ErrCommandNotFound
+}
+
+// callBot forwards an invocation to the bot's endpoint and relays its answer
+func (s *Service) callBot(ctx context.Context, cmd *model.BotCommand, inv *Invocation) (string, error) {
+	payload, err := json.Marshal(&BotRequest{
+		ID:             uuid.New().String(),
+		CommandID:      cmd.ID,
+		Command:        "/" + cmd.Name,
+		Text:           inv.Args,
+		ConversationID: inv.Conversation.ID,
+		UserID:         inv.UserID,
+		Username:       inv.Username,
+		CreatedAt:      time.Now().UTC(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, botTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cmd.URL, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoChat-Commands/1.0")
+	req.Header.Set("X-GoChat-Event", "command")
+	req.Header.Set(webhooksig.TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhooksig.SignatureHeader, webhooksig.Sign([]byte(cmd.Secret), timestamp, payload))
+
+	failed := fmt.Sprintf("/%s failed: @%s didn't answer. Try again later.", cmd.Name, cmd.BotUsername)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		logger.Errorf("Command /%s of bot %s: %v", cmd.Name, cmd.BotID, err)
+		return failed, nil
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		logger.Errorf("Command /%s of bot %s: status %d, %v", cmd.Name, cmd.BotID, resp.StatusCode, err)
+		return failed, nil
+	}
+
+	answer := parseBotResponse(resp.Header.Get("Content-Type"), body)
+	if answer.Text == "" {
+		return "", nil
+	}
+
+	if answer.ResponseType != ResponseInChannel {
+		return answer.Text, nil
+	}
+	if err := inv.Post(ctx, cmd.BotID, answer.Text); err != nil {
+		return "", err
+	}
+	return "", nil
+}
+
+// parseBotResponse reads a JSON answer, or uses a plain text body as an ephemeral reply
+func parseBotResponse(contentType string, body []byte) BotResponse {
+	var answer BotResponse
+	if strings.HasPrefix(contentType, "application/json") {
+		if err := json.Unmarshal(body, &answer); err != nil {
+			return BotResponse{}
+		}
+	} else {
+		answer.Text = string(body)
+	}
+
+	answer.Text = strings.TrimSpace(answer.Text)
+	if runes := []rune(answer.Text); len(runes) > maxReplyLength {
+		answer.Text = string(runes[:maxReplyLength])
+	}
+	return answer
+}
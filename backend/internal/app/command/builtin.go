@@ -0,0 +1,196 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+)
+
+const (
+	// maxTopicLength matches conversations.topic
+	maxTopicLength = 250
+	// maxMuteDuration is the longest timed mute; longer ones should be indefinite
+	maxMuteDuration = 365 * 24 * time.Hour
+)
+
+func (s *Service) registerBuiltins() {
+	manageGroup := Requirement{Scope: model.ScopeGroupsManage, GroupOnly: true}
+
+	s.Register("help", "", "List the commands available in this conversation", Requirement{}, s.help)
+	s.Register("invite", "@username [@username...]", "Add people or bots to this group",
+		Requirement{Scope: model.ScopeGroupsManage, Permission: model.PermissionAddMembers, GroupOnly: true}, s.invite)
+	s.Register("leave", "", "Leave this group", manageGroup, s.leave)
+	// Showing the topic is open to everyone; changing it needs edit_info (checked by topic)
+	s.Register("topic", "[text | clear]", "Show or change the topic of this conversation",
+		Requirement{Scope: model.ScopeGroupsManage}, s.topic)
+	s.Register("mute", "[duration | off]", "Mute this conversation, e.g. /mute 8h (until unmuted by default)", Requirement{}, s.mute)
+}
+
+func (s *Service) help(ctx context.Context, inv *Invocation) (string, error) {
+	commands, err := s.list(ctx, inv.Conversation.ID)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("Available commands:")
+	for _, cmd := range commands {
+		b.WriteString("\n/" + cmd.Name)
+		if cmd.Usage != "" {
+			b.WriteString(" " + cmd.Usage)
+		}
+		if cmd.Description != "" {
+			b.WriteString(" - " + cmd.Description)
+		}
+		if cmd.BotUsername != "" {
+			b.WriteString(" (@" + cmd.BotUsername + ")")
+		}
+	}
+	b.WriteString("\nStart a message with // to send it with a leading slash.")
+	return b.String(), nil
+}
+
+func (s *Service) invite(ctx context.Context, inv *Invocation) (string, error) {
+	usernames := strings.Fields(inv.Args)
+	if len(usernames) == 0 {
+		return "Usage: /invite @username [@username...]", nil
+	}
+
+	var userIDs, problems []string
+	for _, name := range usernames {
+		name = strings.TrimPrefix(name, "@")
+
+		user, err := s.userRepo.GetByUsername(ctx, name)
+		if errors.Is(err, repository.ErrUserNotFound) || (err == nil && !user.IsActive) {
+			problems = append(problems, fmt.Sprintf("No user named @%s.", name))
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if slices.Contains(userIDs, user.ID) {
+			continue
+		}
+		if inv.participant(user.ID) != nil {
+			problems = append(problems, fmt.Sprintf("@%s is already here.", user.Username))
+			continue
+		}
+		userIDs = append(userIDs, user.ID)
+	}
+
+	if len(userIDs) > 0 {
+		if _, err := inv.Groups.AddMembers(ctx, inv.UserID, inv.Conversation.ID, userIDs); err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(problems, "\n"), nil
+}
+
+func (s *Service) leave(ctx context.Context, inv *Invocation) (string, error) {
+	if err := inv.Groups.LeaveGroup(ctx, inv.UserID, inv.Conversation.ID); err != nil {
+		return "", err
+	}
+
+	name := "the group"
+	if inv.Conversation.Name != nil {
+		name = *inv.Conversation.Name
+	}
+	return "You left " + name + ".", nil
+}
+
+func (s *Service) topic(ctx context.Context, inv *Invocation) (string, error) {
+	switch {
+	case inv.Args == "":
+		if inv.Conversation.Topic == nil {
+			return "No topic is set. Use /topic <text> to set one.", nil
+		}
+		return "Topic: " + *inv.Conversation.Topic, nil
+
+	case !inv.participant(inv.UserID).Can(inv.Conversation.Type, model.PermissionEditInfo):
+		return "Only admins can change the topic of this group.", nil
+
+	case strings.EqualFold(inv.Args, "clear"):
+		return "", s.setTopic(ctx, inv, nil)
+
+	case utf8.RuneCountInString(inv.Args) > maxTopicLength:
+		return fmt.Sprintf("The topic can't be longer than %d characters.", maxTopicLength), nil
+	}
+
+	topic := inv.Args
+	return "", s.setTopic(ctx, inv, &topic)
+}
+
+// setTopic changes the topic (nil clears it), records it in the history and tells the
+// participants about the updated conversation
+func (s *Service) setTopic(ctx context.Context, inv *Invocation, topic *string) error {
+	if err := s.convRepo.SetTopic(ctx, inv.Conversation.ID, topic); err != nil {
+		return err
+	}
+
+	text := "cleared the topic"
+	if topic != nil {
+		text = "changed the topic to: " + *topic
+	}
+	if err := inv.Groups.PostSystemMessage(ctx, inv.Conversation.ID, &model.SystemEvent{
+		Event:   model.SystemEventTopicChanged,
+		ActorID: inv.UserID,
+		Topic:   topic,
+	}, text); err != nil {
+		return err
+	}
+	return inv.Groups.NotifyUpdated(ctx, inv.UserID, inv.Conversation.ID)
+}
+
+func (s *Service) mute(ctx context.Context, inv *Invocation) (string, error) {
+	switch {
+	case strings.EqualFold(inv.Args, "off"):
+		if err := s.convRepo.SetMuted(ctx, inv.Conversation.ID, inv.UserID, false, nil); err != nil {
+			return "", err
+		}
+		return "Unmuted this conversation.", nil
+
+	case inv.Args == "":
+		if err := s.convRepo.SetMuted(ctx, inv.Conversation.ID, inv.UserID, true, nil); err != nil {
+			return "", err
+		}
+		return "Muted this conversation until you unmute it with /mute off.", nil
+	}
+
+	d, err := parseDuration(inv.Args)
+	if err != nil || d <= 0 || d > maxMuteDuration {
+		return "Usage: /mute [duration | off], e.g. /mute 30m, /mute 8h or /mute 2d", nil
+	}
+
+	until := time.Now().Add(d)
+	if err := s.convRepo.SetMuted(ctx, inv.Conversation.ID, inv.UserID, true, &until); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Muted this conversation until %s.", until.UTC().Format("Jan 2 15:04 MST")), nil
+}
+
+// parseDuration accepts Go durations ("90m", "8h") plus days and weeks ("2d", "1w"),
+// up to maxMuteDuration
+func parseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil {
+				return 0, err
+			}
+			if count > int(maxMuteDuration/unit) {
+				return 0, fmt.Errorf("duration %q too long", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,429 @@
+// Package command runs the slash commands typed in conversations. Built-in commands
+// are handled here; the others are forwarded to the HTTP endpoint of the bot that
+// registered them.
+package command
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/internal/config"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/safehttp"
+)
+
+var (
+	ErrConversationNotFound = errors.New("conversation not found")
+	ErrNotParticipant       = errors.New("not a participant of the conversation")
+	ErrBotNotFound          = errors.New("bot not found")
+	ErrCommandNotFound      = errors.New("command not found")
+	ErrCommandExists        = errors.New("bot already has a command with this name")
+	ErrReservedName         = errors.New("command name is reserved for a built-in command")
+	ErrInvalidName          = errors.New("command names use lowercase letters, digits, '-' and '_'")
+	ErrInvalidURL           = errors.New("invalid command URL")
+	ErrTooManyCommands      = errors.New("too many commands for this bot")
+)
+
+// maxCommandsPerBot limits the commands a bot can register
+const maxCommandsPerBot = 25
+
+// secretPrefix makes signing secrets recognizable (same scheme as webhooks)
+const secretPrefix = "whsec_"
+
+// namePattern is the syntax of command names, without the leading slash
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// Caller is who typed a command: a user's session, or an API token limited to its scopes
+type Caller struct {
+	UserID   string
+	APIToken bool     // Only the commands its Scopes allow run
+	Scopes   []string // Of the API token
+}
+
+// HasScope checks if the caller may use what a scope grants (sessions may use everything)
+func (c Caller) HasScope(scope string) bool {
+	return !c.APIToken || slices.Contains(c.Scopes, scope)
+}
+
+// Requirement is what running a built-in command needs beyond taking part in the
+// conversation. It is checked before the command runs.
+type Requirement struct {
+	Scope      string           // API tokens need this scope (the one of the matching REST endpoints)
+	Permission model.Permission // The invoker needs this permission
+	GroupOnly  bool             // The command only makes sense in groups
+}
+
+// Invocation is a command typed by a user in a conversation
+type Invocation struct {
+	UserID       string
+	Username     string
+	Conversation *model.Conversation
+	Participants []model.Participant
+	Name         string // Without the leading slash
+	Bot          string // Username from "/name@bot", to pick among bots with the same command
+	Args         string
+	Post         PostFunc
+	Groups       GroupManager
+}
+
+// participant returns the participant with the given user ID
+func (inv *Invocation) participant(userID string) *model.Participant {
+	for i := range inv.Participants {
+		if inv.Participants[i].UserID == userID {
+			return &inv.Participants[i]
+		}
+	}
+	return nil
+}
+
+// PostFunc posts a message to the conversation of the invocation
+type PostFunc func(ctx context.Context, senderID, content string) error
+
+// GroupManager changes the members of a group like the REST endpoints do, with the
+// same live events and system messages (implemented by the chat service)
+type GroupManager interface {
+	AddMembers(ctx context.Context, actorID, conversationID string, userIDs []string) ([]model.Participant, error)
+	LeaveGroup(ctx context.Context, userID, conversationID string) error
+	PostSystemMessage(ctx context.Context, conversationID string, event *model.SystemEvent, text string) error
+	NotifyUpdated(ctx context.Context, actorID, conversationID string) error
+}
+
+// Handler runs a command and returns the reply shown only to the invoker ("" for none).
+// Handlers that want everyone to see something post it with Invocation.Post.
+type Handler func(ctx context.Context, inv *Invocation) (string, error)
+
+// builtin is a command handled in-process
+type builtin struct {
+	info    model.CommandInfo
+	require Requirement
+	run     Handler
+}
+
+// CreateBotCommandRequest is the request to register a command for a bot
+type CreateBotCommandRequest struct {
+	Name        string `json:"name" validate:"required,max=32"`
+	Description string `json:"description" validate:"max=255"`
+	Usage       string `json:"usage" validate:"max=100"`
+	URL         string `json:"url" validate:"required,url,max=2048"`
+}
+
+// CreatedBotCommand is returned once at registration, with the signing secret
+type CreatedBotCommand struct {
+	model.BotCommandResponse
+	Secret string `json:"secret"`
+}
+
+// Service parses and runs slash commands
+type Service struct {
+	config      *config.Config
+	convRepo    repository.ConversationRepository
+	userRepo    repository.UserRepository
+	commandRepo repository.BotCommandRepository
+	client      *http.Client
+	builtins    map[string]*builtin
+}
+
+// NewService creates a new command service with the built-in commands (Fx provider)
+func NewService(
+	cfg *config.Config,
+	convRepo repository.ConversationRepository,
+	userRepo repository.UserRepository,
+	commandRepo repository.BotCommandRepository,
+) *Service {
+	s := &Service{
+		config:      cfg,
+		convRepo:    convRepo,
+		userRepo:    userRepo,
+		commandRepo: commandRepo,
+		client:      safehttp.NewClient(botTimeout, cfg.Webhook.AllowPrivateNetworks),
+		builtins:    make(map[string]*builtin),
+	}
+	s.registerBuiltins()
+
+	logger.Infof("Command service initialized (%d built-in commands)", len(s.builtins))
+	return s
+}
+
+// Register adds a built-in command. It panics on invalid or duplicate names,
+// which are programming errors.
+func (s *Service) Register(name, usage, description string, require Requirement, run Handler) {
+	if !namePattern.MatchString(name) {
+		panic("command: invalid name " + name)
+	}
+	if _, ok := s.builtins[name]; ok {
+		panic("command: duplicate command " + name)
+	}
+	s.builtins[name] = &builtin{
+		info:    model.CommandInfo{Name: name, Usage: usage, Description: description, BuiltIn: true},
+		require: require,
+		run:     run,
+	}
+}
+
+// Parse splits "/name args" (or "/name@bot args") into its parts. ok is false when
+// the text isn't a command, e.g. "//escaped" or a path like "/usr/bin".
+func Parse(text string) (name, bot, args string, ok bool) {
+	if !strings.HasPrefix(text, "/") {
+		return "", "", "", false
+	}
+
+	head := text[1:]
+	if i := strings.IndexFunc(head, unicode.IsSpace); i >= 0 {
+		head, args = head[:i], head[i+1:]
+	}
+	name, bot, _ = strings.Cut(strings.ToLower(head), "@")
+	if !namePattern.MatchString(name) {
+		return "", "", "", false
+	}
+	return name, bot, strings.TrimSpace(args), true
+}
+
+// IsCommand reports whether a message is a slash command
+func IsCommand(text string) bool {
+	_, _, _, ok := Parse(text)
+	return ok
+}
+
+// Run parses and runs a command typed by a user in a conversation and
+// returns the reply to show to the user only
+func (s *Service) Run(ctx context.Context, caller Caller, conversationID, text string, post PostFunc, groups GroupManager) (string, error) {
+	name, bot, args, ok := Parse(text)
+	if !ok {
+		return "", fmt.Errorf("not a command: %q", text)
+	}
+
+	inv, err := s.invocation(ctx, caller.UserID, conversationID)
+	if err != nil {
+		return "", err
+	}
+	inv.Name, inv.Bot, inv.Args, inv.Post, inv.Groups = name, bot, args, post, groups
+
+	if b, ok := s.builtins[name]; ok && bot == "" {
+		if refusal := b.refusal(caller, inv); refusal != "" {
+			return refusal, nil
+		}
+		return b.run(ctx, inv)
+	}
+
+	cmd, err := s.findBotCommand(ctx, inv)
+	if errors.Is(err, ErrCommandNotFound) {
+		return fmt.Sprintf("/%s is not a valid command. Type /help to see the available commands.", name), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return s.callBot(ctx, cmd, inv)
+}
+
+// refusal returns why the caller can't run a built-in command, or "" when they can
+func (b *builtin) refusal(caller Caller, inv *Invocation) string {
+	switch {
+	case b.require.GroupOnly && !inv.Conversation.Type.IsGroup():
+		return fmt.Sprintf("/%s only works in groups.", b.info.Name)
+	case b.require.Scope != "" && !caller.HasScope(b.require.Scope):
+		return fmt.Sprintf("This token can't run /%s (missing %s scope).", b.info.Name, b.require.Scope)
+	case b.require.Permission != "" && !inv.participant(inv.UserID).Can(inv.Conversation.Type, b.require.Permission):
+		return fmt.Sprintf("You don't have permission to run /%s in this conversation.", b.info.Name)
+	}
+	return ""
+}
+
+// List returns the commands available in a conversation: the built-in ones
+// and those of the bots taking part in it
+func (s *Service) List(ctx context.Context, userID, conversationID string) ([]model.CommandInfo, error) {
+	inv, err := s.invocation(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	return s.list(ctx, inv.Conversation.ID)
+}
+
+func (s *Service) list(ctx context.Context, conversationID string) ([]model.CommandInfo, error) {
+	commands := make([]model.CommandInfo, 0, len(s.builtins))
+	for _, b := range s.builtins {
+		commands = append(commands, b.info)
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+
+	botCommands, err := s.commandRepo.ListForConversation(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	for _, cmd := range botCommands {
+		commands = append(commands, model.CommandInfo{
+			Name:        cmd.Name,
+			Usage:       cmd.Usage,
+			Description: cmd.Description,
+			BotID:       cmd.BotID,
+			BotUsername: cmd.BotUsername,
+		})
+	}
+	return commands, nil
+}
+
+// invocation loads the conversation and checks that the user takes part in it
+func (s *Service) invocation(ctx context.Context, userID, conversationID string) (*Invocation, error) {
+	if _, err := uuid.Parse(conversationID); err != nil {
+		return nil, ErrConversationNotFound
+	}
+
+	conv, err := s.convRepo.GetByID(ctx, conversationID)
+	if err != nil {
+		if errors.Is(err, repository.ErrConversationNotFound) {
+			return nil, ErrConversationNotFound
+		}
+		return nil, err
+	}
+
+	participants, err := s.convRepo.GetParticipants(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	inv := &Invocation{UserID: userID, Conversation: conv, Participants: participants}
+	p := inv.participant(userID)
+	if p == nil {
+		return nil, ErrNotParticipant
+	}
+	if p.User != nil {
+		inv.Username = p.User.Username
+	}
+	return inv, nil
+}
+
+// CreateBotCommand registers a command for a bot owned by the user
+func (s *Service) CreateBotCommand(ctx context.Context, ownerID, botID string, req *CreateBotCommandRequest) (*CreatedBotCommand, error) {
+	if err := s.requireBotOwner(ctx, ownerID, botID); err != nil {
+		return nil, err
+	}
+
+	name := strings.ToLower(strings.TrimPrefix(req.Name, "/"))
+	if !namePattern.MatchString(name) {
+		return nil, ErrInvalidName
+	}
+	if _, ok := s.builtins[name]; ok {
+		return nil, ErrReservedName
+	}
+	if err := safehttp.CheckURL(req.URL, s.config.Webhook.AllowPrivateNetworks); err != nil {
+		return nil, ErrInvalidURL
+	}
+
+	existing, err := s.commandRepo.ListByBot(ctx, botID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxCommandsPerBot {
+		return nil, ErrTooManyCommands
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := &model.BotCommand{
+		BotID:       botID,
+		CreatedBy:   ownerID,
+		Name:        name,
+		Description: req.Description,
+		Usage:       req.Usage,
+		URL:         req.URL,
+		Secret:      secret,
+	}
+	if err := s.commandRepo.Create(ctx, cmd); err != nil {
+		if errors.Is(err, repository.ErrBotCommandAlreadyExists) {
+			return nil, ErrCommandExists
+		}
+		return nil, err
+	}
+
+	logger.Infof("Command /%s registered for bot %s by user %s", cmd.Name, botID, ownerID)
+
+	return &CreatedBotCommand{BotCommandResponse: cmd.ToResponse(), Secret: secret}, nil
+}
+
+// ListBotCommands returns the commands of a bot owned by the user
+func (s *Service) ListBotCommands(ctx context.Context, ownerID, botID string) ([]model.BotCommandResponse, error) {
+	if err := s.requireBotOwner(ctx, ownerID, botID); err != nil {
+		return nil, err
+	}
+
+	commands, err := s.commandRepo.ListByBot(ctx, botID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.BotCommandResponse, 0, len(commands))
+	for _, cmd := range commands {
+		result = append(result, cmd.ToResponse())
+	}
+	return result, nil
+}
+
+// DeleteBotCommand removes a command of a bot owned by the user
+func (s *Service) DeleteBotCommand(ctx context.Context, ownerID, botID, commandID string) error {
+	if err := s.requireBotOwner(ctx, ownerID, botID); err != nil {
+		return err
+	}
+	if _, err := uuid.Parse(commandID); err != nil {
+		return ErrCommandNotFound
+	}
+
+	cmd, err := s.commandRepo.GetByID(ctx, commandID)
+	if err != nil {
+		if errors.Is(err, repository.ErrBotCommandNotFound) {
+			return ErrCommandNotFound
+		}
+		return err
+	}
+	if cmd.BotID != botID {
+		return ErrCommandNotFound
+	}
+
+	if err := s.commandRepo.Delete(ctx, commandID); err != nil {
+		if errors.Is(err, repository.ErrBotCommandNotFound) {
+			return ErrCommandNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Service) requireBotOwner(ctx context.Context, ownerID, botID string) error {
+	if _, err := uuid.Parse(botID); err != nil {
+		return ErrBotNotFound
+	}
+
+	bot, err := s.userRepo.GetByID(ctx, botID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrBotNotFound
+		}
+		return err
+	}
+	if !bot.IsBot || bot.BotOwnerID == nil || *bot.BotOwnerID != ownerID {
+		return ErrBotNotFound
+	}
+	return nil
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -5,10 +5,15 @@ import (
 
 	"github.com/Beretta350/gochat/internal/app/auth"
 	"github.com/Beretta350/gochat/internal/app/chat"
+	"github.com/Beretta350/gochat/internal/app/command"
 	"github.com/Beretta350/gochat/internal/app/handler"
 	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/internal/app/webhook"
 	"github.com/Beretta350/gochat/internal/app/worker"
 	"github.com/Beretta350/gochat/internal/config"
+	"github.com/Beretta350/gochat/pkg/mailer"
+	"github.com/Beretta350/gochat/pkg/oidc"
+	"github.com/Beretta350/gochat/pkg/passkey"
 	"github.com/Beretta350/gochat/pkg/postgres"
 	"github.com/Beretta350/gochat/pkg/redisclient"
 )
@@ -19,27 +24,55 @@ var Module = fx.Options(
 	fx.Provide(config.NewConfig),
 	fx.Provide(postgres.NewClient),
 	fx.Provide(redisclient.NewClient),
+	fx.Provide(mailer.NewMailer),
+	fx.Provide(passkey.NewService),
+	fx.Provide(oidc.NewRegistry),
 
 	// Auth
 	fx.Provide(auth.NewJWTService),
+	fx.Provide(auth.NewPasswordHasher),
 	fx.Provide(auth.NewService),
 
 	// Repositories
 	fx.Provide(repository.NewUserRepository),
 	fx.Provide(repository.NewConversationRepository),
 	fx.Provide(repository.NewMessageRepository),
+	fx.Provide(repository.NewSessionRepository),
+	fx.Provide(repository.NewUserTokenRepository),
+	fx.Provide(repository.NewRecoveryCodeRepository),
+	fx.Provide(repository.NewPasskeyRepository),
+	fx.Provide(repository.NewUserIdentityRepository),
+	fx.Provide(repository.NewSigningKeyRepository),
+	fx.Provide(repository.NewAuditRepository),
+	fx.Provide(repository.NewAPITokenRepository),
+	fx.Provide(repository.NewWebhookRepository),
+	fx.Provide(repository.NewIncomingWebhookRepository),
+	fx.Provide(repository.NewBotCommandRepository),
+	fx.Provide(repository.NewGroupInviteRepository),
+	fx.Provide(repository.NewJoinRequestRepository),
 
 	// Services
+	fx.Provide(command.NewService),
 	fx.Provide(chat.NewService),
+	fx.Provide(webhook.NewService),
 	// Provide ChatServiceInterface from chat.Service
 	fx.Provide(func(s *chat.Service) handler.ChatServiceInterface { return s }),
+	fx.Provide(func(s *chat.Service) auth.ConversationNotifier { return s }),
 
 	// Workers
 	fx.Provide(worker.NewMessageWorker),
+	fx.Provide(webhook.NewDispatcher),
 
 	// Handlers
 	fx.Provide(handler.NewHealthHandler),
 	fx.Provide(handler.NewAuthHandler),
+	fx.Provide(handler.NewJWKSHandler),
+	fx.Provide(handler.NewAdminHandler),
+	fx.Provide(handler.NewBotHandler),
+	fx.Provide(handler.NewWebhookHandler),
+	fx.Provide(handler.NewCommandHandler),
 	fx.Provide(handler.NewConversationHandler),
+	fx.Provide(handler.NewGroupHandler),
+	fx.Provide(handler.NewMessageHandler),
 	fx.Provide(handler.NewWebSocketHandler),
 )
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Beretta350/gochat/internal/app/auth"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/validator"
+)
+
+// AdminHandler handles administration requests
+type AdminHandler struct {
+	authService *auth.Service
+}
+
+// NewAdminHandler creates a new admin handler (Fx provider)
+func NewAdminHandler(authService *auth.Service) *AdminHandler {
+	logger.Info("Admin handler initialized")
+	return &AdminHandler{authService: authService}
+}
+
+// UnlockLogin lifts a login lockout of an email and/or IP address
+// POST /api/v1/admin/login-lockouts/unlock
+func (h *AdminHandler) UnlockLogin(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req auth.UnlockLoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	req.Email = validator.SanitizeString(req.Email)
+	req.IP = validator.SanitizeString(req.IP)
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+
+	if err := h.authService.UnlockLogin(c.Context(), userID, &req); err != nil {
+		logger.Errorf("Unlock login error: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to unlock login")
+	}
+
+	return c.JSON(fiber.Map{"message": "Login unlocked"})
+}
+
+// AuditLog returns the latest audit entries (?action=login.locked&limit=50)
+// GET /api/v1/admin/audit-log
+func (h *AdminHandler) AuditLog(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+
+	entries, err := h.authService.ListAuditLog(c.Context(), c.Query("action"), limit)
+	if err != nil {
+		logger.Errorf("List audit log error: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get audit log")
+	}
+
+	return c.JSON(fiber.Map{"entries": entries})
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Beretta350/gochat/internal/app/auth"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/validator"
+)
+
+// apiTokenError maps API token and bot errors to HTTP errors
+func apiTokenError(err error, action string) error {
+	switch {
+	case errors.Is(err, auth.ErrAPITokenNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Token not found")
+	case errors.Is(err, auth.ErrBotNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Bot not found")
+	case errors.Is(err, auth.ErrUsernameTaken):
+		return fiber.NewError(fiber.StatusConflict, "Username already taken")
+	case errors.Is(err, auth.ErrTooManyBots):
+		return fiber.NewError(fiber.StatusConflict, "Bot limit reached")
+	}
+	logger.Errorf("%s error: %v", action, err)
+	return fiber.NewError(fiber.StatusInternalServerError, "Failed to "+action)
+}
+
+// parseAPITokenRequest parses and validates a token creation request
+func parseAPITokenRequest(c *fiber.Ctx) (*auth.CreateAPITokenRequest, error) {
+	var req auth.CreateAPITokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	req.Name = validator.SanitizeString(req.Name)
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+	return &req, nil
+}
+
+// ListAPITokens returns the current user's personal API tokens
+// GET /api/v1/auth/tokens
+func (h *AuthHandler) ListAPITokens(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	tokens, err := h.authService.ListAPITokens(c.Context(), userID)
+	if err != nil {
+		return apiTokenError(err, "list tokens")
+	}
+
+	return c.JSON(fiber.Map{
+		"tokens": tokens,
+	})
+}
+
+// CreateAPIToken creates a personal API token. The token is only returned once.
+// POST /api/v1/auth/tokens
+func (h *AuthHandler) CreateAPIToken(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	req, err := parseAPITokenRequest(c)
+	if req == nil {
+		return err
+	}
+
+	token, err := h.authService.CreateAPIToken(c.Context(), userID, req)
+	if err != nil {
+		return apiTokenError(err, "create token")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(token)
+}
+
+// RevokeAPIToken revokes one of the current user's personal API tokens
+// DELETE /api/v1/auth/tokens/:id
+func (h *AuthHandler) RevokeAPIToken(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.authService.RevokeAPIToken(c.Context(), userID, c.Params("id")); err != nil {
+		return apiTokenError(err, "revoke token")
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
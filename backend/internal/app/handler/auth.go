@@ -2,11 +2,14 @@ package handler
 
 import (
 	"errors"
+	"math"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 
 	"github.com/Beretta350/gochat/internal/app/auth"
+	"github.com/Beretta350/gochat/internal/app/middleware"
 	"github.com/Beretta350/gochat/internal/config"
 	"github.com/Beretta350/gochat/pkg/logger"
 	"github.com/Beretta350/gochat/pkg/validator"
@@ -32,15 +35,62 @@ func NewAuthHandler(authService *auth.Service, cfg *config.Config) *AuthHandler
 	}
 }
 
-// setTokenCookies sets HttpOnly cookies for access and refresh tokens
-func (h *AuthHandler) setTokenCookies(c *fiber.Ctx, tokens *auth.TokenPair) {
-	sameSite := fiber.CookieSameSiteLaxMode
+// sameSite maps the configured SameSite mode to Fiber's value
+func (h *AuthHandler) sameSite() string {
 	switch h.config.Cookie.SameSite {
 	case "Strict":
-		sameSite = fiber.CookieSameSiteStrictMode
+		return fiber.CookieSameSiteStrictMode
 	case "None":
-		sameSite = fiber.CookieSameSiteNoneMode
+		return fiber.CookieSameSiteNoneMode
+	}
+	return fiber.CookieSameSiteLaxMode
+}
+
+// setCSRFCookie issues a new double-submit CSRF token.
+// The cookie is readable by JS and the token is also returned in the X-CSRF-Token header
+// for frontends on a different origin that can't read the API's cookies.
+func (h *AuthHandler) setCSRFCookie(c *fiber.Ctx) (string, error) {
+	token, err := middleware.NewCSRFToken()
+	if err != nil {
+		return "", err
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     middleware.CSRFCookie,
+		Value:    token,
+		Path:     "/",
+		Domain:   h.config.Cookie.Domain,
+		MaxAge:   int(h.config.JWT.RefreshExpiry.Seconds()),
+		Secure:   h.config.Cookie.Secure,
+		HTTPOnly: false,
+		SameSite: h.sameSite(),
+	})
+	c.Set(middleware.CSRFHeader, token)
+
+	return token, nil
+}
+
+// loginBlocked answers 429 with a Retry-After header when logins are throttled, nil otherwise
+func loginBlocked(c *fiber.Ctx, err error) error {
+	var blocked *auth.LoginBlockedError
+	if !errors.As(err, &blocked) {
+		return nil
 	}
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+	return fiber.NewError(fiber.StatusTooManyRequests, "Too many failed login attempts, please try again later")
+}
+
+// clientInfo extracts the device info stored with a new session
+func clientInfo(c *fiber.Ctx) auth.ClientInfo {
+	return auth.ClientInfo{
+		IP:        c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	}
+}
+
+// setTokenCookies sets HttpOnly cookies for access and refresh tokens
+func (h *AuthHandler) setTokenCookies(c *fiber.Ctx, tokens *auth.TokenPair) {
+	sameSite := h.sameSite()
 
 	// Access token cookie
 	c.Cookie(&fiber.Cookie{
@@ -65,6 +115,11 @@ func (h *AuthHandler) setTokenCookies(c *fiber.Ctx, tokens *auth.TokenPair) {
 		HTTPOnly: true,
 		SameSite: sameSite,
 	})
+
+	// Rotate the CSRF token together with the session cookies
+	if _, err := h.setCSRFCookie(c); err != nil {
+		logger.Errorf("Failed to issue CSRF token: %v", err)
+	}
 }
 
 // clearTokenCookies clears the auth cookies (for logout)
@@ -88,6 +143,15 @@ func (h *AuthHandler) clearTokenCookies(c *fiber.Ctx) {
 		Expires:  time.Now().Add(-1 * time.Hour),
 		HTTPOnly: true,
 	})
+
+	c.Cookie(&fiber.Cookie{
+		Name:    middleware.CSRFCookie,
+		Value:   "",
+		Path:    "/",
+		Domain:  h.config.Cookie.Domain,
+		MaxAge:  -1,
+		Expires: time.Now().Add(-1 * time.Hour),
+	})
 }
 
 // Register handles user registration
@@ -110,7 +174,7 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		})
 	}
 
-	response, err := h.authService.Register(c.Context(), &req)
+	response, err := h.authService.Register(c.Context(), &req, clientInfo(c))
 	if err != nil {
 		if errors.Is(err, auth.ErrEmailAlreadyExists) {
 			return fiber.NewError(fiber.StatusConflict, "Email already exists")
@@ -149,15 +213,26 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		})
 	}
 
-	response, err := h.authService.Login(c.Context(), &req)
+	response, err := h.authService.Login(c.Context(), &req, clientInfo(c))
 	if err != nil {
 		if errors.Is(err, auth.ErrInvalidCredentials) {
 			return fiber.NewError(fiber.StatusUnauthorized, "Invalid email or password")
 		}
+		if blocked := loginBlocked(c, err); blocked != nil {
+			return blocked
+		}
 		logger.Errorf("Login error: %v", err)
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to login")
 	}
 
+	// Password was right, but a second factor is needed before any session exists
+	if response.MFARequired {
+		return c.JSON(fiber.Map{
+			"mfa_required":    true,
+			"challenge_token": response.ChallengeToken,
+		})
+	}
+
 	// Set HttpOnly cookies
 	h.setTokenCookies(c, response.Tokens)
 
@@ -188,6 +263,10 @@ func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
 			h.clearTokenCookies(c)
 			return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired refresh token")
 		}
+		if errors.Is(err, auth.ErrSessionRevoked) || errors.Is(err, auth.ErrTokenReused) {
+			h.clearTokenCookies(c)
+			return fiber.NewError(fiber.StatusUnauthorized, "Session revoked")
+		}
 		if errors.Is(err, auth.ErrUserNotFound) {
 			h.clearTokenCookies(c)
 			return fiber.NewError(fiber.StatusUnauthorized, "User not found")
@@ -206,15 +285,143 @@ func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
 	})
 }
 
-// Logout handles user logout
+// Logout revokes the current session and clears the cookies
 // POST /api/v1/auth/logout
 func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	if err := h.authService.Logout(c.Context(), c.Cookies(RefreshTokenCookie), c.Cookies(AccessTokenCookie)); err != nil {
+		logger.Errorf("Logout error: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to logout")
+	}
+
+	h.clearTokenCookies(c)
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+// ForgotPassword sends a password reset link by email
+// POST /api/v1/auth/forgot-password
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req auth.ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	req.Email = validator.SanitizeString(req.Email)
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+
+	// Handled in the background: same response, as fast, whether or not the email is registered
+	h.authService.ForgotPassword(&req)
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "If an account exists for this email, a reset link has been sent",
+	})
+}
+
+// ResetPassword sets a new password using an emailed reset token
+// POST /api/v1/auth/reset-password
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req auth.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	req.Token = validator.SanitizeString(req.Token)
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+
+	if err := h.authService.ResetPassword(c.Context(), &req); err != nil {
+		if errors.Is(err, auth.ErrInvalidResetToken) {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired reset token")
+		}
+		logger.Errorf("Reset password error: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to reset password")
+	}
+
+	// Every session was revoked, including this browser's
 	h.clearTokenCookies(c)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+// VerifyEmail confirms an email address with the emailed token
+// POST /api/v1/auth/verify-email
+func (h *AuthHandler) VerifyEmail(c *fiber.Ctx) error {
+	var req auth.VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	req.Token = validator.SanitizeString(req.Token)
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+
+	if err := h.authService.VerifyEmail(c.Context(), &req); err != nil {
+		if errors.Is(err, auth.ErrInvalidVerificationToken) {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired verification token")
+		}
+		logger.Errorf("Verify email error: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to verify email")
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+// ResendVerification sends a new verification link to the current user
+// POST /api/v1/auth/verify-email/resend
+func (h *AuthHandler) ResendVerification(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.authService.ResendVerification(c.Context(), userID); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrEmailAlreadyVerified):
+			return fiber.NewError(fiber.StatusConflict, "Email already verified")
+		case errors.Is(err, auth.ErrUserNotFound):
+			return fiber.NewError(fiber.StatusNotFound, "User not found")
+		}
+		logger.Errorf("Resend verification error: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to send verification email")
+	}
+
 	return c.JSON(fiber.Map{
 		"success": true,
 	})
 }
 
+// CSRF issues a fresh CSRF token (cookie + response body)
+// GET /api/v1/auth/csrf
+func (h *AuthHandler) CSRF(c *fiber.Ctx) error {
+	token, err := h.setCSRFCookie(c)
+	if err != nil {
+		logger.Errorf("CSRF token error: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to issue CSRF token")
+	}
+
+	return c.JSON(fiber.Map{
+		"csrf_token": token,
+	})
+}
+
 // Me returns the current user info
 // GET /api/v1/auth/me
 func (h *AuthHandler) Me(c *fiber.Ctx) error {
@@ -233,3 +440,64 @@ func (h *AuthHandler) Me(c *fiber.Ctx) error {
 		"username": username,
 	})
 }
+
+// ListSessions returns the active sessions (devices) of the current user
+// GET /api/v1/auth/sessions
+func (h *AuthHandler) ListSessions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	sessionID, _ := c.Locals("session_id").(string)
+
+	sessions, err := h.authService.ListSessions(c.Context(), userID, sessionID)
+	if err != nil {
+		logger.Errorf("Failed to list sessions: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to list sessions")
+	}
+
+	return c.JSON(fiber.Map{
+		"sessions": sessions,
+		"count":    len(sessions),
+	})
+}
+
+// RevokeSession logs out one device
+// DELETE /api/v1/auth/sessions/:id
+func (h *AuthHandler) RevokeSession(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	currentSessionID, _ := c.Locals("session_id").(string)
+	sessionID := c.Params("id")
+
+	if err := h.authService.RevokeSession(c.Context(), userID, sessionID); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Session not found")
+		}
+		logger.Errorf("Failed to revoke session: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to revoke session")
+	}
+
+	// Revoking the current session is a logout
+	if sessionID == currentSessionID {
+		h.clearTokenCookies(c)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+// RevokeOtherSessions logs out every device except the current one
+// DELETE /api/v1/auth/sessions
+func (h *AuthHandler) RevokeOtherSessions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	sessionID, _ := c.Locals("session_id").(string)
+
+	count, err := h.authService.RevokeOtherSessions(c.Context(), userID, sessionID)
+	if err != nil {
+		logger.Errorf("Failed to revoke sessions: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to revoke sessions")
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"revoked": count,
+	})
+}
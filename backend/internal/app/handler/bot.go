@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Beretta350/gochat/internal/app/auth"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/validator"
+)
+
+// BotHandler handles bot account management
+type BotHandler struct {
+	authService *auth.Service
+}
+
+// NewBotHandler creates a new bot handler (Fx provider)
+func NewBotHandler(authService *auth.Service) *BotHandler {
+	logger.Info("Bot handler initialized")
+	return &BotHandler{authService: authService}
+}
+
+// Create creates a bot owned by the current user
+// POST /api/v1/bots
+func (h *BotHandler) Create(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req auth.CreateBotRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	req.Username = validator.SanitizeString(req.Username)
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+
+	bot, err := h.authService.CreateBot(c.Context(), userID, &req)
+	if err != nil {
+		return apiTokenError(err, "create bot")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(bot)
+}
+
+// List returns the current user's bots
+// GET /api/v1/bots
+func (h *BotHandler) List(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	bots, err := h.authService.ListBots(c.Context(), userID)
+	if err != nil {
+		return apiTokenError(err, "list bots")
+	}
+
+	return c.JSON(fiber.Map{
+		"bots": bots,
+	})
+}
+
+// Delete deactivates a bot and revokes its tokens
+// DELETE /api/v1/bots/:id
+func (h *BotHandler) Delete(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.authService.DeleteBot(c.Context(), userID, c.Params("id")); err != nil {
+		return apiTokenError(err, "delete bot")
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+// ListTokens returns the API tokens of a bot
+// GET /api/v1/bots/:id/tokens
+func (h *BotHandler) ListTokens(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	tokens, err := h.authService.ListBotTokens(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return apiTokenError(err, "list bot tokens")
+	}
+
+	return c.JSON(fiber.Map{
+		"tokens": tokens,
+	})
+}
+
+// CreateToken creates an API token for a bot. The token is only returned once.
+// POST /api/v1/bots/:id/tokens
+func (h *BotHandler) CreateToken(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	req, err := parseAPITokenRequest(c)
+	if req == nil {
+		return err
+	}
+
+	token, err := h.authService.CreateBotToken(c.Context(), userID, c.Params("id"), req)
+	if err != nil {
+		return apiTokenError(err, "create bot token")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(token)
+}
+
+// RevokeToken revokes an API token of a bot
+// DELETE /api/v1/bots/:id/tokens/:tokenId
+func (h *BotHandler) RevokeToken(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.authService.RevokeBotToken(c.Context(), userID, c.Params("id"), c.Params("tokenId")); err != nil {
+		return apiTokenError(err, "revoke bot token")
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Beretta350/gochat/internal/app/command"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/validator"
+)
+
+// CommandHandler handles the slash command registry and help listing
+type CommandHandler struct {
+	commands *command.Service
+}
+
+// NewCommandHandler creates a new command handler (Fx provider)
+func NewCommandHandler(commands *command.Service) *CommandHandler {
+	logger.Info("Command handler initialized")
+	return &CommandHandler{commands: commands}
+}
+
+// commandError maps command service errors to HTTP errors
+func commandError(err error, action string) error {
+	switch {
+	case errors.Is(err, command.ErrBotNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Bot not found")
+	case errors.Is(err, command.ErrCommandNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Command not found")
+	case errors.Is(err, command.ErrConversationNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Conversation not found")
+	case errors.Is(err, command.ErrNotParticipant):
+		return fiber.NewError(fiber.StatusForbidden, "You are not a participant of this conversation")
+	case errors.Is(err, command.ErrInvalidName):
+		return fiber.NewError(fiber.StatusBadRequest, "Command names use lowercase letters, digits, '-' and '_' (up to 32 characters)")
+	case errors.Is(err, command.ErrReservedName):
+		return fiber.NewError(fiber.StatusConflict, "This name is reserved for a built-in command")
+	case errors.Is(err, command.ErrCommandExists):
+		return fiber.NewError(fiber.StatusConflict, "The bot already has a command with this name")
+	case errors.Is(err, command.ErrInvalidURL):
+		return fiber.NewError(fiber.StatusBadRequest, "Command URL must be a public http(s) URL")
+	case errors.Is(err, command.ErrTooManyCommands):
+		return fiber.NewError(fiber.StatusConflict, "Command limit reached")
+	}
+	logger.Errorf("%s error: %v", action, err)
+	return fiber.NewError(fiber.StatusInternalServerError, "Failed to "+action)
+}
+
+// ListForConversation returns the commands available in a conversation (help listing)
+// GET /api/v1/conversations/:id/commands
+func (h *CommandHandler) ListForConversation(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	commands, err := h.commands.List(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return commandError(err, "list commands")
+	}
+
+	return c.JSON(fiber.Map{
+		"commands": commands,
+	})
+}
+
+// CreateForBot registers a slash command handled by the bot's endpoint.
+// The signing secret is only returned once.
+// POST /api/v1/bots/:id/commands
+func (h *CommandHandler) CreateForBot(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req command.CreateBotCommandRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	req.Name = validator.SanitizeString(req.Name)
+	req.Description = validator.SanitizeString(req.Description)
+	req.Usage = validator.SanitizeString(req.Usage)
+	req.URL = validator.SanitizeString(req.URL)
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+
+	created, err := h.commands.CreateBotCommand(c.Context(), userID, c.Params("id"), &req)
+	if err != nil {
+		return commandError(err, "create command")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// ListForBot returns the commands of a bot
+// GET /api/v1/bots/:id/commands
+func (h *CommandHandler) ListForBot(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	commands, err := h.commands.ListBotCommands(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return commandError(err, "list commands")
+	}
+
+	return c.JSON(fiber.Map{
+		"commands": commands,
+	})
+}
+
+// DeleteForBot removes a command of a bot
+// DELETE /api/v1/bots/:id/commands/:commandId
+func (h *CommandHandler) DeleteForBot(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.commands.DeleteBotCommand(c.Context(), userID, c.Params("id"), c.Params("commandId")); err != nil {
+		return commandError(err, "delete command")
+	}
+
+	return c.JSON(fiber.Map{"success": true})
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/Beretta350/gochat/internal/app/model"
 	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/internal/app/webhook"
 	"github.com/Beretta350/gochat/pkg/logger"
 )
 
@@ -18,11 +19,14 @@ type ConversationHandler struct {
 	userRepo    repository.UserRepository
 	msgRepo     repository.MessageRepository
 	chatService ChatServiceInterface
+	webhooks    *webhook.Service
 }
 
 // ChatServiceInterface defines methods needed from chat service
 type ChatServiceInterface interface {
 	GetOnlineUsersFromList(ctx context.Context, userIDs []string) ([]string, error)
+	PostSystemMessage(ctx context.Context, conversationID string, event *model.SystemEvent, text string) error
+	NotifyCreated(ctx context.Context, actorID string, conv *model.Conversation, participants []model.Participant)
 }
 
 // NewConversationHandler creates a new conversation handler (Fx provider)
@@ -31,6 +35,7 @@ func NewConversationHandler(
 	userRepo repository.UserRepository,
 	msgRepo repository.MessageRepository,
 	chatService ChatServiceInterface,
+	webhooks *webhook.Service,
 ) *ConversationHandler {
 	logger.Info("Conversation handler initialized")
 	return &ConversationHandler{
@@ -38,6 +43,7 @@ func NewConversationHandler(
 		userRepo:    userRepo,
 		msgRepo:     msgRepo,
 		chatService: chatService,
+		webhooks:    webhooks,
 	}
 }
 
@@ -52,6 +58,35 @@ func toParticipantResponses(participants []model.Participant) []*model.Participa
 	return result
 }
 
+// addMuteState tells the user whether they muted the conversation (with /mute)
+func addMuteState(data fiber.Map, participants []model.Participant, userID string) {
+	for _, p := range participants {
+		if p.UserID == userID && p.IsMuted(time.Now()) {
+			data["muted"] = true
+			if p.MutedUntil != nil {
+				data["muted_until"] = p.MutedUntil
+			}
+			return
+		}
+	}
+}
+
+// resolveEmails returns the IDs of the users with the given emails
+func resolveEmails(ctx context.Context, userRepo repository.UserRepository, emails []string) ([]string, error) {
+	ids := make([]string, 0, len(emails))
+	for _, email := range emails {
+		user, err := userRepo.GetByEmail(ctx, email)
+		if err != nil {
+			if errors.Is(err, repository.ErrUserNotFound) {
+				return nil, fiber.NewError(fiber.StatusNotFound, "User with email not found: "+email)
+			}
+			return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to find user")
+		}
+		ids = append(ids, user.ID)
+	}
+	return ids, nil
+}
+
 // CreateDirectRequest represents a request to create a direct conversation
 // Accepts either participant_id (UUID) or participant_email
 type CreateDirectRequest struct {
@@ -59,10 +94,12 @@ type CreateDirectRequest struct {
 	ParticipantEmail string `json:"participant_email"`
 }
 
-// CreateGroupRequest represents a request to create a group conversation
+// CreateGroupRequest represents a request to create a group conversation (or a channel)
 // Accepts either participant_ids (UUIDs) or participant_emails
 type CreateGroupRequest struct {
 	Name              string   `json:"name" validate:"required"`
+	Type              string   `json:"type"`       // group (default) or channel
+	Visibility        string   `json:"visibility"` // private (default) or public
 	ParticipantIDs    []string `json:"participant_ids"`
 	ParticipantEmails []string `json:"participant_emails"`
 }
@@ -99,20 +136,26 @@ func (h *ConversationHandler) Create(c *fiber.Ctx) error {
 	if groupReq.Name == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "Group name is required")
 	}
+	switch model.ConversationType(groupReq.Type) {
+	case "":
+		groupReq.Type = string(model.ConversationTypeGroup)
+	case model.ConversationTypeGroup, model.ConversationTypeChannel:
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, "Type must be group or channel")
+	}
+	switch model.Visibility(groupReq.Visibility) {
+	case "", model.VisibilityPrivate, model.VisibilityPublic:
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, "Visibility must be private or public")
+	}
 
 	// Resolve participant IDs from emails if needed
 	if len(groupReq.ParticipantIDs) == 0 && len(groupReq.ParticipantEmails) > 0 {
-		groupReq.ParticipantIDs = make([]string, 0, len(groupReq.ParticipantEmails))
-		for _, email := range groupReq.ParticipantEmails {
-			user, err := h.userRepo.GetByEmail(c.Context(), email)
-			if err != nil {
-				if errors.Is(err, repository.ErrUserNotFound) {
-					return fiber.NewError(fiber.StatusNotFound, "User with email not found: "+email)
-				}
-				return fiber.NewError(fiber.StatusInternalServerError, "Failed to find user")
-			}
-			groupReq.ParticipantIDs = append(groupReq.ParticipantIDs, user.ID)
+		ids, err := resolveEmails(c.Context(), h.userRepo, groupReq.ParticipantEmails)
+		if err != nil {
+			return err
 		}
+		groupReq.ParticipantIDs = ids
 	}
 
 	if len(groupReq.ParticipantIDs) == 0 {
@@ -158,6 +201,7 @@ func (h *ConversationHandler) createDirect(c *fiber.Ctx, userID, participantID s
 	participants, _ := h.convRepo.GetParticipants(c.Context(), conv.ID)
 
 	logger.Infof("Direct conversation created: %s", conv.ID)
+	h.notifyCreated(c, userID, conv, participants)
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"conversation": conv,
@@ -193,9 +237,10 @@ func (h *ConversationHandler) createGroup(c *fiber.Ctx, userID string, req *Crea
 
 	// Create group conversation
 	conv := &model.Conversation{
-		Type:      model.ConversationTypeGroup,
-		Name:      &req.Name,
-		CreatedBy: &userID,
+		Type:       model.ConversationType(req.Type),
+		Name:       &req.Name,
+		Visibility: model.Visibility(req.Visibility),
+		CreatedBy:  &userID,
 	}
 
 	if err := h.convRepo.Create(c.Context(), conv, participantIDs); err != nil {
@@ -205,7 +250,13 @@ func (h *ConversationHandler) createGroup(c *fiber.Ctx, userID string, req *Crea
 
 	participants, _ := h.convRepo.GetParticipants(c.Context(), conv.ID)
 
-	logger.Infof("Group conversation created: %s (%s)", conv.ID, req.Name)
+	logger.Infof("Group conversation created: %s (%s, %s)", conv.ID, req.Name, req.Type)
+	h.notifyCreated(c, userID, conv, participants)
+
+	created := &model.SystemEvent{Event: model.SystemEventGroupCreated, ActorID: userID, Name: conv.Name}
+	if err := h.chatService.PostSystemMessage(c.Context(), conv.ID, created, `created the `+req.Type+` "`+req.Name+`"`); err != nil {
+		logger.Errorf("Failed to post creation of group %s: %v", conv.ID, err)
+	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"conversation": conv,
@@ -213,6 +264,16 @@ func (h *ConversationHandler) createGroup(c *fiber.Ctx, userID string, req *Crea
 	})
 }
 
+// notifyCreated sends the conversation_created event to the participants (the creator's
+// other devices included) and the conversation.created webhook event (to the bots taking part in it)
+func (h *ConversationHandler) notifyCreated(c *fiber.Ctx, userID string, conv *model.Conversation, participants []model.Participant) {
+	h.chatService.NotifyCreated(c.Context(), userID, conv, participants)
+	h.webhooks.Notify(c.Context(), model.WebhookEventConversationCreated, conv.ID, userID, fiber.Map{
+		"conversation": conv,
+		"participants": toParticipantResponses(participants),
+	})
+}
+
 // List returns all conversations for the authenticated user
 // GET /api/v1/conversations
 func (h *ConversationHandler) List(c *fiber.Ctx) error {
@@ -237,6 +298,7 @@ func (h *ConversationHandler) List(c *fiber.Ctx) error {
 		if lastMessage != nil {
 			convData["last_message"] = lastMessage
 		}
+		addMuteState(convData, participants, userID)
 		result = append(result, convData)
 	}
 
@@ -267,22 +329,25 @@ func (h *ConversationHandler) Get(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get participants")
 	}
 
-	isParticipant := false
-	for _, p := range participants {
-		if p.UserID == userID {
-			isParticipant = true
+	var self *model.Participant
+	for i := range participants {
+		if participants[i].UserID == userID {
+			self = &participants[i]
 			break
 		}
 	}
 
-	if !isParticipant {
+	if self == nil {
 		return fiber.NewError(fiber.StatusForbidden, "You are not a participant of this conversation")
 	}
 
-	return c.JSON(fiber.Map{
+	convData := fiber.Map{
 		"conversation": conv,
 		"participants": toParticipantResponses(participants),
-	})
+		"permissions":  self.Permissions(conv.Type), // What the user may do, to show the right controls
+	}
+	addMuteState(convData, participants, userID)
+	return c.JSON(convData)
 }
 
 // GetMessages returns messages for a conversation with pagination
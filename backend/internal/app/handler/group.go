@@ -0,0 +1,409 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Beretta350/gochat/internal/app/chat"
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/validator"
+)
+
+// GroupHandler handles the management of group conversations
+type GroupHandler struct {
+	chatService *chat.Service
+	userRepo    repository.UserRepository
+}
+
+// NewGroupHandler creates a new group handler (Fx provider)
+func NewGroupHandler(chatService *chat.Service, userRepo repository.UserRepository) *GroupHandler {
+	logger.Info("Group handler initialized")
+	return &GroupHandler{chatService: chatService, userRepo: userRepo}
+}
+
+// AddMembersRequest represents a request to add members to a group
+// Accepts either participant_ids (UUIDs) or participant_emails
+type AddMembersRequest struct {
+	ParticipantIDs    []string `json:"participant_ids" validate:"omitempty,dive,uuid"`
+	ParticipantEmails []string `json:"participant_emails" validate:"omitempty,dive,email"`
+}
+
+// SetRoleRequest represents a request to change the role of a group member
+type SetRoleRequest struct {
+	Role model.ParticipantRole `json:"role" validate:"required,oneof=admin member"`
+}
+
+// groupError maps group management errors to HTTP errors
+func groupError(err error, action string) error {
+	switch {
+	case errors.Is(err, chat.ErrConversationNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Conversation not found")
+	case errors.Is(err, chat.ErrNotParticipant):
+		return fiber.NewError(fiber.StatusForbidden, "You are not a participant of this conversation")
+	case errors.Is(err, chat.ErrNotGroup):
+		return fiber.NewError(fiber.StatusBadRequest, "This conversation is not a group")
+	case errors.Is(err, chat.ErrUserNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Participant not found")
+	case errors.Is(err, chat.ErrAlreadyMember):
+		return fiber.NewError(fiber.StatusConflict, "Already a member of the group")
+	case errors.Is(err, chat.ErrNotMember):
+		return fiber.NewError(fiber.StatusNotFound, "Member not found")
+	case errors.Is(err, chat.ErrForbidden):
+		return fiber.NewError(fiber.StatusForbidden, "Only admins can do this in this group")
+	case errors.Is(err, chat.ErrLastAdmin):
+		return fiber.NewError(fiber.StatusConflict, "Make another member an admin first")
+	case errors.Is(err, chat.ErrInviteNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Invite link not found")
+	case errors.Is(err, chat.ErrInviteExpired):
+		return fiber.NewError(fiber.StatusGone, "This invite link expired")
+	case errors.Is(err, chat.ErrTooManyInvites):
+		return fiber.NewError(fiber.StatusConflict, "Too many invite links, revoke one first")
+	case errors.Is(err, chat.ErrJoinRequestNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Join request not found")
+	case errors.Is(err, chat.ErrJoinRequestPending):
+		return fiber.NewError(fiber.StatusConflict, "You already asked to join this group")
+	case errors.Is(err, chat.ErrJoinRequestDecided):
+		return fiber.NewError(fiber.StatusConflict, "This join request was already decided")
+	}
+	logger.Errorf("%s error: %v", action, err)
+	return fiber.NewError(fiber.StatusInternalServerError, "Failed to "+action)
+}
+
+// Update renames a group and/or changes its description or visibility
+// PATCH /api/v1/conversations/:id
+func (h *GroupHandler) Update(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req model.GroupUpdate
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.Name == nil && req.Description == nil && req.Visibility == nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Nothing to update")
+	}
+
+	if req.Name != nil {
+		name := validator.SanitizeString(*req.Name)
+		req.Name = &name
+	}
+	if req.Description != nil {
+		description := validator.SanitizeString(*req.Description)
+		req.Description = &description
+	}
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+
+	conv, err := h.chatService.UpdateGroup(c.Context(), userID, c.Params("id"), &req)
+	if err != nil {
+		return groupError(err, "update group")
+	}
+
+	return c.JSON(fiber.Map{
+		"conversation": conv,
+	})
+}
+
+// AddMembers adds people or bots to a group
+// POST /api/v1/conversations/:id/participants
+func (h *GroupHandler) AddMembers(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req AddMembersRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+
+	userIDs := req.ParticipantIDs
+	if len(req.ParticipantEmails) > 0 {
+		ids, err := resolveEmails(c.Context(), h.userRepo, req.ParticipantEmails)
+		if err != nil {
+			return err
+		}
+		userIDs = append(userIDs, ids...)
+	}
+	if len(userIDs) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "At least one participant is required")
+	}
+
+	added, err := h.chatService.AddMembers(c.Context(), userID, c.Params("id"), userIDs)
+	if err != nil {
+		return groupError(err, "add members")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"participants": toParticipantResponses(added),
+	})
+}
+
+// RemoveMember removes a member from a group
+// DELETE /api/v1/conversations/:id/participants/:userId
+func (h *GroupHandler) RemoveMember(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.chatService.RemoveMember(c.Context(), userID, c.Params("id"), c.Params("userId")); err != nil {
+		return groupError(err, "remove member")
+	}
+
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// Leave removes the user from a group
+// POST /api/v1/conversations/:id/leave
+func (h *GroupHandler) Leave(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.chatService.LeaveGroup(c.Context(), userID, c.Params("id")); err != nil {
+		return groupError(err, "leave group")
+	}
+
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// SetRole promotes a member to admin or demotes an admin to member
+// PUT /api/v1/conversations/:id/participants/:userId/role
+func (h *GroupHandler) SetRole(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req SetRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+
+	participant, err := h.chatService.SetRole(c.Context(), userID, c.Params("id"), c.Params("userId"), req.Role)
+	if err != nil {
+		return groupError(err, "change role")
+	}
+
+	return c.JSON(fiber.Map{
+		"participant": participant.ToResponse(),
+	})
+}
+
+// CreateInvite creates an invite link to a group
+// POST /api/v1/conversations/:id/invites
+func (h *GroupHandler) CreateInvite(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req model.GroupInviteCreate
+	if err := c.BodyParser(&req); err != nil && len(c.Body()) > 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+
+	invite, err := h.chatService.CreateInvite(c.Context(), userID, c.Params("id"), &req)
+	if err != nil {
+		return groupError(err, "create invite link")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(invite)
+}
+
+// ListInvites returns the invite links of a group
+// GET /api/v1/conversations/:id/invites
+func (h *GroupHandler) ListInvites(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	invites, err := h.chatService.ListInvites(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return groupError(err, "list invite links")
+	}
+
+	return c.JSON(fiber.Map{
+		"invites": invites,
+		"count":   len(invites),
+	})
+}
+
+// RevokeInvite revokes an invite link
+// DELETE /api/v1/conversations/:id/invites/:inviteId
+func (h *GroupHandler) RevokeInvite(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.chatService.RevokeInvite(c.Context(), userID, c.Params("id"), c.Params("inviteId")); err != nil {
+		return groupError(err, "revoke invite link")
+	}
+
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// PreviewInvite shows the group of an invite link before joining it
+// GET /api/v1/invites/:token
+func (h *GroupHandler) PreviewInvite(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	preview, err := h.chatService.PreviewInvite(c.Context(), userID, c.Params("token"))
+	if err != nil {
+		return groupError(err, "preview invite link")
+	}
+
+	return c.JSON(preview)
+}
+
+// JoinByInvite joins the group of an invite link, or asks to join it when the link
+// needs approval (202 Accepted with the join request)
+// POST /api/v1/invites/:token/join
+func (h *GroupHandler) JoinByInvite(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	conv, joinRequest, err := h.chatService.JoinByInvite(c.Context(), userID, c.Params("token"))
+	if err != nil {
+		return groupError(err, "join group")
+	}
+
+	if joinRequest != nil {
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"join_request": joinRequest.ToResponse(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"conversation": conv,
+	})
+}
+
+// Directory lists the public groups, searched by name and description (?q=&limit=&offset=)
+// GET /api/v1/groups
+func (h *GroupHandler) Directory(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	query := validator.SanitizeString(c.Query("q"))
+	if len(query) > 100 {
+		return fiber.NewError(fiber.StatusBadRequest, "Search query is too long")
+	}
+	limit := c.QueryInt("limit", 20)
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 50 {
+		limit = 50
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	groups, err := h.chatService.SearchDirectory(c.Context(), userID, query, limit, offset)
+	if err != nil {
+		return groupError(err, "search groups")
+	}
+
+	return c.JSON(fiber.Map{
+		"groups": groups,
+		"count":  len(groups),
+	})
+}
+
+// JoinPublic joins a public group from the directory
+// POST /api/v1/conversations/:id/join
+func (h *GroupHandler) JoinPublic(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	conv, err := h.chatService.JoinPublic(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return groupError(err, "join group")
+	}
+
+	return c.JSON(fiber.Map{
+		"conversation": conv,
+	})
+}
+
+// RequestToJoin asks the admins of a group to let the user in
+// POST /api/v1/conversations/:id/join-requests
+func (h *GroupHandler) RequestToJoin(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req model.JoinRequestCreate
+	if err := c.BodyParser(&req); err != nil && len(c.Body()) > 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	req.Message = validator.SanitizeString(req.Message)
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+
+	joinRequest, err := h.chatService.RequestToJoin(c.Context(), userID, c.Params("id"), &req)
+	if err != nil {
+		return groupError(err, "request to join")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"join_request": joinRequest.ToResponse(),
+	})
+}
+
+// ListJoinRequests returns the pending join requests of a group
+// GET /api/v1/conversations/:id/join-requests
+func (h *GroupHandler) ListJoinRequests(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	requests, err := h.chatService.ListJoinRequests(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return groupError(err, "list join requests")
+	}
+
+	return c.JSON(fiber.Map{
+		"join_requests": requests,
+		"count":         len(requests),
+	})
+}
+
+// ApproveJoinRequest lets the requester in
+// POST /api/v1/conversations/:id/join-requests/:requestId/approve
+func (h *GroupHandler) ApproveJoinRequest(c *fiber.Ctx) error {
+	return h.decideJoinRequest(c, true)
+}
+
+// DenyJoinRequest refuses a join request
+// POST /api/v1/conversations/:id/join-requests/:requestId/deny
+func (h *GroupHandler) DenyJoinRequest(c *fiber.Ctx) error {
+	return h.decideJoinRequest(c, false)
+}
+
+func (h *GroupHandler) decideJoinRequest(c *fiber.Ctx, approve bool) error {
+	userID := c.Locals("user_id").(string)
+
+	joinRequest, err := h.chatService.DecideJoinRequest(c.Context(), userID, c.Params("id"), c.Params("requestId"), approve)
+	if err != nil {
+		action := "deny join request"
+		if approve {
+			action = "approve join request"
+		}
+		return groupError(err, action)
+	}
+
+	return c.JSON(fiber.Map{
+		"join_request": joinRequest.ToResponse(),
+	})
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Beretta350/gochat/internal/app/auth"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+// JWKSHandler publishes the public JWT signing keys
+type JWKSHandler struct {
+	jwtService *auth.JWTService
+}
+
+// NewJWKSHandler creates a new JWKS handler (Fx provider)
+func NewJWKSHandler(jwtService *auth.JWTService) *JWKSHandler {
+	logger.Info("JWKS handler initialized")
+	return &JWKSHandler{jwtService: jwtService}
+}
+
+// JWKS returns the JSON Web Key Set. New keys are published an hour before
+// they sign tokens, so a few minutes of caching is safe.
+// GET /.well-known/jwks.json
+func (h *JWKSHandler) JWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(h.jwtService.JWKS())
+}
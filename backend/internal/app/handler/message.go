@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Beretta350/gochat/internal/app/chat"
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/validator"
+)
+
+// MessageHandler sends and edits messages over HTTP, for bots and scripts
+// that don't keep a WebSocket open
+type MessageHandler struct {
+	chatService *chat.Service
+}
+
+// NewMessageHandler creates a new message handler (Fx provider)
+func NewMessageHandler(chatService *chat.Service) *MessageHandler {
+	logger.Info("Message handler initialized")
+	return &MessageHandler{chatService: chatService}
+}
+
+// messageError maps chat service errors to HTTP errors
+func messageError(c *fiber.Ctx, err error, action string) error {
+	switch {
+	case errors.Is(err, chat.ErrConversationNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Conversation not found")
+	case errors.Is(err, chat.ErrNotParticipant):
+		return fiber.NewError(fiber.StatusForbidden, "You are not a participant of this conversation")
+	case errors.Is(err, chat.ErrMessageNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Message not found")
+	case errors.Is(err, chat.ErrMessageNotSaved):
+		c.Set(fiber.HeaderRetryAfter, "1")
+		return fiber.NewError(fiber.StatusConflict, "Message not saved yet, please try again in a moment")
+	case errors.Is(err, chat.ErrNotSender):
+		return fiber.NewError(fiber.StatusForbidden, "You can only edit your own messages")
+	case errors.Is(err, chat.ErrReadOnly):
+		return fiber.NewError(fiber.StatusForbidden, "Only admins can post in this channel")
+	case errors.Is(err, chat.ErrForbidden):
+		return fiber.NewError(fiber.StatusForbidden, "You don't have permission to do this in this group")
+	case errors.Is(err, chat.ErrSystemMessage):
+		return fiber.NewError(fiber.StatusConflict, "System messages can't be edited or deleted")
+	case errors.Is(err, chat.ErrTooManyPins):
+		return fiber.NewError(fiber.StatusConflict, "Too many pinned messages, unpin one first")
+	case errors.Is(err, chat.ErrCardsBotsOnly):
+		return fiber.NewError(fiber.StatusForbidden, "Only bots can send cards")
+	case errors.Is(err, chat.ErrInvalidType):
+		return fiber.NewError(fiber.StatusBadRequest, "Unknown message type")
+	case errors.Is(err, chat.ErrInvalidCard), errors.Is(err, chat.ErrUnexpectedMetadata):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	logger.Errorf("%s error: %v", action, err)
+	return fiber.NewError(fiber.StatusInternalServerError, "Failed to "+action)
+}
+
+// Send posts a message to a conversation, like a WebSocket message
+// POST /api/v1/conversations/:id/messages
+func (h *MessageHandler) Send(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req model.MessageCreate
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	req.ConversationID = c.Params("id")
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+
+	client := chat.Client{UserID: userID}
+	client.SessionID, _ = c.Locals("session_id").(string)
+	client.APITokenID, _ = c.Locals("api_token_id").(string)
+	client.Scopes, _ = c.Locals("scopes").([]string)
+
+	msg, reply, err := h.chatService.Send(c.Context(), client, &chat.WebSocketMessage{
+		ConversationID: req.ConversationID,
+		Content:        req.Content,
+		Type:           req.Type,
+		Metadata:       req.Metadata,
+	})
+	if err != nil {
+		return messageError(c, err, "send message")
+	}
+	// A slash command was run instead: nothing was sent by the user
+	if reply != nil {
+		return c.JSON(reply)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(msg)
+}
+
+// Update edits a message sent by the user, e.g. a bot updating its card
+// after an interaction
+// PATCH /api/v1/conversations/:id/messages/:messageId
+func (h *MessageHandler) Update(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req model.MessageUpdate
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.Content == nil && len(req.Metadata) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Nothing to update")
+	}
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+
+	msg, err := h.chatService.UpdateMessage(c.Context(), userID, c.Params("id"), c.Params("messageId"), &req)
+	if err != nil {
+		return messageError(c, err, "update message")
+	}
+
+	return c.JSON(msg)
+}
+
+// Delete deletes a message. Admins can delete anyone's messages in their groups.
+// DELETE /api/v1/conversations/:id/messages/:messageId
+func (h *MessageHandler) Delete(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.chatService.DeleteMessage(c.Context(), userID, c.Params("id"), c.Params("messageId")); err != nil {
+		return messageError(c, err, "delete message")
+	}
+
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// Pin pins a message to the conversation
+// POST /api/v1/conversations/:id/messages/:messageId/pin
+func (h *MessageHandler) Pin(c *fiber.Ctx) error {
+	return h.setPinned(c, true)
+}
+
+// Unpin unpins a message
+// DELETE /api/v1/conversations/:id/messages/:messageId/pin
+func (h *MessageHandler) Unpin(c *fiber.Ctx) error {
+	return h.setPinned(c, false)
+}
+
+func (h *MessageHandler) setPinned(c *fiber.Ctx, pinned bool) error {
+	userID := c.Locals("user_id").(string)
+
+	msg, err := h.chatService.SetPinned(c.Context(), userID, c.Params("id"), c.Params("messageId"), pinned)
+	if err != nil {
+		action := "unpin message"
+		if pinned {
+			action = "pin message"
+		}
+		return messageError(c, err, action)
+	}
+
+	return c.JSON(msg)
+}
+
+// ListPinned returns the pinned messages of a conversation, most recently pinned first
+// GET /api/v1/conversations/:id/pins
+func (h *MessageHandler) ListPinned(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	messages, err := h.chatService.PinnedMessages(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return messageError(c, err, "list pinned messages")
+	}
+
+	return c.JSON(fiber.Map{
+		"messages": messages,
+		"count":    len(messages),
+	})
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Beretta350/gochat/internal/app/auth"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+// oidcStateCookie binds an SSO login to the browser that started it (login CSRF protection)
+const oidcStateCookie = "oidc_state"
+
+// oidcErrorCode maps SSO errors to the code passed to the frontend login page
+func oidcErrorCode(err error) string {
+	switch {
+	case errors.Is(err, auth.ErrOIDCInvalidState):
+		return "sso_expired"
+	case errors.Is(err, auth.ErrOIDCEmailMissing):
+		return "sso_email_missing"
+	case errors.Is(err, auth.ErrOIDCEmailNotVerified):
+		return "sso_email_not_verified"
+	case errors.Is(err, auth.ErrOIDCFailed):
+		return "sso_failed"
+	}
+	logger.Errorf("SSO login error: %v", err)
+	return "sso_error"
+}
+
+// redirectToLogin sends the browser back to the frontend login page with an error code
+func (h *AuthHandler) redirectToLogin(c *fiber.Ctx, code string) error {
+	return c.Redirect(h.config.Server.FrontendURL+"/login?error="+url.QueryEscape(code), fiber.StatusFound)
+}
+
+// setOIDCStateCookie sets (or clears, with an empty state) the SSO state cookie.
+// Lax is required: the callback is a cross-site navigation from the provider.
+func (h *AuthHandler) setOIDCStateCookie(c *fiber.Ctx, state string) {
+	maxAge := int(h.config.OIDC.StateExpiry.Seconds())
+	if state == "" {
+		maxAge = -1
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/v1/auth/oidc",
+		Domain:   h.config.Cookie.Domain,
+		MaxAge:   maxAge,
+		Secure:   h.config.Cookie.Secure,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// OIDCProviders lists the configured SSO providers
+// GET /api/v1/auth/oidc/providers
+func (h *AuthHandler) OIDCProviders(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"providers": h.authService.OIDCProviders(),
+	})
+}
+
+// OIDCLogin redirects the browser to the identity provider
+// GET /api/v1/auth/oidc/:provider/login?redirect=/chat
+func (h *AuthHandler) OIDCLogin(c *fiber.Ctx) error {
+	authURL, state, err := h.authService.BeginOIDCLogin(c.Context(), c.Params("provider"), c.Query("redirect"))
+	if err != nil {
+		if errors.Is(err, auth.ErrOIDCProviderNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Unknown identity provider")
+		}
+		return h.redirectToLogin(c, oidcErrorCode(err))
+	}
+
+	h.setOIDCStateCookie(c, state)
+
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// OIDCCallback completes the SSO login and redirects back to the frontend
+// GET /api/v1/auth/oidc/:provider/callback
+func (h *AuthHandler) OIDCCallback(c *fiber.Ctx) error {
+	state := c.Query("state")
+	cookieState := c.Cookies(oidcStateCookie)
+	h.setOIDCStateCookie(c, "")
+
+	// The user cancelled or the provider refused the login
+	if providerErr := c.Query("error"); providerErr != "" {
+		logger.Warnf("SSO login with %s returned error %q: %s", c.Params("provider"), providerErr, c.Query("error_description"))
+		return h.redirectToLogin(c, "sso_cancelled")
+	}
+
+	if state == "" || c.Query("code") == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookieState)) != 1 {
+		return h.redirectToLogin(c, "sso_expired")
+	}
+
+	login, err := h.authService.FinishOIDCLogin(c.Context(), c.Params("provider"), c.Query("code"), state, clientInfo(c))
+	if err != nil {
+		if errors.Is(err, auth.ErrOIDCProviderNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Unknown identity provider")
+		}
+		return h.redirectToLogin(c, oidcErrorCode(err))
+	}
+
+	// 2FA still applies: the login page exchanges the challenge with /auth/2fa/verify.
+	// The token goes in the fragment so it isn't sent to servers or logged.
+	if login.MFARequired {
+		return c.Redirect(h.config.Server.FrontendURL+"/login#mfa_challenge="+url.QueryEscape(login.ChallengeToken), fiber.StatusFound)
+	}
+
+	// Set HttpOnly cookies
+	h.setTokenCookies(c, login.Tokens)
+
+	return c.Redirect(h.config.Server.FrontendURL+login.Redirect, fiber.StatusFound)
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Beretta350/gochat/internal/app/auth"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/validator"
+)
+
+// passkeyError maps passkey errors to HTTP errors
+func passkeyError(err error, action string) error {
+	switch {
+	case errors.Is(err, auth.ErrPasskeyFailed):
+		return fiber.NewError(fiber.StatusUnauthorized, "Passkey verification failed")
+	case errors.Is(err, auth.ErrCeremonyExpired):
+		return fiber.NewError(fiber.StatusBadRequest, "Passkey ceremony expired, please try again")
+	case errors.Is(err, auth.ErrPasskeyAlreadyExists):
+		return fiber.NewError(fiber.StatusConflict, "Passkey already registered")
+	case errors.Is(err, auth.ErrPasskeyNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Passkey not found")
+	case errors.Is(err, auth.ErrUserNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+	logger.Errorf("%s error: %v", action, err)
+	return fiber.NewError(fiber.StatusInternalServerError, "Failed to "+action)
+}
+
+// BeginPasskeyRegistration returns the options for navigator.credentials.create()
+// POST /api/v1/auth/passkeys/register/begin
+func (h *AuthHandler) BeginPasskeyRegistration(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	options, err := h.authService.BeginPasskeyRegistration(c.Context(), userID)
+	if err != nil {
+		return passkeyError(err, "start passkey registration")
+	}
+
+	return c.JSON(options)
+}
+
+// FinishPasskeyRegistration verifies and stores a new passkey
+// POST /api/v1/auth/passkeys/register/finish
+func (h *AuthHandler) FinishPasskeyRegistration(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req auth.FinishPasskeyRegistrationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	req.Name = validator.SanitizeString(req.Name)
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+
+	passkey, err := h.authService.FinishPasskeyRegistration(c.Context(), userID, &req, clientInfo(c))
+	if err != nil {
+		return passkeyError(err, "register passkey")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(passkey)
+}
+
+// BeginPasskeyLogin returns the options for navigator.credentials.get()
+// POST /api/v1/auth/passkeys/login/begin
+func (h *AuthHandler) BeginPasskeyLogin(c *fiber.Ctx) error {
+	options, err := h.authService.BeginPasskeyLogin(c.Context())
+	if err != nil {
+		return passkeyError(err, "start passkey login")
+	}
+
+	return c.JSON(options)
+}
+
+// FinishPasskeyLogin verifies the passkey and logs the user in
+// POST /api/v1/auth/passkeys/login/finish
+func (h *AuthHandler) FinishPasskeyLogin(c *fiber.Ctx) error {
+	var req auth.FinishPasskeyLoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+
+	response, err := h.authService.FinishPasskeyLogin(c.Context(), &req, clientInfo(c))
+	if err != nil {
+		return passkeyError(err, "login with passkey")
+	}
+
+	// Set HttpOnly cookies
+	h.setTokenCookies(c, response.Tokens)
+
+	// Return user info (tokens are in cookies, not in response body)
+	return c.JSON(fiber.Map{
+		"user": response.User,
+	})
+}
+
+// ListPasskeys returns the current user's passkeys
+// GET /api/v1/auth/passkeys
+func (h *AuthHandler) ListPasskeys(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	passkeys, err := h.authService.ListPasskeys(c.Context(), userID)
+	if err != nil {
+		return passkeyError(err, "list passkeys")
+	}
+
+	return c.JSON(fiber.Map{
+		"passkeys": passkeys,
+	})
+}
+
+// DeletePasskey removes one of the current user's passkeys
+// DELETE /api/v1/auth/passkeys/:id
+func (h *AuthHandler) DeletePasskey(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.authService.DeletePasskey(c.Context(), userID, c.Params("id")); err != nil {
+		return passkeyError(err, "delete passkey")
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Beretta350/gochat/internal/app/auth"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/validator"
+)
+
+// twoFactorError maps 2FA errors to HTTP errors
+func twoFactorError(err error, action string) error {
+	switch {
+	case errors.Is(err, auth.ErrTwoFactorAlreadyEnabled):
+		return fiber.NewError(fiber.StatusConflict, "Two-factor authentication already enabled")
+	case errors.Is(err, auth.ErrTwoFactorNotEnabled):
+		return fiber.NewError(fiber.StatusBadRequest, "Two-factor authentication not enabled")
+	case errors.Is(err, auth.ErrTwoFactorNotEnrolled):
+		return fiber.NewError(fiber.StatusBadRequest, "Two-factor enrollment not started")
+	case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid two-factor code")
+	case errors.Is(err, auth.ErrInvalidChallenge):
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired challenge")
+	case errors.Is(err, auth.ErrTooManyAttempts):
+		return fiber.NewError(fiber.StatusTooManyRequests, "Too many attempts, please log in again")
+	case errors.Is(err, auth.ErrUserNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+	logger.Errorf("%s error: %v", action, err)
+	return fiber.NewError(fiber.StatusInternalServerError, "Failed to "+action)
+}
+
+// EnrollTwoFactor starts 2FA enrollment and returns the TOTP secret
+// POST /api/v1/auth/2fa/enroll
+func (h *AuthHandler) EnrollTwoFactor(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	enrollment, err := h.authService.EnrollTwoFactor(c.Context(), userID)
+	if err != nil {
+		return twoFactorError(err, "enroll two-factor authentication")
+	}
+
+	return c.JSON(enrollment)
+}
+
+// ConfirmTwoFactor enables 2FA with a first code and returns the recovery codes
+// POST /api/v1/auth/2fa/confirm
+func (h *AuthHandler) ConfirmTwoFactor(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req auth.ConfirmTwoFactorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	req.Code = validator.SanitizeString(req.Code)
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+
+	codes, err := h.authService.ConfirmTwoFactor(c.Context(), userID, &req)
+	if err != nil {
+		return twoFactorError(err, "confirm two-factor authentication")
+	}
+
+	return c.JSON(fiber.Map{
+		"success":        true,
+		"recovery_codes": codes,
+	})
+}
+
+// VerifyTwoFactor completes a login that returned mfa_required
+// POST /api/v1/auth/2fa/verify
+func (h *AuthHandler) VerifyTwoFactor(c *fiber.Ctx) error {
+	var req auth.VerifyTwoFactorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	req.Code = validator.SanitizeString(req.Code)
+	req.RecoveryCode = validator.SanitizeString(req.RecoveryCode)
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+
+	response, err := h.authService.VerifyTwoFactor(c.Context(), &req, clientInfo(c))
+	if err != nil {
+		if blocked := loginBlocked(c, err); blocked != nil {
+			return blocked
+		}
+		return twoFactorError(err, "verify two-factor code")
+	}
+
+	// Set HttpOnly cookies
+	h.setTokenCookies(c, response.Tokens)
+
+	// Return user info (tokens are in cookies, not in response body)
+	return c.JSON(fiber.Map{
+		"user": response.User,
+	})
+}
+
+// DisableTwoFactor turns 2FA off (requires the password and a code)
+// POST /api/v1/auth/2fa/disable
+func (h *AuthHandler) DisableTwoFactor(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req auth.DisableTwoFactorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	req.Code = validator.SanitizeString(req.Code)
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+
+	if err := h.authService.DisableTwoFactor(c.Context(), userID, &req); err != nil {
+		// Same answer for a wrong password or code; 403 so clients don't try a token refresh
+		if errors.Is(err, auth.ErrInvalidCredentials) || errors.Is(err, auth.ErrInvalidTwoFactorCode) {
+			return fiber.NewError(fiber.StatusForbidden, "Invalid password or code")
+		}
+		return twoFactorError(err, "disable two-factor authentication")
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
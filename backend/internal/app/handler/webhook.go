@@ -0,0 +1,275 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Beretta350/gochat/internal/app/webhook"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/validator"
+)
+
+// WebhookHandler handles outgoing webhook subscriptions
+type WebhookHandler struct {
+	webhooks *webhook.Service
+}
+
+// NewWebhookHandler creates a new webhook handler (Fx provider)
+func NewWebhookHandler(webhooks *webhook.Service) *WebhookHandler {
+	logger.Info("Webhook handler initialized")
+	return &WebhookHandler{webhooks: webhooks}
+}
+
+// webhookError maps webhook service errors to HTTP errors
+func webhookError(err error, action string) error {
+	switch {
+	case errors.Is(err, webhook.ErrWebhookNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Webhook not found")
+	case errors.Is(err, webhook.ErrBotNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Bot not found")
+	case errors.Is(err, webhook.ErrNotParticipant):
+		return fiber.NewError(fiber.StatusForbidden, "You are not a participant of this conversation")
+	case errors.Is(err, webhook.ErrNotAllowed):
+		return fiber.NewError(fiber.StatusForbidden, "Only admins can manage the webhooks of this conversation")
+	case errors.Is(err, webhook.ErrInvalidURL):
+		return fiber.NewError(fiber.StatusBadRequest, "Webhook URL must be a public http(s) URL")
+	case errors.Is(err, webhook.ErrTooManyWebhooks):
+		return fiber.NewError(fiber.StatusConflict, "Webhook limit reached")
+	case errors.Is(err, webhook.ErrIncomingWebhookNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Webhook not found")
+	case errors.Is(err, webhook.ErrNotGroup):
+		return fiber.NewError(fiber.StatusBadRequest, "Incoming webhooks can only post into groups and channels")
+	case errors.Is(err, webhook.ErrEmptyMessage):
+		return fiber.NewError(fiber.StatusBadRequest, "text is required")
+	case errors.Is(err, webhook.ErrMessageTooLong):
+		return fiber.NewError(fiber.StatusBadRequest, "text must be at most "+strconv.Itoa(webhook.MaxIncomingMessageLength)+" characters")
+	case errors.Is(err, webhook.ErrBotNotParticipant):
+		return fiber.NewError(fiber.StatusForbidden, "The bot is no longer a participant of this conversation")
+	case errors.Is(err, webhook.ErrCannotAddBot):
+		return fiber.NewError(fiber.StatusForbidden, "You are not allowed to add the bot to this group")
+	}
+	logger.Errorf("%s error: %v", action, err)
+	return fiber.NewError(fiber.StatusInternalServerError, "Failed to "+action)
+}
+
+// parseWebhookRequest parses and validates a webhook creation request
+func parseWebhookRequest(c *fiber.Ctx) (*webhook.CreateWebhookRequest, error) {
+	var req webhook.CreateWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	req.URL = validator.SanitizeString(req.URL)
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+	return &req, nil
+}
+
+// CreateForConversation subscribes a URL to the events of a conversation.
+// The signing secret is only returned once.
+// POST /api/v1/conversations/:id/webhooks
+func (h *WebhookHandler) CreateForConversation(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	req, err := parseWebhookRequest(c)
+	if req == nil {
+		return err
+	}
+
+	created, err := h.webhooks.CreateConversationWebhook(c.Context(), userID, c.Params("id"), req)
+	if err != nil {
+		return webhookError(err, "create webhook")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// ListForConversation returns the webhooks of a conversation
+// GET /api/v1/conversations/:id/webhooks
+func (h *WebhookHandler) ListForConversation(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	webhooks, err := h.webhooks.ListConversationWebhooks(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return webhookError(err, "list webhooks")
+	}
+
+	return c.JSON(fiber.Map{
+		"webhooks": webhooks,
+	})
+}
+
+// CreateForBot subscribes a URL to the events of every conversation a bot is in.
+// The signing secret is only returned once.
+// POST /api/v1/bots/:id/webhooks
+func (h *WebhookHandler) CreateForBot(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	req, err := parseWebhookRequest(c)
+	if req == nil {
+		return err
+	}
+
+	created, err := h.webhooks.CreateBotWebhook(c.Context(), userID, c.Params("id"), req)
+	if err != nil {
+		return webhookError(err, "create webhook")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// ListForBot returns the webhooks of a bot
+// GET /api/v1/bots/:id/webhooks
+func (h *WebhookHandler) ListForBot(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	webhooks, err := h.webhooks.ListBotWebhooks(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return webhookError(err, "list webhooks")
+	}
+
+	return c.JSON(fiber.Map{
+		"webhooks": webhooks,
+	})
+}
+
+// Delete deletes a webhook
+// DELETE /api/v1/webhooks/:id
+func (h *WebhookHandler) Delete(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.webhooks.DeleteWebhook(c.Context(), userID, c.Params("id")); err != nil {
+		return webhookError(err, "delete webhook")
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+// Enable re-enables a webhook disabled after repeated failures
+// POST /api/v1/webhooks/:id/enable
+func (h *WebhookHandler) Enable(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	webhook, err := h.webhooks.EnableWebhook(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return webhookError(err, "enable webhook")
+	}
+
+	return c.JSON(webhook)
+}
+
+// Deliveries returns the delivery log of a webhook (?limit=50)
+// GET /api/v1/webhooks/:id/deliveries
+func (h *WebhookHandler) Deliveries(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+
+	deliveries, err := h.webhooks.ListDeliveries(c.Context(), userID, c.Params("id"), limit)
+	if err != nil {
+		return webhookError(err, "list deliveries")
+	}
+
+	return c.JSON(fiber.Map{
+		"deliveries": deliveries,
+	})
+}
+
+// CreateIncoming creates an incoming webhook posting into a group as one of the user's bots.
+// The token (and URL) is only returned once.
+// POST /api/v1/conversations/:id/incoming-webhooks
+func (h *WebhookHandler) CreateIncoming(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req webhook.CreateIncomingWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	req.Name = validator.SanitizeString(req.Name)
+
+	if validationErrors := validator.Struct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"errors": validationErrors,
+		})
+	}
+
+	created, err := h.webhooks.CreateIncomingWebhook(c.Context(), userID, c.Params("id"), &req)
+	if err != nil {
+		return webhookError(err, "create incoming webhook")
+	}
+	created.URL = c.BaseURL() + "/api/v1/hooks/" + created.Token
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// ListIncoming returns the incoming webhooks of a conversation
+// GET /api/v1/conversations/:id/incoming-webhooks
+func (h *WebhookHandler) ListIncoming(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	webhooks, err := h.webhooks.ListIncomingWebhooks(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return webhookError(err, "list incoming webhooks")
+	}
+
+	return c.JSON(fiber.Map{
+		"webhooks": webhooks,
+	})
+}
+
+// DeleteIncoming deletes an incoming webhook
+// DELETE /api/v1/incoming-webhooks/:id
+func (h *WebhookHandler) DeleteIncoming(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	if err := h.webhooks.DeleteIncomingWebhook(c.Context(), userID, c.Params("id")); err != nil {
+		return webhookError(err, "delete incoming webhook")
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+// Receive posts a message through an incoming webhook. The secret token in the path
+// is the only authentication. Accepts a JSON body, or a form with a "payload" JSON
+// field like Slack's legacy integrations.
+// POST /api/v1/hooks/:token
+func (h *WebhookHandler) Receive(c *fiber.Ctx) error {
+	var msg webhook.IncomingMessage
+	if payload := c.FormValue("payload"); payload != "" {
+		if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid payload")
+		}
+	} else if err := c.BodyParser(&msg); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	out, err := h.webhooks.PostIncoming(c.Context(), c.Params("token"), &msg)
+	if err != nil {
+		return webhookError(err, "post message")
+	}
+
+	return c.JSON(fiber.Map{
+		"ok":         true,
+		"message_id": out.ID,
+	})
+}
@@ -2,33 +2,56 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"strings"
 
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 
 	"github.com/Beretta350/gochat/internal/app/auth"
 	"github.com/Beretta350/gochat/internal/app/chat"
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/config"
 	"github.com/Beretta350/gochat/pkg/logger"
 )
 
 // WebSocketHandler handles WebSocket connections
 type WebSocketHandler struct {
 	chatService *chat.Service
-	jwtService  *auth.JWTService
+	authService *auth.Service
+	config      *config.Config
 }
 
 // NewWebSocketHandler creates a new WebSocket handler (Fx provider)
-func NewWebSocketHandler(chatService *chat.Service, jwtService *auth.JWTService) *WebSocketHandler {
+func NewWebSocketHandler(chatService *chat.Service, authService *auth.Service, cfg *config.Config) *WebSocketHandler {
 	logger.Info("WebSocket handler initialized")
 	return &WebSocketHandler{
 		chatService: chatService,
-		jwtService:  jwtService,
+		authService: authService,
+		config:      cfg,
 	}
 }
 
+// checkOrigin rejects cross-site WebSocket hijacking: browsers always send Origin
+// on upgrade and attach our cookies, so it must be same-host or in ALLOWED_ORIGINS.
+// Non-browser clients don't send Origin and authenticate with the query token.
+func (h *WebSocketHandler) checkOrigin(c *fiber.Ctx) bool {
+	origin := c.Get(fiber.HeaderOrigin)
+	if origin == "" {
+		return true
+	}
+	return config.IsSameHost(origin, c.Hostname()) || h.config.CORS.IsOriginAllowed(origin)
+}
+
 // Upgrade is a middleware that checks if the request is a WebSocket upgrade
 func (h *WebSocketHandler) Upgrade(c *fiber.Ctx) error {
 	if websocket.IsWebSocketUpgrade(c) {
+		if !h.checkOrigin(c) {
+			logger.Warnf("WebSocket upgrade rejected for origin %s", c.Get(fiber.HeaderOrigin))
+			return fiber.NewError(fiber.StatusForbidden, "Origin not allowed")
+		}
+
 		// Try to get token from cookie first (preferred)
 		token := c.Cookies(AccessTokenCookie)
 
@@ -37,15 +60,29 @@ func (h *WebSocketHandler) Upgrade(c *fiber.Ctx) error {
 			token = c.Query("token")
 		}
 
+		// Bots and scripts may also send their API token as a bearer header
+		if token == "" {
+			if header := c.Get(fiber.HeaderAuthorization); len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+				token = header[7:]
+			}
+		}
+
 		if token == "" {
 			return fiber.NewError(fiber.StatusUnauthorized, "Missing token")
 		}
 
-		// Validate JWT token
-		claims, err := h.jwtService.ValidateAccessToken(token)
+		if auth.IsAPIToken(token) {
+			return h.upgradeAPIToken(c, token)
+		}
+
+		// Validate JWT token and session
+		claims, err := h.authService.ValidateAccessToken(c.Context(), token)
 		if err != nil {
-			if err == auth.ErrExpiredToken {
+			switch {
+			case errors.Is(err, auth.ErrExpiredToken):
 				return fiber.NewError(fiber.StatusUnauthorized, "Token expired")
+			case errors.Is(err, auth.ErrSessionRevoked):
+				return fiber.NewError(fiber.StatusUnauthorized, "Session revoked")
 			}
 			return fiber.NewError(fiber.StatusUnauthorized, "Invalid token")
 		}
@@ -54,12 +91,37 @@ func (h *WebSocketHandler) Upgrade(c *fiber.Ctx) error {
 		c.Locals("user_id", claims.UserID)
 		c.Locals("email", claims.Email)
 		c.Locals("username", claims.Username)
+		c.Locals("session_id", claims.SessionID)
 
 		return c.Next()
 	}
 	return fiber.ErrUpgradeRequired
 }
 
+// upgradeAPIToken authenticates a WebSocket upgrade with an API token.
+// Receiving messages needs conversations:read; sending is checked per message (messages:write).
+func (h *WebSocketHandler) upgradeAPIToken(c *fiber.Ctx, token string) error {
+	principal, err := h.authService.ValidateAPIToken(c.Context(), token)
+	if err != nil {
+		if !errors.Is(err, auth.ErrInvalidAPIToken) {
+			logger.Errorf("Error validating API token: %v", err)
+		}
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid token")
+	}
+
+	if !slices.Contains(principal.Scopes, model.ScopeConversationsRead) {
+		return fiber.NewError(fiber.StatusForbidden, "Token is missing the "+model.ScopeConversationsRead+" scope")
+	}
+
+	c.Locals("user_id", principal.UserID)
+	c.Locals("email", principal.Email)
+	c.Locals("username", principal.Username)
+	c.Locals("api_token_id", principal.TokenID)
+	c.Locals("scopes", principal.Scopes)
+
+	return c.Next()
+}
+
 // Handle handles WebSocket connections
 func (h *WebSocketHandler) Handle(ctx context.Context) func(*websocket.Conn) {
 	return func(c *websocket.Conn) {
@@ -80,9 +142,14 @@ func (h *WebSocketHandler) Handle(ctx context.Context) func(*websocket.Conn) {
 			return
 		}
 
+		client := chat.Client{UserID: userIDStr}
+		client.SessionID, _ = c.Locals("session_id").(string)
+		client.APITokenID, _ = c.Locals("api_token_id").(string)
+		client.Scopes, _ = c.Locals("scopes").([]string)
+
 		requestID := c.Query("request_id", "unknown")
 		logger.Infof("[%s] WebSocket connection: %s (%v)", requestID, userIDStr, username)
 
-		h.chatService.HandleConnection(ctx, c, userIDStr)
+		h.chatService.HandleConnection(ctx, c, client)
 	}
 }
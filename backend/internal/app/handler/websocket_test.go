@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Beretta350/gochat/internal/config"
+)
+
+func TestWebSocketUpgradeChecksOrigin(t *testing.T) {
+	tests := []struct {
+		name       string
+		origin     string
+		wantStatus int
+	}{
+		// Past the origin check, the upgrade stops at the missing token
+		{name: "allowed origin", origin: "https://app.example.com", wantStatus: fiber.StatusUnauthorized},
+		{name: "same host", origin: "http://example.com", wantStatus: fiber.StatusUnauthorized},
+		{name: "no origin (not a browser)", wantStatus: fiber.StatusUnauthorized},
+		{name: "other site", origin: "https://evil.example.net", wantStatus: fiber.StatusForbidden},
+		{name: "lookalike", origin: "https://app.example.com.evil.example.net", wantStatus: fiber.StatusForbidden},
+	}
+
+	h := NewWebSocketHandler(nil, nil, &config.Config{CORS: config.CORSConfig{AllowedOrigins: "https://app.example.com"}})
+	app := fiber.New()
+	app.Get("/ws", h.Upgrade, func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, "http://example.com/ws", nil)
+			req.Header.Set(fiber.HeaderConnection, "Upgrade")
+			req.Header.Set(fiber.HeaderUpgrade, "websocket")
+			if tt.origin != "" {
+				req.Header.Set(fiber.HeaderOrigin, tt.origin)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
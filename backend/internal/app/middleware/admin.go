@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Beretta350/gochat/internal/app/auth"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+// RequireAdmin rejects users who aren't administrators.
+// Must run after AuthMiddleware.
+func RequireAdmin(authService *auth.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user_id").(string)
+		isAdmin, err := authService.IsAdmin(c.Context(), userID)
+		if err != nil {
+			logger.Errorf("Error checking admin role for user %s: %v", userID, err)
+			return fiber.NewError(fiber.StatusUnauthorized, "User not found")
+		}
+		if !isAdmin {
+			return fiber.NewError(fiber.StatusForbidden, "Admin access required")
+		}
+
+		return c.Next()
+	}
+}
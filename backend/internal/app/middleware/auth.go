@@ -1,53 +1,72 @@
 package middleware
 
 import (
+	"errors"
+	"slices"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 
 	"github.com/Beretta350/gochat/internal/app/auth"
+	"github.com/Beretta350/gochat/pkg/logger"
 )
 
 const (
-	AccessTokenCookie = "access_token"
+	AccessTokenCookie  = "access_token"
+	RefreshTokenCookie = "refresh_token"
 )
 
-// getTokenFromRequest extracts the JWT token from cookie or Authorization header
-func getTokenFromRequest(c *fiber.Ctx) string {
-	// First, try to get token from cookie (preferred, more secure)
-	token := c.Cookies(AccessTokenCookie)
-	if token != "" {
-		return token
-	}
-
-	// Fallback to Authorization header (for backward compatibility / API clients)
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header
+func bearerToken(c *fiber.Ctx) (string, bool) {
 	authHeader := c.Get("Authorization")
 	if authHeader == "" {
-		return ""
+		return "", false
 	}
 
 	// Check Bearer prefix
 	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-		return ""
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" || parts[1] == "" {
+		return "", false
+	}
+
+	return parts[1], true
+}
+
+// getTokenFromRequest extracts the JWT token from Authorization header or cookie.
+// An explicit bearer token wins so that CSRF exemption for bearer clients
+// always matches the credential that is actually used.
+func getTokenFromRequest(c *fiber.Ctx) string {
+	// API clients send the token explicitly
+	if token, ok := bearerToken(c); ok {
+		return token
 	}
 
-	return parts[1]
+	// Browsers authenticate with the HttpOnly cookie
+	return c.Cookies(AccessTokenCookie)
 }
 
-// AuthMiddleware creates a JWT authentication middleware
-func AuthMiddleware(jwtService *auth.JWTService) fiber.Handler {
+// AuthMiddleware creates an authentication middleware. Sessions (JWT) can use every route.
+// API tokens are only accepted when the route declares scopes, and must grant all of them:
+// account routes (sessions, 2FA, tokens...) are never reachable with an API token.
+func AuthMiddleware(authService *auth.Service, scopes ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		tokenString := getTokenFromRequest(c)
 		if tokenString == "" {
 			return fiber.NewError(fiber.StatusUnauthorized, "Missing authentication")
 		}
 
-		// Validate token
-		claims, err := jwtService.ValidateAccessToken(tokenString)
+		if auth.IsAPIToken(tokenString) {
+			return authenticateAPIToken(c, authService, tokenString, scopes)
+		}
+
+		// Validate token and session
+		claims, err := authService.ValidateAccessToken(c.Context(), tokenString)
 		if err != nil {
-			if err == auth.ErrExpiredToken {
+			switch {
+			case errors.Is(err, auth.ErrExpiredToken):
 				return fiber.NewError(fiber.StatusUnauthorized, "Token expired")
+			case errors.Is(err, auth.ErrSessionRevoked):
+				return fiber.NewError(fiber.StatusUnauthorized, "Session revoked")
 			}
 			return fiber.NewError(fiber.StatusUnauthorized, "Invalid token")
 		}
@@ -56,20 +75,50 @@ func AuthMiddleware(jwtService *auth.JWTService) fiber.Handler {
 		c.Locals("user_id", claims.UserID)
 		c.Locals("email", claims.Email)
 		c.Locals("username", claims.Username)
+		c.Locals("session_id", claims.SessionID)
 
 		return c.Next()
 	}
 }
 
+// authenticateAPIToken validates an API token and checks it grants the route's scopes
+func authenticateAPIToken(c *fiber.Ctx, authService *auth.Service, token string, scopes []string) error {
+	if len(scopes) == 0 {
+		return fiber.NewError(fiber.StatusForbidden, "API tokens can't be used on this endpoint")
+	}
+
+	principal, err := authService.ValidateAPIToken(c.Context(), token)
+	if err != nil {
+		if !errors.Is(err, auth.ErrInvalidAPIToken) {
+			logger.Errorf("Error validating API token: %v", err)
+		}
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid token")
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(principal.Scopes, scope) {
+			return fiber.NewError(fiber.StatusForbidden, "Token is missing the "+scope+" scope")
+		}
+	}
+
+	c.Locals("user_id", principal.UserID)
+	c.Locals("email", principal.Email)
+	c.Locals("username", principal.Username)
+	c.Locals("api_token_id", principal.TokenID)
+	c.Locals("scopes", principal.Scopes)
+
+	return c.Next()
+}
+
 // OptionalAuthMiddleware extracts user info if token is present, but doesn't require it
-func OptionalAuthMiddleware(jwtService *auth.JWTService) fiber.Handler {
+func OptionalAuthMiddleware(authService *auth.Service) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		tokenString := getTokenFromRequest(c)
 		if tokenString == "" {
 			return c.Next()
 		}
 
-		claims, err := jwtService.ValidateAccessToken(tokenString)
+		claims, err := authService.ValidateAccessToken(c.Context(), tokenString)
 		if err != nil {
 			return c.Next()
 		}
@@ -77,6 +126,7 @@ func OptionalAuthMiddleware(jwtService *auth.JWTService) fiber.Handler {
 		c.Locals("user_id", claims.UserID)
 		c.Locals("email", claims.Email)
 		c.Locals("username", claims.Username)
+		c.Locals("session_id", claims.SessionID)
 
 		return c.Next()
 	}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Beretta350/gochat/internal/app/auth"
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/internal/config"
+)
+
+// fakeAPITokenRepo knows API tokens by their SHA-256, like the real one
+type fakeAPITokenRepo struct {
+	repository.APITokenRepository
+	tokens map[string]*model.APIToken
+}
+
+func (r fakeAPITokenRepo) GetByHash(_ context.Context, tokenHash string) (*model.APIToken, error) {
+	if token, ok := r.tokens[tokenHash]; ok {
+		return token, nil
+	}
+	return nil, repository.ErrAPITokenNotFound
+}
+
+func (fakeAPITokenRepo) MarkUsed(context.Context, string) error { return nil }
+
+// fakeUserRepo knows every user
+type fakeUserRepo struct {
+	repository.UserRepository
+}
+
+func (fakeUserRepo) GetByID(_ context.Context, id string) (*model.User, error) {
+	return &model.User{ID: id, Username: "bot", IsBot: true, IsActive: true}, nil
+}
+
+func TestAuthMiddlewareEnforcesTokenScopes(t *testing.T) {
+	const readToken = auth.APITokenPrefix + "read-only"
+	sum := sha256.Sum256([]byte(readToken))
+	authService := auth.NewService(auth.ServiceParams{
+		Config:   &config.Config{},
+		UserRepo: fakeUserRepo{},
+		APITokenRepo: fakeAPITokenRepo{tokens: map[string]*model.APIToken{
+			hex.EncodeToString(sum[:]): {ID: "token-id", UserID: "bot-id", Scopes: []string{model.ScopeConversationsRead}},
+		}},
+	})
+
+	app := fiber.New()
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Get("/conversations", AuthMiddleware(authService, model.ScopeConversationsRead), ok)
+	app.Post("/messages", AuthMiddleware(authService, model.ScopeMessagesWrite), ok)
+	app.Get("/auth/sessions", AuthMiddleware(authService), ok)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		wantStatus int
+	}{
+		{name: "granted scope", method: fiber.MethodGet, path: "/conversations", token: readToken, wantStatus: fiber.StatusOK},
+		{name: "missing scope", method: fiber.MethodPost, path: "/messages", token: readToken, wantStatus: fiber.StatusForbidden},
+		{name: "account route", method: fiber.MethodGet, path: "/auth/sessions", token: readToken, wantStatus: fiber.StatusForbidden},
+		{name: "unknown token", method: fiber.MethodGet, path: "/conversations", token: auth.APITokenPrefix + "unknown", wantStatus: fiber.StatusUnauthorized},
+		{name: "no token", method: fiber.MethodGet, path: "/conversations", wantStatus: fiber.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set(fiber.HeaderAuthorization, "Bearer "+tt.token)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
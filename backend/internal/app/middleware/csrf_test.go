@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCSRFProtection(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		cookies    map[string]string
+		header     string // X-CSRF-Token
+		bearer     bool
+		wantStatus int
+	}{
+		{
+			name:       "read with cookies",
+			method:     fiber.MethodGet,
+			cookies:    map[string]string{AccessTokenCookie: "jwt"},
+			wantStatus: fiber.StatusOK,
+		},
+		{
+			name:       "write without the header",
+			method:     fiber.MethodPost,
+			cookies:    map[string]string{AccessTokenCookie: "jwt", CSRFCookie: "token"},
+			wantStatus: fiber.StatusForbidden,
+		},
+		{
+			name:       "write with another token",
+			method:     fiber.MethodDelete,
+			cookies:    map[string]string{RefreshTokenCookie: "jwt", CSRFCookie: "token"},
+			header:     "forged",
+			wantStatus: fiber.StatusForbidden,
+		},
+		{
+			name:       "write without the cookie",
+			method:     fiber.MethodPatch,
+			cookies:    map[string]string{AccessTokenCookie: "jwt"},
+			header:     "token",
+			wantStatus: fiber.StatusForbidden,
+		},
+		{
+			name:       "write echoing the cookie",
+			method:     fiber.MethodPost,
+			cookies:    map[string]string{AccessTokenCookie: "jwt", CSRFCookie: "token"},
+			header:     "token",
+			wantStatus: fiber.StatusOK,
+		},
+		{
+			name:       "bearer token",
+			method:     fiber.MethodPost,
+			cookies:    map[string]string{AccessTokenCookie: "jwt"},
+			bearer:     true,
+			wantStatus: fiber.StatusOK,
+		},
+		{
+			name:       "not logged in",
+			method:     fiber.MethodPost,
+			wantStatus: fiber.StatusOK,
+		},
+	}
+
+	app := fiber.New()
+	app.Use(CSRFProtection())
+	app.All("/api/v1/conversations", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/conversations", nil)
+			for name, value := range tt.cookies {
+				req.AddCookie(&http.Cookie{Name: name, Value: value})
+			}
+			if tt.header != "" {
+				req.Header.Set(CSRFHeader, tt.header)
+			}
+			if tt.bearer {
+				req.Header.Set(fiber.HeaderAuthorization, "Bearer gct_token")
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -46,12 +46,16 @@ func Setup(app *fiber.App, cfg *config.Config) {
 	// CORS - configured via config
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     cfg.CORS.AllowedOrigins,
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-Request-ID",
+		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-Request-ID, X-CSRF-Token",
+		ExposeHeaders:    "X-CSRF-Token",
 		AllowCredentials: true,
 		MaxAge:           86400,
 	}))
 
+	// CSRF - double-submit cookie for cookie-authenticated mutating requests
+	app.Use(CSRFProtection())
+
 	// Rate Limiter - 100 requests per minute per IP
 	app.Use(limiter.New(limiter.Config{
 		Max:               100,
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Beretta350/gochat/internal/config"
+)
+
+func TestCORSPreflightAllowsPatch(t *testing.T) {
+	app := fiber.New()
+	Setup(app, &config.Config{CORS: config.CORSConfig{AllowedOrigins: "https://app.example.com"}})
+	app.Patch("/api/v1/conversations/:id", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	req := httptest.NewRequest(fiber.MethodOptions, "/api/v1/conversations/8b3d468f-d93d-431e-ba9c-9ca14b4ece77", nil)
+	req.Header.Set(fiber.HeaderOrigin, "https://app.example.com")
+	req.Header.Set(fiber.HeaderAccessControlRequestMethod, fiber.MethodPatch)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusNoContent)
+	}
+	if methods := resp.Header.Get(fiber.HeaderAccessControlAllowMethods); !strings.Contains(methods, fiber.MethodPatch) {
+		t.Errorf("allowed methods = %q, want PATCH", methods)
+	}
+}
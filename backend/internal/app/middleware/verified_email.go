@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Beretta350/gochat/internal/app/auth"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+// RequireVerifiedEmail rejects users who haven't confirmed their email address.
+// Must run after AuthMiddleware. When disabled it lets every request through.
+func RequireVerifiedEmail(authService *auth.Service, enabled bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !enabled {
+			return c.Next()
+		}
+
+		userID, _ := c.Locals("user_id").(string)
+		verified, err := authService.IsEmailVerified(c.Context(), userID)
+		if err != nil {
+			logger.Errorf("Error checking email verification for user %s: %v", userID, err)
+			return fiber.NewError(fiber.StatusUnauthorized, "User not found")
+		}
+		if !verified {
+			return fiber.NewError(fiber.StatusForbidden, "Email not verified")
+		}
+
+		return c.Next()
+	}
+}
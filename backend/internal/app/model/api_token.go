@@ -0,0 +1,65 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// API token scopes
+const (
+	ScopeConversationsRead = "conversations:read" // List conversations, read history, receive messages
+	ScopeMessagesWrite     = "messages:write"     // Send messages
+	ScopeGroupsManage      = "groups:manage"      // Create and manage group conversations
+)
+
+// APITokenScopes lists every valid scope
+var APITokenScopes = []string{ScopeConversationsRead, ScopeMessagesWrite, ScopeGroupsManage}
+
+// APIToken represents a long-lived bearer token of a user or a bot
+type APIToken struct {
+	ID          string
+	UserID      string // The user (or bot) the token acts as
+	CreatedBy   *string
+	Name        string
+	TokenPrefix string // First characters of the token, to tell tokens apart
+	TokenHash   string // SHA-256 of the token, the token itself is never stored
+	Scopes      []string
+	ExpiresAt   *time.Time
+	LastUsedAt  *time.Time
+	RevokedAt   *time.Time
+	CreatedAt   time.Time
+}
+
+// HasScope checks if the token grants a scope
+func (t *APIToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// IsUsable checks that the token was neither revoked nor expired
+func (t *APIToken) IsUsable() bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || time.Now().Before(*t.ExpiresAt))
+}
+
+// APITokenResponse represents an API token in API responses
+type APITokenResponse struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	Scopes      []string   `json:"scopes"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ToResponse converts APIToken to APITokenResponse
+func (t *APIToken) ToResponse() APITokenResponse {
+	return APITokenResponse{
+		ID:          t.ID,
+		Name:        t.Name,
+		TokenPrefix: t.TokenPrefix,
+		Scopes:      t.Scopes,
+		ExpiresAt:   t.ExpiresAt,
+		LastUsedAt:  t.LastUsedAt,
+		CreatedAt:   t.CreatedAt,
+	}
+}
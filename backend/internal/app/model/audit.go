@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Audit actions
+const (
+	AuditLoginLocked   = "login.locked"
+	AuditLoginUnlocked = "login.unlocked"
+)
+
+// AuditEntry is a security event of the audit log
+type AuditEntry struct {
+	ID           string                 `json:"id"`
+	ActorID      *string                `json:"actor_id,omitempty"` // nil for system events
+	Action       string                 `json:"action"`
+	TargetUserID *string                `json:"target_user_id,omitempty"`
+	TargetEmail  string                 `json:"target_email,omitempty"`
+	IPAddress    string                 `json:"ip_address,omitempty"`
+	Metadata     map[string]interface{} `json:"metadata"`
+	CreatedAt    time.Time              `json:"created_at"`
+}
@@ -0,0 +1,70 @@
+package model
+
+// Card action types
+const (
+	CardActionButton = "button"
+	CardActionSelect = "select"
+)
+
+// Card button styles
+const (
+	CardStyleDefault = ""
+	CardStylePrimary = "primary"
+	CardStyleDanger  = "danger"
+)
+
+// Card is the metadata of a card message: a title and text with buttons and select
+// menus. Clicking an action sends a message.interaction event to the bot that posted it.
+type Card struct {
+	Title   string       `json:"title,omitempty"`
+	Text    string       `json:"text,omitempty"`
+	Actions []CardAction `json:"actions,omitempty"`
+}
+
+// CardAction is a button or a select menu of a card
+type CardAction struct {
+	Type        string       `json:"type"`      // "button" or "select"
+	ActionID    string       `json:"action_id"` // Unique within the card, sent back on interaction
+	Label       string       `json:"label,omitempty"`
+	Value       string       `json:"value,omitempty"` // Button only
+	Style       string       `json:"style,omitempty"` // Button only: "primary" or "danger"
+	Placeholder string       `json:"placeholder,omitempty"`
+	Options     []CardOption `json:"options,omitempty"` // Select only
+}
+
+// CardOption is an option of a select menu
+type CardOption struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// Action returns the action with the given ID, or nil
+func (c *Card) Action(actionID string) *CardAction {
+	for i := range c.Actions {
+		if c.Actions[i].ActionID == actionID {
+			return &c.Actions[i]
+		}
+	}
+	return nil
+}
+
+// HasOption checks if a select menu offers the value
+func (a *CardAction) HasOption(value string) bool {
+	for _, o := range a.Options {
+		if o.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// CardInteraction is the data of a message.interaction webhook event: a user
+// clicked a button or picked an option of a card
+type CardInteraction struct {
+	MessageID string   `json:"message_id"`
+	ActionID  string   `json:"action_id"`
+	Value     string   `json:"value"`
+	UserID    string   `json:"user_id"`
+	Username  string   `json:"username"`
+	Message   *Message `json:"message"` // The card as it is now, to update it
+}
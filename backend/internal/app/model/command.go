@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// BotCommand is a slash command handled by a bot's HTTP endpoint. It is available
+// in every conversation the bot is a participant of.
+type BotCommand struct {
+	ID          string
+	BotID       string
+	CreatedBy   string
+	Name        string // Without the leading slash
+	Description string
+	Usage       string // Arguments hint, e.g. "<environment>"
+	URL         string
+	Secret      string // HMAC-SHA256 signing secret
+	CreatedAt   time.Time
+
+	// Populated field
+	BotUsername string
+}
+
+// BotCommandResponse represents a bot command in API responses (without its secret)
+type BotCommandResponse struct {
+	ID          string    `json:"id"`
+	BotID       string    `json:"bot_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Usage       string    `json:"usage"`
+	URL         string    `json:"url"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ToResponse converts BotCommand to BotCommandResponse
+func (c *BotCommand) ToResponse() BotCommandResponse {
+	return BotCommandResponse{
+		ID:          c.ID,
+		BotID:       c.BotID,
+		Name:        c.Name,
+		Description: c.Description,
+		Usage:       c.Usage,
+		URL:         c.URL,
+		CreatedAt:   c.CreatedAt,
+	}
+}
+
+// CommandInfo describes a command available in a conversation (help listing)
+type CommandInfo struct {
+	Name        string `json:"name"`
+	Usage       string `json:"usage,omitempty"`
+	Description string `json:"description"`
+	BuiltIn     bool   `json:"built_in"`
+	BotID       string `json:"bot_id,omitempty"`
+	BotUsername string `json:"bot_username,omitempty"`
+}
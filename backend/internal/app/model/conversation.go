@@ -6,24 +6,53 @@ import "time"
 type ConversationType string
 
 const (
-	ConversationTypeDirect ConversationType = "direct"
-	ConversationTypeGroup  ConversationType = "group"
+	ConversationTypeDirect  ConversationType = "direct"
+	ConversationTypeGroup   ConversationType = "group"
+	ConversationTypeChannel ConversationType = "channel" // A group where only admins post
 )
 
+// IsGroup tells if the conversation has members with roles (a group or a channel)
+func (t ConversationType) IsGroup() bool {
+	return t == ConversationTypeGroup || t == ConversationTypeChannel
+}
+
 // Conversation represents a chat conversation
 type Conversation struct {
-	ID        string           `json:"id"`
-	Type      ConversationType `json:"type"`
-	Name      *string          `json:"name,omitempty"` // Only for groups
-	CreatedBy *string          `json:"created_by,omitempty"`
-	CreatedAt time.Time        `json:"created_at"`
-	UpdatedAt time.Time        `json:"updated_at"`
+	ID          string           `json:"id"`
+	Type        ConversationType `json:"type"`
+	Name        *string          `json:"name,omitempty"`        // Only for groups
+	Description *string          `json:"description,omitempty"` // Only for groups
+	Topic       *string          `json:"topic,omitempty"`
+	Visibility  Visibility       `json:"visibility"` // Always private for direct conversations
+	CreatedBy   *string          `json:"created_by,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
 
 	// Populated fields (not stored directly)
 	Participants []Participant `json:"participants,omitempty"`
 	LastMessage  *Message      `json:"last_message,omitempty"`
 }
 
+// Visibility tells who can find a group
+type Visibility string
+
+const (
+	VisibilityPrivate Visibility = "private" // Reachable only by being added, an invite link or a join request
+	VisibilityPublic  Visibility = "public"  // Listed in the directory, anyone can join
+)
+
+// DirectoryGroup is a public group as listed in the directory
+type DirectoryGroup struct {
+	ID          string           `json:"id"`
+	Type        ConversationType `json:"type"` // group or channel
+	Name        *string          `json:"name,omitempty"`
+	Description *string          `json:"description,omitempty"`
+	Topic       *string          `json:"topic,omitempty"`
+	MemberCount int              `json:"member_count"`
+	IsMember    bool             `json:"is_member"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
 // ParticipantRole represents the role in a group conversation
 type ParticipantRole string
 
@@ -39,17 +68,25 @@ type Participant struct {
 	Role           *ParticipantRole
 	JoinedAt       time.Time
 	LeftAt         *time.Time
+	Muted          bool
+	MutedUntil     *time.Time // nil while muted means until unmuted
 
 	// Populated field
 	User *UserResponse
 }
 
+// IsMuted reports whether the participant muted the conversation at the given time
+func (p *Participant) IsMuted(now time.Time) bool {
+	return p.Muted && (p.MutedUntil == nil || now.Before(*p.MutedUntil))
+}
+
 // ParticipantResponse is the flattened API response for a participant
 type ParticipantResponse struct {
 	ID        string           `json:"id"`
 	Email     string           `json:"email"`
 	Username  string           `json:"username"`
 	IsActive  bool             `json:"is_active"`
+	IsBot     bool             `json:"is_bot,omitempty"`
 	Role      *ParticipantRole `json:"role,omitempty"`
 	JoinedAt  time.Time        `json:"joined_at"`
 	CreatedAt time.Time        `json:"created_at"`
@@ -65,6 +102,7 @@ func (p *Participant) ToResponse() *ParticipantResponse {
 		Email:     p.User.Email,
 		Username:  p.User.Username,
 		IsActive:  p.User.IsActive,
+		IsBot:     p.User.IsBot,
 		Role:      p.Role,
 		JoinedAt:  p.JoinedAt,
 		CreatedAt: p.User.CreatedAt,
@@ -78,6 +116,13 @@ type ConversationCreate struct {
 	ParticipantIDs []string         `json:"participant_ids" validate:"required,min=1"`
 }
 
+// GroupUpdate represents the changes to a group; omitted fields are kept
+type GroupUpdate struct {
+	Name        *string `json:"name,omitempty" validate:"omitempty,min=1,max=255"`
+	Description *string `json:"description,omitempty" validate:"omitempty,max=1000"` // "" removes it
+	Visibility  *string `json:"visibility,omitempty" validate:"omitempty,oneof=private public"`
+}
+
 // ConversationResponse represents a conversation in API responses
 type ConversationResponse struct {
 	ID           string           `json:"id"`
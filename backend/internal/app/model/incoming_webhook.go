@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// IncomingWebhook is a secret URL that posts messages into a group conversation as a bot
+type IncomingWebhook struct {
+	ID             string
+	ConversationID string
+	BotID          string // Sender of the posted messages
+	CreatedBy      string
+	Name           string
+	TokenPrefix    string // First characters of the path token, to tell webhooks apart
+	TokenHash      string // SHA-256 of the path token, the token itself is never stored
+	LastUsedAt     *time.Time
+	CreatedAt      time.Time
+}
+
+// IncomingWebhookResponse represents an incoming webhook in API responses
+type IncomingWebhookResponse struct {
+	ID             string     `json:"id"`
+	ConversationID string     `json:"conversation_id"`
+	BotID          string     `json:"bot_id"`
+	CreatedBy      string     `json:"created_by"`
+	Name           string     `json:"name"`
+	TokenPrefix    string     `json:"token_prefix"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// ToResponse converts IncomingWebhook to IncomingWebhookResponse
+func (w *IncomingWebhook) ToResponse() IncomingWebhookResponse {
+	return IncomingWebhookResponse{
+		ID:             w.ID,
+		ConversationID: w.ConversationID,
+		BotID:          w.BotID,
+		CreatedBy:      w.CreatedBy,
+		Name:           w.Name,
+		TokenPrefix:    w.TokenPrefix,
+		LastUsedAt:     w.LastUsedAt,
+		CreatedAt:      w.CreatedAt,
+	}
+}
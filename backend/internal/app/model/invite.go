@@ -0,0 +1,73 @@
+package model
+
+import "time"
+
+// GroupInvite is a shareable link to join a group
+type GroupInvite struct {
+	ID               string
+	ConversationID   string
+	CreatedBy        string
+	TokenPrefix      string // First characters of the token, to tell links apart
+	TokenHash        string // SHA-256 of the token, the token itself is never stored
+	ExpiresAt        *time.Time
+	MaxUses          *int // Unlimited when nil
+	Uses             int
+	RequiresApproval bool // Joining needs an admin's approval
+	RevokedAt        *time.Time
+	CreatedAt        time.Time
+}
+
+// IsUsable checks that the link was neither revoked, expired nor used up
+func (i *GroupInvite) IsUsable() bool {
+	return i.RevokedAt == nil &&
+		(i.ExpiresAt == nil || time.Now().Before(*i.ExpiresAt)) &&
+		(i.MaxUses == nil || i.Uses < *i.MaxUses)
+}
+
+// GroupInviteCreate represents the options of a new invite link
+type GroupInviteCreate struct {
+	ExpiresInHours   int  `json:"expires_in_hours" validate:"omitempty,min=1,max=8760"` // 0 = never expires
+	MaxUses          int  `json:"max_uses" validate:"omitempty,min=1,max=10000"`        // 0 = unlimited
+	RequiresApproval bool `json:"requires_approval"`
+}
+
+// GroupInviteResponse represents an invite link in API responses
+type GroupInviteResponse struct {
+	ID               string     `json:"id"`
+	ConversationID   string     `json:"conversation_id"`
+	CreatedBy        string     `json:"created_by"`
+	TokenPrefix      string     `json:"token_prefix"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	MaxUses          *int       `json:"max_uses,omitempty"`
+	Uses             int        `json:"uses"`
+	RequiresApproval bool       `json:"requires_approval"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// ToResponse converts GroupInvite to GroupInviteResponse
+func (i *GroupInvite) ToResponse() GroupInviteResponse {
+	return GroupInviteResponse{
+		ID:               i.ID,
+		ConversationID:   i.ConversationID,
+		CreatedBy:        i.CreatedBy,
+		TokenPrefix:      i.TokenPrefix,
+		ExpiresAt:        i.ExpiresAt,
+		MaxUses:          i.MaxUses,
+		Uses:             i.Uses,
+		RequiresApproval: i.RequiresApproval,
+		RevokedAt:        i.RevokedAt,
+		CreatedAt:        i.CreatedAt,
+	}
+}
+
+// GroupPreview is what someone with an invite link sees before joining
+type GroupPreview struct {
+	ConversationID   string     `json:"conversation_id"`
+	Name             *string    `json:"name,omitempty"`
+	Description      *string    `json:"description,omitempty"`
+	MemberCount      int        `json:"member_count"`
+	RequiresApproval bool       `json:"requires_approval"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	IsMember         bool       `json:"is_member"`
+}
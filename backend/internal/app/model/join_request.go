@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// JoinRequestStatus is where a join request stands
+type JoinRequestStatus string
+
+const (
+	JoinRequestPending  JoinRequestStatus = "pending"
+	JoinRequestApproved JoinRequestStatus = "approved"
+	JoinRequestDenied   JoinRequestStatus = "denied"
+)
+
+// JoinRequest is a request of a user to join a group, decided by its admins
+type JoinRequest struct {
+	ID             string
+	ConversationID string
+	UserID         string
+	Username       string  // Filled in by the repository
+	InviteID       *string // Invite link used to ask, if any
+	Message        *string
+	Status         JoinRequestStatus
+	DecidedBy      *string
+	DecidedAt      *time.Time
+	CreatedAt      time.Time
+}
+
+// JoinRequestCreate represents a request to join a group
+type JoinRequestCreate struct {
+	Message string `json:"message" validate:"max=500"` // Optional, shown to the admins
+}
+
+// JoinRequestResponse represents a join request in API responses
+type JoinRequestResponse struct {
+	ID             string            `json:"id"`
+	ConversationID string            `json:"conversation_id"`
+	UserID         string            `json:"user_id"`
+	Username       string            `json:"username"`
+	InviteID       *string           `json:"invite_id,omitempty"`
+	Message        *string           `json:"message,omitempty"`
+	Status         JoinRequestStatus `json:"status"`
+	DecidedBy      *string           `json:"decided_by,omitempty"`
+	DecidedAt      *time.Time        `json:"decided_at,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+}
+
+// ToResponse converts JoinRequest to JoinRequestResponse
+func (r *JoinRequest) ToResponse() JoinRequestResponse {
+	return JoinRequestResponse{
+		ID:             r.ID,
+		ConversationID: r.ConversationID,
+		UserID:         r.UserID,
+		Username:       r.Username,
+		InviteID:       r.InviteID,
+		Message:        r.Message,
+		Status:         r.Status,
+		DecidedBy:      r.DecidedBy,
+		DecidedAt:      r.DecidedAt,
+		CreatedAt:      r.CreatedAt,
+	}
+}
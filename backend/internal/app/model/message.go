@@ -1,33 +1,49 @@
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // MessageType represents the type of message
 type MessageType string
 
 const (
-	MessageTypeText  MessageType = "text"
-	MessageTypeImage MessageType = "image"
-	MessageTypeFile  MessageType = "file"
-	MessageTypeAudio MessageType = "audio"
+	MessageTypeText   MessageType = "text"
+	MessageTypeImage  MessageType = "image"
+	MessageTypeFile   MessageType = "file"
+	MessageTypeAudio  MessageType = "audio"
+	MessageTypeCard   MessageType = "card"   // Interactive card sent by a bot (see Card)
+	MessageTypeSystem MessageType = "system" // Conversation event written by the server (see SystemEvent)
 )
 
 // Message represents a chat message
 type Message struct {
-	ID             string      `json:"id"`
-	ConversationID string      `json:"conversation_id"`
-	SenderID       string      `json:"sender_id"`
-	SenderUsername string      `json:"sender_username,omitempty"`
-	Content        string      `json:"content"`
-	Type           MessageType `json:"type"`
-	SentAt         time.Time   `json:"sent_at"`
+	ID             string          `json:"id"`
+	ConversationID string          `json:"conversation_id"`
+	SenderID       string          `json:"sender_id"`
+	SenderUsername string          `json:"sender_username,omitempty"`
+	Content        string          `json:"content"`
+	Type           MessageType     `json:"type"`
+	Metadata       json.RawMessage `json:"metadata,omitempty"` // Card of a card message, SystemEvent of a system message
+	SentAt         time.Time       `json:"sent_at"`
+	EditedAt       *time.Time      `json:"edited_at,omitempty"`
+	PinnedAt       *time.Time      `json:"pinned_at,omitempty"`
+	PinnedBy       *string         `json:"pinned_by,omitempty"`
 }
 
 // MessageCreate represents data to create/send a message
 type MessageCreate struct {
-	ConversationID string `json:"conversation_id" validate:"required,uuid"`
-	Content        string `json:"content" validate:"required,min=1"`
-	Type           string `json:"type,omitempty" validate:"omitempty,oneof=text image file audio"`
+	ConversationID string          `json:"conversation_id" validate:"required,uuid"`
+	Content        string          `json:"content" validate:"required,min=1"`
+	Type           string          `json:"type,omitempty" validate:"omitempty,oneof=text image file audio card"`
+	Metadata       json.RawMessage `json:"metadata,omitempty"`
+}
+
+// MessageUpdate represents the changes to a message; omitted fields are kept
+type MessageUpdate struct {
+	Content  *string         `json:"content,omitempty" validate:"omitempty,min=1"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
 }
 
 // WebSocketMessage represents a message received via WebSocket
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// Passkey represents a WebAuthn credential registered by a user
+type Passkey struct {
+	ID              string
+	UserID          string
+	CredentialID    []byte
+	PublicKey       []byte
+	AttestationType string
+	AAGUID          []byte
+	SignCount       uint32
+	Transports      []string
+	BackupEligible  bool
+	BackupState     bool
+	Name            string
+	CreatedAt       time.Time
+	LastUsedAt      *time.Time
+}
+
+// PasskeyResponse represents a passkey in API responses
+type PasskeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Synced     bool       `json:"synced"` // Backed up by the platform (iCloud Keychain, Google Password Manager, ...)
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// ToResponse converts Passkey to PasskeyResponse
+func (p *Passkey) ToResponse() PasskeyResponse {
+	return PasskeyResponse{
+		ID:         p.ID,
+		Name:       p.Name,
+		Synced:     p.BackupState,
+		CreatedAt:  p.CreatedAt,
+		LastUsedAt: p.LastUsedAt,
+	}
+}
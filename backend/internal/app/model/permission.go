@@ -0,0 +1,74 @@
+package model
+
+import "slices"
+
+// Permission is something a participant may do in a conversation, depending on their role
+type Permission string
+
+const (
+	PermissionPostMessages   Permission = "post_messages"
+	PermissionAddMembers     Permission = "add_members"
+	PermissionRemoveMembers  Permission = "remove_members"
+	PermissionEditInfo       Permission = "edit_info"       // Name, description and topic
+	PermissionDeleteMessages Permission = "delete_messages" // Other people's messages; everyone can delete their own
+	PermissionPinMessages    Permission = "pin_messages"
+	PermissionManageRoles    Permission = "manage_roles"    // Promote and demote admins
+	PermissionManageInvites  Permission = "manage_invites"  // Create and revoke invite links
+	PermissionApproveMembers Permission = "approve_members" // Approve or deny join requests
+	PermissionManageWebhooks Permission = "manage_webhooks" // Create conversation webhooks
+)
+
+// rolePermissions lists what each role of a group may do
+var rolePermissions = map[ParticipantRole][]Permission{
+	ParticipantRoleAdmin: {
+		PermissionPostMessages,
+		PermissionAddMembers,
+		PermissionRemoveMembers,
+		PermissionEditInfo,
+		PermissionDeleteMessages,
+		PermissionPinMessages,
+		PermissionManageRoles,
+		PermissionManageInvites,
+		PermissionApproveMembers,
+		PermissionManageWebhooks,
+	},
+	ParticipantRoleMember: {
+		PermissionPostMessages,
+		PermissionAddMembers,
+	},
+}
+
+// channelMemberPermissions lists what the members of a channel may do: only read
+var channelMemberPermissions = []Permission{}
+
+// directPermissions lists what both people of a direct conversation may do
+var directPermissions = []Permission{PermissionPostMessages, PermissionEditInfo, PermissionPinMessages, PermissionManageWebhooks}
+
+// Permissions returns what the participant may do in a conversation of the given type
+func (p *Participant) Permissions(convType ConversationType) []Permission {
+	switch {
+	case !convType.IsGroup():
+		return directPermissions
+	case convType == ConversationTypeChannel && !p.IsAdmin():
+		return channelMemberPermissions
+	}
+	return rolePermissions[p.EffectiveRole()]
+}
+
+// Can checks if the participant may do something in a conversation of the given type
+func (p *Participant) Can(convType ConversationType, permission Permission) bool {
+	return slices.Contains(p.Permissions(convType), permission)
+}
+
+// EffectiveRole returns the role of a group participant (member when not set)
+func (p *Participant) EffectiveRole() ParticipantRole {
+	if p.Role == nil {
+		return ParticipantRoleMember
+	}
+	return *p.Role
+}
+
+// IsAdmin checks if the participant is an admin of the group
+func (p *Participant) IsAdmin() bool {
+	return p.EffectiveRole() == ParticipantRoleAdmin
+}
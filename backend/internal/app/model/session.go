@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// Session revocation reasons
+const (
+	SessionRevokedLogout = "logout"
+	SessionRevokedReuse  = "refresh_reuse"
+	SessionRevokedByUser = "revoked_by_user"
+	SessionRevokedReset  = "password_reset"
+	SessionRevokedSSO    = "sso_account_linked"
+)
+
+// Session represents a login on a device (one refresh token family)
+type Session struct {
+	ID            string     `json:"id"`
+	UserID        string     `json:"user_id"`
+	RefreshJTI    string     `json:"-"` // Current (only valid) refresh token ID
+	UserAgent     string     `json:"user_agent"`
+	IPAddress     string     `json:"ip_address"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastUsedAt    time.Time  `json:"last_used_at"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason *string    `json:"revoked_reason,omitempty"`
+}
+
+// IsActive checks if the session can still be used
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+// SessionResponse represents a session in the device management API
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`   // Session used by this request
+	Connected  bool      `json:"connected"` // Has an open WebSocket connection
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// SigningKey is a stored JWT signing key. PrivateKey is encrypted.
+type SigningKey struct {
+	KID         string
+	Algorithm   string
+	PrivateKey  []byte
+	CreatedAt   time.Time
+	ActivatesAt time.Time  // Signs new tokens from then on; published in the JWKS before
+	ExpiresAt   *time.Time // nil while current; set when a newer key supersedes it
+}
@@ -0,0 +1,30 @@
+package model
+
+// SystemEventType is what happened in a conversation, told by a system message
+type SystemEventType string
+
+const (
+	SystemEventGroupCreated       SystemEventType = "group_created"
+	SystemEventMembersAdded       SystemEventType = "members_added"
+	SystemEventMemberRemoved      SystemEventType = "member_removed"
+	SystemEventMemberLeft         SystemEventType = "member_left"
+	SystemEventMemberJoined       SystemEventType = "member_joined" // With an invite link or from the directory
+	SystemEventGroupRenamed       SystemEventType = "group_renamed"
+	SystemEventDescriptionChanged SystemEventType = "description_changed"
+	SystemEventTopicChanged       SystemEventType = "topic_changed"
+	SystemEventRoleChanged        SystemEventType = "role_changed"
+	SystemEventVisibilityChanged  SystemEventType = "visibility_changed"
+)
+
+// SystemEvent is the metadata of a system message. The sender of the message is
+// the actor; its content is a readable version for clients that don't know the event.
+type SystemEvent struct {
+	Event       SystemEventType `json:"event"`
+	ActorID     string          `json:"actor_id"`
+	UserIDs     []string        `json:"user_ids,omitempty"`    // members_added, member_removed, role_changed
+	Name        *string         `json:"name,omitempty"`        // group_created, group_renamed
+	Description *string         `json:"description,omitempty"` // description_changed (removed when omitted)
+	Topic       *string         `json:"topic,omitempty"`       // topic_changed (cleared when omitted)
+	Role        ParticipantRole `json:"role,omitempty"`        // role_changed
+	Visibility  Visibility      `json:"visibility,omitempty"`  // visibility_changed
+}
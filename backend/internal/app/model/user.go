@@ -1,36 +1,32 @@
 package model
 
-import (
-	"time"
-
-	"golang.org/x/crypto/bcrypt"
-)
+import "time"
 
 // User represents a user in the system
 type User struct {
-	ID           string    `json:"id"`
-	Email        string    `json:"email"`
-	Username     string    `json:"username"`
-	PasswordHash string    `json:"-"` // Never expose in JSON
-	IsActive     bool      `json:"is_active"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID              string     `json:"id"`
+	Email           string     `json:"email"`
+	Username        string     `json:"username"`
+	PasswordHash    string     `json:"-"` // Never expose in JSON (see pkg/password for the format)
+	IsActive        bool       `json:"is_active"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"` // nil until the email is confirmed
+	TOTPSecret      string     `json:"-"`                           // Set at 2FA enrollment
+	TOTPEnabledAt   *time.Time `json:"-"`                           // nil until enrollment is confirmed
+	IsAdmin         bool       `json:"is_admin"`
+	IsBot           bool       `json:"is_bot"`
+	BotOwnerID      *string    `json:"bot_owner_id,omitempty"` // Set for bots
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
-// SetPassword hashes and sets the user's password
-func (u *User) SetPassword(password string) error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return err
-	}
-	u.PasswordHash = string(hash)
-	return nil
+// IsEmailVerified checks if the user confirmed their email address
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
 
-// CheckPassword verifies if the provided password matches
-func (u *User) CheckPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password))
-	return err == nil
+// IsTwoFactorEnabled checks if the user must enter a TOTP code to log in
+func (u *User) IsTwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil && u.TOTPSecret != ""
 }
 
 // UserCreate represents the data needed to create a user
@@ -48,20 +44,29 @@ type UserLogin struct {
 
 // UserResponse represents a user response (without sensitive data)
 type UserResponse struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	Username  string    `json:"username"`
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
+	ID       string `json:"id"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+	IsActive bool   `json:"is_active"`
+	// EmailVerified tells the client whether to show the "verify your email" notice
+	EmailVerified    bool      `json:"email_verified"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled"`
+	IsAdmin          bool      `json:"is_admin,omitempty"`
+	IsBot            bool      `json:"is_bot,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
 }
 
 // ToResponse converts User to UserResponse
 func (u *User) ToResponse() *UserResponse {
 	return &UserResponse{
-		ID:        u.ID,
-		Email:     u.Email,
-		Username:  u.Username,
-		IsActive:  u.IsActive,
-		CreatedAt: u.CreatedAt,
+		ID:               u.ID,
+		Email:            u.Email,
+		Username:         u.Username,
+		IsActive:         u.IsActive,
+		EmailVerified:    u.IsEmailVerified(),
+		TwoFactorEnabled: u.IsTwoFactorEnabled(),
+		IsAdmin:          u.IsAdmin,
+		IsBot:            u.IsBot,
+		CreatedAt:        u.CreatedAt,
 	}
 }
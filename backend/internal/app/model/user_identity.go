@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// UserIdentity links a user to their account at an external identity provider
+type UserIdentity struct {
+	ID          string
+	UserID      string
+	Provider    string // Provider ID from OIDC_PROVIDERS
+	Subject     string // "sub" claim, stable per provider
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt *time.Time
+}
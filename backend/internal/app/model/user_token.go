@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// UserTokenPurpose represents what a single-use user token is for
+type UserTokenPurpose string
+
+const (
+	UserTokenPasswordReset     UserTokenPurpose = "password_reset"
+	UserTokenEmailVerification UserTokenPurpose = "email_verification"
+)
+
+// UserToken represents a single-use token sent to a user by email
+type UserToken struct {
+	ID        string
+	UserID    string
+	Purpose   UserTokenPurpose
+	TokenHash string // SHA-256 of the token, the token itself is never stored
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
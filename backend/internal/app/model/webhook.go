@@ -0,0 +1,109 @@
+package model
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// Webhook events
+const (
+	WebhookEventMessageCreated      = "message.created"
+	WebhookEventMessageUpdated      = "message.updated"
+	WebhookEventConversationCreated = "conversation.created"
+	WebhookEventParticipantAdded    = "participant.added"
+	WebhookEventParticipantRemoved  = "participant.removed"
+	WebhookEventMessageInteraction  = "message.interaction" // Sent to the bot that posted the card only
+)
+
+// WebhookEvents lists every event a webhook can subscribe to
+var WebhookEvents = []string{
+	WebhookEventMessageCreated,
+	WebhookEventMessageUpdated,
+	WebhookEventConversationCreated,
+	WebhookEventParticipantAdded,
+	WebhookEventParticipantRemoved,
+	WebhookEventMessageInteraction,
+}
+
+// WebhookEvent is the JSON body POSTed to webhooks
+type WebhookEvent struct {
+	ID             string          `json:"id"` // Same for every delivery attempt
+	Event          string          `json:"event"`
+	ConversationID string          `json:"conversation_id"`
+	ActorID        string          `json:"actor_id,omitempty"` // User or bot that caused the event
+	BotID          string          `json:"bot_id,omitempty"`   // Only this bot's webhooks receive the event
+	CreatedAt      time.Time       `json:"created_at"`
+	Data           json.RawMessage `json:"data"`
+}
+
+// Webhook is an outgoing webhook subscription. It belongs either to a conversation
+// or to a bot, in which case it receives the events of every conversation the bot is in.
+type Webhook struct {
+	ID             string
+	CreatedBy      string
+	ConversationID *string
+	BotID          *string
+	URL            string
+	Secret         string // HMAC-SHA256 signing secret
+	Events         []string
+	FailureCount   int // Consecutive failed deliveries
+	DisabledAt     *time.Time
+	LastDeliveryAt *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// IsActive checks that the webhook was not disabled
+func (w *Webhook) IsActive() bool {
+	return w.DisabledAt == nil
+}
+
+// Subscribes checks if the webhook wants an event
+func (w *Webhook) Subscribes(event string) bool {
+	return slices.Contains(w.Events, event)
+}
+
+// WebhookResponse represents a webhook in API responses (without its secret)
+type WebhookResponse struct {
+	ID             string     `json:"id"`
+	ConversationID *string    `json:"conversation_id,omitempty"`
+	BotID          *string    `json:"bot_id,omitempty"`
+	URL            string     `json:"url"`
+	Events         []string   `json:"events"`
+	Active         bool       `json:"active"`
+	FailureCount   int        `json:"failure_count"`
+	DisabledAt     *time.Time `json:"disabled_at,omitempty"`
+	LastDeliveryAt *time.Time `json:"last_delivery_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// ToResponse converts Webhook to WebhookResponse
+func (w *Webhook) ToResponse() WebhookResponse {
+	return WebhookResponse{
+		ID:             w.ID,
+		ConversationID: w.ConversationID,
+		BotID:          w.BotID,
+		URL:            w.URL,
+		Events:         w.Events,
+		Active:         w.IsActive(),
+		FailureCount:   w.FailureCount,
+		DisabledAt:     w.DisabledAt,
+		LastDeliveryAt: w.LastDeliveryAt,
+		CreatedAt:      w.CreatedAt,
+	}
+}
+
+// WebhookDelivery is one delivery attempt of an event to a webhook
+type WebhookDelivery struct {
+	ID         string    `json:"id"`
+	WebhookID  string    `json:"webhook_id"`
+	EventID    string    `json:"event_id"` // Same for every attempt (X-GoChat-Delivery header)
+	Event      string    `json:"event"`
+	Attempt    int       `json:"attempt"`
+	StatusCode *int      `json:"status_code,omitempty"` // nil when no response was received
+	Error      *string   `json:"error,omitempty"`
+	DurationMs int       `json:"duration_ms"`
+	Success    bool      `json:"success"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/postgres"
+)
+
+var (
+	ErrAPITokenNotFound = errors.New("api token not found")
+)
+
+// APITokenRepository defines the interface for API token persistence
+type APITokenRepository interface {
+	Create(ctx context.Context, token *model.APIToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*model.APIToken, error)
+	ListByUser(ctx context.Context, userID string) ([]model.APIToken, error)
+	MarkUsed(ctx context.Context, id string) error
+	Revoke(ctx context.Context, userID, id string) error
+	RevokeAllForUser(ctx context.Context, userID string) ([]string, error)
+}
+
+// PostgresAPITokenRepository implements APITokenRepository with PostgreSQL
+type PostgresAPITokenRepository struct {
+	db *postgres.Client
+}
+
+// NewAPITokenRepository creates a new API token repository (Fx provider)
+func NewAPITokenRepository(db *postgres.Client) APITokenRepository {
+	logger.Info("API token repository initialized")
+	return &PostgresAPITokenRepository{db: db}
+}
+
+const apiTokenColumns = `
+	id, user_id, created_by, name, token_prefix, token_hash, scopes,
+	expires_at, last_used_at, revoked_at, created_at
+`
+
+func (r *PostgresAPITokenRepository) Create(ctx context.Context, token *model.APIToken) error {
+	query := `
+		INSERT INTO api_tokens (user_id, created_by, name, token_prefix, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	return r.db.Pool.QueryRow(ctx, query,
+		token.UserID,
+		token.CreatedBy,
+		token.Name,
+		token.TokenPrefix,
+		token.TokenHash,
+		token.Scopes,
+		token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+}
+
+// GetByHash finds a token by its hash, including revoked and expired ones (see APIToken.IsUsable)
+func (r *PostgresAPITokenRepository) GetByHash(ctx context.Context, tokenHash string) (*model.APIToken, error) {
+	query := `SELECT ` + apiTokenColumns + ` FROM api_tokens WHERE token_hash = $1`
+	return r.scanAPIToken(r.db.Pool.QueryRow(ctx, query, tokenHash))
+}
+
+// ListByUser returns the tokens of a user that were not revoked
+func (r *PostgresAPITokenRepository) ListByUser(ctx context.Context, userID string) ([]model.APIToken, error) {
+	query := `
+		SELECT ` + apiTokenColumns + `
+		FROM api_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at
+	`
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []model.APIToken{}
+	for rows.Next() {
+		token, err := r.scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, rows.Err()
+}
+
+// MarkUsed records the last use of a token (at most once a minute, to limit writes)
+func (r *PostgresAPITokenRepository) MarkUsed(ctx context.Context, id string) error {
+	query := `
+		UPDATE api_tokens
+		SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`
+	_, err := r.db.Pool.Exec(ctx, query, id)
+	return err
+}
+
+// Revoke revokes one of the user's tokens
+func (r *PostgresAPITokenRepository) Revoke(ctx context.Context, userID, id string) error {
+	query := `
+		UPDATE api_tokens
+		SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+	result, err := r.db.Pool.Exec(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// RevokeAllForUser revokes every token of a user and returns their IDs
+func (r *PostgresAPITokenRepository) RevokeAllForUser(ctx context.Context, userID string) ([]string, error) {
+	query := `
+		UPDATE api_tokens
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+		RETURNING id
+	`
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *PostgresAPITokenRepository) scanAPIToken(row pgx.Row) (*model.APIToken, error) {
+	var t model.APIToken
+	err := row.Scan(
+		&t.ID,
+		&t.UserID,
+		&t.CreatedBy,
+		&t.Name,
+		&t.TokenPrefix,
+		&t.TokenHash,
+		&t.Scopes,
+		&t.ExpiresAt,
+		&t.LastUsedAt,
+		&t.RevokedAt,
+		&t.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAPITokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/postgres"
+)
+
+// AuditRepository defines the interface for audit log persistence
+type AuditRepository interface {
+	Create(ctx context.Context, entry *model.AuditEntry) error
+	List(ctx context.Context, action string, limit int) ([]model.AuditEntry, error)
+}
+
+// PostgresAuditRepository implements AuditRepository with PostgreSQL
+type PostgresAuditRepository struct {
+	db *postgres.Client
+}
+
+// NewAuditRepository creates a new audit log repository (Fx provider)
+func NewAuditRepository(db *postgres.Client) AuditRepository {
+	logger.Info("Audit repository initialized")
+	return &PostgresAuditRepository{db: db}
+}
+
+func (r *PostgresAuditRepository) Create(ctx context.Context, entry *model.AuditEntry) error {
+	if entry.Metadata == nil {
+		entry.Metadata = map[string]interface{}{}
+	}
+
+	query := `
+		INSERT INTO audit_log (actor_id, action, target_user_id, target_email, ip_address, metadata)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6)
+		RETURNING id, created_at
+	`
+	return r.db.Pool.QueryRow(ctx, query,
+		entry.ActorID,
+		entry.Action,
+		entry.TargetUserID,
+		entry.TargetEmail,
+		entry.IPAddress,
+		entry.Metadata,
+	).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+// List returns the latest entries, optionally only of one action
+func (r *PostgresAuditRepository) List(ctx context.Context, action string, limit int) ([]model.AuditEntry, error) {
+	query := `
+		SELECT id, actor_id, action, target_user_id, COALESCE(target_email, ''),
+		       COALESCE(ip_address, ''), metadata, created_at
+		FROM audit_log
+		WHERE $1 = '' OR action = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := r.db.Pool.Query(ctx, query, action, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []model.AuditEntry{}
+	for rows.Next() {
+		var e model.AuditEntry
+		if err := rows.Scan(
+			&e.ID,
+			&e.ActorID,
+			&e.Action,
+			&e.TargetUserID,
+			&e.TargetEmail,
+			&e.IPAddress,
+			&e.Metadata,
+			&e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/postgres"
+)
+
+var (
+	ErrBotCommandNotFound      = errors.New("bot command not found")
+	ErrBotCommandAlreadyExists = errors.New("bot command already exists")
+)
+
+// BotCommandRepository defines the interface for bot command persistence
+type BotCommandRepository interface {
+	Create(ctx context.Context, cmd *model.BotCommand) error
+	GetByID(ctx context.Context, id string) (*model.BotCommand, error)
+	ListByBot(ctx context.Context, botID string) ([]model.BotCommand, error)
+	ListForConversation(ctx context.Context, conversationID string) ([]model.BotCommand, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// PostgresBotCommandRepository implements BotCommandRepository with PostgreSQL
+type PostgresBotCommandRepository struct {
+	db *postgres.Client
+}
+
+// NewBotCommandRepository creates a new bot command repository (Fx provider)
+func NewBotCommandRepository(db *postgres.Client) BotCommandRepository {
+	logger.Info("Bot command repository initialized")
+	return &PostgresBotCommandRepository{db: db}
+}
+
+const botCommandColumns = `
+	c.id, c.bot_id, c.created_by, c.name, c.description, c.usage, c.url, c.secret, c.created_at, u.username
+`
+
+func (r *PostgresBotCommandRepository) Create(ctx context.Context, cmd *model.BotCommand) error {
+	query := `
+		INSERT INTO bot_commands (bot_id, created_by, name, description, usage, url, secret)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	err := r.db.Pool.QueryRow(ctx, query,
+		cmd.BotID,
+		cmd.CreatedBy,
+		cmd.Name,
+		cmd.Description,
+		cmd.Usage,
+		cmd.URL,
+		cmd.Secret,
+	).Scan(&cmd.ID, &cmd.CreatedAt)
+
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrBotCommandAlreadyExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresBotCommandRepository) GetByID(ctx context.Context, id string) (*model.BotCommand, error) {
+	query := `
+		SELECT ` + botCommandColumns + `
+		FROM bot_commands c
+		JOIN users u ON u.id = c.bot_id
+		WHERE c.id = $1
+	`
+	return r.scanBotCommand(r.db.Pool.QueryRow(ctx, query, id))
+}
+
+// ListByBot returns the commands of a bot
+func (r *PostgresBotCommandRepository) ListByBot(ctx context.Context, botID string) ([]model.BotCommand, error) {
+	query := `
+		SELECT ` + botCommandColumns + `
+		FROM bot_commands c
+		JOIN users u ON u.id = c.bot_id
+		WHERE c.bot_id = $1
+		ORDER BY c.name
+	`
+	return r.listBotCommands(ctx, query, botID)
+}
+
+// ListForConversation returns the commands of the active bots taking part in a conversation.
+// When several bots register the same name, the oldest registration comes first.
+func (r *PostgresBotCommandRepository) ListForConversation(ctx context.Context, conversationID string) ([]model.BotCommand, error) {
+	query := `
+		SELECT ` + botCommandColumns + `
+		FROM bot_commands c
+		JOIN users u ON u.id = c.bot_id
+		JOIN conversation_participants cp ON cp.user_id = c.bot_id
+		WHERE cp.conversation_id = $1 AND cp.left_at IS NULL AND u.is_active = true
+		ORDER BY c.name, c.created_at
+	`
+	return r.listBotCommands(ctx, query, conversationID)
+}
+
+func (r *PostgresBotCommandRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.Pool.Exec(ctx, `DELETE FROM bot_commands WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrBotCommandNotFound
+	}
+	return nil
+}
+
+func (r *PostgresBotCommandRepository) listBotCommands(ctx context.Context, query string, args ...interface{}) ([]model.BotCommand, error) {
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	commands := []model.BotCommand{}
+	for rows.Next() {
+		cmd, err := r.scanBotCommand(rows)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, *cmd)
+	}
+	return commands, rows.Err()
+}
+
+func (r *PostgresBotCommandRepository) scanBotCommand(row pgx.Row) (*model.BotCommand, error) {
+	var c model.BotCommand
+	err := row.Scan(
+		&c.ID,
+		&c.BotID,
+		&c.CreatedBy,
+		&c.Name,
+		&c.Description,
+		&c.Usage,
+		&c.URL,
+		&c.Secret,
+		&c.CreatedAt,
+		&c.BotUsername,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBotCommandNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
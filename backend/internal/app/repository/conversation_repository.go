@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 
@@ -15,6 +17,9 @@ var (
 	ErrConversationNotFound = errors.New("conversation not found")
 )
 
+// likeEscaper escapes the LIKE wildcards of a search query
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 // ConversationRepository defines the interface for conversation persistence
 type ConversationRepository interface {
 	Create(ctx context.Context, conv *model.Conversation, participantIDs []string) error
@@ -23,7 +28,14 @@ type ConversationRepository interface {
 	FindDirectConversation(ctx context.Context, userID1, userID2 string) (*model.Conversation, error)
 	AddParticipant(ctx context.Context, conversationID, userID string, role *model.ParticipantRole) error
 	RemoveParticipant(ctx context.Context, conversationID, userID string) error
+	SetRole(ctx context.Context, conversationID, userID string, role model.ParticipantRole) error
 	GetParticipants(ctx context.Context, conversationID string) ([]model.Participant, error)
+	SetName(ctx context.Context, conversationID, name string) error
+	SetDescription(ctx context.Context, conversationID string, description *string) error
+	SetTopic(ctx context.Context, conversationID string, topic *string) error
+	SetVisibility(ctx context.Context, conversationID string, visibility model.Visibility) error
+	SearchPublic(ctx context.Context, userID, query string, limit, offset int) ([]model.DirectoryGroup, error)
+	SetMuted(ctx context.Context, conversationID, userID string, muted bool, until *time.Time) error
 }
 
 // PostgresConversationRepository implements ConversationRepository with PostgreSQL
@@ -46,11 +58,14 @@ func (r *PostgresConversationRepository) Create(ctx context.Context, conv *model
 
 	// Create conversation
 	query := `
-		INSERT INTO conversations (type, name, created_by)
-		VALUES ($1, $2, $3)
+		INSERT INTO conversations (type, name, visibility, created_by)
+		VALUES ($1, $2, $3, $4)
 		RETURNING id, created_at, updated_at
 	`
-	err = tx.QueryRow(ctx, query, conv.Type, conv.Name, conv.CreatedBy).
+	if conv.Visibility == "" {
+		conv.Visibility = model.VisibilityPrivate
+	}
+	err = tx.QueryRow(ctx, query, conv.Type, conv.Name, conv.Visibility, conv.CreatedBy).
 		Scan(&conv.ID, &conv.CreatedAt, &conv.UpdatedAt)
 	if err != nil {
 		return err
@@ -59,7 +74,7 @@ func (r *PostgresConversationRepository) Create(ctx context.Context, conv *model
 	// Add participants
 	for _, userID := range participantIDs {
 		var role *model.ParticipantRole
-		if conv.Type == model.ConversationTypeGroup {
+		if conv.Type.IsGroup() {
 			if conv.CreatedBy != nil && *conv.CreatedBy == userID {
 				adminRole := model.ParticipantRoleAdmin
 				role = &adminRole
@@ -83,7 +98,7 @@ func (r *PostgresConversationRepository) Create(ctx context.Context, conv *model
 
 func (r *PostgresConversationRepository) GetByID(ctx context.Context, id string) (*model.Conversation, error) {
 	query := `
-		SELECT id, type, name, created_by, created_at, updated_at
+		SELECT id, type, name, description, topic, visibility, created_by, created_at, updated_at
 		FROM conversations
 		WHERE id = $1
 	`
@@ -92,6 +107,9 @@ func (r *PostgresConversationRepository) GetByID(ctx context.Context, id string)
 		&conv.ID,
 		&conv.Type,
 		&conv.Name,
+		&conv.Description,
+		&conv.Topic,
+		&conv.Visibility,
 		&conv.CreatedBy,
 		&conv.CreatedAt,
 		&conv.UpdatedAt,
@@ -107,7 +125,7 @@ func (r *PostgresConversationRepository) GetByID(ctx context.Context, id string)
 
 func (r *PostgresConversationRepository) GetByUserID(ctx context.Context, userID string) ([]model.Conversation, error) {
 	query := `
-		SELECT c.id, c.type, c.name, c.created_by, c.created_at, c.updated_at
+		SELECT c.id, c.type, c.name, c.description, c.topic, c.visibility, c.created_by, c.created_at, c.updated_at
 		FROM conversations c
 		JOIN conversation_participants cp ON c.id = cp.conversation_id
 		WHERE cp.user_id = $1 AND cp.left_at IS NULL
@@ -126,6 +144,9 @@ func (r *PostgresConversationRepository) GetByUserID(ctx context.Context, userID
 			&conv.ID,
 			&conv.Type,
 			&conv.Name,
+			&conv.Description,
+			&conv.Topic,
+			&conv.Visibility,
 			&conv.CreatedBy,
 			&conv.CreatedAt,
 			&conv.UpdatedAt,
@@ -140,7 +161,7 @@ func (r *PostgresConversationRepository) GetByUserID(ctx context.Context, userID
 
 func (r *PostgresConversationRepository) FindDirectConversation(ctx context.Context, userID1, userID2 string) (*model.Conversation, error) {
 	query := `
-		SELECT c.id, c.type, c.name, c.created_by, c.created_at, c.updated_at
+		SELECT c.id, c.type, c.name, c.description, c.topic, c.visibility, c.created_by, c.created_at, c.updated_at
 		FROM conversations c
 		WHERE c.type = 'direct'
 		AND EXISTS (
@@ -158,6 +179,9 @@ func (r *PostgresConversationRepository) FindDirectConversation(ctx context.Cont
 		&conv.ID,
 		&conv.Type,
 		&conv.Name,
+		&conv.Description,
+		&conv.Topic,
+		&conv.Visibility,
 		&conv.CreatedBy,
 		&conv.CreatedAt,
 		&conv.UpdatedAt,
@@ -191,10 +215,21 @@ func (r *PostgresConversationRepository) RemoveParticipant(ctx context.Context,
 	return err
 }
 
+// SetRole changes the role of a participant of a group
+func (r *PostgresConversationRepository) SetRole(ctx context.Context, conversationID, userID string, role model.ParticipantRole) error {
+	query := `
+		UPDATE conversation_participants
+		SET role = $3
+		WHERE conversation_id = $1 AND user_id = $2 AND left_at IS NULL
+	`
+	_, err := r.db.Pool.Exec(ctx, query, conversationID, userID, role)
+	return err
+}
+
 func (r *PostgresConversationRepository) GetParticipants(ctx context.Context, conversationID string) ([]model.Participant, error) {
 	query := `
-		SELECT cp.conversation_id, cp.user_id, cp.role, cp.joined_at, cp.left_at,
-		       u.id, u.email, u.username, u.is_active, u.created_at
+		SELECT cp.conversation_id, cp.user_id, cp.role, cp.joined_at, cp.left_at, cp.muted, cp.muted_until,
+		       u.id, u.email, u.username, u.is_active, u.is_bot, u.created_at
 		FROM conversation_participants cp
 		JOIN users u ON cp.user_id = u.id
 		WHERE cp.conversation_id = $1 AND cp.left_at IS NULL
@@ -215,10 +250,13 @@ func (r *PostgresConversationRepository) GetParticipants(ctx context.Context, co
 			&p.Role,
 			&p.JoinedAt,
 			&p.LeftAt,
+			&p.Muted,
+			&p.MutedUntil,
 			&u.ID,
 			&u.Email,
 			&u.Username,
 			&u.IsActive,
+			&u.IsBot,
 			&u.CreatedAt,
 		)
 		if err != nil {
@@ -229,3 +267,95 @@ func (r *PostgresConversationRepository) GetParticipants(ctx context.Context, co
 	}
 	return participants, nil
 }
+
+// SetName renames a conversation
+func (r *PostgresConversationRepository) SetName(ctx context.Context, conversationID, name string) error {
+	result, err := r.db.Pool.Exec(ctx, `UPDATE conversations SET name = $2 WHERE id = $1`, conversationID, name)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrConversationNotFound
+	}
+	return nil
+}
+
+// SetDescription sets or clears (nil) the description of a conversation
+func (r *PostgresConversationRepository) SetDescription(ctx context.Context, conversationID string, description *string) error {
+	result, err := r.db.Pool.Exec(ctx, `UPDATE conversations SET description = $2 WHERE id = $1`, conversationID, description)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrConversationNotFound
+	}
+	return nil
+}
+
+// SetTopic sets or clears (nil) the topic of a conversation
+func (r *PostgresConversationRepository) SetTopic(ctx context.Context, conversationID string, topic *string) error {
+	result, err := r.db.Pool.Exec(ctx, `UPDATE conversations SET topic = $2 WHERE id = $1`, conversationID, topic)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrConversationNotFound
+	}
+	return nil
+}
+
+// SetVisibility makes a group public or private
+func (r *PostgresConversationRepository) SetVisibility(ctx context.Context, conversationID string, visibility model.Visibility) error {
+	result, err := r.db.Pool.Exec(ctx, `UPDATE conversations SET visibility = $2 WHERE id = $1`, conversationID, visibility)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrConversationNotFound
+	}
+	return nil
+}
+
+// SearchPublic lists public groups whose name or description contains the query
+// (all of them when empty), the biggest first. Private groups are never returned.
+func (r *PostgresConversationRepository) SearchPublic(ctx context.Context, userID, query string, limit, offset int) ([]model.DirectoryGroup, error) {
+	sql := `
+		SELECT c.id, c.type, c.name, c.description, c.topic, c.created_at,
+		       (SELECT COUNT(*) FROM conversation_participants cp
+		        WHERE cp.conversation_id = c.id AND cp.left_at IS NULL) AS member_count,
+		       EXISTS (SELECT 1 FROM conversation_participants cp
+		               WHERE cp.conversation_id = c.id AND cp.user_id = $1 AND cp.left_at IS NULL) AS is_member
+		FROM conversations c
+		WHERE c.type IN ('group', 'channel') AND c.visibility = 'public'
+		  AND ($2 = '' OR c.name ILIKE $3 OR c.description ILIKE $3)
+		ORDER BY member_count DESC, c.name, c.id
+		LIMIT $4 OFFSET $5
+	`
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+	rows, err := r.db.Pool.Query(ctx, sql, userID, query, pattern, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []model.DirectoryGroup{}
+	for rows.Next() {
+		var g model.DirectoryGroup
+		if err := rows.Scan(&g.ID, &g.Type, &g.Name, &g.Description, &g.Topic, &g.CreatedAt, &g.MemberCount, &g.IsMember); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// SetMuted mutes a conversation for a participant, until the given time or (nil) until unmuted
+func (r *PostgresConversationRepository) SetMuted(ctx context.Context, conversationID, userID string, muted bool, until *time.Time) error {
+	query := `
+		UPDATE conversation_participants
+		SET muted = $3, muted_until = $4
+		WHERE conversation_id = $1 AND user_id = $2 AND left_at IS NULL
+	`
+	_, err := r.db.Pool.Exec(ctx, query, conversationID, userID, muted, until)
+	return err
+}
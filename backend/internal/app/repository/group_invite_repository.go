@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/postgres"
+)
+
+var (
+	ErrGroupInviteNotFound = errors.New("group invite not found")
+	ErrGroupInviteUsedUp   = errors.New("group invite revoked, expired or used up")
+)
+
+// GroupInviteRepository defines the interface for group invite persistence
+type GroupInviteRepository interface {
+	Create(ctx context.Context, invite *model.GroupInvite) error
+	GetByID(ctx context.Context, id string) (*model.GroupInvite, error)
+	GetByHash(ctx context.Context, tokenHash string) (*model.GroupInvite, error)
+	ListByConversation(ctx context.Context, conversationID string) ([]model.GroupInvite, error)
+	Use(ctx context.Context, id string) error
+	Revoke(ctx context.Context, id string) error
+}
+
+// PostgresGroupInviteRepository implements GroupInviteRepository with PostgreSQL
+type PostgresGroupInviteRepository struct {
+	db *postgres.Client
+}
+
+// NewGroupInviteRepository creates a new group invite repository (Fx provider)
+func NewGroupInviteRepository(db *postgres.Client) GroupInviteRepository {
+	logger.Info("Group invite repository initialized")
+	return &PostgresGroupInviteRepository{db: db}
+}
+
+const groupInviteColumns = `
+	id, conversation_id, created_by, token_prefix, token_hash, expires_at, max_uses, uses,
+	requires_approval, revoked_at, created_at
+`
+
+func (r *PostgresGroupInviteRepository) Create(ctx context.Context, invite *model.GroupInvite) error {
+	query := `
+		INSERT INTO group_invites (conversation_id, created_by, token_prefix, token_hash, expires_at, max_uses, requires_approval)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	return r.db.Pool.QueryRow(ctx, query,
+		invite.ConversationID,
+		invite.CreatedBy,
+		invite.TokenPrefix,
+		invite.TokenHash,
+		invite.ExpiresAt,
+		invite.MaxUses,
+		invite.RequiresApproval,
+	).Scan(&invite.ID, &invite.CreatedAt)
+}
+
+func (r *PostgresGroupInviteRepository) GetByID(ctx context.Context, id string) (*model.GroupInvite, error) {
+	query := `SELECT ` + groupInviteColumns + ` FROM group_invites WHERE id = $1`
+	return r.scanGroupInvite(r.db.Pool.QueryRow(ctx, query, id))
+}
+
+func (r *PostgresGroupInviteRepository) GetByHash(ctx context.Context, tokenHash string) (*model.GroupInvite, error) {
+	query := `SELECT ` + groupInviteColumns + ` FROM group_invites WHERE token_hash = $1`
+	return r.scanGroupInvite(r.db.Pool.QueryRow(ctx, query, tokenHash))
+}
+
+// ListByConversation returns the invite links of a group that weren't revoked, newest first
+func (r *PostgresGroupInviteRepository) ListByConversation(ctx context.Context, conversationID string) ([]model.GroupInvite, error) {
+	query := `
+		SELECT ` + groupInviteColumns + `
+		FROM group_invites
+		WHERE conversation_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.Pool.Query(ctx, query, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invites := []model.GroupInvite{}
+	for rows.Next() {
+		invite, err := r.scanGroupInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, *invite)
+	}
+	return invites, rows.Err()
+}
+
+// Use counts a use of an invite link. The checks are repeated in the update, so two
+// people joining at once can't go over max_uses.
+func (r *PostgresGroupInviteRepository) Use(ctx context.Context, id string) error {
+	query := `
+		UPDATE group_invites
+		SET uses = uses + 1
+		WHERE id = $1
+		  AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		  AND (max_uses IS NULL OR uses < max_uses)
+	`
+	result, err := r.db.Pool.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrGroupInviteUsedUp
+	}
+	return nil
+}
+
+func (r *PostgresGroupInviteRepository) Revoke(ctx context.Context, id string) error {
+	query := `UPDATE group_invites SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+	result, err := r.db.Pool.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrGroupInviteNotFound
+	}
+	return nil
+}
+
+func (r *PostgresGroupInviteRepository) scanGroupInvite(row pgx.Row) (*model.GroupInvite, error) {
+	var i model.GroupInvite
+	err := row.Scan(
+		&i.ID,
+		&i.ConversationID,
+		&i.CreatedBy,
+		&i.TokenPrefix,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.MaxUses,
+		&i.Uses,
+		&i.RequiresApproval,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrGroupInviteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/postgres"
+)
+
+var (
+	ErrIncomingWebhookNotFound = errors.New("incoming webhook not found")
+)
+
+// IncomingWebhookRepository defines the interface for incoming webhook persistence
+type IncomingWebhookRepository interface {
+	Create(ctx context.Context, webhook *model.IncomingWebhook) error
+	GetByID(ctx context.Context, id string) (*model.IncomingWebhook, error)
+	GetByHash(ctx context.Context, tokenHash string) (*model.IncomingWebhook, error)
+	ListByConversation(ctx context.Context, conversationID string) ([]model.IncomingWebhook, error)
+	MarkUsed(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string) error
+}
+
+// PostgresIncomingWebhookRepository implements IncomingWebhookRepository with PostgreSQL
+type PostgresIncomingWebhookRepository struct {
+	db *postgres.Client
+}
+
+// NewIncomingWebhookRepository creates a new incoming webhook repository (Fx provider)
+func NewIncomingWebhookRepository(db *postgres.Client) IncomingWebhookRepository {
+	logger.Info("Incoming webhook repository initialized")
+	return &PostgresIncomingWebhookRepository{db: db}
+}
+
+const incomingWebhookColumns = `
+	id, conversation_id, bot_id, created_by, name, token_prefix, token_hash, last_used_at, created_at
+`
+
+func (r *PostgresIncomingWebhookRepository) Create(ctx context.Context, webhook *model.IncomingWebhook) error {
+	query := `
+		INSERT INTO incoming_webhooks (conversation_id, bot_id, created_by, name, token_prefix, token_hash)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	return r.db.Pool.QueryRow(ctx, query,
+		webhook.ConversationID,
+		webhook.BotID,
+		webhook.CreatedBy,
+		webhook.Name,
+		webhook.TokenPrefix,
+		webhook.TokenHash,
+	).Scan(&webhook.ID, &webhook.CreatedAt)
+}
+
+func (r *PostgresIncomingWebhookRepository) GetByID(ctx context.Context, id string) (*model.IncomingWebhook, error) {
+	query := `SELECT ` + incomingWebhookColumns + ` FROM incoming_webhooks WHERE id = $1`
+	return r.scanIncomingWebhook(r.db.Pool.QueryRow(ctx, query, id))
+}
+
+func (r *PostgresIncomingWebhookRepository) GetByHash(ctx context.Context, tokenHash string) (*model.IncomingWebhook, error) {
+	query := `SELECT ` + incomingWebhookColumns + ` FROM incoming_webhooks WHERE token_hash = $1`
+	return r.scanIncomingWebhook(r.db.Pool.QueryRow(ctx, query, tokenHash))
+}
+
+// ListByConversation returns the incoming webhooks of a conversation
+func (r *PostgresIncomingWebhookRepository) ListByConversation(ctx context.Context, conversationID string) ([]model.IncomingWebhook, error) {
+	query := `
+		SELECT ` + incomingWebhookColumns + `
+		FROM incoming_webhooks
+		WHERE conversation_id = $1
+		ORDER BY created_at
+	`
+	rows, err := r.db.Pool.Query(ctx, query, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []model.IncomingWebhook{}
+	for rows.Next() {
+		webhook, err := r.scanIncomingWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// MarkUsed records the last use of a webhook (at most once a minute, to limit writes)
+func (r *PostgresIncomingWebhookRepository) MarkUsed(ctx context.Context, id string) error {
+	query := `
+		UPDATE incoming_webhooks
+		SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`
+	_, err := r.db.Pool.Exec(ctx, query, id)
+	return err
+}
+
+func (r *PostgresIncomingWebhookRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.Pool.Exec(ctx, `DELETE FROM incoming_webhooks WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrIncomingWebhookNotFound
+	}
+	return nil
+}
+
+func (r *PostgresIncomingWebhookRepository) scanIncomingWebhook(row pgx.Row) (*model.IncomingWebhook, error) {
+	var w model.IncomingWebhook
+	err := row.Scan(
+		&w.ID,
+		&w.ConversationID,
+		&w.BotID,
+		&w.CreatedBy,
+		&w.Name,
+		&w.TokenPrefix,
+		&w.TokenHash,
+		&w.LastUsedAt,
+		&w.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrIncomingWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/postgres"
+)
+
+var (
+	ErrJoinRequestNotFound = errors.New("join request not found")
+	ErrJoinRequestExists   = errors.New("join request already pending")
+)
+
+// JoinRequestRepository defines the interface for join request persistence
+type JoinRequestRepository interface {
+	Create(ctx context.Context, req *model.JoinRequest) error
+	GetByID(ctx context.Context, id string) (*model.JoinRequest, error)
+	ListPending(ctx context.Context, conversationID string) ([]model.JoinRequest, error)
+	Decide(ctx context.Context, req *model.JoinRequest, status model.JoinRequestStatus, decidedBy string) error
+}
+
+// PostgresJoinRequestRepository implements JoinRequestRepository with PostgreSQL
+type PostgresJoinRequestRepository struct {
+	db *postgres.Client
+}
+
+// NewJoinRequestRepository creates a new join request repository (Fx provider)
+func NewJoinRequestRepository(db *postgres.Client) JoinRequestRepository {
+	logger.Info("Join request repository initialized")
+	return &PostgresJoinRequestRepository{db: db}
+}
+
+const joinRequestColumns = `
+	jr.id, jr.conversation_id, jr.user_id, u.username, jr.invite_id, jr.message, jr.status,
+	jr.decided_by, jr.decided_at, jr.created_at
+`
+
+func (r *PostgresJoinRequestRepository) Create(ctx context.Context, req *model.JoinRequest) error {
+	query := `
+		INSERT INTO join_requests (conversation_id, user_id, invite_id, message)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status, created_at
+	`
+	err := r.db.Pool.QueryRow(ctx, query,
+		req.ConversationID,
+		req.UserID,
+		req.InviteID,
+		req.Message,
+	).Scan(&req.ID, &req.Status, &req.CreatedAt)
+	if isDuplicateKeyError(err) {
+		return ErrJoinRequestExists
+	}
+	return err
+}
+
+func (r *PostgresJoinRequestRepository) GetByID(ctx context.Context, id string) (*model.JoinRequest, error) {
+	query := `
+		SELECT ` + joinRequestColumns + `
+		FROM join_requests jr
+		JOIN users u ON u.id = jr.user_id
+		WHERE jr.id = $1
+	`
+	return r.scanJoinRequest(r.db.Pool.QueryRow(ctx, query, id))
+}
+
+// ListPending returns the requests of a group waiting for a decision, oldest first
+func (r *PostgresJoinRequestRepository) ListPending(ctx context.Context, conversationID string) ([]model.JoinRequest, error) {
+	query := `
+		SELECT ` + joinRequestColumns + `
+		FROM join_requests jr
+		JOIN users u ON u.id = jr.user_id
+		WHERE jr.conversation_id = $1 AND jr.status = 'pending'
+		ORDER BY jr.created_at
+	`
+	rows, err := r.db.Pool.Query(ctx, query, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []model.JoinRequest{}
+	for rows.Next() {
+		req, err := r.scanJoinRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, *req)
+	}
+	return requests, rows.Err()
+}
+
+// Decide approves or denies a pending request. ErrJoinRequestNotFound means it was
+// decided in the meantime.
+func (r *PostgresJoinRequestRepository) Decide(ctx context.Context, req *model.JoinRequest, status model.JoinRequestStatus, decidedBy string) error {
+	query := `
+		UPDATE join_requests
+		SET status = $2, decided_by = $3, decided_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+		RETURNING decided_at
+	`
+	err := r.db.Pool.QueryRow(ctx, query, req.ID, status, decidedBy).Scan(&req.DecidedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrJoinRequestNotFound
+	}
+	if err != nil {
+		return err
+	}
+	req.Status = status
+	req.DecidedBy = &decidedBy
+	return nil
+}
+
+func (r *PostgresJoinRequestRepository) scanJoinRequest(row pgx.Row) (*model.JoinRequest, error) {
+	var req model.JoinRequest
+	err := row.Scan(
+		&req.ID,
+		&req.ConversationID,
+		&req.UserID,
+		&req.Username,
+		&req.InviteID,
+		&req.Message,
+		&req.Status,
+		&req.DecidedBy,
+		&req.DecidedAt,
+		&req.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrJoinRequestNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
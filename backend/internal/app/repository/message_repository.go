@@ -23,6 +23,10 @@ type MessageRepository interface {
 	GetByID(ctx context.Context, id string) (*model.Message, error)
 	GetByConversation(ctx context.Context, conversationID string, cursor *time.Time, limit int) (*model.MessagesPage, error)
 	GetLastMessage(ctx context.Context, conversationID string) (*model.Message, error)
+	Update(ctx context.Context, msg *model.Message) error
+	Delete(ctx context.Context, id string) error
+	SetPinned(ctx context.Context, msg *model.Message, pinnedBy *string) error
+	GetPinned(ctx context.Context, conversationID string) ([]model.Message, error)
 }
 
 // PostgresMessageRepository implements MessageRepository with PostgreSQL
@@ -38,8 +42,8 @@ func NewMessageRepository(db *postgres.Client) MessageRepository {
 
 func (r *PostgresMessageRepository) Create(ctx context.Context, msg *model.Message) error {
 	query := `
-		INSERT INTO messages (conversation_id, sender_id, content, type, sent_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO messages (conversation_id, sender_id, content, type, metadata, sent_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id
 	`
 	return r.db.Pool.QueryRow(ctx, query,
@@ -47,6 +51,7 @@ func (r *PostgresMessageRepository) Create(ctx context.Context, msg *model.Messa
 		msg.SenderID,
 		msg.Content,
 		msg.Type,
+		msg.Metadata,
 		msg.SentAt,
 	).Scan(&msg.ID)
 }
@@ -62,15 +67,20 @@ func (r *PostgresMessageRepository) CreateBatch(ctx context.Context, msgs []*mod
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
+	// Keep the ID the message was delivered with, so clients can refer to it (edits,
+	// card interactions). A message read again after a worker crash is skipped.
 	for _, msg := range msgs {
 		_, err = tx.Exec(ctx, `
-			INSERT INTO messages (conversation_id, sender_id, content, type, sent_at)
-			VALUES ($1, $2, $3, $4, $5)
+			INSERT INTO messages (id, conversation_id, sender_id, content, type, metadata, sent_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (id) DO NOTHING
 		`,
+			msg.ID,
 			msg.ConversationID,
 			msg.SenderID,
 			msg.Content,
 			msg.Type,
+			msg.Metadata,
 			msg.SentAt,
 		)
 		if err != nil {
@@ -84,7 +94,7 @@ func (r *PostgresMessageRepository) CreateBatch(ctx context.Context, msgs []*mod
 
 func (r *PostgresMessageRepository) GetByID(ctx context.Context, id string) (*model.Message, error) {
 	query := `
-		SELECT m.id, m.conversation_id, m.sender_id, u.username, m.content, m.type, m.sent_at
+		SELECT m.id, m.conversation_id, m.sender_id, u.username, m.content, m.type, m.metadata, m.sent_at, m.edited_at, m.pinned_at, m.pinned_by
 		FROM messages m
 		JOIN users u ON m.sender_id = u.id
 		WHERE m.id = $1
@@ -98,7 +108,11 @@ func (r *PostgresMessageRepository) GetByID(ctx context.Context, id string) (*mo
 		&msg.SenderUsername,
 		&msg.Content,
 		&msg.Type,
+		&msg.Metadata,
 		&msg.SentAt,
+		&msg.EditedAt,
+		&msg.PinnedAt,
+		&msg.PinnedBy,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, ErrMessageNotFound
@@ -125,7 +139,7 @@ func (r *PostgresMessageRepository) GetByConversation(ctx context.Context, conve
 		// Cursor means "get messages older than this timestamp"
 		query = `
 			SELECT * FROM (
-				SELECT m.id, m.conversation_id, m.sender_id, u.username, m.content, m.type, m.sent_at
+				SELECT m.id, m.conversation_id, m.sender_id, u.username, m.content, m.type, m.metadata, m.sent_at, m.edited_at, m.pinned_at, m.pinned_by
 				FROM messages m
 				JOIN users u ON m.sender_id = u.id
 				WHERE m.conversation_id = $1 AND m.sent_at < $2
@@ -139,7 +153,7 @@ func (r *PostgresMessageRepository) GetByConversation(ctx context.Context, conve
 		// No cursor = get the most recent messages
 		query = `
 			SELECT * FROM (
-				SELECT m.id, m.conversation_id, m.sender_id, u.username, m.content, m.type, m.sent_at
+				SELECT m.id, m.conversation_id, m.sender_id, u.username, m.content, m.type, m.metadata, m.sent_at, m.edited_at, m.pinned_at, m.pinned_by
 				FROM messages m
 				JOIN users u ON m.sender_id = u.id
 				WHERE m.conversation_id = $1
@@ -168,7 +182,11 @@ func (r *PostgresMessageRepository) GetByConversation(ctx context.Context, conve
 			&msg.SenderUsername,
 			&msg.Content,
 			&msg.Type,
+			&msg.Metadata,
 			&msg.SentAt,
+			&msg.EditedAt,
+			&msg.PinnedAt,
+			&msg.PinnedBy,
 		)
 		if err != nil {
 			return nil, err
@@ -202,7 +220,7 @@ func (r *PostgresMessageRepository) GetByConversation(ctx context.Context, conve
 
 func (r *PostgresMessageRepository) GetLastMessage(ctx context.Context, conversationID string) (*model.Message, error) {
 	query := `
-		SELECT m.id, m.conversation_id, m.sender_id, u.username, m.content, m.type, m.sent_at
+		SELECT m.id, m.conversation_id, m.sender_id, u.username, m.content, m.type, m.metadata, m.sent_at, m.edited_at, m.pinned_at, m.pinned_by
 		FROM messages m
 		JOIN users u ON m.sender_id = u.id
 		WHERE m.conversation_id = $1
@@ -218,7 +236,11 @@ func (r *PostgresMessageRepository) GetLastMessage(ctx context.Context, conversa
 		&msg.SenderUsername,
 		&msg.Content,
 		&msg.Type,
+		&msg.Metadata,
 		&msg.SentAt,
+		&msg.EditedAt,
+		&msg.PinnedAt,
+		&msg.PinnedBy,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil // No messages yet, not an error
@@ -229,3 +251,83 @@ func (r *PostgresMessageRepository) GetLastMessage(ctx context.Context, conversa
 
 	return &msg, nil
 }
+
+// Update saves the content and metadata of a message and sets its edited_at
+func (r *PostgresMessageRepository) Update(ctx context.Context, msg *model.Message) error {
+	query := `
+		UPDATE messages
+		SET content = $2, metadata = $3, edited_at = NOW()
+		WHERE id = $1
+		RETURNING edited_at
+	`
+	err := r.db.Pool.QueryRow(ctx, query, msg.ID, msg.Content, msg.Metadata).Scan(&msg.EditedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrMessageNotFound
+	}
+	return err
+}
+
+func (r *PostgresMessageRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.Pool.Exec(ctx, `DELETE FROM messages WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrMessageNotFound
+	}
+	return nil
+}
+
+// SetPinned pins a message (pinnedBy set) or unpins it (nil)
+func (r *PostgresMessageRepository) SetPinned(ctx context.Context, msg *model.Message, pinnedBy *string) error {
+	query := `
+		UPDATE messages
+		SET pinned_at = CASE WHEN $2::uuid IS NULL THEN NULL ELSE NOW() END, pinned_by = $2
+		WHERE id = $1
+		RETURNING pinned_at, pinned_by
+	`
+	err := r.db.Pool.QueryRow(ctx, query, msg.ID, pinnedBy).Scan(&msg.PinnedAt, &msg.PinnedBy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrMessageNotFound
+	}
+	return err
+}
+
+// GetPinned returns the pinned messages of a conversation, most recently pinned first
+func (r *PostgresMessageRepository) GetPinned(ctx context.Context, conversationID string) ([]model.Message, error) {
+	query := `
+		SELECT m.id, m.conversation_id, m.sender_id, u.username, m.content, m.type, m.metadata, m.sent_at, m.edited_at, m.pinned_at, m.pinned_by
+		FROM messages m
+		JOIN users u ON m.sender_id = u.id
+		WHERE m.conversation_id = $1 AND m.pinned_at IS NOT NULL
+		ORDER BY m.pinned_at DESC
+	`
+	rows, err := r.db.Pool.Query(ctx, query, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]model.Message, 0)
+	for rows.Next() {
+		var msg model.Message
+		err := rows.Scan(
+			&msg.ID,
+			&msg.ConversationID,
+			&msg.SenderID,
+			&msg.SenderUsername,
+			&msg.Content,
+			&msg.Type,
+			&msg.Metadata,
+			&msg.SentAt,
+			&msg.EditedAt,
+			&msg.PinnedAt,
+			&msg.PinnedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/postgres"
+)
+
+var (
+	ErrPasskeyNotFound      = errors.New("passkey not found")
+	ErrPasskeyAlreadyExists = errors.New("passkey already registered")
+)
+
+// PasskeyRepository defines the interface for WebAuthn credential persistence
+type PasskeyRepository interface {
+	Create(ctx context.Context, passkey *model.Passkey) error
+	ListByUser(ctx context.Context, userID string) ([]model.Passkey, error)
+	GetByCredentialID(ctx context.Context, credentialID []byte) (*model.Passkey, error)
+	MarkUsed(ctx context.Context, id string, signCount uint32, backupState bool) error
+	Delete(ctx context.Context, userID, id string) error
+}
+
+// PostgresPasskeyRepository implements PasskeyRepository with PostgreSQL
+type PostgresPasskeyRepository struct {
+	db *postgres.Client
+}
+
+// NewPasskeyRepository creates a new passkey repository (Fx provider)
+func NewPasskeyRepository(db *postgres.Client) PasskeyRepository {
+	logger.Info("Passkey repository initialized")
+	return &PostgresPasskeyRepository{db: db}
+}
+
+const passkeyColumns = `
+	id, user_id, credential_id, public_key, attestation_type, aaguid, sign_count,
+	transports, backup_eligible, backup_state, name, created_at, last_used_at
+`
+
+func (r *PostgresPasskeyRepository) Create(ctx context.Context, passkey *model.Passkey) error {
+	query := `
+		INSERT INTO webauthn_credentials (
+			user_id, credential_id, public_key, attestation_type, aaguid, sign_count,
+			transports, backup_eligible, backup_state, name
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at
+	`
+	err := r.db.Pool.QueryRow(ctx, query,
+		passkey.UserID,
+		passkey.CredentialID,
+		passkey.PublicKey,
+		passkey.AttestationType,
+		passkey.AAGUID,
+		int64(passkey.SignCount),
+		passkey.Transports,
+		passkey.BackupEligible,
+		passkey.BackupState,
+		passkey.Name,
+	).Scan(&passkey.ID, &passkey.CreatedAt)
+
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrPasskeyAlreadyExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresPasskeyRepository) ListByUser(ctx context.Context, userID string) ([]model.Passkey, error) {
+	query := `SELECT ` + passkeyColumns + ` FROM webauthn_credentials WHERE user_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var passkeys []model.Passkey
+	for rows.Next() {
+		passkey, err := r.scanPasskey(rows)
+		if err != nil {
+			return nil, err
+		}
+		passkeys = append(passkeys, *passkey)
+	}
+	return passkeys, rows.Err()
+}
+
+func (r *PostgresPasskeyRepository) GetByCredentialID(ctx context.Context, credentialID []byte) (*model.Passkey, error) {
+	query := `SELECT ` + passkeyColumns + ` FROM webauthn_credentials WHERE credential_id = $1`
+	return r.scanPasskey(r.db.Pool.QueryRow(ctx, query, credentialID))
+}
+
+// MarkUsed stores the new sign counter and backup state after a login
+func (r *PostgresPasskeyRepository) MarkUsed(ctx context.Context, id string, signCount uint32, backupState bool) error {
+	query := `
+		UPDATE webauthn_credentials
+		SET sign_count = $2, backup_state = $3, last_used_at = NOW()
+		WHERE id = $1
+	`
+	_, err := r.db.Pool.Exec(ctx, query, id, int64(signCount), backupState)
+	return err
+}
+
+// Delete removes one of the user's passkeys
+func (r *PostgresPasskeyRepository) Delete(ctx context.Context, userID, id string) error {
+	query := `DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2`
+	result, err := r.db.Pool.Exec(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrPasskeyNotFound
+	}
+	return nil
+}
+
+func (r *PostgresPasskeyRepository) scanPasskey(row pgx.Row) (*model.Passkey, error) {
+	var p model.Passkey
+	var signCount int64
+	err := row.Scan(
+		&p.ID,
+		&p.UserID,
+		&p.CredentialID,
+		&p.PublicKey,
+		&p.AttestationType,
+		&p.AAGUID,
+		&signCount,
+		&p.Transports,
+		&p.BackupEligible,
+		&p.BackupState,
+		&p.Name,
+		&p.CreatedAt,
+		&p.LastUsedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPasskeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.SignCount = uint32(signCount)
+	return &p, nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/postgres"
+)
+
+var (
+	ErrRecoveryCodeNotFound = errors.New("recovery code not found")
+)
+
+// RecoveryCodeRepository defines the interface for 2FA recovery code persistence
+type RecoveryCodeRepository interface {
+	Replace(ctx context.Context, userID string, codeHashes []string) error
+	Consume(ctx context.Context, userID, codeHash string) error
+	CountUnused(ctx context.Context, userID string) (int, error)
+	DeleteForUser(ctx context.Context, userID string) error
+}
+
+// PostgresRecoveryCodeRepository implements RecoveryCodeRepository with PostgreSQL
+type PostgresRecoveryCodeRepository struct {
+	db *postgres.Client
+}
+
+// NewRecoveryCodeRepository creates a new recovery code repository (Fx provider)
+func NewRecoveryCodeRepository(db *postgres.Client) RecoveryCodeRepository {
+	logger.Info("Recovery code repository initialized")
+	return &PostgresRecoveryCodeRepository{db: db}
+}
+
+// Replace deletes every code of the user and stores the new ones
+func (r *PostgresRecoveryCodeRepository) Replace(ctx context.Context, userID string, codeHashes []string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO recovery_codes (user_id, code_hash)
+			VALUES ($1, $2)
+		`, userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Consume marks an unused code as used.
+// Returns ErrRecoveryCodeNotFound if it doesn't exist or was already used.
+func (r *PostgresRecoveryCodeRepository) Consume(ctx context.Context, userID, codeHash string) error {
+	query := `
+		UPDATE recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	result, err := r.db.Pool.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRecoveryCodeRepository) CountUnused(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL
+	`, userID).Scan(&count)
+	return count, err
+}
+
+func (r *PostgresRecoveryCodeRepository) DeleteForUser(ctx context.Context, userID string) error {
+	_, err := r.db.Pool.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	return err
+}
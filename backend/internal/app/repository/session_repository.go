@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/postgres"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrStaleRefreshJTI = errors.New("refresh token is not the current one")
+)
+
+// SessionRepository defines the interface for session persistence
+type SessionRepository interface {
+	Create(ctx context.Context, session *model.Session) error
+	GetByID(ctx context.Context, id string) (*model.Session, error)
+	Rotate(ctx context.Context, id, currentJTI, newJTI string, expiresAt time.Time) error
+	ListActiveByUser(ctx context.Context, userID string) ([]model.Session, error)
+	Revoke(ctx context.Context, id, reason string) error
+	RevokeAllForUser(ctx context.Context, userID, exceptID, reason string) ([]string, error)
+}
+
+// PostgresSessionRepository implements SessionRepository with PostgreSQL
+type PostgresSessionRepository struct {
+	db *postgres.Client
+}
+
+// NewSessionRepository creates a new session repository (Fx provider)
+func NewSessionRepository(db *postgres.Client) SessionRepository {
+	logger.Info("Session repository initialized")
+	return &PostgresSessionRepository{db: db}
+}
+
+func (r *PostgresSessionRepository) Create(ctx context.Context, session *model.Session) error {
+	query := `
+		INSERT INTO sessions (user_id, refresh_jti, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, last_used_at
+	`
+	return r.db.Pool.QueryRow(ctx, query,
+		session.UserID,
+		session.RefreshJTI,
+		session.UserAgent,
+		session.IPAddress,
+		session.ExpiresAt,
+	).Scan(&session.ID, &session.CreatedAt, &session.LastUsedAt)
+}
+
+func (r *PostgresSessionRepository) GetByID(ctx context.Context, id string) (*model.Session, error) {
+	query := `
+		SELECT id, user_id, refresh_jti, COALESCE(user_agent, ''), COALESCE(ip_address, ''),
+		       created_at, last_used_at, expires_at, revoked_at, revoked_reason
+		FROM sessions
+		WHERE id = $1
+	`
+	return r.scanSession(r.db.Pool.QueryRow(ctx, query, id))
+}
+
+// Rotate swaps the current refresh token ID atomically.
+// Returns ErrStaleRefreshJTI if currentJTI is not the latest one (token reuse)
+// or the session was revoked in the meantime.
+func (r *PostgresSessionRepository) Rotate(ctx context.Context, id, currentJTI, newJTI string, expiresAt time.Time) error {
+	query := `
+		UPDATE sessions
+		SET refresh_jti = $3, last_used_at = NOW(), expires_at = $4
+		WHERE id = $1 AND refresh_jti = $2 AND revoked_at IS NULL
+	`
+	result, err := r.db.Pool.Exec(ctx, query, id, currentJTI, newJTI, expiresAt)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrStaleRefreshJTI
+	}
+	return nil
+}
+
+func (r *PostgresSessionRepository) ListActiveByUser(ctx context.Context, userID string) ([]model.Session, error) {
+	query := `
+		SELECT id, user_id, refresh_jti, COALESCE(user_agent, ''), COALESCE(ip_address, ''),
+		       created_at, last_used_at, expires_at, revoked_at, revoked_reason
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_used_at DESC
+	`
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []model.Session
+	for rows.Next() {
+		session, err := r.scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *session)
+	}
+	return sessions, rows.Err()
+}
+
+func (r *PostgresSessionRepository) Revoke(ctx context.Context, id, reason string) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = NOW(), revoked_reason = $2
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+	_, err := r.db.Pool.Exec(ctx, query, id, reason)
+	return err
+}
+
+// RevokeAllForUser revokes every active session of a user except exceptID (may be empty)
+// and returns the revoked IDs
+func (r *PostgresSessionRepository) RevokeAllForUser(ctx context.Context, userID, exceptID, reason string) ([]string, error) {
+	query := `
+		UPDATE sessions
+		SET revoked_at = NOW(), revoked_reason = $3
+		WHERE user_id = $1 AND revoked_at IS NULL AND ($2 = '' OR id::text <> $2)
+		RETURNING id
+	`
+	rows, err := r.db.Pool.Query(ctx, query, userID, exceptID, reason)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *PostgresSessionRepository) scanSession(row pgx.Row) (*model.Session, error) {
+	var s model.Session
+	err := row.Scan(
+		&s.ID,
+		&s.UserID,
+		&s.RefreshJTI,
+		&s.UserAgent,
+		&s.IPAddress,
+		&s.CreatedAt,
+		&s.LastUsedAt,
+		&s.ExpiresAt,
+		&s.RevokedAt,
+		&s.RevokedReason,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
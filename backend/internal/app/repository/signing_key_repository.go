@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/postgres"
+)
+
+// SigningKeyRepository defines the interface for JWT signing key persistence
+type SigningKeyRepository interface {
+	ListUsable(ctx context.Context) ([]model.SigningKey, error)
+	CreateNext(ctx context.Context, key *model.SigningKey, previous *time.Time, grace time.Duration) (bool, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// PostgresSigningKeyRepository implements SigningKeyRepository with PostgreSQL
+type PostgresSigningKeyRepository struct {
+	db *postgres.Client
+}
+
+// NewSigningKeyRepository creates a new signing key repository (Fx provider)
+func NewSigningKeyRepository(db *postgres.Client) SigningKeyRepository {
+	logger.Info("Signing key repository initialized")
+	return &PostgresSigningKeyRepository{db: db}
+}
+
+// ListUsable returns the keys that are not past their grace period, newest first
+func (r *PostgresSigningKeyRepository) ListUsable(ctx context.Context) ([]model.SigningKey, error) {
+	query := `
+		SELECT kid, algorithm, private_key, created_at, activates_at, expires_at
+		FROM signing_keys
+		WHERE expires_at IS NULL OR expires_at > NOW()
+		ORDER BY activates_at DESC
+	`
+	rows, err := r.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []model.SigningKey
+	for rows.Next() {
+		var k model.SigningKey
+		if err := rows.Scan(&k.KID, &k.Algorithm, &k.PrivateKey, &k.CreatedAt, &k.ActivatesAt, &k.ExpiresAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// CreateNext stores the next key of its algorithm and starts the grace period of the current keys
+// (they expire grace after the new key activates). previous is the activation time of the newest
+// key the caller saw (nil if none): if another instance rotated in the meantime, nothing is
+// created and false is returned.
+func (r *PostgresSigningKeyRepository) CreateNext(ctx context.Context, key *model.SigningKey, previous *time.Time, grace time.Duration) (bool, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialize rotations across instances
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('signing_keys'))`); err != nil {
+		return false, err
+	}
+
+	var newest *time.Time
+	err = tx.QueryRow(ctx,
+		`SELECT MAX(activates_at) FROM signing_keys WHERE algorithm = $1 AND expires_at IS NULL`,
+		key.Algorithm,
+	).Scan(&newest)
+	if err != nil {
+		return false, err
+	}
+	if newest != nil && (previous == nil || newest.After(*previous)) {
+		return false, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE signing_keys
+		SET expires_at = $1
+		WHERE expires_at IS NULL
+	`, key.ActivatesAt.Add(grace))
+	if err != nil {
+		return false, err
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO signing_keys (kid, algorithm, private_key, activates_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at
+	`, key.KID, key.Algorithm, key.PrivateKey, key.ActivatesAt).Scan(&key.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// DeleteExpired removes keys past their grace period
+func (r *PostgresSigningKeyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.Pool.Exec(ctx, `DELETE FROM signing_keys WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
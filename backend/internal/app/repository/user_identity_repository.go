@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/postgres"
+)
+
+var (
+	ErrIdentityNotFound      = errors.New("identity not found")
+	ErrIdentityAlreadyLinked = errors.New("identity already linked")
+)
+
+// UserIdentityRepository defines the interface for linked identity persistence
+type UserIdentityRepository interface {
+	Create(ctx context.Context, identity *model.UserIdentity) error
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*model.UserIdentity, error)
+	MarkLogin(ctx context.Context, id, email string) error
+}
+
+// PostgresUserIdentityRepository implements UserIdentityRepository with PostgreSQL
+type PostgresUserIdentityRepository struct {
+	db *postgres.Client
+}
+
+// NewUserIdentityRepository creates a new user identity repository (Fx provider)
+func NewUserIdentityRepository(db *postgres.Client) UserIdentityRepository {
+	logger.Info("User identity repository initialized")
+	return &PostgresUserIdentityRepository{db: db}
+}
+
+func (r *PostgresUserIdentityRepository) Create(ctx context.Context, identity *model.UserIdentity) error {
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email, last_login_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NOW())
+		RETURNING id, created_at, last_login_at
+	`
+	err := r.db.Pool.QueryRow(ctx, query,
+		identity.UserID,
+		identity.Provider,
+		identity.Subject,
+		identity.Email,
+	).Scan(&identity.ID, &identity.CreatedAt, &identity.LastLoginAt)
+
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrIdentityAlreadyLinked
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresUserIdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*model.UserIdentity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, COALESCE(email, ''), created_at, last_login_at
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2
+	`
+	var identity model.UserIdentity
+	err := r.db.Pool.QueryRow(ctx, query, provider, subject).Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+		&identity.LastLoginAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrIdentityNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// MarkLogin records a login and the email currently asserted by the provider
+func (r *PostgresUserIdentityRepository) MarkLogin(ctx context.Context, id, email string) error {
+	query := `
+		UPDATE user_identities
+		SET last_login_at = NOW(), email = COALESCE(NULLIF($2, ''), email)
+		WHERE id = $1
+	`
+	_, err := r.db.Pool.Exec(ctx, query, id, email)
+	return err
+}
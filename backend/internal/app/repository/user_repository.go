@@ -23,7 +23,13 @@ type UserRepository interface {
 	GetByEmail(ctx context.Context, email string) (*model.User, error)
 	GetByUsername(ctx context.Context, username string) (*model.User, error)
 	Update(ctx context.Context, user *model.User) error
+	RehashPassword(ctx context.Context, id, oldHash, newHash string) error
+	MarkEmailVerified(ctx context.Context, id string) error
+	SetPendingTOTP(ctx context.Context, id, secret string) error
+	EnableTOTP(ctx context.Context, id string) error
+	DisableTOTP(ctx context.Context, id string) error
 	Delete(ctx context.Context, id string) error
+	ListBots(ctx context.Context, ownerID string) ([]model.User, error)
 }
 
 // PostgresUserRepository implements UserRepository with PostgreSQL
@@ -39,8 +45,8 @@ func NewUserRepository(db *postgres.Client) UserRepository {
 
 func (r *PostgresUserRepository) Create(ctx context.Context, user *model.User) error {
 	query := `
-		INSERT INTO users (email, username, password_hash, is_active)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO users (email, username, password_hash, is_active, email_verified_at, is_bot, bot_owner_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at, updated_at
 	`
 	err := r.db.Pool.QueryRow(ctx, query,
@@ -48,6 +54,9 @@ func (r *PostgresUserRepository) Create(ctx context.Context, user *model.User) e
 		user.Username,
 		user.PasswordHash,
 		user.IsActive,
+		user.EmailVerifiedAt,
+		user.IsBot,
+		user.BotOwnerID,
 	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
@@ -61,7 +70,8 @@ func (r *PostgresUserRepository) Create(ctx context.Context, user *model.User) e
 
 func (r *PostgresUserRepository) GetByID(ctx context.Context, id string) (*model.User, error) {
 	query := `
-		SELECT id, email, username, password_hash, is_active, created_at, updated_at
+		SELECT id, email, username, password_hash, is_active, email_verified_at,
+		       COALESCE(totp_secret, ''), totp_enabled_at, is_admin, is_bot, bot_owner_id, created_at, updated_at
 		FROM users
 		WHERE id = $1 AND is_active = true
 	`
@@ -70,7 +80,8 @@ func (r *PostgresUserRepository) GetByID(ctx context.Context, id string) (*model
 
 func (r *PostgresUserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
 	query := `
-		SELECT id, email, username, password_hash, is_active, created_at, updated_at
+		SELECT id, email, username, password_hash, is_active, email_verified_at,
+		       COALESCE(totp_secret, ''), totp_enabled_at, is_admin, is_bot, bot_owner_id, created_at, updated_at
 		FROM users
 		WHERE email = $1 AND is_active = true
 	`
@@ -79,7 +90,8 @@ func (r *PostgresUserRepository) GetByEmail(ctx context.Context, email string) (
 
 func (r *PostgresUserRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
 	query := `
-		SELECT id, email, username, password_hash, is_active, created_at, updated_at
+		SELECT id, email, username, password_hash, is_active, email_verified_at,
+		       COALESCE(totp_secret, ''), totp_enabled_at, is_admin, is_bot, bot_owner_id, created_at, updated_at
 		FROM users
 		WHERE username = $1 AND is_active = true
 	`
@@ -107,6 +119,78 @@ func (r *PostgresUserRepository) Update(ctx context.Context, user *model.User) e
 	return err
 }
 
+// RehashPassword replaces a password hash with a stronger one of the same password.
+// It does nothing if the password was changed in the meantime.
+func (r *PostgresUserRepository) RehashPassword(ctx context.Context, id, oldHash, newHash string) error {
+	query := `
+		UPDATE users
+		SET password_hash = $3
+		WHERE id = $1 AND password_hash = $2
+	`
+	_, err := r.db.Pool.Exec(ctx, query, id, oldHash, newHash)
+	return err
+}
+
+// MarkEmailVerified records that the user confirmed their email (keeps the first confirmation time)
+func (r *PostgresUserRepository) MarkEmailVerified(ctx context.Context, id string) error {
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		WHERE id = $1 AND is_active = true
+	`
+	result, err := r.db.Pool.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// SetPendingTOTP stores a new TOTP secret that is not enforced until EnableTOTP.
+// Fails with ErrUserNotFound if 2FA is already enabled.
+func (r *PostgresUserRepository) SetPendingTOTP(ctx context.Context, id, secret string) error {
+	query := `
+		UPDATE users
+		SET totp_secret = $2, updated_at = NOW()
+		WHERE id = $1 AND is_active = true AND totp_enabled_at IS NULL
+	`
+	return r.execOne(ctx, query, id, secret)
+}
+
+// EnableTOTP starts enforcing the pending TOTP secret
+func (r *PostgresUserRepository) EnableTOTP(ctx context.Context, id string) error {
+	query := `
+		UPDATE users
+		SET totp_enabled_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND is_active = true AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL
+	`
+	return r.execOne(ctx, query, id)
+}
+
+// DisableTOTP removes the TOTP secret
+func (r *PostgresUserRepository) DisableTOTP(ctx context.Context, id string) error {
+	query := `
+		UPDATE users
+		SET totp_secret = NULL, totp_enabled_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND is_active = true
+	`
+	return r.execOne(ctx, query, id)
+}
+
+// execOne runs an UPDATE that must match exactly one active user
+func (r *PostgresUserRepository) execOne(ctx context.Context, query string, args ...interface{}) error {
+	result, err := r.db.Pool.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
 func (r *PostgresUserRepository) Delete(ctx context.Context, id string) error {
 	query := `UPDATE users SET is_active = false, updated_at = NOW() WHERE id = $1`
 	result, err := r.db.Pool.Exec(ctx, query, id)
@@ -119,6 +203,32 @@ func (r *PostgresUserRepository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// ListBots returns the active bots of an owner
+func (r *PostgresUserRepository) ListBots(ctx context.Context, ownerID string) ([]model.User, error) {
+	query := `
+		SELECT id, email, username, password_hash, is_active, email_verified_at,
+		       COALESCE(totp_secret, ''), totp_enabled_at, is_admin, is_bot, bot_owner_id, created_at, updated_at
+		FROM users
+		WHERE bot_owner_id = $1 AND is_bot = true AND is_active = true
+		ORDER BY created_at
+	`
+	rows, err := r.db.Pool.Query(ctx, query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bots := []model.User{}
+	for rows.Next() {
+		bot, err := r.scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		bots = append(bots, *bot)
+	}
+	return bots, rows.Err()
+}
+
 func (r *PostgresUserRepository) scanUser(row pgx.Row) (*model.User, error) {
 	var user model.User
 	err := row.Scan(
@@ -127,6 +237,12 @@ func (r *PostgresUserRepository) scanUser(row pgx.Row) (*model.User, error) {
 		&user.Username,
 		&user.PasswordHash,
 		&user.IsActive,
+		&user.EmailVerifiedAt,
+		&user.TOTPSecret,
+		&user.TOTPEnabledAt,
+		&user.IsAdmin,
+		&user.IsBot,
+		&user.BotOwnerID,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
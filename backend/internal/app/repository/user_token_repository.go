@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/postgres"
+)
+
+var (
+	ErrUserTokenNotFound = errors.New("user token not found")
+)
+
+// UserTokenRepository defines the interface for single-use user token persistence
+type UserTokenRepository interface {
+	Create(ctx context.Context, token *model.UserToken) error
+	Consume(ctx context.Context, purpose model.UserTokenPurpose, tokenHash string) (*model.UserToken, error)
+	InvalidateForUser(ctx context.Context, userID string, purpose model.UserTokenPurpose) error
+}
+
+// PostgresUserTokenRepository implements UserTokenRepository with PostgreSQL
+type PostgresUserTokenRepository struct {
+	db *postgres.Client
+}
+
+// NewUserTokenRepository creates a new user token repository (Fx provider)
+func NewUserTokenRepository(db *postgres.Client) UserTokenRepository {
+	logger.Info("User token repository initialized")
+	return &PostgresUserTokenRepository{db: db}
+}
+
+func (r *PostgresUserTokenRepository) Create(ctx context.Context, token *model.UserToken) error {
+	query := `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	return r.db.Pool.QueryRow(ctx, query,
+		token.UserID,
+		token.Purpose,
+		token.TokenHash,
+		token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+}
+
+// Consume marks a valid (unused, unexpired) token as used and returns it.
+// Returns ErrUserTokenNotFound if the token doesn't exist, expired or was already used.
+func (r *PostgresUserTokenRepository) Consume(ctx context.Context, purpose model.UserTokenPurpose, tokenHash string) (*model.UserToken, error) {
+	query := `
+		UPDATE user_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, purpose, token_hash, expires_at, used_at, created_at
+	`
+	var t model.UserToken
+	err := r.db.Pool.QueryRow(ctx, query, tokenHash, purpose).Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Purpose,
+		&t.TokenHash,
+		&t.ExpiresAt,
+		&t.UsedAt,
+		&t.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// InvalidateForUser marks all outstanding tokens of a purpose as used
+func (r *PostgresUserTokenRepository) InvalidateForUser(ctx context.Context, userID string, purpose model.UserTokenPurpose) error {
+	query := `
+		UPDATE user_tokens
+		SET used_at = NOW()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`
+	_, err := r.db.Pool.Exec(ctx, query, userID, purpose)
+	return err
+}
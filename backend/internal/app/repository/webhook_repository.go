@@ -0,0 +1,261 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/postgres"
+)
+
+var (
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
+// WebhookRepository defines the interface for webhook and delivery log persistence
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *model.Webhook) error
+	GetByID(ctx context.Context, id string) (*model.Webhook, error)
+	ListByConversation(ctx context.Context, conversationID string) ([]model.Webhook, error)
+	ListByBot(ctx context.Context, botID string) ([]model.Webhook, error)
+	ListActiveForConversation(ctx context.Context, conversationID string) ([]model.Webhook, error)
+	Delete(ctx context.Context, id string) error
+	Enable(ctx context.Context, id string) error
+	RecordSuccess(ctx context.Context, id string) error
+	RecordFailure(ctx context.Context, id string, disableAfter int) (disabled bool, err error)
+	CreateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, webhookID string, limit int) ([]model.WebhookDelivery, error)
+	DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// PostgresWebhookRepository implements WebhookRepository with PostgreSQL
+type PostgresWebhookRepository struct {
+	db *postgres.Client
+}
+
+// NewWebhookRepository creates a new webhook repository (Fx provider)
+func NewWebhookRepository(db *postgres.Client) WebhookRepository {
+	logger.Info("Webhook repository initialized")
+	return &PostgresWebhookRepository{db: db}
+}
+
+const webhookColumns = `
+	id, created_by, conversation_id, bot_id, url, secret, events,
+	failure_count, disabled_at, last_delivery_at, created_at, updated_at
+`
+
+func (r *PostgresWebhookRepository) Create(ctx context.Context, webhook *model.Webhook) error {
+	query := `
+		INSERT INTO webhooks (created_by, conversation_id, bot_id, url, secret, events)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+	return r.db.Pool.QueryRow(ctx, query,
+		webhook.CreatedBy,
+		webhook.ConversationID,
+		webhook.BotID,
+		webhook.URL,
+		webhook.Secret,
+		webhook.Events,
+	).Scan(&webhook.ID, &webhook.CreatedAt, &webhook.UpdatedAt)
+}
+
+func (r *PostgresWebhookRepository) GetByID(ctx context.Context, id string) (*model.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1`
+	return r.scanWebhook(r.db.Pool.QueryRow(ctx, query, id))
+}
+
+// ListByConversation returns the webhooks of a conversation
+func (r *PostgresWebhookRepository) ListByConversation(ctx context.Context, conversationID string) ([]model.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE conversation_id = $1 ORDER BY created_at`
+	return r.listWebhooks(ctx, query, conversationID)
+}
+
+// ListByBot returns the webhooks of a bot
+func (r *PostgresWebhookRepository) ListByBot(ctx context.Context, botID string) ([]model.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE bot_id = $1 ORDER BY created_at`
+	return r.listWebhooks(ctx, query, botID)
+}
+
+// ListActiveForConversation returns the active webhooks that receive the events of a conversation:
+// its own webhooks, as long as their creator still takes part in it, and those of the bots taking part in it
+func (r *PostgresWebhookRepository) ListActiveForConversation(ctx context.Context, conversationID string) ([]model.Webhook, error) {
+	query := `
+		WITH members AS (
+			SELECT user_id FROM conversation_participants
+			WHERE conversation_id = $1 AND left_at IS NULL
+		)
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE disabled_at IS NULL
+		  AND ((conversation_id = $1 AND created_by IN (SELECT user_id FROM members))
+		    OR bot_id IN (SELECT user_id FROM members))
+	`
+	return r.listWebhooks(ctx, query, conversationID)
+}
+
+func (r *PostgresWebhookRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.Pool.Exec(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// Enable re-enables a disabled webhook and resets its failure count
+func (r *PostgresWebhookRepository) Enable(ctx context.Context, id string) error {
+	query := `UPDATE webhooks SET disabled_at = NULL, failure_count = 0 WHERE id = $1`
+	result, err := r.db.Pool.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// RecordSuccess resets the failure count after a successful delivery
+func (r *PostgresWebhookRepository) RecordSuccess(ctx context.Context, id string) error {
+	query := `UPDATE webhooks SET failure_count = 0, last_delivery_at = NOW() WHERE id = $1`
+	_, err := r.db.Pool.Exec(ctx, query, id)
+	return err
+}
+
+// RecordFailure counts a failed delivery and disables the webhook once
+// disableAfter consecutive deliveries failed. It reports whether this call disabled it.
+func (r *PostgresWebhookRepository) RecordFailure(ctx context.Context, id string, disableAfter int) (bool, error) {
+	query := `
+		UPDATE webhooks w
+		SET failure_count = w.failure_count + 1,
+		    last_delivery_at = NOW(),
+		    disabled_at = CASE
+		        WHEN w.disabled_at IS NULL AND $2 > 0 AND w.failure_count + 1 >= $2 THEN NOW()
+		        ELSE w.disabled_at
+		    END
+		FROM (SELECT id, disabled_at FROM webhooks WHERE id = $1 FOR UPDATE) old
+		WHERE w.id = old.id
+		RETURNING old.disabled_at IS NULL AND w.disabled_at IS NOT NULL
+	`
+	var disabled bool
+	err := r.db.Pool.QueryRow(ctx, query, id, disableAfter).Scan(&disabled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, ErrWebhookNotFound
+	}
+	return disabled, err
+}
+
+// CreateDelivery logs a delivery attempt
+func (r *PostgresWebhookRepository) CreateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event, attempt, status_code, error, duration_ms, success)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`
+	return r.db.Pool.QueryRow(ctx, query,
+		delivery.WebhookID,
+		delivery.EventID,
+		delivery.Event,
+		delivery.Attempt,
+		delivery.StatusCode,
+		delivery.Error,
+		delivery.DurationMs,
+		delivery.Success,
+	).Scan(&delivery.ID, &delivery.CreatedAt)
+}
+
+// ListDeliveries returns the latest delivery attempts of a webhook, newest first
+func (r *PostgresWebhookRepository) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]model.WebhookDelivery, error) {
+	query := `
+		SELECT id, webhook_id, event_id, event, attempt, status_code, error, duration_ms, success, created_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := r.db.Pool.Query(ctx, query, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []model.WebhookDelivery{}
+	for rows.Next() {
+		var d model.WebhookDelivery
+		if err := rows.Scan(
+			&d.ID,
+			&d.WebhookID,
+			&d.EventID,
+			&d.Event,
+			&d.Attempt,
+			&d.StatusCode,
+			&d.Error,
+			&d.DurationMs,
+			&d.Success,
+			&d.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// DeleteDeliveriesBefore prunes the delivery log
+func (r *PostgresWebhookRepository) DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.Pool.Exec(ctx, `DELETE FROM webhook_deliveries WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+func (r *PostgresWebhookRepository) listWebhooks(ctx context.Context, query string, args ...interface{}) ([]model.Webhook, error) {
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []model.Webhook{}
+	for rows.Next() {
+		webhook, err := r.scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (r *PostgresWebhookRepository) scanWebhook(row pgx.Row) (*model.Webhook, error) {
+	var w model.Webhook
+	err := row.Scan(
+		&w.ID,
+		&w.CreatedBy,
+		&w.ConversationID,
+		&w.BotID,
+		&w.URL,
+		&w.Secret,
+		&w.Events,
+		&w.FailureCount,
+		&w.DisabledAt,
+		&w.LastDeliveryAt,
+		&w.CreatedAt,
+		&w.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
@@ -0,0 +1,416 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/internal/config"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/redisclient"
+	"github.com/Beretta350/gochat/pkg/safehttp"
+	"github.com/Beretta350/gochat/pkg/webhooksig"
+)
+
+// Headers sent with each delivery, besides the signature headers of webhooksig
+const (
+	EventHeader    = "X-GoChat-Event"
+	DeliveryHeader = "X-GoChat-Delivery" // Event ID, the same for every attempt
+	AttemptHeader  = "X-GoChat-Attempt"
+)
+
+const (
+	consumerGroup    = "webhook-dispatchers"
+	readBatchSize    = 100
+	readBlock        = 2 * time.Second
+	pollInterval     = time.Second
+	pruneInterval    = time.Hour
+	retryBaseDelay   = 10 * time.Second
+	retryMaxDelay    = time.Hour
+	maxResponseBytes = 64 << 10
+	maxErrorLength   = 500
+)
+
+// job is a queued delivery of one event to one webhook
+type job struct {
+	WebhookID string          `json:"webhook_id"`
+	EventID   string          `json:"event_id"`
+	Event     string          `json:"event"`
+	Attempt   int             `json:"attempt"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// Dispatcher turns conversation events into webhook deliveries.
+//
+// It reads new messages from the message stream with its own consumer group (so the
+// real-time path only pays for the XADD it already does) and other events from the
+// webhook events stream, then queues one job per subscribed webhook in a Redis sorted
+// set scored by due time. Workers send the jobs, retrying failures with exponential backoff.
+type Dispatcher struct {
+	config   config.WebhookConfig
+	redis    *redisclient.Client
+	repo     repository.WebhookRepository
+	client   *http.Client
+	consumer string
+}
+
+// NewDispatcher creates a new webhook dispatcher (Fx provider)
+func NewDispatcher(cfg *config.Config, redis *redisclient.Client, repo repository.WebhookRepository) *Dispatcher {
+	consumer, err := os.Hostname()
+	if err != nil || consumer == "" {
+		consumer = uuid.New().String()
+	}
+
+	logger.Info("Webhook dispatcher initialized")
+	return &Dispatcher{
+		config:   cfg.Webhook,
+		redis:    redis,
+		repo:     repo,
+		client:   safehttp.NewClient(cfg.Webhook.Timeout, cfg.Webhook.AllowPrivateNetworks),
+		consumer: consumer,
+	}
+}
+
+// Start runs the dispatcher until ctx is cancelled
+func (d *Dispatcher) Start(ctx context.Context) {
+	// Only events from now on: webhooks created today shouldn't receive last month's messages
+	for _, stream := range []string{redisclient.MessagesStream, redisclient.WebhookEventsStream} {
+		if err := d.redis.CreateStreamGroup(ctx, stream, consumerGroup, "$"); err != nil {
+			logger.Errorf("Failed to create webhook consumer group on %s: %v", stream, err)
+		}
+	}
+
+	workers := max(d.config.Workers, 1)
+	jobs := make(chan string)
+	for i := 0; i < workers; i++ {
+		go d.deliverJobs(ctx, jobs)
+	}
+	go d.pollQueue(ctx, jobs, workers)
+	go d.pruneDeliveries(ctx)
+
+	logger.Infof("Webhook dispatcher started (%d workers)", workers)
+
+	d.readEvents(ctx)
+	logger.Info("Webhook dispatcher stopped")
+}
+
+// readEvents consumes both event streams and queues the deliveries
+func (d *Dispatcher) readEvents(ctx context.Context) {
+	streams := []string{redisclient.MessagesStream, redisclient.WebhookEventsStream}
+
+	for ctx.Err() == nil {
+		results, err := d.redis.ReadStreamsGroup(ctx, consumerGroup, d.consumer, streams, readBatchSize, readBlock)
+		if err != nil {
+			if !errors.Is(err, redis.Nil) && ctx.Err() == nil {
+				logger.Errorf("Error reading webhook events: %v", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		for _, stream := range results {
+			for _, msg := range stream.Messages {
+				if event := parseEvent(stream.Stream, msg.Values); event != nil {
+					d.fanOut(ctx, event)
+				}
+				if err := d.redis.AckStream(ctx, stream.Stream, consumerGroup, msg.ID); err != nil {
+					logger.Errorf("Error acknowledging webhook event %s: %v", msg.ID, err)
+				}
+			}
+		}
+	}
+}
+
+// parseEvent reads an event from a stream entry
+func parseEvent(stream string, values map[string]interface{}) *model.WebhookEvent {
+	if stream == redisclient.WebhookEventsStream {
+		raw, _ := values["event"].(string)
+		var event model.WebhookEvent
+		if err := json.Unmarshal([]byte(raw), &event); err != nil {
+			logger.Errorf("Skipping malformed webhook event: %v", err)
+			return nil
+		}
+		return &event
+	}
+
+	// A message published by the chat service
+	data, _ := values["data"].(string)
+	conversationID, _ := values["conversation_id"].(string)
+	senderID, _ := values["sender_id"].(string)
+	if data == "" || conversationID == "" {
+		return nil
+	}
+
+	createdAt := time.Now()
+	if sentAt, _ := values["sent_at"].(string); sentAt != "" {
+		if ms, err := strconv.ParseInt(sentAt, 10, 64); err == nil {
+			createdAt = time.UnixMilli(ms)
+		}
+	}
+
+	return &model.WebhookEvent{
+		ID:             uuid.New().String(),
+		Event:          model.WebhookEventMessageCreated,
+		ConversationID: conversationID,
+		ActorID:        senderID,
+		CreatedAt:      createdAt.UTC(),
+		Data:           json.RawMessage(data),
+	}
+}
+
+// fanOut queues a delivery for every webhook subscribed to the event
+func (d *Dispatcher) fanOut(ctx context.Context, event *model.WebhookEvent) {
+	webhooks, err := d.repo.ListActiveForConversation(ctx, event.ConversationID)
+	if err != nil {
+		logger.Errorf("Error finding webhooks of conversation %s: %v", event.ConversationID, err)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logger.Errorf("Error marshaling webhook event %s: %v", event.ID, err)
+		return
+	}
+
+	now := time.Now()
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event.Event) {
+			continue
+		}
+		// Events for a bot (interactions with its cards) only go to that bot
+		if event.BotID != "" && (webhook.BotID == nil || *webhook.BotID != event.BotID) {
+			continue
+		}
+		// A bot doesn't get its own messages back
+		if webhook.BotID != nil && *webhook.BotID == event.ActorID {
+			continue
+		}
+
+		raw, err := json.Marshal(&job{
+			WebhookID: webhook.ID,
+			EventID:   event.ID,
+			Event:     event.Event,
+			Attempt:   1,
+			Payload:   payload,
+		})
+		if err != nil {
+			continue
+		}
+		if err := d.redis.ScheduleWebhookDelivery(ctx, string(raw), now); err != nil {
+			logger.Errorf("Error queuing webhook delivery for %s: %v", webhook.ID, err)
+		}
+	}
+}
+
+// pollQueue hands due jobs to the workers
+func (d *Dispatcher) pollQueue(ctx context.Context, jobs chan<- string, workers int) {
+	// A claimed job is retried by any instance once its lease is over
+	lease := 2*d.config.Timeout + 30*time.Second
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		claimed, err := d.redis.ClaimWebhookDeliveries(ctx, workers, lease)
+		if err != nil && ctx.Err() == nil {
+			logger.Errorf("Error claiming webhook deliveries: %v", err)
+		}
+
+		for _, j := range claimed {
+			select {
+			case jobs <- j:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		// Keep going while the queue is backed up
+		if len(claimed) == workers {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) deliverJobs(ctx context.Context, jobs <-chan string) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case raw := <-jobs:
+			d.deliver(ctx, raw)
+		}
+	}
+}
+
+// deliver sends one job and records the outcome
+func (d *Dispatcher) deliver(ctx context.Context, raw string) {
+	var j job
+	if err := json.Unmarshal([]byte(raw), &j); err != nil {
+		logger.Errorf("Dropping malformed webhook job: %v", err)
+		d.complete(ctx, raw)
+		return
+	}
+
+	webhook, err := d.repo.GetByID(ctx, j.WebhookID)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			d.complete(ctx, raw) // Deleted meanwhile
+		} else {
+			logger.Errorf("Error loading webhook %s: %v", j.WebhookID, err)
+		}
+		return
+	}
+	if !webhook.IsActive() {
+		d.complete(ctx, raw)
+		return
+	}
+
+	delivery := d.send(ctx, webhook, &j)
+	if err := d.repo.CreateDelivery(ctx, delivery); err != nil {
+		logger.Errorf("Error logging delivery to webhook %s: %v", webhook.ID, err)
+	}
+
+	if delivery.Success {
+		if err := d.repo.RecordSuccess(ctx, webhook.ID); err != nil {
+			logger.Errorf("Error updating webhook %s: %v", webhook.ID, err)
+		}
+		d.complete(ctx, raw)
+		return
+	}
+
+	if j.Attempt < d.config.MaxAttempts {
+		next := j
+		next.Attempt++
+		nextRaw, err := json.Marshal(&next)
+		if err == nil {
+			err = d.redis.RescheduleWebhookDelivery(ctx, raw, string(nextRaw), time.Now().Add(retryDelay(j.Attempt)))
+		}
+		if err != nil {
+			logger.Errorf("Error rescheduling delivery to webhook %s: %v", webhook.ID, err)
+		}
+		return
+	}
+
+	// Out of attempts: the delivery failed
+	disabled, err := d.repo.RecordFailure(ctx, webhook.ID, d.config.DisableAfter)
+	if err != nil {
+		logger.Errorf("Error updating webhook %s: %v", webhook.ID, err)
+	}
+	if disabled {
+		logger.Warnf("Webhook %s disabled after %d failed deliveries", webhook.ID, d.config.DisableAfter)
+	}
+	d.complete(ctx, raw)
+}
+
+// send POSTs the signed payload
+func (d *Dispatcher) send(ctx context.Context, webhook *model.Webhook, j *job) *model.WebhookDelivery {
+	delivery := &model.WebhookDelivery{
+		WebhookID: webhook.ID,
+		EventID:   j.EventID,
+		Event:     j.Event,
+		Attempt:   j.Attempt,
+	}
+
+	start := time.Now()
+	status, err := d.post(ctx, webhook, j)
+	delivery.DurationMs = int(time.Since(start).Milliseconds())
+
+	if status != 0 {
+		delivery.StatusCode = &status
+	}
+	switch {
+	case err != nil:
+		msg := err.Error()
+		if len(msg) > maxErrorLength {
+			msg = msg[:maxErrorLength]
+		}
+		delivery.Error = &msg
+	case status < 200 || status > 299:
+		msg := fmt.Sprintf("unexpected status %d", status)
+		delivery.Error = &msg
+	default:
+		delivery.Success = true
+	}
+	return delivery
+}
+
+func (d *Dispatcher) post(ctx context.Context, webhook *model.Webhook, j *job) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(j.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoChat-Webhooks/1.0")
+	req.Header.Set(EventHeader, j.Event)
+	req.Header.Set(DeliveryHeader, j.EventID)
+	req.Header.Set(AttemptHeader, strconv.Itoa(j.Attempt))
+	req.Header.Set(webhooksig.TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhooksig.SignatureHeader, webhooksig.Sign([]byte(webhook.Secret), timestamp, j.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) complete(ctx context.Context, raw string) {
+	if err := d.redis.CompleteWebhookDelivery(ctx, raw); err != nil {
+		logger.Errorf("Error removing webhook job: %v", err)
+	}
+}
+
+// pruneDeliveries deletes delivery log entries past the retention period
+func (d *Dispatcher) pruneDeliveries(ctx context.Context) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := d.repo.DeleteDeliveriesBefore(ctx, time.Now().Add(-d.config.DeliveryRetention))
+			if err != nil {
+				logger.Errorf("Error pruning webhook deliveries: %v", err)
+			} else if n > 0 {
+				logger.Infof("Pruned %d webhook deliveries", n)
+			}
+		}
+	}
+}
+
+// retryDelay is the wait after a failed attempt: 10s, 20s, 40s... at most an hour
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempt && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMaxDelay)
+}
@@ -0,0 +1,302 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/chat"
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+var (
+	ErrIncomingWebhookNotFound = errors.New("incoming webhook not found")
+	ErrNotGroup                = errors.New("incoming webhooks need a group conversation")
+	ErrEmptyMessage            = errors.New("message has no text")
+	ErrMessageTooLong          = errors.New("message is too long")
+	ErrBotNotParticipant       = errors.New("bot is no longer a participant of the conversation")
+	ErrCannotAddBot            = errors.New("not allowed to add the bot to the conversation")
+)
+
+const (
+	// IncomingTokenPrefix makes incoming webhook tokens recognizable (e.g. by secret scanners)
+	IncomingTokenPrefix = "ghk_"
+	// incomingTokenDisplayLength is how much of the token is kept to tell webhooks apart
+	incomingTokenDisplayLength = len(IncomingTokenPrefix) + 8
+	// MaxIncomingMessageLength is the longest message an incoming webhook can post (in characters)
+	MaxIncomingMessageLength = 4000
+)
+
+// CreateIncomingWebhookRequest is the request to create an incoming webhook
+type CreateIncomingWebhookRequest struct {
+	Name  string `json:"name" validate:"required,max=100"`
+	BotID string `json:"bot_id" validate:"required,uuid"`
+}
+
+// CreatedIncomingWebhook is returned once at creation, with the secret token
+type CreatedIncomingWebhook struct {
+	model.IncomingWebhookResponse
+	Token string `json:"token"`
+	URL   string `json:"url"` // Set by the handler, which knows the public address
+}
+
+// IncomingMessage is the body accepted by incoming webhooks. "text" is enough; attachments
+// follow Slack's incoming webhook format so existing integrations work unchanged.
+// Other Slack fields (username, icon_url, channel...) are accepted and ignored.
+type IncomingMessage struct {
+	Text        string               `json:"text"`
+	Attachments []IncomingAttachment `json:"attachments"`
+}
+
+// IncomingAttachment is a Slack-style attachment, flattened into the message text
+type IncomingAttachment struct {
+	Fallback  string                    `json:"fallback"`
+	Pretext   string                    `json:"pretext"`
+	Title     string                    `json:"title"`
+	TitleLink string                    `json:"title_link"`
+	Text      string                    `json:"text"`
+	Fields    []IncomingAttachmentField `json:"fields"`
+}
+
+// IncomingAttachmentField is a title/value pair of an attachment
+type IncomingAttachmentField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// slackLink matches Slack links: <https://example.com|label> or <https://example.com>
+var slackLink = regexp.MustCompile(`<(https?://[^|>\s]+)(?:\|([^>]+))?>`)
+
+// Content returns the plain text of the message
+func (m *IncomingMessage) Content() string {
+	parts := []string{m.Text}
+	for _, a := range m.Attachments {
+		var lines []string
+		if a.Pretext != "" {
+			lines = append(lines, a.Pretext)
+		}
+		if a.Title != "" {
+			if a.TitleLink != "" {
+				lines = append(lines, a.Title+" ("+a.TitleLink+")")
+			} else {
+				lines = append(lines, a.Title)
+			}
+		}
+		if a.Text != "" {
+			lines = append(lines, a.Text)
+		}
+		for _, f := range a.Fields {
+			lines = append(lines, f.Title+": "+f.Value)
+		}
+		if len(lines) == 0 && a.Fallback != "" {
+			lines = append(lines, a.Fallback)
+		}
+		parts = append(parts, strings.Join(lines, "\n"))
+	}
+
+	var nonEmpty []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+
+	return slackLink.ReplaceAllStringFunc(strings.Join(nonEmpty, "\n"), func(link string) string {
+		m := slackLink.FindStringSubmatch(link)
+		if m[2] == "" {
+			return m[1]
+		}
+		return m[2] + " (" + m[1] + ")"
+	})
+}
+
+// CreateIncomingWebhook creates an incoming webhook posting into a group as one of the user's bots.
+// The bot joins the group if it isn't a participant yet. In a channel, only those who may post
+// there (its admins) can create one; the bot stays a member, and may post through the webhook only.
+func (s *Service) CreateIncomingWebhook(ctx context.Context, userID, conversationID string, req *CreateIncomingWebhookRequest) (*CreatedIncomingWebhook, error) {
+	if err := s.requireParticipant(ctx, userID, conversationID); err != nil {
+		return nil, err
+	}
+	if err := s.requireBotOwner(ctx, userID, req.BotID); err != nil {
+		return nil, err
+	}
+
+	conv, err := s.convRepo.GetByID(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if !conv.Type.IsGroup() {
+		return nil, ErrNotGroup
+	}
+	if err := s.requirePermission(ctx, userID, conversationID, model.PermissionPostMessages); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.incomingRepo.ListByConversation(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxWebhooksPerTarget {
+		return nil, ErrTooManyWebhooks
+	}
+
+	plain, err := generateIncomingToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// Created before the bot joins, so nothing can fail once it did: on failure the
+	// webhook is deleted and the group is left as it was
+	webhook := &model.IncomingWebhook{
+		ConversationID: conversationID,
+		BotID:          req.BotID,
+		CreatedBy:      userID,
+		Name:           req.Name,
+		TokenPrefix:    plain[:incomingTokenDisplayLength],
+		TokenHash:      hashToken(plain),
+	}
+	if err := s.incomingRepo.Create(ctx, webhook); err != nil {
+		return nil, err
+	}
+	if err := s.addBot(ctx, userID, conversationID, req.BotID); err != nil {
+		if deleteErr := s.incomingRepo.Delete(ctx, webhook.ID); deleteErr != nil {
+			logger.Errorf("Failed to delete incoming webhook %s after a failed creation: %v", webhook.ID, deleteErr)
+		}
+		return nil, err
+	}
+
+	logger.Infof("Incoming webhook %s created in conversation %s by user %s", webhook.ID, conversationID, userID)
+
+	return &CreatedIncomingWebhook{IncomingWebhookResponse: webhook.ToResponse(), Token: plain}, nil
+}
+
+// addBot adds the bot of an incoming webhook to its group, unless it is a participant already.
+// The user adds it like any member: same permission, system message and events.
+func (s *Service) addBot(ctx context.Context, userID, conversationID, botID string) error {
+	err := s.requireParticipant(ctx, botID, conversationID)
+	if !errors.Is(err, ErrNotParticipant) {
+		return err
+	}
+
+	_, err = s.chat.AddMembers(ctx, userID, conversationID, []string{botID})
+	switch {
+	case errors.Is(err, chat.ErrForbidden):
+		return ErrCannotAddBot
+	case errors.Is(err, chat.ErrUserNotFound):
+		return ErrBotNotFound
+	case err != nil && !errors.Is(err, chat.ErrAlreadyMember):
+		return err
+	}
+	logger.Infof("Bot %s added to conversation %s for an incoming webhook", botID, conversationID)
+	return nil
+}
+
+// ListIncomingWebhooks returns the incoming webhooks of a conversation
+func (s *Service) ListIncomingWebhooks(ctx context.Context, userID, conversationID string) ([]model.IncomingWebhookResponse, error) {
+	if err := s.requireParticipant(ctx, userID, conversationID); err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.incomingRepo.ListByConversation(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.IncomingWebhookResponse, 0, len(webhooks))
+	for i := range webhooks {
+		result = append(result, webhooks[i].ToResponse())
+	}
+	return result, nil
+}
+
+// DeleteIncomingWebhook deletes an incoming webhook created by the user
+func (s *Service) DeleteIncomingWebhook(ctx context.Context, userID, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrIncomingWebhookNotFound
+	}
+
+	webhook, err := s.incomingRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrIncomingWebhookNotFound) {
+			return ErrIncomingWebhookNotFound
+		}
+		return err
+	}
+	if webhook.CreatedBy != userID {
+		return ErrIncomingWebhookNotFound
+	}
+
+	return s.incomingRepo.Delete(ctx, id)
+}
+
+// PostIncoming posts a message through an incoming webhook. It takes the same path as
+// messages sent over the WebSocket (stream persistence, fan-out, outgoing webhooks).
+func (s *Service) PostIncoming(ctx context.Context, token string, msg *IncomingMessage) (*chat.OutgoingMessage, error) {
+	if !strings.HasPrefix(token, IncomingTokenPrefix) {
+		return nil, ErrIncomingWebhookNotFound
+	}
+
+	webhook, err := s.incomingRepo.GetByHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrIncomingWebhookNotFound) {
+			return nil, ErrIncomingWebhookNotFound
+		}
+		return nil, err
+	}
+
+	content := msg.Content()
+	if content == "" {
+		return nil, ErrEmptyMessage
+	}
+	if utf8.RuneCountInString(content) > MaxIncomingMessageLength {
+		return nil, ErrMessageTooLong
+	}
+
+	// The bot must still exist (deleted bots are deactivated)
+	if _, err := s.userRepo.GetByID(ctx, webhook.BotID); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrIncomingWebhookNotFound
+		}
+		return nil, err
+	}
+
+	out, err := s.chat.PostIncomingMessage(ctx, webhook.BotID, &chat.WebSocketMessage{
+		ConversationID: webhook.ConversationID,
+		Content:        content,
+	})
+	if err != nil {
+		if errors.Is(err, chat.ErrNotParticipant) {
+			return nil, ErrBotNotParticipant
+		}
+		return nil, err
+	}
+
+	if err := s.incomingRepo.MarkUsed(ctx, webhook.ID); err != nil {
+		logger.Errorf("Failed to record use of incoming webhook %s: %v", webhook.ID, err)
+	}
+
+	return out, nil
+}
+
+func generateIncomingToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return IncomingTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
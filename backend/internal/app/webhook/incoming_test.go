@@ -0,0 +1,223 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/chat"
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/internal/config"
+	"github.com/Beretta350/gochat/pkg/redisclient"
+)
+
+// fakeConvRepo keeps conversations in memory; methods not overridden panic
+type fakeConvRepo struct {
+	repository.ConversationRepository
+
+	mu            sync.Mutex
+	conversations map[string]*model.Conversation
+	participants  map[string][]model.Participant
+}
+
+func (r *fakeConvRepo) GetByID(_ context.Context, id string) (*model.Conversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conv, ok := r.conversations[id]
+	if !ok {
+		return nil, repository.ErrConversationNotFound
+	}
+	return conv, nil
+}
+
+func (r *fakeConvRepo) GetParticipants(_ context.Context, conversationID string) ([]model.Participant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.participants[conversationID]), nil
+}
+
+func (r *fakeConvRepo) AddParticipant(_ context.Context, conversationID, userID string, role *model.ParticipantRole) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.participants[conversationID] = append(r.participants[conversationID], model.Participant{
+		ConversationID: conversationID,
+		UserID:         userID,
+		Role:           role,
+		User:           &model.UserResponse{ID: userID},
+	})
+	return nil
+}
+
+// participant returns a participant of a conversation, or nil
+func (r *fakeConvRepo) participant(conversationID, userID string) *model.Participant {
+	participants, _ := r.GetParticipants(context.Background(), conversationID)
+	for i := range participants {
+		if participants[i].UserID == userID {
+			return &participants[i]
+		}
+	}
+	return nil
+}
+
+// fakeUserRepo knows the bots it was given; everyone else is an active user
+type fakeUserRepo struct {
+	repository.UserRepository
+
+	bots map[string]*model.User
+}
+
+func (r *fakeUserRepo) GetByID(_ context.Context, id string) (*model.User, error) {
+	if bot, ok := r.bots[id]; ok {
+		return bot, nil
+	}
+	return &model.User{ID: id, IsActive: true}, nil
+}
+
+// fakeIncomingRepo keeps incoming webhooks in memory
+type fakeIncomingRepo struct {
+	repository.IncomingWebhookRepository
+
+	mu       sync.Mutex
+	webhooks []model.IncomingWebhook
+}
+
+func (r *fakeIncomingRepo) Create(_ context.Context, webhook *model.IncomingWebhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhook.ID = uuid.New().String()
+	r.webhooks = append(r.webhooks, *webhook)
+	return nil
+}
+
+func (r *fakeIncomingRepo) ListByConversation(_ context.Context, conversationID string) ([]model.IncomingWebhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []model.IncomingWebhook
+	for _, webhook := range r.webhooks {
+		if webhook.ConversationID == conversationID {
+			result = append(result, webhook)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeIncomingRepo) GetByHash(_ context.Context, tokenHash string) (*model.IncomingWebhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, webhook := range r.webhooks {
+		if webhook.TokenHash == tokenHash {
+			return &webhook, nil
+		}
+	}
+	return nil, repository.ErrIncomingWebhookNotFound
+}
+
+func (r *fakeIncomingRepo) MarkUsed(context.Context, string) error {
+	return nil
+}
+
+func (r *fakeIncomingRepo) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.webhooks = slices.DeleteFunc(r.webhooks, func(webhook model.IncomingWebhook) bool {
+		return webhook.ID == id
+	})
+	return nil
+}
+
+func TestCreateIncomingWebhook(t *testing.T) {
+	ownerID, botID := uuid.New().String(), uuid.New().String()
+	admin, member := model.ParticipantRoleAdmin, model.ParticipantRoleMember
+
+	tests := []struct {
+		name      string
+		convType  model.ConversationType
+		ownerRole model.ParticipantRole
+		inactive  bool // Bot deactivated, so it can't be added
+		wantErr   error
+		wantRole  model.ParticipantRole // Of the bot once created
+	}{
+		{name: "member of a group", convType: model.ConversationTypeGroup, ownerRole: member, wantRole: member},
+		{name: "admin of a channel", convType: model.ConversationTypeChannel, ownerRole: admin, wantRole: member},
+		{name: "member of a channel", convType: model.ConversationTypeChannel, ownerRole: member, wantErr: ErrNotAllowed},
+		{name: "direct conversation", convType: model.ConversationTypeDirect, ownerRole: admin, wantErr: ErrNotGroup},
+		{name: "bot can't be added", convType: model.ConversationTypeGroup, ownerRole: admin, inactive: true, wantErr: ErrBotNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			client, err := redisclient.NewClient(&config.Config{Redis: config.RedisConfig{Addr: mr.Addr()}})
+			if err != nil {
+				t.Fatalf("connecting to miniredis: %v", err)
+			}
+			t.Cleanup(func() { _ = client.Close() })
+
+			conv := &model.Conversation{ID: uuid.New().String(), Type: tt.convType}
+			convs := &fakeConvRepo{
+				conversations: map[string]*model.Conversation{conv.ID: conv},
+				participants:  map[string][]model.Participant{},
+			}
+			adminID := uuid.New().String()
+			_ = convs.AddParticipant(context.Background(), conv.ID, adminID, &admin)
+			_ = convs.AddParticipant(context.Background(), conv.ID, ownerID, &tt.ownerRole)
+
+			users := &fakeUserRepo{bots: map[string]*model.User{
+				botID: {ID: botID, IsActive: !tt.inactive, IsBot: true, BotOwnerID: &ownerID},
+			}}
+			incoming := &fakeIncomingRepo{}
+			chatService := chat.NewService(&config.Config{}, client, convs, users, nil, nil, nil, nil)
+			s := NewService(&config.Config{}, client, chatService, nil, incoming, convs, users)
+
+			created, err := s.CreateIncomingWebhook(context.Background(), ownerID, conv.ID, &CreateIncomingWebhookRequest{Name: "CI", BotID: botID})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				if convs.participant(conv.ID, botID) != nil {
+					t.Error("bot added although the webhook was refused")
+				}
+				if len(incoming.webhooks) != 0 {
+					t.Error("webhook kept although its creation failed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateIncomingWebhook: %v", err)
+			}
+			if created.Token == "" || len(incoming.webhooks) != 1 {
+				t.Errorf("webhook not created: %+v", created)
+			}
+
+			bot := convs.participant(conv.ID, botID)
+			if bot == nil {
+				t.Fatal("bot not added to the conversation")
+			}
+			if bot.EffectiveRole() != tt.wantRole {
+				t.Errorf("bot role = %q, want %q", bot.EffectiveRole(), tt.wantRole)
+			}
+
+			// The bot posts through its webhook, even in a channel, but isn't given the right
+			// to post (or anything else) as a member
+			if _, err := s.PostIncoming(context.Background(), created.Token, &IncomingMessage{Text: "Build passed"}); err != nil {
+				t.Errorf("posting through the webhook: %v", err)
+			}
+			_, err = chatService.PostMessage(context.Background(), botID, &chat.WebSocketMessage{ConversationID: conv.ID, Content: "hi"})
+			if wantErr := bot.Can(conv.Type, model.PermissionPostMessages); (err == nil) != wantErr {
+				t.Errorf("bot posting as a member: err = %v, can post = %v", err, wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+// Package webhook manages outgoing webhook subscriptions, delivers conversation
+// events to them, and posts messages received by incoming webhooks.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/chat"
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/internal/config"
+	"github.com/Beretta350/gochat/pkg/logger"
+	"github.com/Beretta350/gochat/pkg/redisclient"
+	"github.com/Beretta350/gochat/pkg/safehttp"
+)
+
+var (
+	ErrWebhookNotFound = errors.New("webhook not found")
+	ErrNotParticipant  = errors.New("not a participant of the conversation")
+	ErrNotAllowed      = errors.New("not allowed to manage the webhooks of the conversation")
+	ErrBotNotFound     = errors.New("bot not found")
+	ErrInvalidURL      = errors.New("invalid webhook URL")
+	ErrTooManyWebhooks = errors.New("too many webhooks")
+)
+
+// maxWebhooksPerTarget limits the webhooks of one conversation or bot
+const maxWebhooksPerTarget = 10
+
+// secretPrefix makes signing secrets recognizable
+const secretPrefix = "whsec_"
+
+// CreateWebhookRequest is the request to subscribe a URL to events
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,url,max=2048"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=message.created message.updated conversation.created participant.added participant.removed message.interaction"`
+}
+
+// CreatedWebhook is returned once at creation, with the signing secret
+type CreatedWebhook struct {
+	model.WebhookResponse
+	Secret string `json:"secret"`
+}
+
+// Service manages outgoing webhook subscriptions, publishes events for delivery
+// and posts the messages of incoming webhooks
+type Service struct {
+	config       *config.Config
+	redis        *redisclient.Client
+	chat         *chat.Service
+	webhookRepo  repository.WebhookRepository
+	incomingRepo repository.IncomingWebhookRepository
+	convRepo     repository.ConversationRepository
+	userRepo     repository.UserRepository
+}
+
+// NewService creates a new webhook service (Fx provider)
+func NewService(
+	cfg *config.Config,
+	redis *redisclient.Client,
+	chatService *chat.Service,
+	webhookRepo repository.WebhookRepository,
+	incomingRepo repository.IncomingWebhookRepository,
+	convRepo repository.ConversationRepository,
+	userRepo repository.UserRepository,
+) *Service {
+	logger.Info("Webhook service initialized")
+	return &Service{
+		config:       cfg,
+		redis:        redis,
+		chat:         chatService,
+		webhookRepo:  webhookRepo,
+		incomingRepo: incomingRepo,
+		convRepo:     convRepo,
+		userRepo:     userRepo,
+	}
+}
+
+// Notify publishes an event of a conversation to its webhooks (see chat.Service.NotifyWebhooks)
+func (s *Service) Notify(ctx context.Context, event, conversationID, actorID string, data interface{}) {
+	s.chat.NotifyWebhooks(ctx, event, conversationID, actorID, data)
+}
+
+// CreateConversationWebhook subscribes a URL to the events of a conversation. Only its
+// admins may (both people of a direct conversation).
+func (s *Service) CreateConversationWebhook(ctx context.Context, userID, conversationID string, req *CreateWebhookRequest) (*CreatedWebhook, error) {
+	if err := s.requirePermission(ctx, userID, conversationID, model.PermissionManageWebhooks); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.webhookRepo.ListByConversation(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxWebhooksPerTarget {
+		return nil, ErrTooManyWebhooks
+	}
+
+	return s.create(ctx, &model.Webhook{CreatedBy: userID, ConversationID: &conversationID}, req)
+}
+
+// ListConversationWebhooks returns the webhooks of a conversation. Their URLs often hold
+// secrets, so like creating them it is limited to those who manage them.
+func (s *Service) ListConversationWebhooks(ctx context.Context, userID, conversationID string) ([]model.WebhookResponse, error) {
+	if err := s.requirePermission(ctx, userID, conversationID, model.PermissionManageWebhooks); err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.webhookRepo.ListByConversation(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	return toResponses(webhooks), nil
+}
+
+// CreateBotWebhook subscribes a URL to the events of every conversation a bot is in
+func (s *Service) CreateBotWebhook(ctx context.Context, ownerID, botID string, req *CreateWebhookRequest) (*CreatedWebhook, error) {
+	if err := s.requireBotOwner(ctx, ownerID, botID); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.webhookRepo.ListByBot(ctx, botID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxWebhooksPerTarget {
+		return nil, ErrTooManyWebhooks
+	}
+
+	return s.create(ctx, &model.Webhook{CreatedBy: ownerID, BotID: &botID}, req)
+}
+
+// ListBotWebhooks returns the webhooks of a bot
+func (s *Service) ListBotWebhooks(ctx context.Context, ownerID, botID string) ([]model.WebhookResponse, error) {
+	if err := s.requireBotOwner(ctx, ownerID, botID); err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.webhookRepo.ListByBot(ctx, botID)
+	if err != nil {
+		return nil, err
+	}
+	return toResponses(webhooks), nil
+}
+
+// DeleteWebhook deletes a webhook created by the user
+func (s *Service) DeleteWebhook(ctx context.Context, userID, id string) error {
+	if _, err := s.ownedWebhook(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.webhookRepo.Delete(ctx, id)
+}
+
+// EnableWebhook re-enables a webhook that was disabled after repeated failures
+func (s *Service) EnableWebhook(ctx context.Context, userID, id string) (*model.WebhookResponse, error) {
+	if _, err := s.ownedWebhook(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	if err := s.webhookRepo.Enable(ctx, id); err != nil {
+		return nil, err
+	}
+
+	webhook, err := s.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	resp := webhook.ToResponse()
+	return &resp, nil
+}
+
+// ListDeliveries returns the latest delivery attempts of a webhook created by the user
+func (s *Service) ListDeliveries(ctx context.Context, userID, id string, limit int) ([]model.WebhookDelivery, error) {
+	if _, err := s.ownedWebhook(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.ListDeliveries(ctx, id, limit)
+}
+
+func (s *Service) create(ctx context.Context, webhook *model.Webhook, req *CreateWebhookRequest) (*CreatedWebhook, error) {
+	if err := s.checkURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.URL = req.URL
+	webhook.Secret = secret
+	webhook.Events = uniqueEvents(req.Events)
+
+	if err := s.webhookRepo.Create(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Webhook %s created by user %s", webhook.ID, webhook.CreatedBy)
+
+	return &CreatedWebhook{WebhookResponse: webhook.ToResponse(), Secret: secret}, nil
+}
+
+// ownedWebhook loads a webhook if the user created it
+func (s *Service) ownedWebhook(ctx context.Context, userID, id string) (*model.Webhook, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	webhook, err := s.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	if webhook.CreatedBy != userID {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+func (s *Service) requireParticipant(ctx context.Context, userID, conversationID string) error {
+	if _, err := uuid.Parse(conversationID); err != nil {
+		return ErrNotParticipant
+	}
+
+	participants, err := s.convRepo.GetParticipants(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+	for _, p := range participants {
+		if p.UserID == userID {
+			return nil
+		}
+	}
+	return ErrNotParticipant
+}
+
+// requirePermission checks that the user takes part in the conversation and may do something there
+func (s *Service) requirePermission(ctx context.Context, userID, conversationID string, permission model.Permission) error {
+	if _, err := uuid.Parse(conversationID); err != nil {
+		return ErrNotParticipant
+	}
+
+	conv, err := s.convRepo.GetByID(ctx, conversationID)
+	if err != nil {
+		if errors.Is(err, repository.ErrConversationNotFound) {
+			return ErrNotParticipant
+		}
+		return err
+	}
+	participants, err := s.convRepo.GetParticipants(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+	for i := range participants {
+		if participants[i].UserID != userID {
+			continue
+		}
+		if !participants[i].Can(conv.Type, permission) {
+			return ErrNotAllowed
+		}
+		return nil
+	}
+	return ErrNotParticipant
+}
+
+func (s *Service) requireBotOwner(ctx context.Context, ownerID, botID string) error {
+	if _, err := uuid.Parse(botID); err != nil {
+		return ErrBotNotFound
+	}
+
+	bot, err := s.userRepo.GetByID(ctx, botID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrBotNotFound
+		}
+		return err
+	}
+	if !bot.IsBot || bot.BotOwnerID == nil || *bot.BotOwnerID != ownerID {
+		return ErrBotNotFound
+	}
+	return nil
+}
+
+// checkURL accepts public http(s) URLs (or any http(s) URL when private networks are allowed)
+func (s *Service) checkURL(raw string) error {
+	if err := safehttp.CheckURL(raw, s.config.Webhook.AllowPrivateNetworks); err != nil {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func uniqueEvents(events []string) []string {
+	unique := make([]string, 0, len(events))
+	for _, event := range events {
+		if !slices.Contains(unique, event) {
+			unique = append(unique, event)
+		}
+	}
+	return unique
+}
+
+func toResponses(webhooks []model.Webhook) []model.WebhookResponse {
+	result := make([]model.WebhookResponse, 0, len(webhooks))
+	for i := range webhooks {
+		result = append(result, webhooks[i].ToResponse())
+	}
+	return result
+}
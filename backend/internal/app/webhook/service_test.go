@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/internal/app/model"
+	"github.com/Beretta350/gochat/internal/app/repository"
+	"github.com/Beretta350/gochat/internal/config"
+)
+
+// fakeWebhookRepo keeps outgoing webhooks in memory
+type fakeWebhookRepo struct {
+	repository.WebhookRepository
+
+	mu       sync.Mutex
+	webhooks []model.Webhook
+}
+
+func (r *fakeWebhookRepo) Create(_ context.Context, webhook *model.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhook.ID = uuid.New().String()
+	r.webhooks = append(r.webhooks, *webhook)
+	return nil
+}
+
+func (r *fakeWebhookRepo) ListByConversation(_ context.Context, conversationID string) ([]model.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []model.Webhook
+	for _, webhook := range r.webhooks {
+		if webhook.ConversationID != nil && *webhook.ConversationID == conversationID {
+			result = append(result, webhook)
+		}
+	}
+	return result, nil
+}
+
+func TestConversationWebhooksNeedManageWebhooks(t *testing.T) {
+	adminID, memberID, outsiderID := uuid.New().String(), uuid.New().String(), uuid.New().String()
+	admin, member := model.ParticipantRoleAdmin, model.ParticipantRoleMember
+
+	conv := &model.Conversation{ID: uuid.New().String(), Type: model.ConversationTypeGroup}
+	convs := &fakeConvRepo{
+		conversations: map[string]*model.Conversation{conv.ID: conv},
+		participants:  map[string][]model.Participant{},
+	}
+	_ = convs.AddParticipant(context.Background(), conv.ID, adminID, &admin)
+	_ = convs.AddParticipant(context.Background(), conv.ID, memberID, &member)
+
+	s := NewService(&config.Config{}, nil, nil, &fakeWebhookRepo{}, nil, convs, nil)
+	req := &CreateWebhookRequest{URL: "https://ci.example.com/hooks/gochat?token=secret", Events: []string{model.WebhookEventMessageCreated}}
+
+	if _, err := s.CreateConversationWebhook(context.Background(), adminID, conv.ID, req); err != nil {
+		t.Fatalf("admin creating a webhook: %v", err)
+	}
+	webhooks, err := s.ListConversationWebhooks(context.Background(), adminID, conv.ID)
+	if err != nil || len(webhooks) != 1 {
+		t.Fatalf("admin listing webhooks: %v, %d webhooks", err, len(webhooks))
+	}
+
+	tests := []struct {
+		name    string
+		userID  string
+		wantErr error
+	}{
+		{name: "member", userID: memberID, wantErr: ErrNotAllowed},
+		{name: "outsider", userID: outsiderID, wantErr: ErrNotParticipant},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.CreateConversationWebhook(context.Background(), tt.userID, conv.ID, req); !errors.Is(err, tt.wantErr) {
+				t.Errorf("create: err = %v, want %v", err, tt.wantErr)
+			}
+			if _, err := s.ListConversationWebhooks(context.Background(), tt.userID, conv.ID); !errors.Is(err, tt.wantErr) {
+				t.Errorf("list: err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
 	"strconv"
 	"sync"
 	"time"
@@ -115,6 +116,11 @@ func (w *MessageWorker) parseMessage(values map[string]interface{}) *model.Messa
 		msgType = "text"
 	}
 
+	var metadata json.RawMessage
+	if raw, _ := values["metadata"].(string); raw != "" {
+		metadata = json.RawMessage(raw)
+	}
+
 	// Parse sent_at
 	var sentAt time.Time
 	if sentAtVal, ok := values["sent_at"]; ok {
@@ -137,6 +143,7 @@ func (w *MessageWorker) parseMessage(values map[string]interface{}) *model.Messa
 		SenderID:       senderID,
 		Content:        content,
 		Type:           model.MessageType(msgType),
+		Metadata:       metadata,
 		SentAt:         sentAt,
 	}
 }
@@ -0,0 +1,189 @@
+// Package keyset generates and serializes asymmetric JWT signing keys
+// and publishes their public part as a JSON Web Key Set.
+package keyset
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/google/uuid"
+)
+
+// Supported algorithms
+const (
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrDecrypt              = errors.New("cannot decrypt private key")
+)
+
+// rsaBits is the size of generated RSA keys
+const rsaBits = 2048
+
+// Key is a signing key pair identified by its kid
+type Key struct {
+	ID        string
+	Algorithm string
+	Private   crypto.Signer
+}
+
+// Public returns the public key
+func (k *Key) Public() crypto.PublicKey {
+	return k.Private.Public()
+}
+
+// IsSupported checks if keys can be generated for an algorithm
+func IsSupported(alg string) bool {
+	return alg == RS256 || alg == EdDSA
+}
+
+// Generate creates a new key with a random ID
+func Generate(alg string) (*Key, error) {
+	var signer crypto.Signer
+	switch alg {
+	case RS256:
+		key, err := rsa.GenerateKey(rand.Reader, rsaBits)
+		if err != nil {
+			return nil, err
+		}
+		signer = key
+	case EdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		signer = key
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+	}
+
+	return &Key{ID: uuid.New().String(), Algorithm: alg, Private: signer}, nil
+}
+
+// MarshalPrivate encodes the private key as PKCS #8 and encrypts it with the secret
+func (k *Key) MarshalPrivate(secret []byte) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.Private)
+	if err != nil {
+		return nil, err
+	}
+	return seal(secret, der)
+}
+
+// ParsePrivate decrypts and decodes a key produced by MarshalPrivate
+func ParsePrivate(id, alg string, data, secret []byte) (*Key, error) {
+	der, err := open(secret, data)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	var signer crypto.Signer
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if alg != RS256 {
+			return nil, fmt.Errorf("%w: RSA key for %s", ErrUnsupportedAlgorithm, alg)
+		}
+		signer = key
+	case ed25519.PrivateKey:
+		if alg != EdDSA {
+			return nil, fmt.Errorf("%w: Ed25519 key for %s", ErrUnsupportedAlgorithm, alg)
+		}
+		signer = key
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedAlgorithm, parsed)
+	}
+
+	return &Key{ID: id, Algorithm: alg, Private: signer}, nil
+}
+
+// JWK is the public part of a key (RFC 7517, RFC 8037 for Ed25519)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// Set is a JSON Web Key Set
+type Set struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK returns the public JSON Web Key
+func (k *Key) JWK() JWK {
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Algorithm}
+
+	switch pub := k.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+
+	return jwk
+}
+
+// seal encrypts data with AES-256-GCM under a key derived from the secret
+func seal(secret, data []byte) ([]byte, error) {
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+// open decrypts data produced by seal
+func open(secret, data []byte) ([]byte, error) {
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plain, nil
+}
+
+func newGCM(secret []byte) (cipher.AEAD, error) {
+	key := sha256.Sum256(append([]byte("gochat signing key encryption:"), secret...))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
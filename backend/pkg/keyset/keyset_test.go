@@ -0,0 +1,115 @@
+package keyset
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestGenerateSignAndVerify(t *testing.T) {
+	methods := map[string]jwt.SigningMethod{
+		RS256: jwt.SigningMethodRS256,
+		EdDSA: jwt.SigningMethodEdDSA,
+	}
+
+	for alg, method := range methods {
+		t.Run(alg, func(t *testing.T) {
+			key, err := Generate(alg)
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+
+			token := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "user"})
+			token.Header["kid"] = key.ID
+			signed, err := token.SignedString(key.Private)
+			if err != nil {
+				t.Fatalf("sign: %v", err)
+			}
+
+			// Verify with the key rebuilt from its JWK, like a third-party service would
+			pub := publicKeyFromJWK(t, key.JWK())
+			if _, err := jwt.Parse(signed, func(*jwt.Token) (interface{}, error) { return pub, nil },
+				jwt.WithValidMethods([]string{alg})); err != nil {
+				t.Fatalf("verify with JWK: %v", err)
+			}
+		})
+	}
+}
+
+func TestMarshalPrivateRoundTrip(t *testing.T) {
+	for _, alg := range []string{RS256, EdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			key, err := Generate(alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := key.MarshalPrivate([]byte("secret"))
+			if err != nil {
+				t.Fatalf("MarshalPrivate: %v", err)
+			}
+
+			parsed, err := ParsePrivate(key.ID, alg, data, []byte("secret"))
+			if err != nil {
+				t.Fatalf("ParsePrivate: %v", err)
+			}
+			if parsed.JWK() != key.JWK() {
+				t.Error("parsed key differs from the original")
+			}
+
+			if _, err := ParsePrivate(key.ID, alg, data, []byte("other")); !errors.Is(err, ErrDecrypt) {
+				t.Errorf("wrong secret: got %v, want ErrDecrypt", err)
+			}
+		})
+	}
+}
+
+func TestParsePrivateRejectsAlgorithmMismatch(t *testing.T) {
+	key, err := Generate(EdDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := key.MarshalPrivate([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParsePrivate(key.ID, RS256, data, []byte("secret")); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("got %v, want ErrUnsupportedAlgorithm", err)
+	}
+}
+
+func TestGenerateUnsupported(t *testing.T) {
+	if _, err := Generate("HS256"); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("got %v, want ErrUnsupportedAlgorithm", err)
+	}
+}
+
+func publicKeyFromJWK(t *testing.T, jwk JWK) interface{} {
+	t.Helper()
+
+	decode := func(s string) []byte {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	switch jwk.Kty {
+	case "RSA":
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(decode(jwk.N)),
+			E: int(new(big.Int).SetBytes(decode(jwk.E)).Int64()),
+		}
+	case "OKP":
+		return ed25519.PublicKey(decode(jwk.X))
+	}
+	t.Fatalf("unexpected key type %q", jwk.Kty)
+	return nil
+}
@@ -0,0 +1,54 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+// FileMailer writes each email as an .eml file (local development and tests)
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer creates a new file mailer writing to dir
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+// Send writes the message to a new file
+func (m *FileMailer) Send(_ context.Context, msg *Message) error {
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.New().String()[:8])
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, buildMessage(m.from, msg), 0o600); err != nil {
+		return err
+	}
+	logger.Infof("Email to %s written to %s", msg.To, path)
+	return nil
+}
+
+// LogMailer prints emails to the application log (default for development)
+type LogMailer struct {
+	from string
+}
+
+// NewLogMailer creates a new log mailer
+func NewLogMailer(from string) *LogMailer {
+	return &LogMailer{from: from}
+}
+
+// Send logs the message
+func (m *LogMailer) Send(_ context.Context, msg *Message) error {
+	logger.Infof("Email from %s to %s | %s\n%s", m.from, msg.To, msg.Subject, msg.Body)
+	return nil
+}
@@ -0,0 +1,61 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/Beretta350/gochat/internal/config"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+// Message represents a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// NewMailer creates the mailer selected by MAIL_DRIVER (Fx provider)
+func NewMailer(cfg *config.Config) (Mailer, error) {
+	switch cfg.Mail.Driver {
+	case "smtp":
+		logger.Infof("SMTP mailer initialized (%s:%d)", cfg.Mail.SMTPHost, cfg.Mail.SMTPPort)
+		return NewSMTPMailer(cfg.Mail), nil
+	case "file":
+		logger.Infof("File mailer initialized (%s)", cfg.Mail.FileDir)
+		return NewFileMailer(cfg.Mail.FileDir, cfg.Mail.From)
+	case "log", "":
+		logger.Info("Log mailer initialized")
+		return NewLogMailer(cfg.Mail.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Mail.Driver)
+	}
+}
+
+// buildMessage renders msg as an RFC 5322 message
+func buildMessage(from string, msg *Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", sanitizeHeader(msg.To))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", sanitizeHeader(msg.Subject)))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return buf.Bytes()
+}
+
+// sanitizeHeader strips line breaks to prevent header injection
+func sanitizeHeader(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
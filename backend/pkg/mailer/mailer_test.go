@@ -0,0 +1,55 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildMessage(t *testing.T) {
+	msg := &Message{
+		To:      "alice@example.com\r\nBcc: evil@example.com",
+		Subject: "Reset your password",
+		Body:    "line 1\nline 2",
+	}
+
+	raw := string(buildMessage("GoChat <no-reply@example.com>", msg))
+
+	if strings.Contains(raw, "\r\nBcc:") {
+		t.Errorf("header injection not prevented:\n%s", raw)
+	}
+	if !strings.Contains(raw, "Subject: Reset your password\r\n") {
+		t.Errorf("missing subject header:\n%s", raw)
+	}
+	if !strings.HasSuffix(raw, "\r\n\r\nline 1\r\nline 2") {
+		t.Errorf("unexpected body:\n%s", raw)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+
+	m, err := NewFileMailer(dir, "no-reply@example.com")
+	if err != nil {
+		t.Fatalf("NewFileMailer() error = %v", err)
+	}
+
+	if err := m.Send(context.Background(), &Message{To: "bob@example.com", Subject: "Hi", Body: "hello"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected 1 email file, got %v (err %v)", files, err)
+	}
+
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !strings.Contains(string(content), "To: bob@example.com") {
+		t.Errorf("email file missing recipient:\n%s", content)
+	}
+}
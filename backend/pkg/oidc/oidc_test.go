@@ -0,0 +1,258 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID     = "gochat"
+	testClientSecret = "s3cret"
+	testRedirectURL  = "http://localhost:3000/api/v1/auth/oidc/mock/callback"
+)
+
+// mockIdP is a minimal OpenID provider: discovery, JWKS and a token endpoint
+// that checks the PKCE verifier and returns the next ID token claims
+type mockIdP struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+
+	challenge string        // code_challenge of the pending authorization
+	claims    jwt.MapClaims // claims of the next ID token
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &mockIdP{t: t, key: key, kid: "key-1"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"use": "sig",
+				"kid": idp.kid,
+				"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", idp.token)
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	user, pass, ok := r.BasicAuth()
+	if !ok || user != testClientID || pass != testClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		writeJSON(w, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	if err := r.ParseForm(); err != nil ||
+		r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("code") != "good-code" ||
+		r.PostForm.Get("redirect_uri") != testRedirectURL ||
+		PKCEChallenge(r.PostForm.Get("code_verifier")) != idp.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idp.sign(idp.claims),
+	})
+}
+
+func (idp *mockIdP) sign(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = idp.kid
+	signed, err := token.SignedString(idp.key)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	return signed
+}
+
+func (idp *mockIdP) validClaims(nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"aud":            testClientID,
+		"sub":            "user-123",
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          nonce,
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"name":           "Alice",
+	}
+}
+
+func (idp *mockIdP) provider() *Provider {
+	return NewProvider(Config{
+		Issuer:       idp.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	idp := newMockIdP(t)
+	p := idp.provider()
+	ctx := context.Background()
+
+	verifier, challenge, err := NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", challenge)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	u, _ := url.Parse(authURL)
+	q := u.Query()
+	if u.Path != "/authorize" || q.Get("client_id") != testClientID || q.Get("state") != "state-1" ||
+		q.Get("nonce") != "nonce-1" || q.Get("code_challenge_method") != "S256" ||
+		q.Get("scope") != "openid email profile" || q.Get("redirect_uri") != testRedirectURL {
+		t.Fatalf("unexpected authorization URL %s", authURL)
+	}
+
+	// The provider keeps the challenge and authenticates the user
+	idp.challenge = q.Get("code_challenge")
+	idp.claims = idp.validClaims("nonce-1")
+
+	if _, err := p.Exchange(ctx, "good-code", "wrong-verifier"); !errors.Is(err, ErrExchangeFailed) {
+		t.Fatalf("Exchange with wrong verifier: got %v, want ErrExchangeFailed", err)
+	}
+
+	token, err := p.Exchange(ctx, "good-code", verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	claims, err := p.VerifyIDToken(ctx, token.IDToken, "nonce-1")
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if claims.Subject != "user-123" || claims.Email != "alice@example.com" || !claims.IsEmailVerified() || claims.Name != "Alice" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+}
+
+func TestVerifyIDTokenRejectsInvalidTokens(t *testing.T) {
+	idp := newMockIdP(t)
+	p := idp.provider()
+	ctx := context.Background()
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token func() string
+	}{
+		{"wrong nonce", func() string {
+			return idp.sign(idp.validClaims("other-nonce"))
+		}},
+		{"wrong audience", func() string {
+			c := idp.validClaims("nonce-1")
+			c["aud"] = "someone-else"
+			return idp.sign(c)
+		}},
+		{"wrong issuer", func() string {
+			c := idp.validClaims("nonce-1")
+			c["iss"] = "https://evil.example.com"
+			return idp.sign(c)
+		}},
+		{"expired", func() string {
+			c := idp.validClaims("nonce-1")
+			c["exp"] = time.Now().Add(-time.Hour).Unix()
+			return idp.sign(c)
+		}},
+		{"untrusted key", func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, idp.validClaims("nonce-1"))
+			token.Header["kid"] = idp.kid
+			signed, _ := token.SignedString(otherKey)
+			return signed
+		}},
+		{"unsigned", func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodNone, idp.validClaims("nonce-1"))
+			signed, _ := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+			return signed
+		}},
+		{"symmetric algorithm", func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, idp.validClaims("nonce-1"))
+			signed, _ := token.SignedString([]byte(testClientSecret))
+			return signed
+		}},
+		{"foreign authorized party", func() string {
+			c := idp.validClaims("nonce-1")
+			c["aud"] = []string{testClientID, "other-client"}
+			c["azp"] = "other-client"
+			return idp.sign(c)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := p.VerifyIDToken(ctx, tt.token(), "nonce-1"); !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("got %v, want ErrInvalidIDToken", err)
+			}
+		})
+	}
+}
+
+func TestEmailVerifiedAsString(t *testing.T) {
+	var claims Claims
+	if err := json.Unmarshal([]byte(`{"email_verified":"true"}`), &claims); err != nil {
+		t.Fatal(err)
+	}
+	if !claims.IsEmailVerified() {
+		t.Error(`"true" should be accepted as a verified email`)
+	}
+}
@@ -0,0 +1,50 @@
+package oidc
+
+import (
+	"github.com/Beretta350/gochat/internal/config"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+// ProviderInfo describes a configured provider to clients (login buttons)
+type ProviderInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Registry holds the configured identity providers
+type Registry struct {
+	providers map[string]*Provider
+	infos     []ProviderInfo
+}
+
+// NewRegistry creates a provider for each configured OIDC_PROVIDERS entry (Fx provider)
+func NewRegistry(cfg *config.Config) *Registry {
+	r := &Registry{providers: make(map[string]*Provider)}
+
+	for _, pc := range cfg.OIDC.Providers {
+		r.providers[pc.ID] = NewProvider(Config{
+			Issuer:       pc.Issuer,
+			ClientID:     pc.ClientID,
+			ClientSecret: pc.ClientSecret,
+			RedirectURL:  cfg.OIDC.RedirectURL(pc.ID),
+			Scopes:       pc.Scopes,
+		})
+		r.infos = append(r.infos, ProviderInfo{ID: pc.ID, Name: pc.Name})
+	}
+
+	logger.Infof("OIDC registry initialized (%d provider(s))", len(r.providers))
+	return r
+}
+
+// Get returns a provider by ID
+func (r *Registry) Get(id string) (*Provider, bool) {
+	p, ok := r.providers[id]
+	return p, ok
+}
+
+// List returns the configured providers in configuration order
+func (r *Registry) List() []ProviderInfo {
+	infos := make([]ProviderInfo, len(r.infos))
+	copy(infos, r.infos)
+	return infos
+}
@@ -0,0 +1,136 @@
+// Package passkey runs WebAuthn registration and login ceremonies.
+// Storage of credentials and ceremony state is left to the caller.
+package passkey
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+
+	"github.com/Beretta350/gochat/internal/config"
+	"github.com/Beretta350/gochat/pkg/logger"
+)
+
+var (
+	ErrCeremonyFailed = errors.New("passkey ceremony failed")
+)
+
+// User is the WebAuthn view of an account
+type User struct {
+	ID          []byte // User handle (stable, opaque, at most 64 bytes)
+	Name        string
+	DisplayName string
+	Credentials []webauthn.Credential
+}
+
+func (u *User) WebAuthnID() []byte                         { return u.ID }
+func (u *User) WebAuthnName() string                       { return u.Name }
+func (u *User) WebAuthnDisplayName() string                { return u.DisplayName }
+func (u *User) WebAuthnCredentials() []webauthn.Credential { return u.Credentials }
+
+// UserLookup finds the owner of a credential during a passwordless login
+type UserLookup func(credentialID, userHandle []byte) (*User, error)
+
+// Service runs WebAuthn ceremonies
+type Service struct {
+	webauthn *webauthn.WebAuthn
+	timeout  time.Duration
+}
+
+// NewService creates a new passkey service (Fx provider)
+func NewService(cfg *config.Config) (*Service, error) {
+	timeout := webauthn.TimeoutConfig{
+		Enforce:    true,
+		Timeout:    cfg.WebAuthn.Timeout,
+		TimeoutUVD: cfg.WebAuthn.Timeout,
+	}
+
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.WebAuthn.RPID,
+		RPDisplayName: cfg.WebAuthn.RPName,
+		RPOrigins:     cfg.WebAuthn.Origins(),
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementRequired,
+			UserVerification: protocol.VerificationRequired,
+		},
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        timeout,
+			Registration: timeout,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Passkey service initialized (RP ID %s)", cfg.WebAuthn.RPID)
+	return &Service{webauthn: wa, timeout: cfg.WebAuthn.Timeout}, nil
+}
+
+// Timeout returns how long a ceremony may take (how long to keep its session data)
+func (s *Service) Timeout() time.Duration {
+	return s.timeout
+}
+
+// BeginRegistration returns the options for navigator.credentials.create().
+// Credentials the user already has are excluded so one authenticator isn't registered twice.
+func (s *Service) BeginRegistration(user *User) (*protocol.CredentialCreation, *webauthn.SessionData, error) {
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.Credentials))
+	for _, cred := range user.Credentials {
+		exclusions = append(exclusions, cred.Descriptor())
+	}
+
+	return s.webauthn.BeginRegistration(user, webauthn.WithExclusions(exclusions))
+}
+
+// FinishRegistration verifies the authenticator's attestation response (JSON)
+func (s *Service) FinishRegistration(user *User, session webauthn.SessionData, response []byte) (*webauthn.Credential, error) {
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, errors.Join(ErrCeremonyFailed, err)
+	}
+
+	cred, err := s.webauthn.CreateCredential(user, session, parsed)
+	if err != nil {
+		return nil, errors.Join(ErrCeremonyFailed, err)
+	}
+	return cred, nil
+}
+
+// BeginLogin returns the options for navigator.credentials.get().
+// No user is given: the authenticator offers its discoverable credentials (passkeys).
+func (s *Service) BeginLogin() (*protocol.CredentialAssertion, *webauthn.SessionData, error) {
+	return s.webauthn.BeginDiscoverableLogin()
+}
+
+// FinishLogin verifies the authenticator's assertion response (JSON) and returns
+// the user and the credential with its updated sign counter and flags
+func (s *Service) FinishLogin(session webauthn.SessionData, response []byte, lookup UserLookup) (*User, *webauthn.Credential, error) {
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return nil, nil, errors.Join(ErrCeremonyFailed, err)
+	}
+
+	var found *User
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		user, err := lookup(rawID, userHandle)
+		if err != nil {
+			return nil, err
+		}
+		found = user
+		return user, nil
+	}
+
+	cred, err := s.webauthn.ValidateDiscoverableLogin(handler, session, parsed)
+	if err != nil {
+		return nil, nil, errors.Join(ErrCeremonyFailed, err)
+	}
+
+	// A cloned authenticator replays old counters
+	if cred.Authenticator.CloneWarning {
+		return nil, nil, errors.Join(ErrCeremonyFailed, errors.New("sign counter went backwards"))
+	}
+
+	return found, cred, nil
+}
@@ -0,0 +1,176 @@
+package passkey
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/descope/virtualwebauthn"
+
+	"github.com/Beretta350/gochat/internal/config"
+)
+
+const testOrigin = "https://chat.example.com"
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	svc, err := NewService(&config.Config{
+		WebAuthn: config.WebAuthnConfig{
+			RPID:           "chat.example.com",
+			RPName:         "GoChat",
+			AllowedOrigins: testOrigin,
+			Timeout:        time.Minute,
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	return svc
+}
+
+// softAuthenticator is a software authenticator holding one passkey
+type softAuthenticator struct {
+	rp   virtualwebauthn.RelyingParty
+	auth virtualwebauthn.Authenticator
+	cred virtualwebauthn.Credential
+}
+
+func newSoftAuthenticator(userHandle []byte) *softAuthenticator {
+	return &softAuthenticator{
+		rp:   virtualwebauthn.RelyingParty{ID: "chat.example.com", Name: "GoChat", Origin: testOrigin},
+		auth: virtualwebauthn.NewAuthenticatorWithOptions(virtualwebauthn.AuthenticatorOptions{UserHandle: userHandle}),
+		cred: virtualwebauthn.NewCredential(virtualwebauthn.KeyTypeEC2),
+	}
+}
+
+func (a *softAuthenticator) create(t *testing.T, options any) []byte {
+	t.Helper()
+	raw, _ := json.Marshal(options)
+	parsed, err := virtualwebauthn.ParseAttestationOptions(string(raw))
+	if err != nil {
+		t.Fatalf("ParseAttestationOptions() error = %v", err)
+	}
+	response := virtualwebauthn.CreateAttestationResponse(a.rp, a.auth, a.cred, *parsed)
+	a.auth.AddCredential(a.cred)
+	return []byte(response)
+}
+
+func (a *softAuthenticator) get(t *testing.T, options any) []byte {
+	t.Helper()
+	raw, _ := json.Marshal(options)
+	parsed, err := virtualwebauthn.ParseAssertionOptions(string(raw))
+	if err != nil {
+		t.Fatalf("ParseAssertionOptions() error = %v", err)
+	}
+	response := virtualwebauthn.CreateAssertionResponse(a.rp, a.auth, a.cred, *parsed)
+	a.cred.Counter++
+	return []byte(response)
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	svc := newTestService(t)
+	user := &User{ID: []byte("user-1"), Name: "alice@example.com", DisplayName: "alice"}
+	authenticator := newSoftAuthenticator(user.ID)
+
+	// Registration ceremony
+	creation, session, err := svc.BeginRegistration(user)
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+	cred, err := svc.FinishRegistration(user, *session, authenticator.create(t, creation))
+	if err != nil {
+		t.Fatalf("FinishRegistration() error = %v", err)
+	}
+	if !bytes.Equal(cred.ID, authenticator.cred.ID) {
+		t.Fatalf("registered credential ID = %x; want %x", cred.ID, authenticator.cred.ID)
+	}
+	user.Credentials = append(user.Credentials, *cred)
+
+	lookup := func(credentialID, userHandle []byte) (*User, error) {
+		if !bytes.Equal(userHandle, user.ID) {
+			return nil, errors.New("unknown user")
+		}
+		return user, nil
+	}
+
+	// Passwordless login ceremony
+	assertion, session, err := svc.BeginLogin()
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+	response := authenticator.get(t, assertion)
+
+	found, loginCred, err := svc.FinishLogin(*session, response, lookup)
+	if err != nil {
+		t.Fatalf("FinishLogin() error = %v", err)
+	}
+	if found != user {
+		t.Errorf("FinishLogin() user = %v; want %v", found, user)
+	}
+	if !bytes.Equal(loginCred.ID, cred.ID) {
+		t.Errorf("FinishLogin() credential ID = %x; want %x", loginCred.ID, cred.ID)
+	}
+
+	// The same assertion can't be replayed against a new challenge
+	_, session, err = svc.BeginLogin()
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+	if _, _, err := svc.FinishLogin(*session, response, lookup); !errors.Is(err, ErrCeremonyFailed) {
+		t.Errorf("replayed FinishLogin() error = %v; want ErrCeremonyFailed", err)
+	}
+}
+
+func TestRegistrationExcludesExistingCredentials(t *testing.T) {
+	svc := newTestService(t)
+	user := &User{ID: []byte("user-1"), Name: "alice@example.com", DisplayName: "alice"}
+	authenticator := newSoftAuthenticator(user.ID)
+
+	creation, session, err := svc.BeginRegistration(user)
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+	cred, err := svc.FinishRegistration(user, *session, authenticator.create(t, creation))
+	if err != nil {
+		t.Fatalf("FinishRegistration() error = %v", err)
+	}
+	user.Credentials = append(user.Credentials, *cred)
+
+	creation, _, err = svc.BeginRegistration(user)
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+	if len(creation.Response.CredentialExcludeList) != 1 {
+		t.Errorf("exclude list has %d credentials; want 1", len(creation.Response.CredentialExcludeList))
+	}
+}
+
+func TestLoginRejectsWrongOrigin(t *testing.T) {
+	svc := newTestService(t)
+	user := &User{ID: []byte("user-1"), Name: "alice@example.com", DisplayName: "alice"}
+	authenticator := newSoftAuthenticator(user.ID)
+
+	creation, session, err := svc.BeginRegistration(user)
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+	cred, err := svc.FinishRegistration(user, *session, authenticator.create(t, creation))
+	if err != nil {
+		t.Fatalf("FinishRegistration() error = %v", err)
+	}
+	user.Credentials = append(user.Credentials, *cred)
+
+	// A phishing site relays the challenge from its own origin
+	authenticator.rp.Origin = "https://evil.example.net"
+
+	assertion, session, err := svc.BeginLogin()
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+	lookup := func(credentialID, userHandle []byte) (*User, error) { return user, nil }
+	if _, _, err := svc.FinishLogin(*session, authenticator.get(t, assertion), lookup); !errors.Is(err, ErrCeremonyFailed) {
+		t.Errorf("FinishLogin() from wrong origin error = %v; want ErrCeremonyFailed", err)
+	}
+}
@@ -0,0 +1,168 @@
+// Package password hashes passwords in a self-describing format, so the algorithm
+// and its cost can change without invalidating the hashes already stored:
+//
+//	$argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>   argon2id (PHC string format)
+//	$2a$10$<salt+hash>                               bcrypt (legacy)
+//
+// Verify reports when a hash doesn't use the current parameters, so it can be
+// replaced after a successful login.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Supported algorithms
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported password hash algorithm")
+	ErrInvalidHash          = errors.New("invalid password hash")
+)
+
+const (
+	saltLength = 16
+	keyLength  = 32
+)
+
+// Params configures new hashes
+type Params struct {
+	Algorithm   string // Argon2id or Bcrypt
+	Memory      uint32 // argon2id memory in KiB
+	Iterations  uint32 // argon2id passes
+	Parallelism uint8  // argon2id lanes
+	BcryptCost  int
+}
+
+// DefaultParams follow the OWASP recommendation for argon2id (64 MiB, 3 passes)
+var DefaultParams = Params{
+	Algorithm:   Argon2id,
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	BcryptCost:  bcrypt.DefaultCost,
+}
+
+// Hasher hashes and verifies passwords
+type Hasher struct {
+	params Params
+}
+
+// New creates a hasher producing hashes with the given parameters
+func New(p Params) (*Hasher, error) {
+	switch p.Algorithm {
+	case Argon2id:
+		if p.Memory < 8*uint32(p.Parallelism) || p.Iterations < 1 || p.Parallelism < 1 {
+			return nil, fmt.Errorf("invalid argon2id parameters m=%d t=%d p=%d", p.Memory, p.Iterations, p.Parallelism)
+		}
+	case Bcrypt:
+		if p.BcryptCost < bcrypt.MinCost || p.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("invalid bcrypt cost %d", p.BcryptCost)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, p.Algorithm)
+	}
+	return &Hasher{params: p}, nil
+}
+
+// Hash hashes a password with a random salt
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.params.Algorithm == Bcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.params.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	}
+
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, keyLength)
+
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		Argon2id, argon2.Version, h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify checks a password against a hash of any supported format.
+// needsRehash is true when the password matches but the hash doesn't use the current parameters.
+func (h *Hasher) Verify(password, encoded string) (ok, needsRehash bool, err error) {
+	if isBcrypt(encoded) {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+		}
+		cost, _ := bcrypt.Cost([]byte(encoded))
+		return true, h.params.Algorithm != Bcrypt || cost < h.params.BcryptCost, nil
+	}
+
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, false, err
+	}
+
+	computed := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(computed, key) != 1 {
+		return false, false, nil
+	}
+
+	needsRehash = h.params.Algorithm != Argon2id ||
+		p.Memory != h.params.Memory || p.Iterations != h.params.Iterations || p.Parallelism != h.params.Parallelism ||
+		len(key) != keyLength
+	return true, needsRehash, nil
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// decodeArgon2id parses a PHC argon2id string
+func decodeArgon2id(encoded string) (p Params, salt, key []byte, err error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if parts[1] != Argon2id {
+		return p, nil, nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, parts[1])
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("%w: version %q", ErrInvalidHash, parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, fmt.Errorf("%w: parameters %q", ErrInvalidHash, parts[3])
+	}
+	if p.Iterations < 1 || p.Parallelism < 1 {
+		return p, nil, nil, fmt.Errorf("%w: parameters %q", ErrInvalidHash, parts[3])
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return p, nil, nil, fmt.Errorf("%w: salt", ErrInvalidHash)
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		return p, nil, nil, fmt.Errorf("%w: hash", ErrInvalidHash)
+	}
+
+	p.Algorithm = Argon2id
+	return p, salt, key, nil
+}
@@ -0,0 +1,129 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fastParams keep the tests quick
+var fastParams = Params{Algorithm: Argon2id, Memory: 1024, Iterations: 1, Parallelism: 1, BcryptCost: bcrypt.MinCost}
+
+func newHasher(t *testing.T, p Params) *Hasher {
+	t.Helper()
+	h, err := New(p)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return h
+}
+
+func TestHashAndVerify(t *testing.T) {
+	for _, alg := range []string{Argon2id, Bcrypt} {
+		t.Run(alg, func(t *testing.T) {
+			p := fastParams
+			p.Algorithm = alg
+			h := newHasher(t, p)
+
+			hash, err := h.Hash("Password123")
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			if alg == Argon2id && !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+				t.Errorf("unexpected hash format %q", hash)
+			}
+
+			ok, rehash, err := h.Verify("Password123", hash)
+			if err != nil || !ok || rehash {
+				t.Errorf("Verify(right password) = %v, %v, %v; want true, false, nil", ok, rehash, err)
+			}
+
+			ok, _, err = h.Verify("Password124", hash)
+			if err != nil || ok {
+				t.Errorf("Verify(wrong password) = %v, %v; want false, nil", ok, err)
+			}
+		})
+	}
+}
+
+func TestSaltIsRandom(t *testing.T) {
+	h := newHasher(t, fastParams)
+	a, _ := h.Hash("Password123")
+	b, _ := h.Hash("Password123")
+	if a == b {
+		t.Error("two hashes of the same password should differ")
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("Password123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	weak := fastParams
+	weak.Iterations = 1
+	strong := fastParams
+	strong.Iterations = 2
+	weakHash, _ := newHasher(t, weak).Hash("Password123")
+
+	tests := []struct {
+		name   string
+		params Params
+		hash   string
+		want   bool
+	}{
+		{"bcrypt to argon2id", fastParams, string(legacy), true},
+		{"bcrypt cost raised", Params{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost + 1}, string(legacy), true},
+		{"bcrypt same cost", Params{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost}, string(legacy), false},
+		{"argon2id parameters changed", strong, weakHash, true},
+		{"argon2id to bcrypt", Params{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost}, weakHash, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, rehash, err := newHasher(t, tt.params).Verify("Password123", tt.hash)
+			if err != nil || !ok {
+				t.Fatalf("Verify = %v, %v; want true, nil", ok, err)
+			}
+			if rehash != tt.want {
+				t.Errorf("needsRehash = %v, want %v", rehash, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyInvalidHash(t *testing.T) {
+	h := newHasher(t, fastParams)
+
+	for _, hash := range []string{
+		"",
+		"plaintext",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+		"$argon2id$v=18$m=1024,t=1,p=1$c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!$aGFzaA",
+	} {
+		if ok, _, err := h.Verify("Password123", hash); ok || !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("Verify(%q) = %v, %v; want false, ErrInvalidHash", hash, ok, err)
+		}
+	}
+
+	if _, _, err := h.Verify("Password123", "$scrypt$ln=15$c2FsdA$aGFzaA$x"); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("got %v, want ErrUnsupportedAlgorithm", err)
+	}
+}
+
+func TestNewRejectsInvalidParams(t *testing.T) {
+	for _, p := range []Params{
+		{Algorithm: "md5"},
+		{Algorithm: Argon2id, Memory: 64 * 1024, Iterations: 0, Parallelism: 1},
+		{Algorithm: Bcrypt, BcryptCost: 2},
+	} {
+		if _, err := New(p); err == nil {
+			t.Errorf("New(%+v) should fail", p)
+		}
+	}
+}
@@ -3,6 +3,10 @@ package redisclient
 import (
 	"context"
 	"crypto/tls"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 
@@ -53,15 +57,18 @@ func (c *Client) Publish(ctx context.Context, channel string, message interface{
 	return c.rdb.Publish(ctx, channel, message).Err()
 }
 
-// Subscribe subscribes to a channel and returns a PubSub
-func (c *Client) Subscribe(ctx context.Context, channel string) *redis.PubSub {
-	return c.rdb.Subscribe(ctx, channel)
+// Subscribe subscribes to one or more channels and returns a PubSub
+func (c *Client) Subscribe(ctx context.Context, channels ...string) *redis.PubSub {
+	return c.rdb.Subscribe(ctx, channels...)
 }
 
+// MessagesStream is the stream of sent messages (persisted by the message worker)
+const MessagesStream = "messages:stream"
+
 // AddToStream adds a message to the main stream
 func (c *Client) AddToStream(ctx context.Context, values map[string]interface{}) (string, error) {
 	return c.rdb.XAdd(ctx, &redis.XAddArgs{
-		Stream: "messages:stream",
+		Stream: MessagesStream,
 		Values: values,
 	}).Result()
 }
@@ -71,6 +78,20 @@ func (c *Client) AddToPending(ctx context.Context, userID string, messageJSON st
 	return c.rdb.RPush(ctx, "pending:"+userID, messageJSON).Err()
 }
 
+// AddToPendingMany adds a message to the pending queues of several users in one round trip
+func (c *Client) AddToPendingMany(ctx context.Context, userIDs []string, messageJSON string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	pipe := c.rdb.Pipeline()
+	for _, userID := range userIDs {
+		pipe.RPush(ctx, "pending:"+userID, messageJSON)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
 // GetPendingMessages gets all pending messages for a user and removes them
 func (c *Client) GetPendingMessages(ctx context.Context, userID string) ([]string, error) {
 	key := "pending:" + userID
@@ -87,9 +108,28 @@ func (c *Client) GetPendingMessages(ctx context.Context, userID string) ([]strin
 	return messages, nil
 }
 
+// unsavedMessagePrefix marks the messages sent but not saved by the message worker yet
+const unsavedMessagePrefix = "message:unsaved:"
+
+// MarkMessageUnsaved records that a message of a conversation was sent and waits in the
+// stream to be saved, for at most ttl
+func (c *Client) MarkMessageUnsaved(ctx context.Context, messageID, conversationID string, ttl time.Duration) error {
+	return c.rdb.Set(ctx, unsavedMessagePrefix+messageID, conversationID, ttl).Err()
+}
+
+// IsMessageUnsaved checks if a message of a conversation was sent recently, so it may
+// not be saved yet
+func (c *Client) IsMessageUnsaved(ctx context.Context, messageID, conversationID string) (bool, error) {
+	got, err := c.rdb.Get(ctx, unsavedMessagePrefix+messageID).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	return got == conversationID, err
+}
+
 // CreateConsumerGroup creates a consumer group for the stream
 func (c *Client) CreateConsumerGroup(ctx context.Context, group string) error {
-	err := c.rdb.XGroupCreateMkStream(ctx, "messages:stream", group, "0").Err()
+	err := c.rdb.XGroupCreateMkStream(ctx, MessagesStream, group, "0").Err()
 	if err != nil && err.Error() == "BUSYGROUP Consumer Group name already exists" {
 		return nil
 	}
@@ -101,7 +141,7 @@ func (c *Client) ReadStreamGroup(ctx context.Context, group, consumer string, co
 	return c.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
 		Group:    group,
 		Consumer: consumer,
-		Streams:  []string{"messages:stream", ">"},
+		Streams:  []string{MessagesStream, ">"},
 		Count:    count,
 		Block:    0,
 	}).Result()
@@ -109,34 +149,44 @@ func (c *Client) ReadStreamGroup(ctx context.Context, group, consumer string, co
 
 // AckMessage acknowledges a message
 func (c *Client) AckMessage(ctx context.Context, group, id string) error {
-	return c.rdb.XAck(ctx, "messages:stream", group, id).Err()
+	return c.rdb.XAck(ctx, MessagesStream, group, id).Err()
 }
 
 // ==================== Online Status Tracking ====================
 
-const onlineUsersKey = "online:users"
+// presencePrefix is the prefix of the sorted set of a user's live connections, scored
+// by when each expires (Unix milliseconds) unless its heartbeat refreshes it
+const presencePrefix = "presence:"
 
-// SetUserOnline marks a user as online
-func (c *Client) SetUserOnline(ctx context.Context, userID string) error {
-	return c.rdb.SAdd(ctx, onlineUsersKey, userID).Err()
-}
+// ConnectionAlive records a live connection of a user, or refreshes it. The connection
+// counts as online for ttl, so one left behind by a crashed instance goes away.
+func (c *Client) ConnectionAlive(ctx context.Context, userID, connID string, ttl time.Duration) error {
+	key := presencePrefix + userID
+	now := time.Now()
 
-// SetUserOffline marks a user as offline
-func (c *Client) SetUserOffline(ctx context.Context, userID string) error {
-	return c.rdb.SRem(ctx, onlineUsersKey, userID).Err()
+	pipe := c.rdb.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.Add(ttl).UnixMilli()), Member: connID})
+	pipe.PExpire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
 }
 
-// IsUserOnline checks if a user is online
-func (c *Client) IsUserOnline(ctx context.Context, userID string) (bool, error) {
-	return c.rdb.SIsMember(ctx, onlineUsersKey, userID).Result()
-}
+// ConnectionClosed removes a connection of a user and returns whether the user still
+// has live ones, on this instance or another
+func (c *Client) ConnectionClosed(ctx context.Context, userID, connID string) (bool, error) {
+	key := presencePrefix + userID
 
-// GetOnlineUsers returns all online user IDs
-func (c *Client) GetOnlineUsers(ctx context.Context) ([]string, error) {
-	return c.rdb.SMembers(ctx, onlineUsersKey).Result()
+	pipe := c.rdb.TxPipeline()
+	pipe.ZRem(ctx, key, connID)
+	live := pipe.ZCount(ctx, key, "("+strconv.FormatInt(time.Now().UnixMilli(), 10), "+inf")
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+	return live.Val() > 0, nil
 }
 
-// GetOnlineUsersFromList checks which users from the list are online
+// GetOnlineUsersFromList checks which users from the list have a live connection
 func (c *Client) GetOnlineUsersFromList(ctx context.Context, userIDs []string) ([]string, error) {
 	if len(userIDs) == 0 {
 		return []string{}, nil
@@ -144,10 +194,11 @@ func (c *Client) GetOnlineUsersFromList(ctx context.Context, userIDs []string) (
 
 	// Use pipeline to check multiple users efficiently
 	pipe := c.rdb.Pipeline()
-	cmds := make([]*redis.BoolCmd, len(userIDs))
+	cmds := make([]*redis.IntCmd, len(userIDs))
+	now := "(" + strconv.FormatInt(time.Now().UnixMilli(), 10)
 
 	for i, userID := range userIDs {
-		cmds[i] = pipe.SIsMember(ctx, onlineUsersKey, userID)
+		cmds[i] = pipe.ZCount(ctx, presencePrefix+userID, now, "+inf")
 	}
 
 	_, err := pipe.Exec(ctx)
@@ -157,10 +208,279 @@ func (c *Client) GetOnlineUsersFromList(ctx context.Context, userIDs []string) (
 
 	var onlineUsers []string
 	for i, cmd := range cmds {
-		if cmd.Val() {
+		if cmd.Val() > 0 {
 			onlineUsers = append(onlineUsers, userIDs[i])
 		}
 	}
 
 	return onlineUsers, nil
 }
+
+// ==================== Conversation Members ====================
+
+// MembersChangedChannel broadcasts the IDs of conversations whose members changed to
+// every instance, so they drop the members they cached
+const MembersChangedChannel = "conversations:members_changed"
+
+// ==================== Session Revocation ====================
+
+const (
+	revokedSessionPrefix = "revoked:session:"
+
+	// SessionRevokedChannel broadcasts revoked session IDs to every instance
+	SessionRevokedChannel = "sessions:revoked"
+)
+
+// RevokeSession marks a session as revoked for ttl (the access token lifetime)
+// and notifies all instances so they can drop the session's WebSocket connections
+func (c *Client) RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error {
+	if err := c.rdb.Set(ctx, revokedSessionPrefix+sessionID, 1, ttl).Err(); err != nil {
+		return err
+	}
+	return c.rdb.Publish(ctx, SessionRevokedChannel, sessionID).Err()
+}
+
+// IsSessionRevoked checks if a session was revoked while its access tokens are still alive
+func (c *Client) IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	n, err := c.rdb.Exists(ctx, revokedSessionPrefix+sessionID).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// ==================== Session Connection Tracking ====================
+
+const connectedSessionsKey = "online:sessions"
+
+// SessionConnected increments the number of open WebSocket connections of a session
+func (c *Client) SessionConnected(ctx context.Context, sessionID string) error {
+	return c.rdb.HIncrBy(ctx, connectedSessionsKey, sessionID, 1).Err()
+}
+
+// SessionDisconnected decrements the number of open WebSocket connections of a session
+func (c *Client) SessionDisconnected(ctx context.Context, sessionID string) error {
+	n, err := c.rdb.HIncrBy(ctx, connectedSessionsKey, sessionID, -1).Result()
+	if err != nil {
+		return err
+	}
+	if n <= 0 {
+		return c.rdb.HDel(ctx, connectedSessionsKey, sessionID).Err()
+	}
+	return nil
+}
+
+// GetConnectedSessions returns which of the given sessions have an open WebSocket connection
+func (c *Client) GetConnectedSessions(ctx context.Context, sessionIDs []string) (map[string]bool, error) {
+	connected := make(map[string]bool, len(sessionIDs))
+	if len(sessionIDs) == 0 {
+		return connected, nil
+	}
+
+	counts, err := c.rdb.HMGet(ctx, connectedSessionsKey, sessionIDs...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, count := range counts {
+		connected[sessionIDs[i]] = count != nil
+	}
+	return connected, nil
+}
+
+// ==================== Two-Factor Authentication ====================
+
+const (
+	usedTOTPPrefix         = "totp:used:"
+	mfaChallengeAttempts   = "mfa:attempts:"
+	mfaChallengeUsedPrefix = "mfa:used:"
+)
+
+// MarkTOTPStepUsed records that a user's TOTP code for a time step was accepted.
+// Returns false if it was already used (replayed code).
+func (c *Client) MarkTOTPStepUsed(ctx context.Context, userID string, step int64, ttl time.Duration) (bool, error) {
+	return c.rdb.SetNX(ctx, fmt.Sprintf("%s%s:%d", usedTOTPPrefix, userID, step), 1, ttl).Result()
+}
+
+// IncrMFAChallengeAttempts counts verification attempts for a login challenge
+func (c *Client) IncrMFAChallengeAttempts(ctx context.Context, challengeID string, ttl time.Duration) (int64, error) {
+	key := mfaChallengeAttempts + challengeID
+	n, err := c.rdb.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if n == 1 {
+		c.rdb.Expire(ctx, key, ttl)
+	}
+	return n, nil
+}
+
+// MarkMFAChallengeUsed makes a login challenge single-use.
+// Returns false if it was already used.
+func (c *Client) MarkMFAChallengeUsed(ctx context.Context, challengeID string, ttl time.Duration) (bool, error) {
+	return c.rdb.SetNX(ctx, mfaChallengeUsedPrefix+challengeID, 1, ttl).Result()
+}
+
+// ==================== WebAuthn Ceremonies ====================
+
+const webauthnCeremonyPrefix = "webauthn:ceremony:"
+
+// SaveWebAuthnCeremony stores the state of a passkey ceremony until it completes
+func (c *Client) SaveWebAuthnCeremony(ctx context.Context, ceremonyID string, data []byte, ttl time.Duration) error {
+	return c.rdb.Set(ctx, webauthnCeremonyPrefix+ceremonyID, data, ttl).Err()
+}
+
+// TakeWebAuthnCeremony returns and deletes the state of a passkey ceremony (single use).
+// Returns redis.Nil if it doesn't exist or expired.
+func (c *Client) TakeWebAuthnCeremony(ctx context.Context, ceremonyID string) ([]byte, error) {
+	return c.rdb.GetDel(ctx, webauthnCeremonyPrefix+ceremonyID).Bytes()
+}
+
+// ==================== OIDC Login State ====================
+
+const oidcStatePrefix = "oidc:state:"
+
+// SaveOIDCState stores the state of an SSO login until the provider redirects back
+func (c *Client) SaveOIDCState(ctx context.Context, state string, data []byte, ttl time.Duration) error {
+	return c.rdb.Set(ctx, oidcStatePrefix+state, data, ttl).Err()
+}
+
+// TakeOIDCState returns and deletes the state of an SSO login (single use).
+// Returns redis.Nil if it doesn't exist or expired.
+func (c *Client) TakeOIDCState(ctx context.Context, state string) ([]byte, error) {
+	return c.rdb.GetDel(ctx, oidcStatePrefix+state).Bytes()
+}
+
+// ==================== Login Throttling ====================
+
+const (
+	loginFailuresPrefix = "login:failures:"
+	loginBlockedPrefix  = "login:blocked:"
+)
+
+// IncrLoginFailures counts failed logins for a key (e.g. "email:alice@example.com" or "ip:1.2.3.4").
+// The window restarts at each failure.
+func (c *Client) IncrLoginFailures(ctx context.Context, key string, window time.Duration) (int64, error) {
+	pipe := c.rdb.TxPipeline()
+	incr := pipe.Incr(ctx, loginFailuresPrefix+key)
+	pipe.Expire(ctx, loginFailuresPrefix+key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// BlockLogin rejects logins for a key during d
+func (c *Client) BlockLogin(ctx context.Context, key string, d time.Duration) error {
+	return c.rdb.Set(ctx, loginBlockedPrefix+key, 1, d).Err()
+}
+
+// LoginBlockedFor returns how long logins are still rejected for any of the keys (0 if none is blocked)
+func (c *Client) LoginBlockedFor(ctx context.Context, keys ...string) (time.Duration, error) {
+	pipe := c.rdb.Pipeline()
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		ttls[i] = pipe.PTTL(ctx, loginBlockedPrefix+key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	var longest time.Duration
+	for _, ttl := range ttls {
+		if d := ttl.Val(); d > longest {
+			longest = d
+		}
+	}
+	return longest, nil
+}
+
+// ClearLoginFailures resets the failure count and block of a key
+func (c *Client) ClearLoginFailures(ctx context.Context, key string) error {
+	return c.rdb.Del(ctx, loginFailuresPrefix+key, loginBlockedPrefix+key).Err()
+}
+
+// ==================== Webhooks ====================
+
+const (
+	// WebhookEventsStream carries the other events webhooks can subscribe to
+	WebhookEventsStream = "webhooks:events"
+
+	webhookQueueKey = "webhooks:queue"
+)
+
+// AddWebhookEvent adds an event to the webhook events stream
+func (c *Client) AddWebhookEvent(ctx context.Context, values map[string]interface{}) error {
+	return c.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: WebhookEventsStream,
+		MaxLen: 100000,
+		Approx: true,
+		Values: values,
+	}).Err()
+}
+
+// CreateStreamGroup creates a consumer group on a stream, starting at start ("$" for new entries only)
+func (c *Client) CreateStreamGroup(ctx context.Context, stream, group, start string) error {
+	err := c.rdb.XGroupCreateMkStream(ctx, stream, group, start).Err()
+	if err != nil && err.Error() == "BUSYGROUP Consumer Group name already exists" {
+		return nil
+	}
+	return err
+}
+
+// ReadStreamsGroup reads new entries of several streams as part of a consumer group
+func (c *Client) ReadStreamsGroup(ctx context.Context, group, consumer string, streams []string, count int64, block time.Duration) ([]redis.XStream, error) {
+	args := make([]string, 0, len(streams)*2)
+	args = append(args, streams...)
+	for range streams {
+		args = append(args, ">")
+	}
+	return c.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    group,
+		Consumer: consumer,
+		Streams:  args,
+		Count:    count,
+		Block:    block,
+	}).Result()
+}
+
+// AckStream acknowledges an entry of a stream
+func (c *Client) AckStream(ctx context.Context, stream, group, id string) error {
+	return c.rdb.XAck(ctx, stream, group, id).Err()
+}
+
+// ScheduleWebhookDelivery queues a delivery job to run at the given time
+func (c *Client) ScheduleWebhookDelivery(ctx context.Context, job string, at time.Time) error {
+	return c.rdb.ZAdd(ctx, webhookQueueKey, redis.Z{Score: float64(at.UnixMilli()), Member: job}).Err()
+}
+
+// claimWebhookDeliveries leases due jobs: they stay queued, pushed back by the lease,
+// so a job whose worker died is retried once the lease is over
+var claimWebhookDeliveries = redis.NewScript(`
+local jobs = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, job in ipairs(jobs) do
+	redis.call('ZADD', KEYS[1], ARGV[3], job)
+end
+return jobs
+`)
+
+// ClaimWebhookDeliveries returns up to limit due jobs and leases them for lease
+func (c *Client) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]string, error) {
+	now := time.Now()
+	return claimWebhookDeliveries.Run(ctx, c.rdb, []string{webhookQueueKey},
+		now.UnixMilli(), limit, now.Add(lease).UnixMilli()).StringSlice()
+}
+
+// CompleteWebhookDelivery removes a finished job from the queue
+func (c *Client) CompleteWebhookDelivery(ctx context.Context, job string) error {
+	return c.rdb.ZRem(ctx, webhookQueueKey, job).Err()
+}
+
+// RescheduleWebhookDelivery replaces a job by its next attempt
+func (c *Client) RescheduleWebhookDelivery(ctx context.Context, job, next string, at time.Time) error {
+	pipe := c.rdb.TxPipeline()
+	pipe.ZRem(ctx, webhookQueueKey, job)
+	pipe.ZAdd(ctx, webhookQueueKey, redis.Z{Score: float64(at.UnixMilli()), Member: next})
+	_, err := pipe.Exec(ctx)
+	return err
+}
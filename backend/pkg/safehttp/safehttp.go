@@ -0,0 +1,87 @@
+// Package safehttp builds HTTP clients for calling URLs supplied by users
+// (webhooks, bot command handlers) without exposing the internal network.
+package safehttp
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrInvalidURL     = errors.New("URL must be a public http(s) URL")
+	ErrPrivateAddress = errors.New("URL resolves to a private address")
+)
+
+// cgnat is the shared address space of carrier-grade NAT (RFC 6598)
+var cgnat = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// NewClient builds a client that doesn't follow redirects and, unless private networks
+// are allowed, refuses connections to internal addresses after DNS resolution, so a
+// user-supplied URL can't be used to reach services behind the firewall.
+func NewClient(timeout time.Duration, allowPrivateNetworks bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivateNetworks {
+		dialer.Control = refusePrivateAddresses
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// CheckURL accepts http(s) URLs. Unless private networks are allowed, hosts that are
+// obviously internal are refused here; the client's dialer re-checks the resolved addresses.
+func CheckURL(raw string, allowPrivateNetworks bool) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return ErrInvalidURL
+	}
+	if allowPrivateNetworks {
+		return nil
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrInvalidURL
+	}
+	if ip := net.ParseIP(host); ip != nil && !IsPublicIP(ip) {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+func refusePrivateAddresses(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// IsPublicIP reports whether an address is routable on the internet
+func IsPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!cgnat.Contains(ip)
+}
@@ -0,0 +1,96 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 4226 Appendix D / RFC 6238 Appendix B shared secret
+var rfcKey = []byte("12345678901234567890")
+
+func TestHOTP(t *testing.T) {
+	want := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+	for counter, code := range want {
+		if got := hotp(rfcKey, uint64(counter), 6); got != code {
+			t.Errorf("hotp(counter=%d) = %s; want %s", counter, got, code)
+		}
+	}
+}
+
+func TestTOTPVectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		step := Step(time.Unix(tt.unix, 0))
+		if got := hotp(rfcKey, uint64(step), 8); got != tt.want {
+			t.Errorf("totp(%d) = %s; want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(rfcKey)
+	now := time.Unix(1111111111, 0)
+
+	code, err := Code(secret, now)
+	if err != nil {
+		t.Fatalf("Code() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		code string
+		at   time.Time
+		want bool
+	}{
+		{"current step", code, now, true},
+		{"previous step within skew", code, now.Add(Period), true},
+		{"outside skew", code, now.Add(3 * Period), false},
+		{"wrong code", "000000", now, false},
+		{"wrong length", "12345", now, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(secret, tt.code, tt.at, 1)
+			if ok != tt.want {
+				t.Fatalf("Validate() = %v; want %v", ok, tt.want)
+			}
+			if ok && step != Step(now) {
+				t.Errorf("Validate() step = %d; want %d", step, Step(now))
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+	if _, err := Code(secret, time.Now()); err != nil {
+		t.Errorf("Code() with generated secret error = %v", err)
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("GoChat", "alice@example.com", "JBSWY3DPEHPK3PXP")
+	for _, part := range []string{"otpauth://totp/GoChat:alice@example.com?", "secret=JBSWY3DPEHPK3PXP", "issuer=GoChat", "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("ProvisioningURI() = %s; missing %s", uri, part)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package validator
 
 import (
+	"bufio"
+	"crypto/sha1"
+	"os"
 	"reflect"
 	"strings"
 	"sync"
@@ -12,6 +15,10 @@ import (
 var (
 	validate *validator.Validate
 	once     sync.Once
+
+	// breached holds the SHA-1 of known breached passwords (see LoadBreachedPasswords)
+	breached   map[[sha1.Size]byte]struct{}
+	breachedMu sync.RWMutex
 )
 
 // ValidationError represents a validation error
@@ -54,7 +61,44 @@ func Get() *validator.Validate {
 	return validate
 }
 
-// validateStrongPassword checks for at least one uppercase, one lowercase, and one digit
+// LoadBreachedPasswords reads a list of breached passwords (one per line) that the
+// strongpassword validation rejects. It replaces any previously loaded list.
+func LoadBreachedPasswords(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	set := make(map[[sha1.Size]byte]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line != "" {
+			set[sha1.Sum([]byte(line))] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	breachedMu.Lock()
+	breached = set
+	breachedMu.Unlock()
+	return len(set), nil
+}
+
+// IsBreachedPassword checks a password against the loaded breached-password list
+func IsBreachedPassword(password string) bool {
+	breachedMu.RLock()
+	defer breachedMu.RUnlock()
+
+	_, found := breached[sha1.Sum([]byte(password))]
+	return found
+}
+
+// validateStrongPassword checks for at least one uppercase, one lowercase, and one digit,
+// and that the password isn't a known breached password
 func validateStrongPassword(fl validator.FieldLevel) bool {
 	password := fl.Field().String()
 
@@ -70,7 +114,7 @@ func validateStrongPassword(fl validator.FieldLevel) bool {
 		}
 	}
 
-	return hasUpper && hasLower && hasDigit
+	return hasUpper && hasLower && hasDigit && !IsBreachedPassword(password)
 }
 
 // Struct validates a struct and returns formatted errors
@@ -106,7 +150,20 @@ func msgForTag(fe validator.FieldError) string {
 		return field + " must be at most " + fe.Param() + " characters"
 	case "alphanum":
 		return field + " can only contain letters and numbers"
+	case "len":
+		return field + " must be exactly " + fe.Param() + " characters"
+	case "numeric":
+		return field + " can only contain digits"
+	case "oneof":
+		return field + " must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "ip":
+		return "invalid IP address"
+	case "required_without":
+		return field + " is required when " + strings.ToLower(fe.Param()) + " is empty"
 	case "strongpassword":
+		if password, _ := fe.Value().(string); IsBreachedPassword(password) {
+			return "password appears in a list of breached passwords, please choose another one"
+		}
 		return "password must contain at least one uppercase letter, one lowercase letter, and one digit"
 	default:
 		return fe.Error()
@@ -1,6 +1,9 @@
 package validator
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -151,3 +154,32 @@ func TestValidatorSingleton(t *testing.T) {
 		t.Error("Get() should return the same validator instance")
 	}
 }
+
+func TestBreachedPasswords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte("Password123\r\nQwerty123\n\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := LoadBreachedPasswords(path)
+	if err != nil {
+		t.Fatalf("LoadBreachedPasswords: %v", err)
+	}
+	t.Cleanup(func() {
+		breachedMu.Lock()
+		breached = nil
+		breachedMu.Unlock()
+	})
+	if count != 2 {
+		t.Errorf("loaded %d passwords, want 2", count)
+	}
+
+	errs := Struct(&testRegisterRequest{Email: "test@example.com", Username: "johndoe", Password: "Password123"})
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "breached") {
+		t.Errorf("breached password: got %v, want one breached error", errs)
+	}
+
+	if errs := Struct(&testRegisterRequest{Email: "test@example.com", Username: "johndoe", Password: "Password124"}); len(errs) != 0 {
+		t.Errorf("other password: got %v, want no errors", errs)
+	}
+}
@@ -0,0 +1,62 @@
+// Package webhooksig signs webhook payloads with HMAC-SHA256 and verifies them.
+//
+// The signature covers the timestamp and the body ("<timestamp>.<body>"), so a
+// captured request can't be replayed later with a new timestamp.
+package webhooksig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers set on signed requests
+const (
+	SignatureHeader = "X-GoChat-Signature"
+	TimestampHeader = "X-GoChat-Timestamp"
+)
+
+const signaturePrefix = "sha256="
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrExpiredTimestamp = errors.New("webhook timestamp outside the tolerance")
+)
+
+// Sign returns the signature header value ("sha256=<hex>") of a body sent at timestamp (Unix seconds)
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	return signaturePrefix + hex.EncodeToString(mac(secret, timestamp, body))
+}
+
+// Verify checks a signature header value and that the timestamp is within tolerance of now
+func Verify(secret []byte, signature string, timestamp int64, body []byte, now time.Time, tolerance time.Duration) error {
+	hexSig, ok := strings.CutPrefix(signature, signaturePrefix)
+	if !ok {
+		return ErrInvalidSignature
+	}
+	got, err := hex.DecodeString(hexSig)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal(got, mac(secret, timestamp, body)) {
+		return ErrInvalidSignature
+	}
+
+	age := now.Sub(time.Unix(timestamp, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrExpiredTimestamp
+	}
+	return nil
+}
+
+func mac(secret []byte, timestamp int64, body []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
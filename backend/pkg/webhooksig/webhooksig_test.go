@@ -0,0 +1,51 @@
+package webhooksig
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	secret := []byte("whsec_test")
+	body := []byte(`{"event":"message.created"}`)
+	now := time.Unix(1700000000, 0)
+
+	sig := Sign(secret, now.Unix(), body)
+	if err := Verify(secret, sig, now.Unix(), body, now, 5*time.Minute); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		secret    []byte
+		signature string
+		timestamp int64
+		body      []byte
+		want      error
+	}{
+		{"wrong secret", []byte("other"), sig, now.Unix(), body, ErrInvalidSignature},
+		{"tampered body", secret, sig, now.Unix(), []byte(`{"event":"other"}`), ErrInvalidSignature},
+		{"changed timestamp", secret, sig, now.Unix() + 1, body, ErrInvalidSignature},
+		{"missing prefix", secret, sig[len("sha256="):], now.Unix(), body, ErrInvalidSignature},
+		{"not hex", secret, "sha256=zz", now.Unix(), body, ErrInvalidSignature},
+		{"too old", secret, Sign(secret, now.Add(-time.Hour).Unix(), body), now.Add(-time.Hour).Unix(), body, ErrExpiredTimestamp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.signature, tt.timestamp, tt.body, now, 5*time.Minute)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignKnownValue(t *testing.T) {
+	// echo -n '1700000000.hello' | openssl dgst -sha256 -hmac secret
+	want := "sha256=47b1df0ab12338b2685470b0d2b37033add7c3b2bc8172f313e77413f1bb78c8"
+	if got := Sign([]byte("secret"), 1700000000, []byte("hello")); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
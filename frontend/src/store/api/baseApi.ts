@@ -8,27 +8,63 @@ import { logout } from "../slices/authSlice";
 
 // API URL - use env var in production, relative in dev
 const apiUrl = process.env.NEXT_PUBLIC_API_URL || "";
+
+// CSRF double-submit token: the backend sets a readable csrf_token cookie and
+// also returns it in the X-CSRF-Token header (for cross-origin deployments)
+const CSRF_HEADER = "X-CSRF-Token";
+let csrfToken: string | null = null;
+
+const readCsrfCookie = (): string | null => {
+  if (typeof document === "undefined") return null;
+  const match = document.cookie.match(/(?:^|;\s*)csrf_token=([^;]+)/);
+  return match ? decodeURIComponent(match[1]) : null;
+};
+
 const rawBaseQuery = fetchBaseQuery({
   baseUrl: `${apiUrl}/api/v1`,
   credentials: "include", // Envia cookies automaticamente
   prepareHeaders: (headers) => {
     headers.set("Content-Type", "application/json");
+    const token = readCsrfCookie() ?? csrfToken;
+    if (token) {
+      headers.set(CSRF_HEADER, token);
+    }
     return headers;
   },
 });
 
+// Remembers the latest CSRF token returned by the backend
+const csrfAwareQuery: typeof rawBaseQuery = async (args, api, extraOptions) => {
+  const result = await rawBaseQuery(args, api, extraOptions);
+  const token = result.meta?.response?.headers.get(CSRF_HEADER);
+  if (token) {
+    csrfToken = token;
+  }
+  return result;
+};
+
+const isCsrfError = (error: FetchBaseQueryError): boolean =>
+  error.status === 403 &&
+  (error.data as { error?: string } | undefined)?.error === "Invalid CSRF token";
+
 // BaseQuery with automatic token refresh on 401
 export const baseQuery: BaseQueryFn<
   string | FetchArgs,
   unknown,
   FetchBaseQueryError
 > = async (args, api, extraOptions) => {
-  let result = await rawBaseQuery(args, api, extraOptions);
+  let result = await csrfAwareQuery(args, api, extraOptions);
+
+  // Missing/stale CSRF token: fetch a fresh one and retry once
+  if (result.error && isCsrfError(result.error)) {
+    await csrfAwareQuery("/auth/csrf", api, extraOptions);
+    result = await csrfAwareQuery(args, api, extraOptions);
+  }
 
   // If we get a 401, try to refresh the token
   if (result.error && result.error.status === 401) {
     // Try to refresh the token (cookie is sent automatically)
-    const refreshResult = await rawBaseQuery(
+    const refreshResult = await csrfAwareQuery(
       {
         url: "/auth/refresh",
         method: "POST",
@@ -40,7 +76,7 @@ export const baseQuery: BaseQueryFn<
     if (refreshResult.data) {
       // Refresh succeeded - new cookies are set automatically by backend
       // Retry the original request
-      result = await rawBaseQuery(args, api, extraOptions);
+      result = await csrfAwareQuery(args, api, extraOptions);
     } else {
       // Refresh failed - logout user
       api.dispatch(logout());